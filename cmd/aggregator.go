@@ -1,24 +1,29 @@
 package cmd
 
 import (
-	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 
-	"github.com/go-gota/gota/dataframe"
 	"github.com/spf13/cobra"
 
+	"github.com/redhatinsights/ros-ocp-backend/internal/services"
 	"github.com/redhatinsights/ros-ocp-backend/internal/types"
 	"github.com/redhatinsights/ros-ocp-backend/internal/utils"
 )
 
 var (
 	outputDir     string
+	emit          string
+	emitMsg       types.KafkaMsg
 	aggregatorCmd = &cobra.Command{
 		Use:   "aggregator [input csv file path]",
 		Short: "aggregates CSV data",
-		Args:  cobra.ExactArgs(1),
+		Long: `Aggregates a CSV the way the processor does and writes it to output.csv.
+With --emit, writes the Kruize request bodies the processor would send for each experiment
+to <emit>.json instead, without calling Kruize.`,
+		Args: cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			input_file := args[0]
 			if _, err := os.Stat(input_file); os.IsNotExist(err) {
@@ -35,7 +40,7 @@ var (
 				outputDir, _ = os.Getwd()
 			}
 			outputFile := outputDir + "/output.csv"
-			f, err := os.Open(filepath.Clean(input_file))
+			f, err := utils.OpenCSVFile(input_file)
 			if err != nil {
 				panic(err.Error())
 			}
@@ -43,18 +48,28 @@ var (
 				_ = f.Close()
 			}()
 
-			csv := csv.NewReader(f)
-			records, err := csv.ReadAll()
-			if err != nil {
-				panic(err.Error())
-			}
 			csvType := utils.DetermineCSVType(input_file)
-			columnHeaders := types.GetColumnMapping(csvType)
-			df := dataframe.LoadRecords(records, dataframe.WithTypes(columnHeaders))
-			df, err = utils.Aggregate_data(csvType, df)
+			df, err := utils.StreamAggregate(csvType, f)
 			if err != nil {
 				panic(err.Error())
 			}
+			if emit != "" {
+				payloads, err := services.BuildKruizePayloads(emit, csvType, df, emitMsg)
+				if err != nil {
+					fmt.Printf("Unable to build %s payloads: %v\n", emit, err)
+					os.Exit(1)
+				}
+				content, err := json.MarshalIndent(payloads, "", "  ")
+				if err != nil {
+					panic(err.Error())
+				}
+				payloadFile := filepath.Join(outputDir, emit+".json")
+				if err := os.WriteFile(payloadFile, content, 0600); err != nil {
+					panic(err.Error())
+				}
+				fmt.Printf("%s payloads of %d experiments created at: %s \n", emit, len(payloads), payloadFile)
+				return
+			}
 			fileio, err := os.Create(filepath.Clean(outputFile))
 			if err != nil {
 				panic(err.Error())
@@ -71,5 +86,10 @@ var (
 
 func init() {
 	aggregatorCmd.PersistentFlags().StringVarP(&outputDir, "output-dir", "o", "", "Path to output directory")
+	aggregatorCmd.Flags().StringVar(&emit, "emit", "", "writes Kruize payloads instead of the aggregated CSV (kruize-create, kruize-update, namespace)")
+	aggregatorCmd.Flags().StringVar(&emitMsg.Metadata.Org_id, "org-id", "", "org ID of the experiment names of --emit")
+	aggregatorCmd.Flags().StringVar(&emitMsg.Metadata.Source_id, "source-id", "", "source ID of the experiment names of --emit")
+	aggregatorCmd.Flags().StringVar(&emitMsg.Metadata.Cluster_uuid, "cluster-uuid", "", "cluster UUID of the experiment names of --emit")
+	aggregatorCmd.MarkFlagsRequiredTogether("emit", "org-id", "source-id", "cluster-uuid")
 	rootCmd.AddCommand(aggregatorCmd)
 }
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/services"
+)
+
+var dlqCmd = &cobra.Command{Use: "dlq", Short: "Manage upload messages in the dead-letter topic"}
+
+var dlqFilter services.DeadLetterFilter
+var dlqDryRun bool
+
+var dlqReplayCmd = &cobra.Command{
+	Use:   "replay",
+	Short: "Re-feed dead-lettered upload messages to the processor",
+	Long: `Re-feed dead-lettered upload messages to the upload topic so the processor handles them again.
+Messages are selected by request ID, failed stage or partition:offset in the dead-letter topic.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		replayed, err := services.ReplayDeadLetters(dlqFilter, dlqDryRun)
+		if err != nil {
+			fmt.Printf("Unable to replay dead letters: %v\n", err)
+			os.Exit(1)
+		}
+		if dlqDryRun {
+			fmt.Printf("%d dead letters would be replayed\n", replayed)
+			return
+		}
+		fmt.Printf("Replayed %d dead letters\n", replayed)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(dlqCmd)
+	dlqCmd.AddCommand(dlqReplayCmd)
+	dlqReplayCmd.Flags().StringArrayVar(&dlqFilter.RequestIDs, "request-id", nil, "replays the messages of an upload request")
+	dlqReplayCmd.Flags().StringArrayVar(&dlqFilter.Stages, "stage", nil, "replays the messages that failed at a stage (decode, validate, fetch_csv, parse_csv, database, kruize, produce)")
+	dlqReplayCmd.Flags().StringArrayVar(&dlqFilter.Offsets, "offset", nil, "replays the message at partition:offset of the dead-letter topic")
+	dlqReplayCmd.Flags().BoolVar(&dlqFilter.All, "all", false, "replays every message of the dead-letter topic")
+	dlqReplayCmd.Flags().BoolVar(&dlqDryRun, "dry-run", false, "lists the selected messages without replaying them")
+	dlqReplayCmd.MarkFlagsOneRequired("request-id", "stage", "offset", "all")
+}
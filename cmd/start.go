@@ -1,7 +1,12 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -11,19 +16,37 @@ import (
 	"github.com/redhatinsights/ros-ocp-backend/internal/services"
 	"github.com/redhatinsights/ros-ocp-backend/internal/services/housekeeper"
 	"github.com/redhatinsights/ros-ocp-backend/internal/utils"
+	"github.com/redhatinsights/ros-ocp-backend/internal/utils/kruize"
 )
 
 var startCmd = &cobra.Command{Use: "start", Short: "Use to start ros-ocp-backend services"}
 
+// shutdownContext is done once the service is asked to stop by SIGINT or SIGTERM.
+func shutdownContext(cmd *cobra.Command) (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+}
+
+// exitOnError exits with a non-zero status when a service stopped because of err.
+// Services stopped by a signal return nil and the command exits with 0.
+func exitOnError(service string, err error) {
+	if err != nil {
+		fmt.Printf("%s stopped: %v\n", service, err)
+		os.Exit(1)
+	}
+	fmt.Printf("%s stopped\n", service)
+}
+
 var processorCmd = &cobra.Command{
 	Use:   "processor",
 	Short: "starts ros-ocp processor",
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Println("starting ros-ocp processor")
+		ctx, stop := shutdownContext(cmd)
+		defer stop()
 		cfg := config.GetConfig()
 		go utils.Start_prometheus_server()
-		utils.SetupKruizePerformanceProfile()
-		kafka.StartConsumer(cfg.UploadTopic, services.ProcessReport)
+		kruize.NewDefaultKruizeClient().SetupPerformanceProfile()
+		exitOnError("ros-ocp processor", kafka.StartConsumerWithBackpressure(ctx, cfg.UploadTopic, services.ProcessReport, cfg.ProcessorWorkers, services.KruizeBackpressure()))
 	},
 }
 
@@ -32,9 +55,21 @@ var recommendationPollerCmd = &cobra.Command{
 	Short: "starts ros-ocp recommendation-poller",
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Println("starting ros-ocp recommendation-poller")
+		ctx, stop := shutdownContext(cmd)
+		defer stop()
 		cfg := config.GetConfig()
 		go utils.Start_prometheus_server()
-		kafka.StartConsumer(cfg.RecommendationTopic, services.PollForRecommendations, false)
+		go services.ReleaseRecommendationRetries(ctx)
+		services.StartWebhookDeliveries()
+		err := kafka.StartConsumerWithBackpressure(ctx, cfg.RecommendationTopic, services.PollForRecommendations, 1, services.KruizeBackpressure(), false)
+		if err == nil {
+			waitCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownTimeoutSecs)*time.Second)
+			defer cancel()
+			if waitErr := services.WaitForWebhookDeliveries(waitCtx); waitErr != nil {
+				err = fmt.Errorf("webhook deliveries still running: %w", waitErr)
+			}
+		}
+		exitOnError("ros-ocp recommendation-poller", err)
 	},
 }
 
@@ -43,7 +78,9 @@ var apiCmd = &cobra.Command{
 	Short: "starts ros-ocp api server",
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Println("Starting ros-ocp API server")
-		api.StartAPIServer()
+		ctx, stop := shutdownContext(cmd)
+		defer stop()
+		exitOnError("ros-ocp API server", api.StartAPIServer(ctx))
 	},
 }
 
@@ -54,16 +91,36 @@ var houseKeeperCmd = &cobra.Command{
 		fmt.Println("starting ros-ocp housekeeper service")
 		sourcesFlag, _ := cmd.Flags().GetBool("sources")
 		partitionFlag, _ := cmd.Flags().GetBool("partitions")
+		reconcileFlag, _ := cmd.Flags().GetBool("reconcile")
+		recreateFlag, _ := cmd.Flags().GetBool("recreate")
+		staleFlag, _ := cmd.Flags().GetBool("stale")
+		if recreateFlag && !reconcileFlag {
+			exitOnError("ros-ocp housekeeper", fmt.Errorf("--recreate is only used with --reconcile"))
+		}
 		if sourcesFlag {
-			housekeeper.StartSourcesListenerService()
+			ctx, stop := shutdownContext(cmd)
+			defer stop()
+			exitOnError("ros-ocp housekeeper", housekeeper.StartSourcesListenerService(ctx))
 		}
 		if partitionFlag {
 			housekeeper.DeletePartitions()
 		}
+		if reconcileFlag {
+			ctx, stop := shutdownContext(cmd)
+			defer stop()
+			_, err := housekeeper.ReconcileExperiments(ctx, recreateFlag)
+			exitOnError("ros-ocp housekeeper", err)
+		}
+		if staleFlag {
+			ctx, stop := shutdownContext(cmd)
+			defer stop()
+			_, err := housekeeper.CleanStaleWorkloads(ctx)
+			exitOnError("ros-ocp housekeeper", err)
+		}
 	},
 }
 
-var sources, partitions bool
+var sources, partitions, reconcile, recreate, stale bool
 
 func init() {
 	rootCmd.AddCommand(startCmd)
@@ -74,6 +131,9 @@ func init() {
 
 	houseKeeperCmd.Flags().BoolVar(&sources, "sources", false, "starts sources listener service")
 	houseKeeperCmd.Flags().BoolVar(&partitions, "partitions", false, "deletes older partitions")
-	houseKeeperCmd.MarkFlagsOneRequired("sources", "partitions")
-	houseKeeperCmd.MarkFlagsMutuallyExclusive("sources", "partitions")
+	houseKeeperCmd.Flags().BoolVar(&reconcile, "reconcile", false, "deletes Kruize experiments without a workload and reports workloads without an experiment")
+	houseKeeperCmd.Flags().BoolVar(&recreate, "recreate", false, "with --reconcile, recreates missing experiments from the latest metrics")
+	houseKeeperCmd.Flags().BoolVar(&stale, "stale", false, "marks workloads that stopped reporting stale and deletes the ones stale for long")
+	houseKeeperCmd.MarkFlagsOneRequired("sources", "partitions", "reconcile", "stale")
+	houseKeeperCmd.MarkFlagsMutuallyExclusive("sources", "partitions", "reconcile", "stale")
 }
@@ -36,6 +36,8 @@ func TestMapQueryParameters(t *testing.T) {
 			qoutputs: map[string]interface{}{
 				"recommendation_sets.monitoring_end_time < ?":  now,
 				"recommendation_sets.monitoring_end_time >= ?": firstOfMonth,
+				statusFilterClause(2):                          defaultRecommendationStatuses,
+				staleWorkloadClause:                            nil,
 			},
 			errmsg: `The startTime should be 1st of current month. The endTime should the current time.`,
 		},
@@ -45,6 +47,8 @@ func TestMapQueryParameters(t *testing.T) {
 			qoutputs: map[string]interface{}{
 				"recommendation_sets.monitoring_end_time < ?":  inclusiveEndTime,
 				"recommendation_sets.monitoring_end_time >= ?": startTime,
+				statusFilterClause(2):                          defaultRecommendationStatuses,
+				staleWorkloadClause:                            nil,
 			},
 			errmsg: `The recommendation_sets.monitoring_end_time should be less than or equal to end date!
 				The recommendation_sets.monitoring_end_time should be greater than or equal to start date!`,
@@ -79,6 +83,149 @@ func TestMapQueryParameters(t *testing.T) {
 	}
 }
 
+func TestMapQueryParametersStatusFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []string
+		want     []string
+		wantErr  bool
+	}{
+		{name: "hides dismissed and snoozed by default", want: defaultRecommendationStatuses},
+		{name: "single status", statuses: []string{"dismissed"}, want: []string{"dismissed"}},
+		{name: "several statuses", statuses: []string{"open", "snoozed"}, want: []string{"open", "snoozed"}},
+		{name: "all disables the filter", statuses: []string{"all"}},
+		{name: "invalid status", statuses: []string{"ignored"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+			for _, status := range tt.statuses {
+				c.QueryParams().Add("status", status)
+			}
+
+			for _, mapper := range []func(echo.Context) (map[string]any, error){MapQueryParameters, MapNamespaceQueryParameters} {
+				got, err := mapper(c)
+				if tt.wantErr {
+					assert.ErrorContains(t, err, "invalid status")
+					continue
+				}
+				assert.NoError(t, err)
+				var statusFilters []any
+				for key, value := range got {
+					if strings.Contains(key, "recommendation_statuses.status") {
+						statusFilters = append(statusFilters, value)
+						assert.Equal(t, statusFilterClause(len(tt.want)), key)
+					}
+				}
+				if tt.want == nil {
+					assert.Empty(t, statusFilters)
+				} else {
+					assert.Equal(t, []any{tt.want}, statusFilters)
+				}
+			}
+		})
+	}
+}
+
+func TestMapQueryParametersStaleFilter(t *testing.T) {
+	tests := []struct {
+		name         string
+		includeStale string
+		wantFilter   bool
+		wantErr      bool
+	}{
+		{name: "hides stale workloads by default", wantFilter: true},
+		{name: "include_stale=false", includeStale: "false", wantFilter: true},
+		{name: "include_stale=true disables the filter", includeStale: "true"},
+		{name: "invalid include_stale", includeStale: "sometimes", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+			if tt.includeStale != "" {
+				c.QueryParams().Add("include_stale", tt.includeStale)
+			}
+
+			for _, mapper := range []func(echo.Context) (map[string]any, error){MapQueryParameters, MapNamespaceQueryParameters} {
+				got, err := mapper(c)
+				if tt.wantErr {
+					assert.ErrorContains(t, err, "invalid include_stale")
+					continue
+				}
+				assert.NoError(t, err)
+				_, ok := got[staleWorkloadClause]
+				assert.Equal(t, tt.wantFilter, ok)
+			}
+		})
+	}
+}
+
+func TestMapHistoryQueryParameters(t *testing.T) {
+	column := "historical_recommendation_sets.monitoring_end_time"
+	startTime := time.Date(2023, 3, 23, 0, 0, 0, 0, time.UTC)
+	endTime := time.Date(2023, 3, 24, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		qinputs map[string]string
+		want    map[string]any
+		wantErr bool
+	}{
+		{
+			name:    "explicit range is inclusive of end_date",
+			qinputs: map[string]string{"start_date": "2023-03-23", "end_date": "2023-03-24"},
+			want: map[string]any{
+				column + " >= ?": startTime,
+				column + " < ?":  endTime.Add(24 * time.Hour),
+			},
+		},
+		{
+			name:    "invalid start_date",
+			qinputs: map[string]string{"start_date": "23-03-2023"},
+			wantErr: true,
+		},
+		{
+			name:    "end_date before start_date",
+			qinputs: map[string]string{"start_date": "2023-03-24", "end_date": "2023-03-20"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			c := e.NewContext(req, httptest.NewRecorder())
+			for k, v := range tt.qinputs {
+				c.QueryParams().Add(k, v)
+			}
+
+			got, err := MapHistoryQueryParameters(c, column)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("defaults to the retention period", func(t *testing.T) {
+		e := echo.New()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+
+		got, err := MapHistoryQueryParameters(c, column)
+		assert.NoError(t, err)
+		start := got[column+" >= ?"].(time.Time)
+		end := got[column+" < ?"].(time.Time)
+		assert.Equal(t, end.AddDate(0, 0, -cfg.DataRetentionPeriod), start)
+	})
+}
+
 func TestMapQueryParametersFilterClauses(t *testing.T) {
 	containerCol := "recommendation_sets.container_name"
 	workloadCol := "workloads.workload_name"
@@ -304,6 +451,8 @@ var FlattenedCSVHeaderFixture = []string{
 	"variation_cpu_request_format",
 	"variation_memory_request_amount",
 	"variation_memory_request_format",
+	"cost_monthly_delta_amount",
+	"cost_monthly_delta_currency",
 }
 
 func TestFlattenedCSVHeader(t *testing.T) {
@@ -1,6 +1,10 @@
 package api
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/model"
+)
 
 const timeLayout = "2006-01-02"
 
@@ -51,6 +55,28 @@ func validateWorkloadTypeValues(vals []string) error {
 	return nil
 }
 
+// RecommendationStatusAll disables the default status filter of list endpoints.
+const RecommendationStatusAll = "all"
+
+// defaultRecommendationStatuses are listed when no status param is given.
+var defaultRecommendationStatuses = []string{model.RecommendationStatusOpen, model.RecommendationStatusAccepted}
+
+var validRecommendationStatuses = map[string]bool{
+	model.RecommendationStatusOpen:      true,
+	model.RecommendationStatusAccepted:  true,
+	model.RecommendationStatusDismissed: true,
+	model.RecommendationStatusSnoozed:   true,
+}
+
+func validateRecommendationStatusValues(vals []string) error {
+	for _, v := range vals {
+		if !validRecommendationStatuses[v] {
+			return namespaceAPIErrf(EnableUserAPIErr, "invalid status %q, must be one of: all, open, accepted, dismissed, snoozed", v)
+		}
+	}
+	return nil
+}
+
 // FilterModeClause maps mode to SQL clause suffix, wrap for include, and join for multi-value params.
 var FilterModeClause = map[string]struct {
 	Suffix string
@@ -138,4 +164,46 @@ var FlattenedCSVHeader = []string{
 	"variation_cpu_request_format",
 	"variation_memory_request_amount",
 	"variation_memory_request_format",
+	"cost_monthly_delta_amount",
+	"cost_monthly_delta_currency",
+}
+
+var FlattenedNamespaceCSVHeader = []string{
+	"id",
+	"cluster_uuid",
+	"cluster_alias",
+	"project",
+	"last_reported",
+	"source_id",
+	"current_cpu_limit_amount",
+	"current_cpu_limit_format",
+	"current_memory_limit_amount",
+	"current_memory_limit_format",
+	"current_cpu_request_amount",
+	"current_cpu_request_format",
+	"current_memory_request_amount",
+	"current_memory_request_format",
+	"monitoring_end_time",
+	"recommendation_term",
+	"duration_in_hours",
+	"monitoring_start_time",
+	"recommendation_type",
+	"config_cpu_limit_amount",
+	"config_cpu_limit_format",
+	"config_memory_limit_amount",
+	"config_memory_limit_format",
+	"config_cpu_request_amount",
+	"config_cpu_request_format",
+	"config_memory_request_amount",
+	"config_memory_request_format",
+	"variation_cpu_limit_amount",
+	"variation_cpu_limit_format",
+	"variation_memory_limit_amount",
+	"variation_memory_limit_format",
+	"variation_cpu_request_amount",
+	"variation_cpu_request_format",
+	"variation_memory_request_amount",
+	"variation_memory_request_format",
+	"cost_monthly_delta_amount",
+	"cost_monthly_delta_currency",
 }
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+
+	"gorm.io/datatypes"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/model"
+	"github.com/redhatinsights/ros-ocp-backend/internal/types/kruizePayload"
+)
+
+const (
+	// hoursPerMonth is the average number of hours in a month (8760 / 12).
+	hoursPerMonth = 730
+	bytesPerGiB   = 1024 * 1024 * 1024
+
+	defaultCurrency = "USD"
+	// maxCostRate is the largest rate fitting the NUMERIC(12, 6) cost_rates columns.
+	maxCostRate = 999999.999999
+)
+
+var currencyCodeRegex = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// costRateRequest is the body accepted by PUT /cost-rates. Rates are pointers so a
+// missing rate is rejected instead of silently stored as zero.
+type costRateRequest struct {
+	ClusterUUID       string   `json:"cluster_uuid"`
+	CPUCoreHourRate   *float64 `json:"cpu_core_hour_rate"`
+	MemoryGiBHourRate *float64 `json:"memory_gib_hour_rate"`
+	Currency          string   `json:"currency"`
+}
+
+// validate checks the request and returns the rate card entry to store for orgID.
+func (r costRateRequest) validate(orgID string) (model.CostRate, error) {
+	costRate := model.CostRate{OrgID: orgID, Currency: r.Currency}
+	if r.ClusterUUID != "" {
+		clusterUUID, err := sanitizeParamValue("cluster_uuid", r.ClusterUUID, model.ClusterMaxLen, true, false)
+		if err != nil {
+			return costRate, err
+		}
+		costRate.ClusterUUID = clusterUUID
+	}
+	if r.CPUCoreHourRate == nil || r.MemoryGiBHourRate == nil {
+		return costRate, fmt.Errorf("cpu_core_hour_rate and memory_gib_hour_rate are required")
+	}
+	rates := []struct {
+		name  string
+		value float64
+	}{
+		{"cpu_core_hour_rate", *r.CPUCoreHourRate},
+		{"memory_gib_hour_rate", *r.MemoryGiBHourRate},
+	}
+	for _, rate := range rates {
+		if rate.value < 0 || rate.value > maxCostRate || math.IsNaN(rate.value) {
+			return costRate, fmt.Errorf("%s must be between 0 and %v", rate.name, maxCostRate)
+		}
+	}
+	costRate.CPUCoreHourRate = *r.CPUCoreHourRate
+	costRate.MemoryGiBHourRate = *r.MemoryGiBHourRate
+	if costRate.Currency == "" {
+		costRate.Currency = defaultCurrency
+	}
+	if !currencyCodeRegex.MatchString(costRate.Currency) {
+		return costRate, fmt.Errorf("currency must be a three letter ISO 4217 code")
+	}
+	return costRate, nil
+}
+
+// costRateCard holds the rates of an org keyed by cluster UUID; the "" key is the org-wide rate.
+type costRateCard map[string]model.CostRate
+
+// loadCostRateCard fetches the rate card of an org, as far as user_permissions cover it. Cost
+// estimates are an addition to the recommendation, so a failure is logged and the response is
+// served without them.
+func loadCostRateCard(orgID string, user_permissions map[string][]string) costRateCard {
+	costRates, err := model.GetCostRates(orgID, user_permissions)
+	if err != nil {
+		log.Warnf("unable to fetch cost rates, skipping cost estimates; %v", err)
+		return nil
+	}
+	rateCard := make(costRateCard, len(costRates))
+	for _, costRate := range costRates {
+		rateCard[costRate.ClusterUUID] = costRate
+	}
+	return rateCard
+}
+
+// rateFor returns the cluster rate, falling back to the org-wide rate.
+func (r costRateCard) rateFor(clusterUUID string) (model.CostRate, bool) {
+	if costRate, ok := r[clusterUUID]; ok {
+		return costRate, true
+	}
+	costRate, ok := r[""]
+	return costRate, ok
+}
+
+// monthlyCostDelta estimates how much the monthly cost of the requests changes when moving
+// from current to recommended. Negative values are savings. Returns false when the
+// recommendation has no requests.
+func monthlyCostDelta(current, recommended kruizePayload.ConfigObject, costRate model.CostRate) (float64, bool) {
+	var hourlyDelta float64
+	var ok bool
+	if recommended.Requests.Cpu.Amount > 0 {
+		hourlyDelta += (recommended.Requests.Cpu.Amount - current.Requests.Cpu.Amount) * costRate.CPUCoreHourRate
+		ok = true
+	}
+	if recommended.Requests.Memory.Amount > 0 {
+		hourlyDelta += (recommended.Requests.Memory.Amount - current.Requests.Memory.Amount) / bytesPerGiB * costRate.MemoryGiBHourRate
+		ok = true
+	}
+	return math.Round(hourlyDelta*hoursPerMonth*100) / 100, ok
+}
+
+// injectCostEstimates adds a cost_estimate object to every engine of the API-ready recommendation
+// data. Amounts are computed from jsonData, the raw stored recommendation (cores and bytes),
+// so they do not depend on the units requested by the user.
+func injectCostEstimates(data map[string]interface{}, jsonData datatypes.JSON, rateCard costRateCard, clusterUUID string) map[string]interface{} {
+	costRate, ok := rateCard.rateFor(clusterUUID)
+	if !ok || data == nil {
+		return data
+	}
+	var raw kruizePayload.RecommendationData
+	if err := json.Unmarshal(jsonData, &raw); err != nil {
+		log.Errorf("unable to unmarshal recommendation for cost estimate; %v", err)
+		return data
+	}
+
+	terms, ok := data["recommendation_terms"].(map[string]interface{})
+	if !ok {
+		return data
+	}
+	for _, spec := range model.StoredVariationSpecs {
+		rawTerm := termRecommendation(raw, spec.Term)
+		if rawTerm.RecommendationEngines == nil {
+			continue
+		}
+		intervalData, ok := terms[spec.Term].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		engines, ok := intervalData["recommendation_engines"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		engine, ok := engines[spec.Engine].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		recommended := rawTerm.RecommendationEngines.Cost.Config
+		if spec.Engine == KruizeEnginePerformance {
+			recommended = rawTerm.RecommendationEngines.Performance.Config
+		}
+		delta, ok := monthlyCostDelta(raw.Current, recommended, costRate)
+		if !ok {
+			continue
+		}
+		engine["cost_estimate"] = map[string]interface{}{
+			"monthly_delta": delta,
+			"currency":      costRate.Currency,
+		}
+	}
+	return data
+}
+
+// costEstimateColumns returns the CSV cost columns of a term and engine, empty when no
+// estimate was injected.
+func costEstimateColumns(recommendationsJSON map[string]interface{}, term, engine string) []string {
+	columns := []string{"", ""}
+	terms, _ := recommendationsJSON["recommendation_terms"].(map[string]interface{})
+	intervalData, _ := terms[term].(map[string]interface{})
+	engines, _ := intervalData["recommendation_engines"].(map[string]interface{})
+	engineData, _ := engines[engine].(map[string]interface{})
+	estimate, ok := engineData["cost_estimate"].(map[string]interface{})
+	if !ok {
+		return columns
+	}
+	if delta, ok := estimate["monthly_delta"].(float64); ok {
+		columns[0] = strconv.FormatFloat(delta, 'f', -1, 64)
+	}
+	if currency, ok := estimate["currency"].(string); ok {
+		columns[1] = currency
+	}
+	return columns
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/datatypes"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/model"
+	"github.com/redhatinsights/ros-ocp-backend/internal/types/kruizePayload"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func TestCostRateRequestValidate(t *testing.T) {
+	costRate, err := costRateRequest{CPUCoreHourRate: ptr(0.05), MemoryGiBHourRate: ptr(0.01)}.validate("org")
+	assert.NoError(t, err)
+	assert.Equal(t, model.CostRate{OrgID: "org", CPUCoreHourRate: 0.05, MemoryGiBHourRate: 0.01, Currency: "USD"}, costRate)
+
+	costRate, err = costRateRequest{ClusterUUID: "uuid-1", CPUCoreHourRate: ptr(0.0), MemoryGiBHourRate: ptr(2.5), Currency: "EUR"}.validate("org")
+	assert.NoError(t, err)
+	assert.Equal(t, "uuid-1", costRate.ClusterUUID)
+	assert.Equal(t, "EUR", costRate.Currency)
+
+	_, err = costRateRequest{CPUCoreHourRate: ptr(0.05), MemoryGiBHourRate: ptr(maxCostRate + 1)}.validate("org")
+	assert.ErrorContains(t, err, "memory_gib_hour_rate must be between")
+}
+
+func TestCostRateCardRateFor(t *testing.T) {
+	rateCard := costRateCard{
+		"":       {CPUCoreHourRate: 1},
+		"uuid-1": {ClusterUUID: "uuid-1", CPUCoreHourRate: 2},
+	}
+	costRate, ok := rateCard.rateFor("uuid-1")
+	assert.True(t, ok)
+	assert.Equal(t, 2.0, costRate.CPUCoreHourRate)
+
+	costRate, ok = rateCard.rateFor("uuid-2")
+	assert.True(t, ok)
+	assert.Equal(t, 1.0, costRate.CPUCoreHourRate, "falls back to the org-wide rate")
+
+	_, ok = costRateCard{"uuid-1": {}}.rateFor("uuid-2")
+	assert.False(t, ok)
+
+	_, ok = costRateCard(nil).rateFor("uuid-1")
+	assert.False(t, ok)
+}
+
+func TestMonthlyCostDelta(t *testing.T) {
+	var current, recommended kruizePayload.ConfigObject
+	current.Requests.Cpu.Amount = 2
+	current.Requests.Memory.Amount = 4 * bytesPerGiB
+	recommended.Requests.Cpu.Amount = 0.5
+	recommended.Requests.Memory.Amount = 1 * bytesPerGiB
+	costRate := model.CostRate{CPUCoreHourRate: 0.04, MemoryGiBHourRate: 0.005}
+
+	// (-1.5 cores * 0.04 + -3 GiB * 0.005) * 730 hours
+	delta, ok := monthlyCostDelta(current, recommended, costRate)
+	assert.True(t, ok)
+	assert.Equal(t, -54.75, delta)
+
+	_, ok = monthlyCostDelta(current, kruizePayload.ConfigObject{}, costRate)
+	assert.False(t, ok, "no estimate without recommended requests")
+}
+
+func TestInjectCostEstimates(t *testing.T) {
+	jsonData := datatypes.JSON(rawManifestRecommendationJSON)
+	rateCard := costRateCard{"": {CPUCoreHourRate: 0.04, MemoryGiBHourRate: 0.005, Currency: "USD"}}
+
+	data := UpdateRecommendationJSON("recommendationset", "id", "uuid-1", map[string]string{"cpu": "cores", "memory": "MiB"}, true, jsonData, nil)
+	data = injectCostEstimates(data, jsonData, rateCard, "uuid-1")
+
+	engines := data["recommendation_terms"].(map[string]interface{})[KruizeShortTerm].(map[string]interface{})["recommendation_engines"].(map[string]interface{})
+	// requests go from 1 core / 2 GiB to 0.2504 cores / 512 MiB + 1 byte
+	assert.Equal(t, map[string]interface{}{"monthly_delta": -27.36, "currency": "USD"}, engines[KruizeEngineCost].(map[string]interface{})["cost_estimate"])
+	assert.Equal(t, []string{"-27.36", "USD"}, costEstimateColumns(data, KruizeShortTerm, KruizeEngineCost))
+	assert.Equal(t, []string{"", ""}, costEstimateColumns(data, KruizeShortTerm, KruizeEnginePerformance))
+}
+
+func TestInjectCostEstimates_NoRate(t *testing.T) {
+	jsonData := datatypes.JSON(rawManifestRecommendationJSON)
+	data := UpdateRecommendationJSON("recommendationset", "id", "uuid-1", map[string]string{"cpu": "cores", "memory": "MiB"}, true, jsonData, nil)
+	data = injectCostEstimates(data, jsonData, costRateCard{"uuid-2": {CPUCoreHourRate: 1}}, "uuid-1")
+	assert.Equal(t, []string{"", ""}, costEstimateColumns(data, KruizeShortTerm, KruizeEngineCost))
+}
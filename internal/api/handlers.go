@@ -1,18 +1,25 @@
 package api
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
 
 	"github.com/redhatinsights/platform-go-middlewares/identity"
 	"github.com/redhatinsights/ros-ocp-backend/internal/api/listoptions"
 	"github.com/redhatinsights/ros-ocp-backend/internal/model"
+	"github.com/redhatinsights/ros-ocp-backend/internal/rbac"
+	"github.com/redhatinsights/ros-ocp-backend/internal/utils/webhooks"
 )
 
 func GetRecommendationSetList(c echo.Context) error {
@@ -49,6 +56,7 @@ func GetRecommendationSetList(c echo.Context) error {
 		})
 	}
 
+	rateCard := loadCostRateCard(OrgID, user_permissions)
 	for i := range recommendationSets {
 		recommendationSets[i].RecommendationsJSON = UpdateRecommendationJSON(
 			handlerName,
@@ -59,6 +67,12 @@ func GetRecommendationSetList(c echo.Context) error {
 			recommendationSets[i].Recommendations,
 			&recommendationSets[i].StoredVariationPcts,
 		)
+		recommendationSets[i].RecommendationsJSON = injectCostEstimates(
+			recommendationSets[i].RecommendationsJSON,
+			recommendationSets[i].Recommendations,
+			rateCard,
+			recommendationSets[i].ClusterUUID,
+		)
 	}
 
 	switch apiListOptions.Format {
@@ -112,6 +126,15 @@ func GetRecommendationSet(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, echo.Map{"status": "error", "message": unitParseErr.Error()})
 	}
 
+	format := strings.ToLower(c.QueryParam("format"))
+	var term, engine string
+	if format == ResponseFormatYAMLPatch {
+		term, engine, err = ParseTermEngineParams(c)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"status": "error", "message": err.Error()})
+		}
+	}
+
 	recommendationSetVar := model.RecommendationSet{}
 	recommendationSet, error := recommendationSetVar.GetRecommendationSetByID(OrgID, RecommendationUUID.String(), user_permissions)
 
@@ -121,6 +144,21 @@ func GetRecommendationSet(c echo.Context) error {
 	}
 
 	if len(recommendationSet.Recommendations) != 0 {
+		if format == ResponseFormatYAMLPatch {
+			patch, patchErr := ContainerRecommendationPatch(
+				recommendationSet.WorkloadType,
+				recommendationSet.Workload,
+				recommendationSet.Project,
+				recommendationSet.Container,
+				recommendationSet.Recommendations,
+				term,
+				engine,
+			)
+			if patchErr != nil {
+				return c.JSON(http.StatusUnprocessableEntity, echo.Map{"status": "error", "message": patchErr.Error()})
+			}
+			return c.Blob(http.StatusOK, mimeApplicationYAML, patch)
+		}
 		recommendationSet.RecommendationsJSON = UpdateRecommendationJSON(
 			handlerName,
 			recommendationSet.ID,
@@ -130,12 +168,148 @@ func GetRecommendationSet(c echo.Context) error {
 			recommendationSet.Recommendations,
 			&recommendationSet.StoredVariationPcts,
 		)
+		recommendationSet.RecommendationsJSON = injectCostEstimates(
+			recommendationSet.RecommendationsJSON,
+			recommendationSet.Recommendations,
+			loadCostRateCard(OrgID, user_permissions),
+			recommendationSet.ClusterUUID,
+		)
 		return c.JSON(http.StatusOK, recommendationSet)
 	} else {
 		return c.JSON(http.StatusNotFound, echo.Map{"status": "not_found", "message": "recommendation not found"})
 	}
 }
 
+func GetRecommendationSetKustomizeBundle(c echo.Context) error {
+	XRHID := c.Get("Identity").(identity.XRHID)
+	OrgID := XRHID.Identity.OrgID
+	user_permissions := get_user_permissions(c)
+
+	apiListOptions, err := listoptions.ListAPIOptions(c, listoptions.DefaultContainerRecsDBColumn, listoptions.ContainerAllowedOrderBy)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"status": "error", "message": err.Error()})
+	}
+	// a bundle holds as many recommendations as a CSV export; offset pages through the rest
+	apiListOptions.Limit = cfg.RecordLimitCSV
+
+	queryParams, err := MapQueryParameters(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"status": "error", "message": err.Error()})
+	}
+
+	term, engine, err := ParseTermEngineParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"status": "error", "message": err.Error()})
+	}
+
+	recommendationSet := model.RecommendationSet{}
+	recommendationSets, count, queryErr := recommendationSet.GetRecommendationSets(OrgID, apiListOptions, queryParams, user_permissions)
+	if queryErr != nil {
+		log.Errorf("unable to fetch records from database; %v", queryErr)
+		return c.JSON(http.StatusServiceUnavailable, echo.Map{
+			"status":  "error",
+			"message": "unable to fetch records from database",
+		})
+	}
+
+	var bundle bytes.Buffer
+	if err := WriteKustomizeBundle(&bundle, recommendationSets, term, engine); err != nil {
+		log.Errorf("unable to build kustomize bundle; %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"status": "error", "message": "unable to build kustomize bundle"})
+	}
+
+	filename := fmt.Sprintf("recommendations-kustomize-%s-%s-%s", term, engine, time.Now().Format("20060102"))
+	c.Response().Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.tar.gz", filename))
+	c.Response().Header().Set("X-Total-Count", strconv.Itoa(count))
+	return c.Blob(http.StatusOK, "application/gzip", bundle.Bytes())
+}
+
+func GetRecommendationSummary(c echo.Context) error {
+	XRHID := c.Get("Identity").(identity.XRHID)
+	OrgID := XRHID.Identity.OrgID
+	user_permissions := get_user_permissions(c)
+
+	queryParams, err := MapQueryParameters(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"status": "error", "message": err.Error()})
+	}
+
+	unitChoices, _, unitParseErr := ParseUnitParams(c, "cores", "bytes")
+	if unitParseErr != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"status": "error", "message": unitParseErr.Error()})
+	}
+
+	summary, queryErr := getRecommendationSummaryResponse(OrgID, queryParams, user_permissions, unitChoices)
+	if queryErr != nil {
+		log.Errorf("unable to fetch records from database; %v", queryErr)
+		return c.JSON(http.StatusServiceUnavailable, echo.Map{
+			"status":  "error",
+			"message": "unable to fetch records from database",
+		})
+	}
+	return c.JSON(http.StatusOK, summary)
+}
+
+func GetRecommendationSetHistory(c echo.Context) error {
+	XRHID := c.Get("Identity").(identity.XRHID)
+	OrgID := XRHID.Identity.OrgID
+	user_permissions := get_user_permissions(c)
+	handlerName := "recommendationset-history"
+
+	RecommendationIDStr := c.Param("recommendation-id")
+	RecommendationUUID, err := uuid.Parse(RecommendationIDStr)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"status": "error", "message": "bad recommendation_id"})
+	}
+
+	apiListOptions, err := listoptions.ListAPIOptions(c, listoptions.DefaultContainerHistoryDBColumn, listoptions.ContainerHistoryAllowedOrderBy)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"status": "error", "message": err.Error()})
+	}
+	if apiListOptions.Format != listoptions.ResponseFormatJSON {
+		return c.JSON(http.StatusBadRequest, echo.Map{"status": "error", "message": "recommendation history is only available as application/json"})
+	}
+
+	queryParams, err := MapHistoryQueryParameters(c, "historical_recommendation_sets.monitoring_end_time")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"status": "error", "message": err.Error()})
+	}
+
+	unitChoices, setk8sUnits, unitParseErr := ParseUnitParams(c, "cores", "bytes")
+	if unitParseErr != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"status": "error", "message": unitParseErr.Error()})
+	}
+
+	historicalRecommendationSet := model.HistoricalRecommendationSet{}
+	historicalSets, count, queryErr := historicalRecommendationSet.GetHistoricalRecommendationSets(
+		OrgID, RecommendationUUID.String(), apiListOptions, queryParams, user_permissions,
+	)
+	if queryErr != nil {
+		log.Errorf("unable to fetch history of recommendation %s; %v", RecommendationIDStr, queryErr)
+		return c.JSON(http.StatusServiceUnavailable, echo.Map{
+			"status":  "error",
+			"message": "unable to fetch records from database",
+		})
+	}
+
+	interfaceSlice := make([]any, len(historicalSets))
+	for i := range historicalSets {
+		// History rows have no stored variation columns; percentages are computed from the JSON.
+		historicalSets[i].RecommendationsJSON = UpdateRecommendationJSON(
+			handlerName,
+			historicalSets[i].RecommendationID,
+			historicalSets[i].ClusterUUID,
+			unitChoices,
+			setk8sUnits,
+			historicalSets[i].Recommendations,
+			nil,
+		)
+		interfaceSlice[i] = historicalSets[i]
+	}
+	results := CollectionResponse(interfaceSlice, c.Request(), count, apiListOptions.Limit, apiListOptions.Offset)
+	return c.JSON(http.StatusOK, results)
+}
+
 func GetNamespaceRecommendationSetList(c echo.Context) error {
 	XRHID := c.Get("Identity").(identity.XRHID)
 	OrgID := XRHID.Identity.OrgID
@@ -166,6 +340,7 @@ func GetNamespaceRecommendationSetList(c echo.Context) error {
 		return apiErrResponse(c, queryErr, http.StatusServiceUnavailable, "unable to fetch records from database")
 	}
 
+	rateCard := loadCostRateCard(OrgID, user_permissions)
 	for i := range namespaceRecommendationSets {
 		namespaceRecommendationSets[i].RecommendationsJSON = UpdateRecommendationJSON(
 			handlerName,
@@ -176,6 +351,12 @@ func GetNamespaceRecommendationSetList(c echo.Context) error {
 			namespaceRecommendationSets[i].Recommendations,
 			&namespaceRecommendationSets[i].StoredVariationPcts,
 		)
+		namespaceRecommendationSets[i].RecommendationsJSON = injectCostEstimates(
+			namespaceRecommendationSets[i].RecommendationsJSON,
+			namespaceRecommendationSets[i].Recommendations,
+			rateCard,
+			namespaceRecommendationSets[i].ClusterUUID,
+		)
 	}
 
 	switch apiListOptions.Format {
@@ -187,9 +368,27 @@ func GetNamespaceRecommendationSetList(c echo.Context) error {
 		results := CollectionResponse(interfaceSlice, c.Request(), count, apiListOptions.Limit, apiListOptions.Offset)
 		return c.JSON(http.StatusOK, results)
 	case listoptions.ResponseFormatCSV:
-		// TODO: Add CSV support when export feature is enabled
-		csvErr := errors.New("CSV format is not supported. Please use application/json")
-		return apiErrResponse(c, csvErr, http.StatusNotAcceptable, csvErr.Error())
+		filename := "project-recommendations-" + time.Now().Format("20060102")
+		c.Response().Header().Set(echo.HeaderContentType, "text/csv")
+		c.Response().Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.csv", filename))
+		pipeReader, pipeWriter := io.Pipe()
+
+		go func() {
+			var generationErr error
+			defer func() {
+				if r := recover(); r != nil {
+					generationErr = fmt.Errorf("panic in CSV generation goroutine: %v", r)
+				}
+				if generationErr != nil {
+					_ = pipeWriter.CloseWithError(generationErr)
+					log.Errorf("error during CSV generation (recovered or returned): %v", generationErr)
+				} else {
+					_ = pipeWriter.Close() // graceful closure
+				}
+			}()
+			generationErr = GenerateAndStreamNamespaceCSV(pipeWriter, namespaceRecommendationSets)
+		}()
+		return c.Stream(http.StatusOK, "text/csv", pipeReader)
 	}
 	return nil
 
@@ -212,6 +411,15 @@ func GetNamespaceRecommendationSet(c echo.Context) error {
 		return apiErrResponse(c, unitParseErr, http.StatusBadRequest, unitParseErr.Error())
 	}
 
+	format := strings.ToLower(c.QueryParam("format"))
+	var term, engine string
+	if format == ResponseFormatYAMLPatch {
+		term, engine, err = ParseTermEngineParams(c)
+		if err != nil {
+			return apiErrResponse(c, err, http.StatusBadRequest, err.Error())
+		}
+	}
+
 	recommendationSetVar := model.NamespaceRecommendationSet{}
 	nsRecommendationSet, getNSRecordErr := recommendationSetVar.GetNamespaceRecommendationSetByID(
 		OrgID,
@@ -224,6 +432,13 @@ func GetNamespaceRecommendationSet(c echo.Context) error {
 	}
 
 	if len(nsRecommendationSet.Recommendations) != 0 {
+		if format == ResponseFormatYAMLPatch {
+			quota, quotaErr := NamespaceRecommendationQuota(nsRecommendationSet.Project, nsRecommendationSet.Recommendations, term, engine)
+			if quotaErr != nil {
+				return apiErrResponse(c, quotaErr, http.StatusUnprocessableEntity, quotaErr.Error())
+			}
+			return c.Blob(http.StatusOK, mimeApplicationYAML, quota)
+		}
 		nsRecommendationSet.RecommendationsJSON = UpdateRecommendationJSON(
 			handlerName,
 			nsRecommendationSet.ID,
@@ -233,13 +448,422 @@ func GetNamespaceRecommendationSet(c echo.Context) error {
 			nsRecommendationSet.Recommendations,
 			&nsRecommendationSet.StoredVariationPcts,
 		)
+		nsRecommendationSet.RecommendationsJSON = injectCostEstimates(
+			nsRecommendationSet.RecommendationsJSON,
+			nsRecommendationSet.Recommendations,
+			loadCostRateCard(OrgID, user_permissions),
+			nsRecommendationSet.ClusterUUID,
+		)
 	}
 	return c.JSON(http.StatusOK, nsRecommendationSet)
 }
 
+func GetNamespaceRecommendationSetHistory(c echo.Context) error {
+	XRHID := c.Get("Identity").(identity.XRHID)
+	OrgID := XRHID.Identity.OrgID
+	user_permissions := get_user_permissions(c)
+	handlerName := "namespace-recommendationset-history"
+
+	RecommendationIDStr := c.Param("recommendation-id")
+	RecommendationUUID, err := uuid.Parse(RecommendationIDStr)
+	if err != nil {
+		return apiErrResponse(c, err, http.StatusBadRequest, "bad recommendation-id for project")
+	}
+
+	apiListOptions, listOptionsErr := listoptions.ListAPIOptions(c, listoptions.DefaultNsHistoryDBColumn, listoptions.NsHistoryAllowedOrderBy)
+	if listOptionsErr != nil {
+		return apiErrResponse(c, listOptionsErr, http.StatusBadRequest, listOptionsErr.Error())
+	}
+	if apiListOptions.Format != listoptions.ResponseFormatJSON {
+		formatErr := errors.New("project recommendation history is only available as application/json")
+		return apiErrResponse(c, formatErr, http.StatusBadRequest, formatErr.Error())
+	}
+
+	queryParams, paramErr := MapHistoryQueryParameters(c, "historical_namespace_recommendation_sets.monitoring_end_time")
+	if paramErr != nil {
+		return apiErrResponse(c, paramErr, http.StatusBadRequest, paramErr.Error())
+	}
+
+	unitChoices, setk8sUnits, unitParseErr := ParseUnitParams(c, "cores", "bytes")
+	if unitParseErr != nil {
+		return apiErrResponse(c, unitParseErr, http.StatusBadRequest, unitParseErr.Error())
+	}
+
+	historicalNamespaceRecommendationSet := model.HistoricalNamespaceRecommendationSet{}
+	historicalSets, count, queryErr := historicalNamespaceRecommendationSet.GetHistoricalNamespaceRecommendationSets(
+		OrgID, RecommendationUUID.String(), apiListOptions, queryParams, user_permissions,
+	)
+	if queryErr != nil {
+		return apiErrResponse(c, queryErr, http.StatusServiceUnavailable, "unable to fetch records from database")
+	}
+
+	interfaceSlice := make([]any, len(historicalSets))
+	for i := range historicalSets {
+		historicalSets[i].RecommendationsJSON = UpdateRecommendationJSON(
+			handlerName,
+			historicalSets[i].RecommendationID,
+			historicalSets[i].ClusterUUID,
+			unitChoices,
+			setk8sUnits,
+			historicalSets[i].Recommendations,
+			nil,
+		)
+		interfaceSlice[i] = historicalSets[i]
+	}
+	results := CollectionResponse(interfaceSlice, c.Request(), count, apiListOptions.Limit, apiListOptions.Offset)
+	return c.JSON(http.StatusOK, results)
+}
+
 func GetAppStatus(c echo.Context) error {
 	status := map[string]string{
 		"api-server": "working",
 	}
 	return c.JSON(http.StatusOK, status)
 }
+
+func GetCostRates(c echo.Context) error {
+	XRHID := c.Get("Identity").(identity.XRHID)
+	OrgID := XRHID.Identity.OrgID
+	user_permissions := get_user_permissions(c)
+
+	costRates, err := model.GetCostRates(OrgID, user_permissions)
+	if err != nil {
+		log.Errorf("unable to fetch cost rates; %v", err)
+		return c.JSON(http.StatusServiceUnavailable, echo.Map{
+			"status":  "error",
+			"message": "unable to fetch records from database",
+		})
+	}
+	if costRates == nil {
+		costRates = []model.CostRate{}
+	}
+	return c.JSON(http.StatusOK, echo.Map{"data": costRates})
+}
+
+func PutCostRate(c echo.Context) error {
+	XRHID := c.Get("Identity").(identity.XRHID)
+	OrgID := XRHID.Identity.OrgID
+	write_permissions := get_user_write_permissions(c)
+
+	var request costRateRequest
+	if err := json.NewDecoder(c.Request().Body).Decode(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"status": "error", "message": "invalid request body"})
+	}
+	costRate, err := request.validate(OrgID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"status": "error", "message": err.Error()})
+	}
+	if !rbac.HasClusterAccess(write_permissions, costRate.ClusterUUID) {
+		return c.JSON(http.StatusForbidden, echo.Map{"status": "error", "message": "not allowed to manage cost rates for this scope"})
+	}
+
+	if err := costRate.UpsertCostRate(); err != nil {
+		log.Errorf("unable to save cost rate; %v", err)
+		return c.JSON(http.StatusServiceUnavailable, echo.Map{
+			"status":  "error",
+			"message": "unable to save cost rate",
+		})
+	}
+	return c.JSON(http.StatusOK, costRate)
+}
+
+func DeleteCostRate(c echo.Context) error {
+	XRHID := c.Get("Identity").(identity.XRHID)
+	OrgID := XRHID.Identity.OrgID
+	write_permissions := get_user_write_permissions(c)
+
+	clusterUUID := c.QueryParam("cluster_uuid")
+	if !rbac.HasClusterAccess(write_permissions, clusterUUID) {
+		return c.JSON(http.StatusForbidden, echo.Map{"status": "error", "message": "not allowed to manage cost rates for this scope"})
+	}
+
+	deleted, err := model.DeleteCostRate(OrgID, clusterUUID)
+	if err != nil {
+		log.Errorf("unable to delete cost rate; %v", err)
+		return c.JSON(http.StatusServiceUnavailable, echo.Map{
+			"status":  "error",
+			"message": "unable to delete cost rate",
+		})
+	}
+	if !deleted {
+		return c.JSON(http.StatusNotFound, echo.Map{"status": "not_found", "message": "cost rate not found"})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func GetWebhooks(c echo.Context) error {
+	XRHID := c.Get("Identity").(identity.XRHID)
+	OrgID := XRHID.Identity.OrgID
+	write_permissions := get_user_write_permissions(c)
+
+	// webhook URLs, e.g. Slack or Teams incoming webhooks, are credentials themselves
+	if !rbac.HasClusterAccess(write_permissions, "") {
+		return c.JSON(http.StatusForbidden, echo.Map{"status": "error", "message": "not allowed to manage webhooks"})
+	}
+	webhooks, err := model.ListWebhooks(OrgID)
+	if err != nil {
+		log.Errorf("unable to fetch webhooks; %v", err)
+		return c.JSON(http.StatusServiceUnavailable, echo.Map{
+			"status":  "error",
+			"message": "unable to fetch records from database",
+		})
+	}
+	if webhooks == nil {
+		webhooks = []model.Webhook{}
+	}
+	return c.JSON(http.StatusOK, echo.Map{"data": webhooks})
+}
+
+func PutWebhook(c echo.Context) error {
+	XRHID := c.Get("Identity").(identity.XRHID)
+	OrgID := XRHID.Identity.OrgID
+	write_permissions := get_user_write_permissions(c)
+
+	// webhooks receive events of every cluster of the org
+	if !rbac.HasClusterAccess(write_permissions, "") {
+		return c.JSON(http.StatusForbidden, echo.Map{"status": "error", "message": "not allowed to manage webhooks"})
+	}
+	var request webhookRequest
+	if err := json.NewDecoder(c.Request().Body).Decode(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"status": "error", "message": "invalid request body"})
+	}
+	webhook, err := request.validate(OrgID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"status": "error", "message": err.Error()})
+	}
+	// deliveries check the address again when dialing, since DNS may change after registration
+	if err := webhooks.CheckURL(c.Request().Context(), webhook.URL); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"status": "error", "message": err.Error()})
+	}
+
+	if err := webhook.UpsertWebhook(); err != nil {
+		log.Errorf("unable to save webhook; %v", err)
+		return c.JSON(http.StatusServiceUnavailable, echo.Map{
+			"status":  "error",
+			"message": "unable to save webhook",
+		})
+	}
+	return c.JSON(http.StatusOK, webhook)
+}
+
+func DeleteWebhook(c echo.Context) error {
+	XRHID := c.Get("Identity").(identity.XRHID)
+	OrgID := XRHID.Identity.OrgID
+	write_permissions := get_user_write_permissions(c)
+
+	if !rbac.HasClusterAccess(write_permissions, "") {
+		return c.JSON(http.StatusForbidden, echo.Map{"status": "error", "message": "not allowed to manage webhooks"})
+	}
+	webhookID, err := strconv.ParseUint(c.Param("webhook-id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"status": "error", "message": "bad webhook_id"})
+	}
+
+	deleted, err := model.DeleteWebhook(OrgID, uint(webhookID))
+	if err != nil {
+		log.Errorf("unable to delete webhook; %v", err)
+		return c.JSON(http.StatusServiceUnavailable, echo.Map{
+			"status":  "error",
+			"message": "unable to delete webhook",
+		})
+	}
+	if !deleted {
+		return c.JSON(http.StatusNotFound, echo.Map{"status": "not_found", "message": "webhook not found"})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func PutRecommendationSetStatus(c echo.Context) error {
+	XRHID := c.Get("Identity").(identity.XRHID)
+	OrgID := XRHID.Identity.OrgID
+	user_permissions := get_user_permissions(c)
+
+	RecommendationIDStr := c.Param("recommendation-id")
+	RecommendationUUID, err := uuid.Parse(RecommendationIDStr)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"status": "error", "message": "bad recommendation_id"})
+	}
+
+	status, err := parseRecommendationStatusRequest(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"status": "error", "message": err.Error()})
+	}
+
+	recommendationSetVar := model.RecommendationSet{}
+	recommendationSet, err := recommendationSetVar.GetRecommendationSetByID(OrgID, RecommendationUUID.String(), user_permissions)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"status": "not_found", "message": "recommendation not found"})
+		}
+		log.Errorf("unable to fetch recommendation %s; error %v", RecommendationIDStr, err)
+		return c.JSON(http.StatusServiceUnavailable, echo.Map{"status": "error", "message": "unable to fetch recommendation"})
+	}
+	if !rbac.HasProjectAccess(get_user_write_permissions(c), recommendationSet.ClusterUUID, recommendationSet.Project) {
+		return c.JSON(http.StatusForbidden, echo.Map{"status": "error", "message": "not allowed to change the status of this recommendation"})
+	}
+
+	recommendationID := RecommendationUUID.String()
+	status.RecommendationSetID = &recommendationID
+	if err := status.UpsertRecommendationStatus(); err != nil {
+		log.Errorf("unable to save status of recommendation %s; %v", RecommendationIDStr, err)
+		return c.JSON(http.StatusServiceUnavailable, echo.Map{"status": "error", "message": "unable to save recommendation status"})
+	}
+	return c.JSON(http.StatusOK, status)
+}
+
+func DeleteRecommendationSetStatus(c echo.Context) error {
+	XRHID := c.Get("Identity").(identity.XRHID)
+	OrgID := XRHID.Identity.OrgID
+	user_permissions := get_user_permissions(c)
+
+	RecommendationIDStr := c.Param("recommendation-id")
+	RecommendationUUID, err := uuid.Parse(RecommendationIDStr)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"status": "error", "message": "bad recommendation_id"})
+	}
+
+	recommendationSetVar := model.RecommendationSet{}
+	recommendationSet, err := recommendationSetVar.GetRecommendationSetByID(OrgID, RecommendationUUID.String(), user_permissions)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"status": "not_found", "message": "recommendation not found"})
+		}
+		log.Errorf("unable to fetch recommendation %s; error %v", RecommendationIDStr, err)
+		return c.JSON(http.StatusServiceUnavailable, echo.Map{"status": "error", "message": "unable to fetch recommendation"})
+	}
+	if !rbac.HasProjectAccess(get_user_write_permissions(c), recommendationSet.ClusterUUID, recommendationSet.Project) {
+		return c.JSON(http.StatusForbidden, echo.Map{"status": "error", "message": "not allowed to change the status of this recommendation"})
+	}
+
+	// reopening is idempotent: a recommendation without status is already open
+	if _, err := model.DeleteRecommendationStatus(OrgID, RecommendationUUID.String(), false); err != nil {
+		log.Errorf("unable to delete status of recommendation %s; %v", RecommendationIDStr, err)
+		return c.JSON(http.StatusServiceUnavailable, echo.Map{"status": "error", "message": "unable to delete recommendation status"})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func PutNamespaceRecommendationSetStatus(c echo.Context) error {
+	XRHID := c.Get("Identity").(identity.XRHID)
+	OrgID := XRHID.Identity.OrgID
+	user_permissions := get_user_permissions(c)
+
+	RecommendationIDStr := c.Param("recommendation-id")
+	RecommendationUUID, err := uuid.Parse(RecommendationIDStr)
+	if err != nil {
+		return apiErrResponse(c, err, http.StatusBadRequest, "bad recommendation-id for project")
+	}
+
+	status, err := parseRecommendationStatusRequest(c)
+	if err != nil {
+		return apiErrResponse(c, err, http.StatusBadRequest, err.Error())
+	}
+
+	recommendationSetVar := model.NamespaceRecommendationSet{}
+	recommendationSet, err := recommendationSetVar.GetNamespaceRecommendationSetByID(OrgID, RecommendationUUID.String(), user_permissions)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apiErrResponse(c, err, http.StatusNotFound, "project recommendation not found")
+		}
+		return apiErrResponse(c, err, http.StatusServiceUnavailable, "unable to fetch project recommendation")
+	}
+	if !rbac.HasProjectAccess(get_user_write_permissions(c), recommendationSet.ClusterUUID, recommendationSet.Project) {
+		err := fmt.Errorf("user may not change the status of project recommendation %s", RecommendationIDStr)
+		return apiErrResponse(c, err, http.StatusForbidden, "not allowed to change the status of this project recommendation")
+	}
+
+	recommendationID := RecommendationUUID.String()
+	status.NamespaceRecommendationSetID = &recommendationID
+	if err := status.UpsertRecommendationStatus(); err != nil {
+		return apiErrResponse(c, err, http.StatusServiceUnavailable, "unable to save project recommendation status")
+	}
+	return c.JSON(http.StatusOK, status)
+}
+
+func DeleteNamespaceRecommendationSetStatus(c echo.Context) error {
+	XRHID := c.Get("Identity").(identity.XRHID)
+	OrgID := XRHID.Identity.OrgID
+	user_permissions := get_user_permissions(c)
+
+	RecommendationIDStr := c.Param("recommendation-id")
+	RecommendationUUID, err := uuid.Parse(RecommendationIDStr)
+	if err != nil {
+		return apiErrResponse(c, err, http.StatusBadRequest, "bad recommendation-id for project")
+	}
+
+	recommendationSetVar := model.NamespaceRecommendationSet{}
+	recommendationSet, err := recommendationSetVar.GetNamespaceRecommendationSetByID(OrgID, RecommendationUUID.String(), user_permissions)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apiErrResponse(c, err, http.StatusNotFound, "project recommendation not found")
+		}
+		return apiErrResponse(c, err, http.StatusServiceUnavailable, "unable to fetch project recommendation")
+	}
+	if !rbac.HasProjectAccess(get_user_write_permissions(c), recommendationSet.ClusterUUID, recommendationSet.Project) {
+		err := fmt.Errorf("user may not change the status of project recommendation %s", RecommendationIDStr)
+		return apiErrResponse(c, err, http.StatusForbidden, "not allowed to change the status of this project recommendation")
+	}
+
+	// reopening is idempotent: a recommendation without status is already open
+	if _, err := model.DeleteRecommendationStatus(OrgID, RecommendationUUID.String(), true); err != nil {
+		return apiErrResponse(c, err, http.StatusServiceUnavailable, "unable to delete project recommendation status")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func GetUploadStatus(c echo.Context) error {
+	XRHID := c.Get("Identity").(identity.XRHID)
+	OrgID := XRHID.Identity.OrgID
+	user_permissions := get_user_permissions(c)
+
+	requestID := c.Param("request-id")
+	statuses, err := model.GetUploadStatuses(OrgID, requestID)
+	if err != nil {
+		log.Errorf("unable to fetch status of upload %s; %v", requestID, err)
+		return c.JSON(http.StatusServiceUnavailable, echo.Map{
+			"status":  "error",
+			"message": "unable to fetch records from database",
+		})
+	}
+	// every file of an upload belongs to the same cluster
+	if len(statuses) == 0 || !rbac.HasClusterAccess(user_permissions, statuses[0].ClusterUUID) {
+		return c.JSON(http.StatusNotFound, echo.Map{"status": "not_found", "message": "upload not found"})
+	}
+	return c.JSON(http.StatusOK, echo.Map{"request_id": requestID, "cluster_uuid": statuses[0].ClusterUUID, "files": statuses})
+}
+
+func GetUploadStatusList(c echo.Context) error {
+	XRHID := c.Get("Identity").(identity.XRHID)
+	OrgID := XRHID.Identity.OrgID
+	user_permissions := get_user_permissions(c)
+
+	clusterUUID := c.QueryParam("cluster_uuid")
+	if clusterUUID == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{"status": "error", "message": "cluster_uuid is required"})
+	}
+	if !rbac.HasClusterAccess(user_permissions, clusterUUID) {
+		return c.JSON(http.StatusForbidden, echo.Map{"status": "error", "message": "not allowed to view uploads of this cluster"})
+	}
+	apiListOptions, err := listoptions.ListAPIOptions(c, listoptions.DefaultUploadStatusDBColumn, listoptions.UploadStatusAllowedOrderBy)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"status": "error", "message": err.Error()})
+	}
+	if apiListOptions.Format != listoptions.ResponseFormatJSON {
+		return c.JSON(http.StatusBadRequest, echo.Map{"status": "error", "message": "upload statuses are only available as application/json"})
+	}
+
+	statuses, count, err := model.GetClusterUploadStatuses(OrgID, clusterUUID, apiListOptions)
+	if err != nil {
+		log.Errorf("unable to fetch upload statuses of cluster %s; %v", clusterUUID, err)
+		return c.JSON(http.StatusServiceUnavailable, echo.Map{
+			"status":  "error",
+			"message": "unable to fetch records from database",
+		})
+	}
+	interfaceSlice := make([]any, len(statuses))
+	for i := range statuses {
+		interfaceSlice[i] = statuses[i]
+	}
+	results := CollectionResponse(interfaceSlice, c.Request(), count, apiListOptions.Limit, apiListOptions.Offset)
+	return c.JSON(http.StatusOK, results)
+}
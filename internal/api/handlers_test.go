@@ -1,15 +1,19 @@
 package api
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/labstack/echo/v4"
 	"github.com/redhatinsights/platform-go-middlewares/identity"
 
 	database "github.com/redhatinsights/ros-ocp-backend/internal/db"
+	"github.com/redhatinsights/ros-ocp-backend/internal/db/dbtest"
+	"github.com/redhatinsights/ros-ocp-backend/internal/model"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -41,6 +45,21 @@ func newHandlerContext(t *testing.T, method, path string) (echo.Context, *httpte
 		Identity: identity.Identity{OrgID: "test-org"},
 	})
 	c.Set("user.permissions", map[string][]string{"*": {}})
+	c.Set("user.write_permissions", map[string][]string{"*": {}})
+	return c, rec
+}
+
+// newReadOnlyHandlerContext is newHandlerContext for a user who may read everything and change
+// nothing, with RBAC enabled.
+func newReadOnlyHandlerContext(t *testing.T, method, path string, body string) (echo.Context, *httptest.ResponseRecorder) {
+	t.Helper()
+	original := cfg.RBACEnabled
+	cfg.RBACEnabled = true
+	t.Cleanup(func() { cfg.RBACEnabled = original })
+
+	c, rec := newHandlerContext(t, method, path)
+	c.SetRequest(httptest.NewRequest(method, path, strings.NewReader(body)))
+	c.Set("user.write_permissions", map[string][]string{})
 	return c, rec
 }
 
@@ -91,3 +110,439 @@ func TestGetNamespaceRecommendationSetList_DBError_Returns503(t *testing.T) {
 		t.Errorf("expected status=error, got %q", body["status"])
 	}
 }
+
+func TestGetRecommendationSummary_DBError_Returns503(t *testing.T) {
+	restore := setupBrokenDB(t)
+	defer restore()
+
+	c, rec := newHandlerContext(t, http.MethodGet, "/api/v1/recommendations/openshift/summary")
+
+	if err := GetRecommendationSummary(c); err != nil {
+		t.Fatalf("handler returned Go error: %v", err)
+	}
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", rec.Code)
+	}
+}
+
+func TestGetRecommendationSummary_BadUnit_Returns400(t *testing.T) {
+	c, rec := newHandlerContext(t, http.MethodGet, "/api/v1/recommendations/openshift/summary?cpu-unit=hertz")
+
+	if err := GetRecommendationSummary(c); err != nil {
+		t.Fatalf("handler returned Go error: %v", err)
+	}
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", rec.Code)
+	}
+}
+
+func TestPutCostRate_InvalidBody_Returns400(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "malformed json", body: `{"cpu_core_hour_rate":`},
+		{name: "missing memory rate", body: `{"cpu_core_hour_rate": 0.05}`},
+		{name: "negative rate", body: `{"cpu_core_hour_rate": -1, "memory_gib_hour_rate": 0.01}`},
+		{name: "invalid currency", body: `{"cpu_core_hour_rate": 0.05, "memory_gib_hour_rate": 0.01, "currency": "dollars"}`},
+		{name: "invalid cluster", body: `{"cluster_uuid": "a;b", "cpu_core_hour_rate": 0.05, "memory_gib_hour_rate": 0.01}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, rec := newHandlerContext(t, http.MethodPut, "/api/v1/cost-rates")
+			c.SetRequest(httptest.NewRequest(http.MethodPut, "/api/v1/cost-rates", strings.NewReader(tt.body)))
+
+			if err := PutCostRate(c); err != nil {
+				t.Fatalf("handler returned Go error: %v", err)
+			}
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("expected status 400, got %d", rec.Code)
+			}
+		})
+	}
+}
+
+func TestPutCostRate_DBError_Returns503(t *testing.T) {
+	restore := setupBrokenDB(t)
+	defer restore()
+
+	c, rec := newHandlerContext(t, http.MethodPut, "/api/v1/cost-rates")
+	c.SetRequest(httptest.NewRequest(http.MethodPut, "/api/v1/cost-rates",
+		strings.NewReader(`{"cpu_core_hour_rate": 0.05, "memory_gib_hour_rate": 0.01}`)))
+
+	if err := PutCostRate(c); err != nil {
+		t.Fatalf("handler returned Go error: %v", err)
+	}
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", rec.Code)
+	}
+}
+
+func TestCostRateWrites_ReadOnlyUser_Returns403(t *testing.T) {
+	c, rec := newReadOnlyHandlerContext(t, http.MethodPut, "/api/v1/cost-rates", `{"cpu_core_hour_rate": 0.05, "memory_gib_hour_rate": 0.01}`)
+	if err := PutCostRate(c); err != nil {
+		t.Fatalf("handler returned Go error: %v", err)
+	}
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("PutCostRate: expected status 403, got %d", rec.Code)
+	}
+
+	c, rec = newReadOnlyHandlerContext(t, http.MethodDelete, "/api/v1/cost-rates", "")
+	if err := DeleteCostRate(c); err != nil {
+		t.Fatalf("handler returned Go error: %v", err)
+	}
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("DeleteCostRate: expected status 403, got %d", rec.Code)
+	}
+}
+
+func TestGetCostRates_FiltersByClusterPermissions(t *testing.T) {
+	dbtest.Use(t)
+	for _, clusterUUID := range []string{"", "uuid-1", "uuid-2"} {
+		costRate := model.CostRate{OrgID: "test-org", ClusterUUID: clusterUUID, CPUCoreHourRate: 0.05, MemoryGiBHourRate: 0.01, Currency: "USD"}
+		if err := costRate.UpsertCostRate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	otherOrg := model.CostRate{OrgID: "other-org", CPUCoreHourRate: 0.05, MemoryGiBHourRate: 0.01, Currency: "USD"}
+	if err := otherOrg.UpsertCostRate(); err != nil {
+		t.Fatal(err)
+	}
+
+	c, rec := newReadOnlyHandlerContext(t, http.MethodGet, "/api/v1/cost-rates", "")
+	c.Set("user.permissions", map[string][]string{"openshift.cluster": {"uuid-1"}})
+	if err := GetCostRates(c); err != nil {
+		t.Fatalf("handler returned Go error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		Data []model.CostRate `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	var clusters []string
+	for _, costRate := range body.Data {
+		clusters = append(clusters, costRate.ClusterUUID)
+	}
+	if strings.Join(clusters, ",") != ",uuid-1" {
+		t.Errorf("expected the org-wide rate and the rate of uuid-1, got %q", clusters)
+	}
+}
+
+func TestGetCostRates_DBError_Returns503(t *testing.T) {
+	restore := setupBrokenDB(t)
+	defer restore()
+
+	c, rec := newHandlerContext(t, http.MethodGet, "/api/v1/cost-rates")
+
+	if err := GetCostRates(c); err != nil {
+		t.Fatalf("handler returned Go error: %v", err)
+	}
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", rec.Code)
+	}
+}
+
+func TestPutRecommendationSetStatus_BadRequest_Returns400(t *testing.T) {
+	recommendationID := "550e8400-e29b-41d4-a716-446655440000"
+	tests := []struct {
+		name string
+		id   string
+		body string
+	}{
+		{name: "bad id", id: "not-a-uuid", body: `{"status": "dismissed"}`},
+		{name: "malformed body", id: recommendationID, body: `{"status":`},
+		{name: "invalid status", id: recommendationID, body: `{"status": "ignored"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := "/api/v1/recommendations/openshift/container/" + tt.id + "/status"
+			c, rec := newHandlerContext(t, http.MethodPut, path)
+			c.SetRequest(httptest.NewRequest(http.MethodPut, path, strings.NewReader(tt.body)))
+			c.SetParamNames("recommendation-id")
+			c.SetParamValues(tt.id)
+
+			if err := PutRecommendationSetStatus(c); err != nil {
+				t.Fatalf("handler returned Go error: %v", err)
+			}
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("expected status 400, got %d", rec.Code)
+			}
+		})
+	}
+}
+
+func TestPutRecommendationSetStatus_DBError_Returns503(t *testing.T) {
+	restore := setupBrokenDB(t)
+	defer restore()
+
+	recommendationID := "550e8400-e29b-41d4-a716-446655440000"
+	path := "/api/v1/recommendations/openshift/container/" + recommendationID + "/status"
+	c, rec := newHandlerContext(t, http.MethodPut, path)
+	c.SetRequest(httptest.NewRequest(http.MethodPut, path, strings.NewReader(`{"status": "dismissed"}`)))
+	c.SetParamNames("recommendation-id")
+	c.SetParamValues(recommendationID)
+
+	if err := PutRecommendationSetStatus(c); err != nil {
+		t.Fatalf("handler returned Go error: %v", err)
+	}
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", rec.Code)
+	}
+}
+
+func TestDeleteNamespaceRecommendationSetStatus_DBError_Returns503(t *testing.T) {
+	restore := setupBrokenDB(t)
+	defer restore()
+
+	recommendationID := "550e8400-e29b-41d4-a716-446655440000"
+	c, rec := newHandlerContext(t, http.MethodDelete, "/api/v1/recommendations/openshift/namespace/"+recommendationID+"/status")
+	c.SetParamNames("recommendation-id")
+	c.SetParamValues(recommendationID)
+
+	if err := DeleteNamespaceRecommendationSetStatus(c); err != nil {
+		t.Fatalf("handler returned Go error: %v", err)
+	}
+	if rec.Code != http.StatusServiceUnavailable && EnableUserAPIErr {
+		t.Errorf("expected status 503, got %d", rec.Code)
+	}
+}
+
+func TestGetRecommendationSetHistory_BadID_Returns400(t *testing.T) {
+	c, rec := newHandlerContext(t, http.MethodGet, "/api/v1/recommendations/openshift/container/not-a-uuid/history")
+	c.SetParamNames("recommendation-id")
+	c.SetParamValues("not-a-uuid")
+
+	if err := GetRecommendationSetHistory(c); err != nil {
+		t.Fatalf("handler returned Go error: %v", err)
+	}
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", rec.Code)
+	}
+}
+
+func TestGetRecommendationSetHistory_DBError_Returns503(t *testing.T) {
+	restore := setupBrokenDB(t)
+	defer restore()
+
+	recommendationID := "550e8400-e29b-41d4-a716-446655440000"
+	c, rec := newHandlerContext(t, http.MethodGet, "/api/v1/recommendations/openshift/container/"+recommendationID+"/history")
+	c.SetParamNames("recommendation-id")
+	c.SetParamValues(recommendationID)
+
+	if err := GetRecommendationSetHistory(c); err != nil {
+		t.Fatalf("handler returned Go error: %v", err)
+	}
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", rec.Code)
+	}
+}
+
+func TestGetNamespaceRecommendationSetHistory_DBError_Returns503(t *testing.T) {
+	restore := setupBrokenDB(t)
+	defer restore()
+
+	recommendationID := "550e8400-e29b-41d4-a716-446655440000"
+	c, rec := newHandlerContext(t, http.MethodGet, "/api/v1/recommendations/openshift/namespace/"+recommendationID+"/history")
+	c.SetParamNames("recommendation-id")
+	c.SetParamValues(recommendationID)
+
+	if err := GetNamespaceRecommendationSetHistory(c); err != nil {
+		t.Fatalf("handler returned Go error: %v", err)
+	}
+	if rec.Code != http.StatusServiceUnavailable && EnableUserAPIErr {
+		t.Errorf("expected status 503, got %d", rec.Code)
+	}
+}
+
+func TestPutWebhook_InvalidBody_Returns400(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "malformed json", body: `{"url":`},
+		{name: "http url", body: `{"url": "http://hooks.example.com/ros", "secret": "0123456789abcdef"}`},
+		{name: "short secret", body: `{"url": "https://hooks.example.com/ros", "secret": "short"}`},
+		{name: "negative threshold", body: `{"url": "https://hooks.example.com/ros", "secret": "0123456789abcdef", "min_variation_pct": -5}`},
+		{name: "loopback url", body: `{"url": "https://127.0.0.1/ros", "secret": "0123456789abcdef"}`},
+		{name: "metadata service url", body: `{"url": "https://169.254.169.254/latest", "secret": "0123456789abcdef"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, rec := newHandlerContext(t, http.MethodPut, "/api/v1/webhooks")
+			c.SetRequest(httptest.NewRequest(http.MethodPut, "/api/v1/webhooks", strings.NewReader(tt.body)))
+
+			if err := PutWebhook(c); err != nil {
+				t.Fatalf("handler returned Go error: %v", err)
+			}
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("expected status 400, got %d", rec.Code)
+			}
+		})
+	}
+}
+
+func TestPutWebhook_StoresEncryptedSecret(t *testing.T) {
+	db := dbtest.Use(t)
+	original := cfg.WebhookSecretKey
+	cfg.WebhookSecretKey = base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	t.Cleanup(func() { cfg.WebhookSecretKey = original })
+
+	c, rec := newHandlerContext(t, http.MethodPut, "/api/v1/webhooks")
+	c.SetRequest(httptest.NewRequest(http.MethodPut, "/api/v1/webhooks",
+		strings.NewReader(`{"url": "https://93.184.216.34/ros", "secret": "0123456789abcdef"}`)))
+	if err := PutWebhook(c); err != nil {
+		t.Fatalf("handler returned Go error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), "0123456789abcdef") {
+		t.Errorf("the secret is returned: %s", rec.Body.String())
+	}
+
+	var stored string
+	if err := db.Raw("SELECT secret FROM webhooks WHERE org_id = ?", "test-org").Scan(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored == "" || strings.Contains(stored, "0123456789abcdef") {
+		t.Errorf("the secret is stored in plain text: %q", stored)
+	}
+
+	hooks, err := model.GetWebhooks("test-org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hooks) != 1 || hooks[0].Secret != "0123456789abcdef" {
+		t.Errorf("expected the decrypted secret, got %+v", hooks)
+	}
+}
+
+func TestWebhooks_ReadOnlyUser_Returns403(t *testing.T) {
+	c, rec := newReadOnlyHandlerContext(t, http.MethodGet, "/api/v1/webhooks", "")
+	if err := GetWebhooks(c); err != nil {
+		t.Fatalf("handler returned Go error: %v", err)
+	}
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("GetWebhooks: expected status 403, got %d", rec.Code)
+	}
+
+	c, rec = newReadOnlyHandlerContext(t, http.MethodPut, "/api/v1/webhooks", `{"url": "https://hooks.example.com/ros", "secret": "0123456789abcdef"}`)
+	if err := PutWebhook(c); err != nil {
+		t.Fatalf("handler returned Go error: %v", err)
+	}
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("PutWebhook: expected status 403, got %d", rec.Code)
+	}
+
+	c, rec = newReadOnlyHandlerContext(t, http.MethodDelete, "/api/v1/webhooks/1", "")
+	c.SetParamNames("webhook-id")
+	c.SetParamValues("1")
+	if err := DeleteWebhook(c); err != nil {
+		t.Fatalf("handler returned Go error: %v", err)
+	}
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("DeleteWebhook: expected status 403, got %d", rec.Code)
+	}
+}
+
+func TestGetWebhooks_DoesNotOpenSecrets(t *testing.T) {
+	db := dbtest.Use(t)
+	original := cfg.WebhookSecretKey
+	cfg.WebhookSecretKey = ""
+	t.Cleanup(func() { cfg.WebhookSecretKey = original })
+	if err := db.Exec("INSERT INTO webhooks (org_id, url, secret, updated_at) VALUES (?, ?, ?, CURRENT_TIMESTAMP)",
+		"test-org", "https://hooks.example.com/ros", "not-a-sealed-secret").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	c, rec := newHandlerContext(t, http.MethodGet, "/api/v1/webhooks")
+	if err := GetWebhooks(c); err != nil {
+		t.Fatalf("handler returned Go error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		Data []model.Webhook `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Data) != 1 || body.Data[0].URL != "https://hooks.example.com/ros" {
+		t.Errorf("expected the webhook, got %s", rec.Body.String())
+	}
+}
+
+func TestGetWebhooks_DBError_Returns503(t *testing.T) {
+	restore := setupBrokenDB(t)
+	defer restore()
+
+	c, rec := newHandlerContext(t, http.MethodGet, "/api/v1/webhooks")
+
+	if err := GetWebhooks(c); err != nil {
+		t.Fatalf("handler returned Go error: %v", err)
+	}
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", rec.Code)
+	}
+}
+
+func TestDeleteWebhook_BadID_Returns400(t *testing.T) {
+	c, rec := newHandlerContext(t, http.MethodDelete, "/api/v1/webhooks/abc")
+	c.SetParamNames("webhook-id")
+	c.SetParamValues("abc")
+
+	if err := DeleteWebhook(c); err != nil {
+		t.Fatalf("handler returned Go error: %v", err)
+	}
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", rec.Code)
+	}
+}
+
+func TestGetUploadStatus_DBError_Returns503(t *testing.T) {
+	restore := setupBrokenDB(t)
+	defer restore()
+
+	c, rec := newHandlerContext(t, http.MethodGet, "/api/v1/uploads/req-1")
+	c.SetParamNames("request-id")
+	c.SetParamValues("req-1")
+
+	if err := GetUploadStatus(c); err != nil {
+		t.Fatalf("handler returned Go error: %v", err)
+	}
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", rec.Code)
+	}
+}
+
+func TestGetUploadStatusList_BadRequest_Returns400(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+	}{
+		{name: "missing cluster", path: "/api/v1/uploads"},
+		{name: "bad order_by", path: "/api/v1/uploads?cluster_uuid=c1&order_by=workload"},
+		{name: "csv format", path: "/api/v1/uploads?cluster_uuid=c1&format=csv"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, rec := newHandlerContext(t, http.MethodGet, tt.path)
+
+			if err := GetUploadStatusList(c); err != nil {
+				t.Fatalf("handler returned Go error: %v", err)
+			}
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("expected status 400, got %d", rec.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package api
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/model"
+	"github.com/redhatinsights/ros-ocp-backend/internal/types/workload"
+)
+
+const (
+	kustomizeComponentAPIVersion = "kustomize.config.k8s.io/v1alpha1"
+	kustomizeComponentKind       = "Component"
+	kustomizationFileName        = "kustomization.yaml"
+	kustomizeSkippedFileName     = "skipped.txt"
+)
+
+var unsafePathSegmentChars = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+
+// kustomizeComponent is the kustomization.yaml written per namespace. A Component only carries
+// patches, so it can be pulled into an existing overlay with `components:`.
+type kustomizeComponent struct {
+	APIVersion string                `yaml:"apiVersion"`
+	Kind       string                `yaml:"kind"`
+	Patches    []kustomizePatchEntry `yaml:"patches"`
+}
+
+type kustomizePatchEntry struct {
+	Path string `yaml:"path"`
+}
+
+type bundleWorkloadKey struct {
+	cluster      string
+	namespace    string
+	workloadType string
+	workload     string
+}
+
+// safePathSegment turns cluster, namespace and workload names into a single archive path segment.
+func safePathSegment(name, fallback string) string {
+	segment := unsafePathSegmentChars.ReplaceAllString(name, "_")
+	if segment == "" || segment == "." || segment == ".." {
+		return fallback
+	}
+	return segment
+}
+
+// bundleClusterName names the directory of a cluster. Aliases are not unique, so the UUID is
+// always part of it.
+func bundleClusterName(alias, uuid string) string {
+	if alias == "" || alias == uuid {
+		return uuid
+	}
+	return alias + "_" + uuid
+}
+
+// WriteKustomizeBundle writes a tar.gz holding one kustomize Component per cluster and
+// namespace, with a strategic-merge patch per workload setting the resources of its containers
+// to the selected term and engine. Containers without a usable recommendation are listed in
+// skipped.txt at the root of the archive.
+func WriteKustomizeBundle(w io.Writer, recommendationSets []model.RecommendationSetResult, term, engine string) error {
+	containersByWorkload := make(map[bundleWorkloadKey][]containerPatch)
+	var skipped []string
+
+	for _, recommendationSet := range recommendationSets {
+		cluster := bundleClusterName(recommendationSet.ClusterAlias, recommendationSet.ClusterUUID)
+		key := bundleWorkloadKey{
+			cluster:      safePathSegment(cluster, "_"),
+			namespace:    recommendationSet.Project,
+			workloadType: recommendationSet.WorkloadType,
+			workload:     recommendationSet.Workload,
+		}
+		if _, ok := k8sWorkloadKinds[workload.WorkloadType(key.workloadType)]; !ok {
+			skipped = append(skipped, fmt.Sprintf("%s/%s/%s/%s: unsupported workload type %q",
+				cluster, key.namespace, key.workload, recommendationSet.Container, key.workloadType))
+			continue
+		}
+		resources, err := recommendedResources(recommendationSet.Recommendations, term, engine)
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("%s/%s/%s/%s: %v",
+				cluster, key.namespace, key.workload, recommendationSet.Container, err))
+			continue
+		}
+		containersByWorkload[key] = append(containersByWorkload[key], containerPatch{
+			Name:      recommendationSet.Container,
+			Resources: resources,
+		})
+	}
+
+	keys := make([]bundleWorkloadKey, 0, len(containersByWorkload))
+	for key := range containersByWorkload {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b bundleWorkloadKey) int {
+		return strings.Compare(
+			strings.Join([]string{a.cluster, a.namespace, a.workloadType, a.workload}, "/"),
+			strings.Join([]string{b.cluster, b.namespace, b.workloadType, b.workload}, "/"),
+		)
+	})
+
+	gzipWriter := gzip.NewWriter(w)
+	tarWriter := tar.NewWriter(gzipWriter)
+	modTime := time.Now().UTC()
+
+	writeFile := func(name string, content []byte) error {
+		header := &tar.Header{
+			Name:    name,
+			Mode:    0o644,
+			Size:    int64(len(content)),
+			ModTime: modTime,
+		}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return fmt.Errorf("unable to write %s: %w", name, err)
+		}
+		if _, err := tarWriter.Write(content); err != nil {
+			return fmt.Errorf("unable to write %s: %w", name, err)
+		}
+		return nil
+	}
+
+	// keys are sorted, so all workloads of a namespace are contiguous
+	var component *kustomizeComponent
+	var componentDir string
+	flushComponent := func() error {
+		if component == nil {
+			return nil
+		}
+		content, err := marshalManifest(fmt.Sprintf("%s/%s recommendations", term, engine), component)
+		if err != nil {
+			return err
+		}
+		return writeFile(path.Join(componentDir, kustomizationFileName), content)
+	}
+
+	for _, key := range keys {
+		dir := path.Join(key.cluster, safePathSegment(key.namespace, "_"))
+		if dir != componentDir {
+			if err := flushComponent(); err != nil {
+				return err
+			}
+			component = &kustomizeComponent{APIVersion: kustomizeComponentAPIVersion, Kind: kustomizeComponentKind}
+			componentDir = dir
+		}
+
+		containers := containersByWorkload[key]
+		slices.SortFunc(containers, func(a, b containerPatch) int { return strings.Compare(a.Name, b.Name) })
+		patch, err := newWorkloadPatch(key.workloadType, key.workload, key.namespace, containers)
+		if err != nil {
+			return err
+		}
+		content, err := marshalManifest(fmt.Sprintf("%s/%s recommendation for %s %s", term, engine, strings.ToLower(patch.Kind), key.workload), patch)
+		if err != nil {
+			return err
+		}
+		patchFile := fmt.Sprintf("%s-%s.yaml", strings.ToLower(patch.Kind), safePathSegment(key.workload, "_"))
+		if err := writeFile(path.Join(dir, patchFile), content); err != nil {
+			return err
+		}
+		component.Patches = append(component.Patches, kustomizePatchEntry{Path: patchFile})
+	}
+	if err := flushComponent(); err != nil {
+		return err
+	}
+
+	if len(skipped) > 0 {
+		if err := writeFile(kustomizeSkippedFileName, []byte(strings.Join(skipped, "\n")+"\n")); err != nil {
+			return err
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return fmt.Errorf("unable to close tar archive: %w", err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return fmt.Errorf("unable to close gzip stream: %w", err)
+	}
+	return nil
+}
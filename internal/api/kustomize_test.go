@@ -0,0 +1,121 @@
+package api
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/datatypes"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/model"
+)
+
+func readBundle(t *testing.T, bundle []byte) map[string]string {
+	t.Helper()
+	gzipReader, err := gzip.NewReader(bytes.NewReader(bundle))
+	if err != nil {
+		t.Fatalf("bundle is not gzip: %v", err)
+	}
+	tarReader := tar.NewReader(gzipReader)
+	files := map[string]string{}
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unable to read bundle: %v", err)
+		}
+		content, err := io.ReadAll(tarReader)
+		if err != nil {
+			t.Fatalf("unable to read %s: %v", header.Name, err)
+		}
+		files[header.Name] = string(content)
+	}
+	return files
+}
+
+func TestWriteKustomizeBundle(t *testing.T) {
+	recommendation := datatypes.JSON(rawManifestRecommendationJSON)
+	recommendationSets := []model.RecommendationSetResult{
+		{ClusterAlias: "prod", ClusterUUID: "uuid-1", Project: "shop", Workload: "frontend", WorkloadType: "deployment", Container: "web", Recommendations: recommendation},
+		{ClusterAlias: "prod", ClusterUUID: "uuid-1", Project: "shop", Workload: "frontend", WorkloadType: "deployment", Container: "proxy", Recommendations: recommendation},
+		{ClusterAlias: "prod", ClusterUUID: "uuid-1", Project: "shop", Workload: "db", WorkloadType: "statefulset", Container: "postgres", Recommendations: recommendation},
+		{ClusterAlias: "", ClusterUUID: "uuid-2", Project: "billing", Workload: "worker", WorkloadType: "deploymentconfig", Container: "app", Recommendations: recommendation},
+		{ClusterAlias: "prod", ClusterUUID: "uuid-1", Project: "shop", Workload: "rs", WorkloadType: "cronjob", Container: "job", Recommendations: recommendation},
+	}
+
+	var buf bytes.Buffer
+	err := WriteKustomizeBundle(&buf, recommendationSets, KruizeShortTerm, KruizeEngineCost)
+	assert.NoError(t, err)
+
+	files := readBundle(t, buf.Bytes())
+	assert.ElementsMatch(t, []string{
+		"prod_uuid-1/shop/kustomization.yaml",
+		"prod_uuid-1/shop/deployment-frontend.yaml",
+		"prod_uuid-1/shop/statefulset-db.yaml",
+		"uuid-2/billing/kustomization.yaml",
+		"uuid-2/billing/deploymentconfig-worker.yaml",
+		"skipped.txt",
+	}, slices.Collect(maps.Keys(files)))
+
+	assert.Equal(t, `# short_term/cost recommendations
+apiVersion: kustomize.config.k8s.io/v1alpha1
+kind: Component
+patches:
+  - path: deployment-frontend.yaml
+  - path: statefulset-db.yaml
+`, files["prod_uuid-1/shop/kustomization.yaml"])
+
+	// containers of one workload share a single patch
+	frontend := files["prod_uuid-1/shop/deployment-frontend.yaml"]
+	assert.Contains(t, frontend, "kind: Deployment\n")
+	assert.Contains(t, frontend, "- name: proxy\n")
+	assert.Contains(t, frontend, "- name: web\n")
+
+	assert.Contains(t, files["skipped.txt"], `prod_uuid-1/shop/rs/job: unsupported workload type "cronjob"`)
+}
+
+func TestWriteKustomizeBundle_SameClusterAlias(t *testing.T) {
+	recommendation := datatypes.JSON(rawManifestRecommendationJSON)
+	recommendationSets := []model.RecommendationSetResult{
+		{ClusterAlias: "prod", ClusterUUID: "uuid-1", Project: "shop", Workload: "frontend", WorkloadType: "deployment", Container: "web", Recommendations: recommendation},
+		{ClusterAlias: "prod", ClusterUUID: "uuid-2", Project: "shop", Workload: "frontend", WorkloadType: "deployment", Container: "proxy", Recommendations: recommendation},
+	}
+
+	var buf bytes.Buffer
+	err := WriteKustomizeBundle(&buf, recommendationSets, KruizeShortTerm, KruizeEngineCost)
+	assert.NoError(t, err)
+
+	files := readBundle(t, buf.Bytes())
+	assert.Contains(t, files["prod_uuid-1/shop/deployment-frontend.yaml"], "- name: web\n")
+	assert.NotContains(t, files["prod_uuid-1/shop/deployment-frontend.yaml"], "- name: proxy\n")
+	assert.Contains(t, files["prod_uuid-2/shop/deployment-frontend.yaml"], "- name: proxy\n")
+}
+
+func TestWriteKustomizeBundle_MissingTerm(t *testing.T) {
+	recommendationSets := []model.RecommendationSetResult{
+		{ClusterAlias: "prod", ClusterUUID: "uuid-1", Project: "shop", Workload: "frontend", WorkloadType: "deployment", Container: "web", Recommendations: datatypes.JSON(rawManifestRecommendationJSON)},
+	}
+
+	var buf bytes.Buffer
+	err := WriteKustomizeBundle(&buf, recommendationSets, KruizeLongTerm, KruizeEngineCost)
+	assert.NoError(t, err)
+
+	files := readBundle(t, buf.Bytes())
+	assert.Equal(t, []string{"skipped.txt"}, slices.Collect(maps.Keys(files)))
+	assert.Contains(t, files["skipped.txt"], "prod_uuid-1/shop/frontend/web: no long_term recommendation available")
+}
+
+func TestSafePathSegment(t *testing.T) {
+	assert.Equal(t, "my-cluster.example.com", safePathSegment("my-cluster.example.com", "x"))
+	assert.Equal(t, "a_b", safePathSegment("a/b", "x"))
+	assert.Equal(t, "x", safePathSegment("..", "x"))
+	assert.Equal(t, "x", safePathSegment("", "x"))
+}
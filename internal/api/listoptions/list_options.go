@@ -18,8 +18,11 @@ const (
 	ResponseFormatCSV  = "csv"
 
 	// Default DB columns for OrderBy.
-	DefaultContainerRecsDBColumn = "clusters.last_reported_at"
-	DefaultNsRecsDBColumn        = "clusters.last_reported_at"
+	DefaultContainerRecsDBColumn    = "clusters.last_reported_at"
+	DefaultNsRecsDBColumn           = "clusters.last_reported_at"
+	DefaultContainerHistoryDBColumn = "historical_recommendation_sets.monitoring_end_time"
+	DefaultNsHistoryDBColumn        = "historical_namespace_recommendation_sets.monitoring_end_time"
+	DefaultUploadStatusDBColumn     = "upload_statuses.received_at"
 )
 
 type ListOptions struct {
@@ -91,6 +94,23 @@ var NsAllowedOrderBy = OrderByMap{
 	"memory_variation_long_performance":   "namespace_recommendation_sets.memory_variation_long_performance_pct",
 }
 
+var ContainerHistoryAllowedOrderBy = OrderByMap{
+	"monitoring_start_time": "historical_recommendation_sets.monitoring_start_time",
+	"monitoring_end_time":   "historical_recommendation_sets.monitoring_end_time",
+}
+
+var NsHistoryAllowedOrderBy = OrderByMap{
+	"monitoring_start_time": "historical_namespace_recommendation_sets.monitoring_start_time",
+	"monitoring_end_time":   "historical_namespace_recommendation_sets.monitoring_end_time",
+}
+
+var UploadStatusAllowedOrderBy = OrderByMap{
+	"received_at":  "upload_statuses.received_at",
+	"completed_at": "upload_statuses.completed_at",
+	"status":       "upload_statuses.status",
+	"file_name":    "upload_statuses.file_name",
+}
+
 func parseInt(val string, def int) int {
 	if val == "" {
 		return def
@@ -0,0 +1,252 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"go.yaml.in/yaml/v3"
+	"gorm.io/datatypes"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/types/kruizePayload"
+	"github.com/redhatinsights/ros-ocp-backend/internal/types/workload"
+)
+
+// ResponseFormatYAMLPatch renders a single recommendation as a Kubernetes manifest instead of JSON.
+const ResponseFormatYAMLPatch = "yaml-patch"
+
+const (
+	mimeApplicationYAML = "application/yaml"
+	bytesPerMiB         = 1024 * 1024
+
+	// resourceQuotaName is the metadata.name of the ResourceQuota rendered for project recommendations.
+	resourceQuotaName = "ros-recommended-quota"
+)
+
+type k8sKind struct {
+	APIVersion string
+	Kind       string
+}
+
+// k8sWorkloadKinds maps the workload types reported by the operator to the object a
+// strategic-merge patch has to target.
+var k8sWorkloadKinds = map[workload.WorkloadType]k8sKind{
+	workload.Daemonset:             {"apps/v1", "DaemonSet"},
+	workload.Deployment:            {"apps/v1", "Deployment"},
+	workload.Deploymentconfig:      {"apps.openshift.io/v1", "DeploymentConfig"},
+	workload.Replicaset:            {"apps/v1", "ReplicaSet"},
+	workload.Replicationcontroller: {"v1", "ReplicationController"},
+	workload.Statefulset:           {"apps/v1", "StatefulSet"},
+}
+
+type objectMeta struct {
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace"`
+}
+
+type resourceList map[string]string
+
+type resourceRequirements struct {
+	Requests resourceList `yaml:"requests,omitempty"`
+	Limits   resourceList `yaml:"limits,omitempty"`
+}
+
+type containerPatch struct {
+	Name      string               `yaml:"name"`
+	Resources resourceRequirements `yaml:"resources"`
+}
+
+type podSpecPatch struct {
+	Containers []containerPatch `yaml:"containers"`
+}
+
+type podTemplatePatch struct {
+	Spec podSpecPatch `yaml:"spec"`
+}
+
+type workloadPatchSpec struct {
+	Template podTemplatePatch `yaml:"template"`
+}
+
+// workloadPatch is a strategic-merge patch touching only container resources.
+type workloadPatch struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   objectMeta        `yaml:"metadata"`
+	Spec       workloadPatchSpec `yaml:"spec"`
+}
+
+type resourceQuotaSpec struct {
+	Hard resourceList `yaml:"hard"`
+}
+
+type resourceQuota struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   objectMeta        `yaml:"metadata"`
+	Spec       resourceQuotaSpec `yaml:"spec"`
+}
+
+// ParseTermEngineParams reads the term and engine query params used to pick a single
+// recommendation out of a recommendation set. Defaults to short_term and cost.
+func ParseTermEngineParams(c echo.Context) (string, string, error) {
+	term := c.QueryParam("term")
+	if term == "" {
+		term = KruizeShortTerm
+	}
+	if !slices.Contains(kruizeRecommendationTerms, term) {
+		return "", "", fmt.Errorf("invalid term %q, must be one of: %s", term, strings.Join(kruizeRecommendationTerms, ", "))
+	}
+
+	engine := c.QueryParam("engine")
+	if engine == "" {
+		engine = KruizeEngineCost
+	}
+	if !slices.Contains(kruizeRecommendationEngines, engine) {
+		return "", "", fmt.Errorf("invalid engine %q, must be one of: %s", engine, strings.Join(kruizeRecommendationEngines, ", "))
+	}
+	return term, engine, nil
+}
+
+// cpuQuantity formats cores as a Kubernetes quantity, rounding up to the next millicore.
+func cpuQuantity(cores float64) string {
+	millicores := int64(math.Ceil(cores * 1000))
+	if millicores%1000 == 0 {
+		return fmt.Sprintf("%d", millicores/1000)
+	}
+	return fmt.Sprintf("%dm", millicores)
+}
+
+// memoryQuantity formats bytes as a Kubernetes quantity, rounding up to the next MiB.
+func memoryQuantity(memoryBytes float64) string {
+	return fmt.Sprintf("%dMi", int64(math.Ceil(memoryBytes/bytesPerMiB)))
+}
+
+// termRecommendation returns the named term of a recommendation.
+func termRecommendation(data kruizePayload.RecommendationData, term string) kruizePayload.RecommendationTerm {
+	switch term {
+	case KruizeShortTerm:
+		return data.RecommendationTerms.Short_term
+	case KruizeMediumTerm:
+		return data.RecommendationTerms.Medium_term
+	case KruizeLongTerm:
+		return data.RecommendationTerms.Long_term
+	}
+	return kruizePayload.RecommendationTerm{}
+}
+
+// recommendedResources returns the requests and limits recommended by the given term and
+// engine. jsonData must be the raw stored recommendation (cores and bytes).
+func recommendedResources(jsonData datatypes.JSON, term, engine string) (resourceRequirements, error) {
+	var data kruizePayload.RecommendationData
+	if err := json.Unmarshal(jsonData, &data); err != nil {
+		return resourceRequirements{}, fmt.Errorf("unable to unmarshal recommendation: %w", err)
+	}
+
+	recommendationTerm := termRecommendation(data, term)
+	if recommendationTerm.RecommendationEngines == nil {
+		return resourceRequirements{}, fmt.Errorf("no %s recommendation available", term)
+	}
+
+	config := recommendationTerm.RecommendationEngines.Cost.Config
+	if engine == KruizeEnginePerformance {
+		config = recommendationTerm.RecommendationEngines.Performance.Config
+	}
+
+	resources := resourceRequirements{Requests: resourceList{}, Limits: resourceList{}}
+	if config.Requests.Cpu.Amount > 0 {
+		resources.Requests["cpu"] = cpuQuantity(config.Requests.Cpu.Amount)
+	}
+	if config.Requests.Memory.Amount > 0 {
+		resources.Requests["memory"] = memoryQuantity(config.Requests.Memory.Amount)
+	}
+	if config.Limits.Cpu.Amount > 0 {
+		resources.Limits["cpu"] = cpuQuantity(config.Limits.Cpu.Amount)
+	}
+	if config.Limits.Memory.Amount > 0 {
+		resources.Limits["memory"] = memoryQuantity(config.Limits.Memory.Amount)
+	}
+	if len(resources.Requests) == 0 && len(resources.Limits) == 0 {
+		return resourceRequirements{}, fmt.Errorf("no %s/%s recommendation available", term, engine)
+	}
+	return resources, nil
+}
+
+// newWorkloadPatch builds a strategic-merge patch for the given workload setting the
+// resources of every listed container.
+func newWorkloadPatch(workloadType, workloadName, namespace string, containers []containerPatch) (workloadPatch, error) {
+	kind, ok := k8sWorkloadKinds[workload.WorkloadType(workloadType)]
+	if !ok {
+		return workloadPatch{}, fmt.Errorf("unsupported workload type %q", workloadType)
+	}
+	return workloadPatch{
+		APIVersion: kind.APIVersion,
+		Kind:       kind.Kind,
+		Metadata:   objectMeta{Name: workloadName, Namespace: namespace},
+		Spec: workloadPatchSpec{
+			Template: podTemplatePatch{Spec: podSpecPatch{Containers: containers}},
+		},
+	}, nil
+}
+
+// newResourceQuota builds a ResourceQuota capping a namespace at the recommended resources.
+func newResourceQuota(namespace string, resources resourceRequirements) resourceQuota {
+	hard := resourceList{}
+	for name, quantity := range resources.Requests {
+		hard["requests."+name] = quantity
+	}
+	for name, quantity := range resources.Limits {
+		hard["limits."+name] = quantity
+	}
+	return resourceQuota{
+		APIVersion: "v1",
+		Kind:       "ResourceQuota",
+		Metadata:   objectMeta{Name: resourceQuotaName, Namespace: namespace},
+		Spec:       resourceQuotaSpec{Hard: hard},
+	}
+}
+
+// marshalManifest renders a manifest as YAML preceded by a comment header.
+func marshalManifest(comment string, manifest any) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("# " + comment + "\n")
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(manifest); err != nil {
+		return nil, fmt.Errorf("unable to marshal manifest: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("unable to marshal manifest: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// ContainerRecommendationPatch renders the strategic-merge patch applying a container
+// recommendation to its workload.
+func ContainerRecommendationPatch(workloadType, workloadName, namespace, container string, jsonData datatypes.JSON, term, engine string) ([]byte, error) {
+	resources, err := recommendedResources(jsonData, term, engine)
+	if err != nil {
+		return nil, err
+	}
+	patch, err := newWorkloadPatch(workloadType, workloadName, namespace, []containerPatch{{Name: container, Resources: resources}})
+	if err != nil {
+		return nil, err
+	}
+	comment := fmt.Sprintf("%s/%s recommendation; apply with: oc patch %s %s -n %s --type strategic --patch-file <this file>",
+		term, engine, strings.ToLower(patch.Kind), workloadName, namespace)
+	return marshalManifest(comment, patch)
+}
+
+// NamespaceRecommendationQuota renders the ResourceQuota matching a project recommendation.
+func NamespaceRecommendationQuota(namespace string, jsonData datatypes.JSON, term, engine string) ([]byte, error) {
+	resources, err := recommendedResources(jsonData, term, engine)
+	if err != nil {
+		return nil, err
+	}
+	comment := fmt.Sprintf("%s/%s recommendation; apply with: oc apply -f <this file>", term, engine)
+	return marshalManifest(comment, newResourceQuota(namespace, resources))
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/datatypes"
+)
+
+// Raw stored recommendation (cores and bytes) with a short_term cost recommendation only.
+const rawManifestRecommendationJSON = `{
+	"monitoring_end_time": "2024-01-15T00:00:00.000Z",
+	"current": {
+		"limits": {"cpu": {"amount": 2.0, "format": "cores"}, "memory": {"amount": 4294967296, "format": "bytes"}},
+		"requests": {"cpu": {"amount": 1.0, "format": "cores"}, "memory": {"amount": 2147483648, "format": "bytes"}}
+	},
+	"recommendation_terms": {
+		"short_term": {
+			"duration_in_hours": 24,
+			"recommendation_engines": {
+				"cost": {
+					"config": {
+						"limits": {"cpu": {"amount": 1.5, "format": "cores"}, "memory": {"amount": 1073741824, "format": "bytes"}},
+						"requests": {"cpu": {"amount": 0.2504, "format": "cores"}, "memory": {"amount": 536870913, "format": "bytes"}}
+					}
+				}
+			}
+		},
+		"medium_term": {},
+		"long_term": {}
+	}
+}`
+
+func TestQuantities(t *testing.T) {
+	assert.Equal(t, "251m", cpuQuantity(0.2504))
+	assert.Equal(t, "2", cpuQuantity(2))
+	assert.Equal(t, "1500m", cpuQuantity(1.5))
+	assert.Equal(t, "1024Mi", memoryQuantity(1073741824))
+	assert.Equal(t, "513Mi", memoryQuantity(536870913))
+}
+
+func TestContainerRecommendationPatch(t *testing.T) {
+	patch, err := ContainerRecommendationPatch("deployment", "frontend", "shop", "web",
+		datatypes.JSON(rawManifestRecommendationJSON), KruizeShortTerm, KruizeEngineCost)
+	assert.NoError(t, err)
+
+	want := `# short_term/cost recommendation; apply with: oc patch deployment frontend -n shop --type strategic --patch-file <this file>
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: frontend
+  namespace: shop
+spec:
+  template:
+    spec:
+      containers:
+        - name: web
+          resources:
+            requests:
+              cpu: 251m
+              memory: 513Mi
+            limits:
+              cpu: 1500m
+              memory: 1024Mi
+`
+	assert.Equal(t, want, string(patch))
+}
+
+func TestContainerRecommendationPatch_Errors(t *testing.T) {
+	_, err := ContainerRecommendationPatch("cronjob", "nightly", "shop", "job",
+		datatypes.JSON(rawManifestRecommendationJSON), KruizeShortTerm, KruizeEngineCost)
+	assert.ErrorContains(t, err, "unsupported workload type")
+
+	_, err = ContainerRecommendationPatch("deployment", "frontend", "shop", "web",
+		datatypes.JSON(rawManifestRecommendationJSON), KruizeMediumTerm, KruizeEngineCost)
+	assert.ErrorContains(t, err, "no medium_term recommendation available")
+
+	_, err = ContainerRecommendationPatch("deployment", "frontend", "shop", "web",
+		datatypes.JSON(rawManifestRecommendationJSON), KruizeShortTerm, KruizeEnginePerformance)
+	assert.ErrorContains(t, err, "no short_term/performance recommendation available")
+}
+
+func TestContainerRecommendationPatch_DeploymentConfig(t *testing.T) {
+	patch, err := ContainerRecommendationPatch("deploymentconfig", "legacy", "shop", "app",
+		datatypes.JSON(rawManifestRecommendationJSON), KruizeShortTerm, KruizeEngineCost)
+	assert.NoError(t, err)
+	assert.Contains(t, string(patch), "apiVersion: apps.openshift.io/v1\nkind: DeploymentConfig\n")
+}
+
+func TestNamespaceRecommendationQuota(t *testing.T) {
+	quota, err := NamespaceRecommendationQuota("shop", datatypes.JSON(rawManifestRecommendationJSON), KruizeShortTerm, KruizeEngineCost)
+	assert.NoError(t, err)
+
+	want := `# short_term/cost recommendation; apply with: oc apply -f <this file>
+apiVersion: v1
+kind: ResourceQuota
+metadata:
+  name: ros-recommended-quota
+  namespace: shop
+spec:
+  hard:
+    limits.cpu: 1500m
+    limits.memory: 1024Mi
+    requests.cpu: 251m
+    requests.memory: 513Mi
+`
+	assert.Equal(t, want, string(quota))
+}
+
+func TestParseTermEngineParams(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantTerm   string
+		wantEngine string
+		wantErr    bool
+	}{
+		{name: "defaults", query: "", wantTerm: KruizeShortTerm, wantEngine: KruizeEngineCost},
+		{name: "explicit", query: "term=long_term&engine=performance", wantTerm: KruizeLongTerm, wantEngine: KruizeEnginePerformance},
+		{name: "invalid term", query: "term=yearly", wantErr: true},
+		{name: "invalid engine", query: "engine=cheap", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/?"+tt.query, nil)
+			c := echo.New().NewContext(req, httptest.NewRecorder())
+
+			term, engine, err := ParseTermEngineParams(c)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantTerm, term)
+			assert.Equal(t, tt.wantEngine, engine)
+		})
+	}
+}
@@ -21,9 +21,10 @@ var log *logrus.Entry = logging.GetLogger()
 
 func Rbac(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		permissions := get_user_permissions_from_rbac(c.Request().Header.Get("X-Rh-Identity"))
+		permissions, writePermissions := get_user_permissions_from_rbac(c.Request().Header.Get("X-Rh-Identity"))
 		if permissions != nil {
 			c.Set("user.permissions", permissions)
+			c.Set("user.write_permissions", writePermissions)
 		} else {
 			return echo.NewHTTPError(http.StatusForbidden, "User is not authorized")
 		}
@@ -71,7 +72,22 @@ func aggregate_permissions(acls []types.RbacData) map[string][]string {
 	return permissions
 }
 
-func get_user_permissions_from_rbac(encodedIdentity string) map[string][]string {
+// aggregate_write_permissions is aggregate_permissions of the acls with a write verb, which the
+// endpoints changing state require on top of read access.
+func aggregate_write_permissions(acls []types.RbacData) map[string][]string {
+	writeAcls := []types.RbacData{}
+	for _, acl := range acls {
+		parts := strings.SplitN(acl.Permission, ":", 3)
+		if len(parts) == 3 && (parts[2] == "write" || parts[2] == "*") {
+			writeAcls = append(writeAcls, acl)
+		}
+	}
+	return aggregate_permissions(writeAcls)
+}
+
+// get_user_permissions_from_rbac returns the read and the write permissions of the user; both are
+// nil when the user has no read permission.
+func get_user_permissions_from_rbac(encodedIdentity string) (map[string][]string, map[string][]string) {
 	cfg := config.GetConfig()
 	url := fmt.Sprintf(
 		"%s://%s:%s/api/rbac/v1/access/?application=cost-management&limit=100",
@@ -81,11 +97,11 @@ func get_user_permissions_from_rbac(encodedIdentity string) map[string][]string
 	if len(acls) > 0 {
 		permissions := aggregate_permissions(acls)
 		if len(permissions) > 0 {
-			return permissions
+			return permissions, aggregate_write_permissions(acls)
 		}
-		return nil
+		return nil, nil
 	}
-	return nil
+	return nil, nil
 }
 
 func request_user_access(url, encodedIdentity string) []types.RbacData {
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
 
 	"github.com/redhatinsights/ros-ocp-backend/internal/types"
@@ -103,6 +104,28 @@ func TestAggregatePermissions(t *testing.T) {
 	}
 }
 
+func TestAggregateWritePermissions(t *testing.T) {
+	acls := []types.RbacData{
+		{Permission: "cost-management:openshift.cluster:read"},
+		{
+			Permission: "cost-management:openshift.project:write",
+			ResourceDefinitions: []types.RbacResourceDefinitions{
+				{AttributeFilter: types.AttributeFilter{Value: "my-project"}},
+			},
+		},
+		{Permission: "cost-management:openshift.node:*"},
+	}
+
+	got := aggregate_write_permissions(acls)
+	want := map[string][]string{"openshift.project": {"my-project"}, "openshift.node": {"*"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("aggregate_write_permissions() = %v, want %v", got, want)
+	}
+	if got := aggregate_write_permissions(acls[:1]); len(got) != 0 {
+		t.Errorf("read permissions must not grant write access, got %v", got)
+	}
+}
+
 func TestRequestUserAccess_Non2xxStatus(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/redhatinsights/platform-go-middlewares/identity"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/model"
+)
+
+// maxStatusCommentLen caps the free-text comment stored with a recommendation status.
+const maxStatusCommentLen = 1024
+
+// recommendationStatusRequest is the body accepted by the PUT .../status endpoints.
+type recommendationStatusRequest struct {
+	Status       string `json:"status"`
+	Comment      string `json:"comment"`
+	SnoozedUntil string `json:"snoozed_until"`
+}
+
+// validate checks the request against now and returns the status to store.
+func (r recommendationStatusRequest) validate(now time.Time) (model.RecommendationStatus, error) {
+	status := model.RecommendationStatus{Status: r.Status, Comment: r.Comment}
+	switch r.Status {
+	case model.RecommendationStatusAccepted, model.RecommendationStatusDismissed, model.RecommendationStatusSnoozed:
+	case model.RecommendationStatusOpen:
+		return status, fmt.Errorf("delete the status to reopen a recommendation")
+	default:
+		return status, fmt.Errorf("invalid status %q, must be one of: accepted, dismissed, snoozed", r.Status)
+	}
+	if len(r.Comment) > maxStatusCommentLen {
+		return status, fmt.Errorf("comment must be at most %d characters", maxStatusCommentLen)
+	}
+
+	if r.Status != model.RecommendationStatusSnoozed {
+		if r.SnoozedUntil != "" {
+			return status, fmt.Errorf("snoozed_until is only allowed with status snoozed")
+		}
+		return status, nil
+	}
+	if r.SnoozedUntil == "" {
+		return status, fmt.Errorf("snoozed_until is required with status snoozed")
+	}
+	snoozedUntil, err := time.Parse(time.RFC3339, r.SnoozedUntil)
+	if err != nil {
+		snoozedUntil, err = time.Parse(timeLayout, r.SnoozedUntil)
+		if err != nil {
+			return status, fmt.Errorf("invalid snoozed_until, use YYYY-MM-DD or RFC 3339")
+		}
+	}
+	if !snoozedUntil.After(now) {
+		return status, fmt.Errorf("snoozed_until must be in the future")
+	}
+	snoozedUntil = snoozedUntil.UTC()
+	status.SnoozedUntil = &snoozedUntil
+	return status, nil
+}
+
+// parseRecommendationStatusRequest decodes and validates the request body, recording the
+// org and user from the identity header.
+func parseRecommendationStatusRequest(c echo.Context) (model.RecommendationStatus, error) {
+	var request recommendationStatusRequest
+	if err := json.NewDecoder(c.Request().Body).Decode(&request); err != nil {
+		return model.RecommendationStatus{}, fmt.Errorf("invalid request body")
+	}
+	status, err := request.validate(time.Now().UTC())
+	if err != nil {
+		return status, err
+	}
+	XRHID := c.Get("Identity").(identity.XRHID)
+	status.OrgID = XRHID.Identity.OrgID
+	status.Username = XRHID.Identity.User.Username
+	return status, nil
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecommendationStatusRequestValidate(t *testing.T) {
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name             string
+		request          recommendationStatusRequest
+		wantSnoozedUntil *time.Time
+		wantErr          string
+	}{
+		{name: "accepted", request: recommendationStatusRequest{Status: "accepted", Comment: "rolled out"}},
+		{name: "dismissed", request: recommendationStatusRequest{Status: "dismissed"}},
+		{
+			name:             "snoozed until a date",
+			request:          recommendationStatusRequest{Status: "snoozed", SnoozedUntil: "2024-02-01"},
+			wantSnoozedUntil: ptr(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)),
+		},
+		{
+			name:             "snoozed until a timestamp",
+			request:          recommendationStatusRequest{Status: "snoozed", SnoozedUntil: "2024-01-15T16:00:00+02:00"},
+			wantSnoozedUntil: ptr(time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC)),
+		},
+		{name: "open", request: recommendationStatusRequest{Status: "open"}, wantErr: "delete the status"},
+		{name: "unknown status", request: recommendationStatusRequest{Status: "ignored"}, wantErr: "invalid status"},
+		{name: "snooze without date", request: recommendationStatusRequest{Status: "snoozed"}, wantErr: "snoozed_until is required"},
+		{name: "date without snooze", request: recommendationStatusRequest{Status: "dismissed", SnoozedUntil: "2024-02-01"}, wantErr: "only allowed"},
+		{name: "invalid date", request: recommendationStatusRequest{Status: "snoozed", SnoozedUntil: "01/02/2024"}, wantErr: "invalid snoozed_until"},
+		{name: "past date", request: recommendationStatusRequest{Status: "snoozed", SnoozedUntil: "2024-01-01"}, wantErr: "must be in the future"},
+		{name: "long comment", request: recommendationStatusRequest{Status: "accepted", Comment: string(make([]byte, maxStatusCommentLen+1))}, wantErr: "comment must be at most"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, err := tt.request.validate(now)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.request.Status, status.Status)
+			assert.Equal(t, tt.request.Comment, status.Comment)
+			assert.Equal(t, tt.wantSnoozedUntil, status.SnoozedUntil)
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -22,18 +23,42 @@ var cfg *config.Config = config.GetConfig()
 func registerRecommendationRoutes(v1 *echo.Group) {
 	// Legacy container routes (retained for backward compatibility)
 	v1.GET("/recommendations/openshift", GetRecommendationSetList)
+	v1.GET("/recommendations/openshift/summary", GetRecommendationSummary)
 	v1.GET("/recommendations/openshift/:recommendation-id", GetRecommendationSet)
 
 	// New container routes
 	v1.GET("/recommendations/openshift/container", GetRecommendationSetList)
+	v1.GET("/recommendations/openshift/container/kustomize", GetRecommendationSetKustomizeBundle)
 	v1.GET("/recommendations/openshift/container/:recommendation-id", GetRecommendationSet)
+	v1.GET("/recommendations/openshift/container/:recommendation-id/history", GetRecommendationSetHistory)
+	v1.PUT("/recommendations/openshift/container/:recommendation-id/status", PutRecommendationSetStatus)
+	v1.DELETE("/recommendations/openshift/container/:recommendation-id/status", DeleteRecommendationSetStatus)
 
 	// Project/Namespace
 	v1.GET("/recommendations/openshift/namespace", GetNamespaceRecommendationSetList)
 	v1.GET("/recommendations/openshift/namespace/:recommendation-id", GetNamespaceRecommendationSet)
+	v1.GET("/recommendations/openshift/namespace/:recommendation-id/history", GetNamespaceRecommendationSetHistory)
+	v1.PUT("/recommendations/openshift/namespace/:recommendation-id/status", PutNamespaceRecommendationSetStatus)
+	v1.DELETE("/recommendations/openshift/namespace/:recommendation-id/status", DeleteNamespaceRecommendationSetStatus)
+
+	// Cost model
+	v1.GET("/cost-rates", GetCostRates)
+	v1.PUT("/cost-rates", PutCostRate)
+	v1.DELETE("/cost-rates", DeleteCostRate)
+
+	// Notifications
+	v1.GET("/webhooks", GetWebhooks)
+	v1.PUT("/webhooks", PutWebhook)
+	v1.DELETE("/webhooks/:webhook-id", DeleteWebhook)
+
+	// Upload processing
+	v1.GET("/uploads", GetUploadStatusList)
+	v1.GET("/uploads/:request-id", GetUploadStatus)
 }
 
-func StartAPIServer() {
+// StartAPIServer serves the API until ctx is done, then stops accepting connections and lets
+// in-flight requests finish within SHUTDOWN_TIMEOUT_SECS.
+func StartAPIServer(ctx context.Context) error {
 	app := echo.New()
 	app.Use(echoprometheus.NewMiddlewareWithConfig(echoprometheus.MiddlewareConfig{
 		Subsystem: "rosocp",
@@ -44,16 +69,16 @@ func StartAPIServer() {
 		},
 	}))
 
+	metrics := echo.New()
+	metrics.GET("/metrics", echoprometheus.NewHandler())
 	go func() {
-		metrics := echo.New()
-		metrics.GET("/metrics", echoprometheus.NewHandler())
 		if err := metrics.Start(fmt.Sprintf(":%s", cfg.PrometheusPort)); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Fatal(err)
 		}
 	}()
 	app.Use(middleware.RequestLogger())
 	app.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowMethods: []string{http.MethodGet},
+		AllowMethods: []string{http.MethodGet, http.MethodPut, http.MethodDelete},
 	}))
 
 	app.GET("/status", GetAppStatus)
@@ -72,7 +97,23 @@ func StartAPIServer() {
 		Handler:           app,
 		ReadHeaderTimeout: time.Duration(cfg.ReadHeaderTimeout) * time.Second,
 	}
-	if err := s.ListenAndServe(); err != http.ErrServerClosed {
-		log.Fatal(err)
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Infof("shutting down API server: %v", context.Cause(ctx))
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownTimeoutSecs)*time.Second)
+	defer cancel()
+	_ = metrics.Shutdown(shutdownCtx)
+	if err := s.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("unable to drain API connections: %w", err)
 	}
+	return nil
 }
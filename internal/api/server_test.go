@@ -63,6 +63,33 @@ func TestContainerRecommendationRoutes_ResolveToSharedHandlers(t *testing.T) {
 			wantParamKey: "recommendation-id",
 			wantParamVal: recommendationID,
 		},
+		{
+			name:      "cost rates",
+			path:      "/api/cost-management/v1/cost-rates",
+			wantRoute: "/api/cost-management/v1/cost-rates",
+		},
+		{
+			name:      "webhooks",
+			path:      "/api/cost-management/v1/webhooks",
+			wantRoute: "/api/cost-management/v1/webhooks",
+		},
+		{
+			name:      "savings summary",
+			path:      "/api/cost-management/v1/recommendations/openshift/summary",
+			wantRoute: "/api/cost-management/v1/recommendations/openshift/summary",
+		},
+		{
+			name:      "container kustomize bundle",
+			path:      "/api/cost-management/v1/recommendations/openshift/container/kustomize",
+			wantRoute: "/api/cost-management/v1/recommendations/openshift/container/kustomize",
+		},
+		{
+			name:         "container history",
+			path:         "/api/cost-management/v1/recommendations/openshift/container/" + recommendationID + "/history",
+			wantRoute:    "/api/cost-management/v1/recommendations/openshift/container/:recommendation-id/history",
+			wantParamKey: "recommendation-id",
+			wantParamVal: recommendationID,
+		},
 		{
 			name:      "namespace list unchanged",
 			path:      "/api/cost-management/v1/recommendations/openshift/namespace",
@@ -75,6 +102,13 @@ func TestContainerRecommendationRoutes_ResolveToSharedHandlers(t *testing.T) {
 			wantParamKey: "recommendation-id",
 			wantParamVal: recommendationID,
 		},
+		{
+			name:         "namespace history",
+			path:         "/api/cost-management/v1/recommendations/openshift/namespace/" + recommendationID + "/history",
+			wantRoute:    "/api/cost-management/v1/recommendations/openshift/namespace/:recommendation-id/history",
+			wantParamKey: "recommendation-id",
+			wantParamVal: recommendationID,
+		},
 	}
 
 	for _, tt := range tests {
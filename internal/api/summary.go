@@ -0,0 +1,119 @@
+package api
+
+import (
+	"github.com/redhatinsights/ros-ocp-backend/internal/model"
+)
+
+type summaryAmount struct {
+	Amount float64 `json:"amount"`
+	Format string  `json:"format"`
+}
+
+type provisioningAmount struct {
+	OverProvisioned  float64 `json:"over_provisioned"`
+	UnderProvisioned float64 `json:"under_provisioned"`
+	Format           string  `json:"format"`
+}
+
+type engineSummary struct {
+	CPU    provisioningAmount `json:"cpu"`
+	Memory provisioningAmount `json:"memory"`
+}
+
+type currentRequestsSummary struct {
+	CPU    summaryAmount `json:"cpu"`
+	Memory summaryAmount `json:"memory"`
+}
+
+// recommendationSummary is one total or breakdown entry of the potential savings summary.
+// Grouping fields are omitted when they are not part of the grouping.
+type recommendationSummary struct {
+	ClusterAlias        string                              `json:"cluster_alias,omitempty"`
+	ClusterUUID         string                              `json:"cluster_uuid,omitempty"`
+	Project             string                              `json:"project,omitempty"`
+	WorkloadType        string                              `json:"workload_type,omitempty"`
+	Containers          int64                               `json:"containers"`
+	CurrentRequests     currentRequestsSummary              `json:"current_requests"`
+	RecommendationTerms map[string]map[string]engineSummary `json:"recommendation_terms"`
+}
+
+type recommendationSummaryResponse struct {
+	Total        recommendationSummary   `json:"total"`
+	Cluster      []recommendationSummary `json:"cluster"`
+	Project      []recommendationSummary `json:"project"`
+	WorkloadType []recommendationSummary `json:"workload_type"`
+}
+
+// newRecommendationSummary converts an aggregated row (cores and bytes) to the requested units.
+func newRecommendationSummary(summary model.RecommendationSummary, unitChoices map[string]string) recommendationSummary {
+	cpuUnit, memoryUnit := unitChoices["cpu"], unitChoices["memory"]
+	result := recommendationSummary{
+		ClusterAlias: summary.ClusterAlias,
+		ClusterUUID:  summary.ClusterUUID,
+		Project:      summary.Project,
+		WorkloadType: summary.WorkloadType,
+		Containers:   summary.Containers,
+		CurrentRequests: currentRequestsSummary{
+			CPU:    summaryAmount{Amount: convertCPUUnit(cpuUnit, summary.CPURequestCurrent), Format: cpuUnit},
+			Memory: summaryAmount{Amount: convertMemoryUnit(memoryUnit, summary.MemoryRequestCurrent), Format: memoryUnit},
+		},
+		RecommendationTerms: make(map[string]map[string]engineSummary),
+	}
+	for term, engines := range summary.Terms {
+		result.RecommendationTerms[term] = make(map[string]engineSummary)
+		for engine, totals := range engines {
+			result.RecommendationTerms[term][engine] = engineSummary{
+				CPU: provisioningAmount{
+					OverProvisioned:  convertCPUUnit(cpuUnit, totals.CPUOverProvisioned),
+					UnderProvisioned: convertCPUUnit(cpuUnit, totals.CPUUnderProvisioned),
+					Format:           cpuUnit,
+				},
+				Memory: provisioningAmount{
+					OverProvisioned:  convertMemoryUnit(memoryUnit, totals.MemoryOverProvisioned),
+					UnderProvisioned: convertMemoryUnit(memoryUnit, totals.MemoryUnderProvisioned),
+					Format:           memoryUnit,
+				},
+			}
+		}
+	}
+	return result
+}
+
+func newRecommendationSummaries(summaries []model.RecommendationSummary, unitChoices map[string]string) []recommendationSummary {
+	results := make([]recommendationSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		results = append(results, newRecommendationSummary(summary, unitChoices))
+	}
+	return results
+}
+
+// getRecommendationSummaryResponse runs the total and every breakdown aggregation.
+func getRecommendationSummaryResponse(orgID string, queryParams map[string]interface{}, user_permissions map[string][]string, unitChoices map[string]string) (recommendationSummaryResponse, error) {
+	var response recommendationSummaryResponse
+
+	total, err := model.GetRecommendationSummaries(orgID, model.SummaryGroupTotal, queryParams, user_permissions)
+	if err != nil {
+		return response, err
+	}
+	// an aggregate without GROUP BY always returns a single row
+	if len(total) > 0 {
+		response.Total = newRecommendationSummary(total[0], unitChoices)
+	}
+
+	breakdowns := []struct {
+		groupBy string
+		target  *[]recommendationSummary
+	}{
+		{model.SummaryGroupCluster, &response.Cluster},
+		{model.SummaryGroupProject, &response.Project},
+		{model.SummaryGroupWorkloadType, &response.WorkloadType},
+	}
+	for _, breakdown := range breakdowns {
+		summaries, err := model.GetRecommendationSummaries(orgID, breakdown.groupBy, queryParams, user_permissions)
+		if err != nil {
+			return response, err
+		}
+		*breakdown.target = newRecommendationSummaries(summaries, unitChoices)
+	}
+	return response, nil
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/model"
+)
+
+func TestNewRecommendationSummary(t *testing.T) {
+	summary := model.RecommendationSummary{
+		ClusterUUID:          "uuid-1",
+		ClusterAlias:         "prod",
+		Containers:           3,
+		CPURequestCurrent:    2.5,
+		MemoryRequestCurrent: 3 * 1024 * 1024 * 1024,
+		Terms: map[string]map[string]model.ProvisioningTotals{
+			KruizeShortTerm: {
+				KruizeEngineCost: {
+					CPUOverProvisioned:     1.25,
+					CPUUnderProvisioned:    0.1,
+					MemoryOverProvisioned:  1024 * 1024 * 1024,
+					MemoryUnderProvisioned: 512 * 1024 * 1024,
+				},
+			},
+		},
+	}
+
+	got := newRecommendationSummary(summary, map[string]string{"cpu": "millicores", "memory": "GiB"})
+
+	assert.Equal(t, "prod", got.ClusterAlias)
+	assert.Equal(t, "uuid-1", got.ClusterUUID)
+	assert.Empty(t, got.Project)
+	assert.Equal(t, int64(3), got.Containers)
+	assert.Equal(t, summaryAmount{Amount: 2500, Format: "millicores"}, got.CurrentRequests.CPU)
+	assert.Equal(t, summaryAmount{Amount: 3, Format: "GiB"}, got.CurrentRequests.Memory)
+	assert.Equal(t, engineSummary{
+		CPU:    provisioningAmount{OverProvisioned: 1250, UnderProvisioned: 100, Format: "millicores"},
+		Memory: provisioningAmount{OverProvisioned: 1, UnderProvisioned: 0.5, Format: "GiB"},
+	}, got.RecommendationTerms[KruizeShortTerm][KruizeEngineCost])
+}
+
+func TestNewRecommendationSummaries_Empty(t *testing.T) {
+	got := newRecommendationSummaries(nil, map[string]string{"cpu": "cores", "memory": "bytes"})
+	assert.NotNil(t, got, "breakdowns must serialize as [] rather than null")
+	assert.Empty(t, got)
+}
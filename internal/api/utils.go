@@ -134,6 +134,12 @@ func MapQueryParameters(c echo.Context) (map[string]interface{}, error) {
 	if err := applyParamFilter(c, queryParams, "container", "recommendation_sets.container_name", model.NamespaceMaxLen, false, SkipSanitizationForContainer); err != nil {
 		errs = append(errs, err)
 	}
+	if err := applyStatusFilter(c, queryParams); err != nil {
+		errs = append(errs, err)
+	}
+	if err := applyStaleFilter(c, queryParams); err != nil {
+		errs = append(errs, err)
+	}
 	if len(errs) > 0 {
 		return queryParams, errors.Join(errs...)
 	}
@@ -471,6 +477,12 @@ func MapNamespaceQueryParameters(c echo.Context) (map[string]any, error) {
 	if err := applyParamFilter(c, queryParams, "project", "namespace_recommendation_sets.namespace_name", model.NamespaceMaxLen, false, SkipSanitizationForNamespace); err != nil {
 		errs = append(errs, err)
 	}
+	if err := applyStatusFilter(c, queryParams); err != nil {
+		errs = append(errs, err)
+	}
+	if err := applyStaleFilter(c, queryParams); err != nil {
+		errs = append(errs, err)
+	}
 	if len(errs) > 0 {
 		return queryParams, errors.Join(errs...)
 	}
@@ -478,6 +490,88 @@ func MapNamespaceQueryParameters(c echo.Context) (map[string]any, error) {
 	return queryParams, nil
 }
 
+// statusFilterClause returns the clause matching the effective recommendation status against n values.
+func statusFilterClause(n int) string {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+	return fmt.Sprintf("(%s) IN (%s)", model.RecommendationStatusSQL, placeholders)
+}
+
+// applyStatusFilter filters recommendations on their status. Without a status param dismissed
+// and snoozed recommendations are hidden; status=all returns every recommendation.
+func applyStatusFilter(c echo.Context, queryParams map[string]any) error {
+	statuses := c.QueryParams()["status"]
+	if len(statuses) == 0 {
+		statuses = defaultRecommendationStatuses
+	}
+	if slices.Contains(statuses, RecommendationStatusAll) {
+		return nil
+	}
+	if err := validateRecommendationStatusValues(statuses); err != nil {
+		return err
+	}
+	queryParams[statusFilterClause(len(statuses))] = statuses
+	return nil
+}
+
+// staleWorkloadClause hides the recommendations of workloads the housekeeper marked stale.
+const staleWorkloadClause = "workloads.stale_at IS NULL"
+
+// applyStaleFilter hides the recommendations of stale workloads unless include_stale is true.
+func applyStaleFilter(c echo.Context, queryParams map[string]any) error {
+	includeStale := false
+	if value := c.QueryParam("include_stale"); value != "" {
+		var err error
+		includeStale, err = strconv.ParseBool(value)
+		if err != nil {
+			return namespaceAPIErrf(EnableUserAPIErr, "invalid include_stale %q, must be true or false", value)
+		}
+	}
+	if !includeStale {
+		queryParams[staleWorkloadClause] = nil
+	}
+	return nil
+}
+
+// MapHistoryQueryParameters maps start_date/end_date onto the monitoring_end_time column of a
+// historical recommendation table. Without start_date the whole retention period is returned.
+func MapHistoryQueryParameters(c echo.Context, monitoringEndTimeColumn string) (map[string]any, error) {
+	queryParams := make(map[string]any)
+	var startTimestamp, endTimestamp time.Time
+
+	now := time.Now().UTC().Truncate(time.Second)
+
+	startDateStr := c.QueryParam("start_date")
+	if startDateStr == "" {
+		startTimestamp = now.AddDate(0, 0, -cfg.DataRetentionPeriod)
+	} else {
+		var err error
+		startTimestamp, err = time.Parse(timeLayout, startDateStr)
+		if err != nil {
+			return queryParams, namespaceAPIErrf(EnableUserAPIErr, "invalid start_date format, use YYYY-MM-DD")
+		}
+	}
+	queryParams[monitoringEndTimeColumn+" >= ?"] = startTimestamp
+
+	endDateStr := c.QueryParam("end_date")
+	if endDateStr == "" {
+		endTimestamp = now
+	} else {
+		var err error
+		endTimestamp, err = time.Parse(timeLayout, endDateStr)
+		if err != nil {
+			return queryParams, namespaceAPIErrf(EnableUserAPIErr, "invalid end_date format, use YYYY-MM-DD")
+		}
+		// Inclusive user-provided end_date timestamp
+		endTimestamp = endTimestamp.Add(24 * time.Hour)
+	}
+	if endTimestamp.Before(startTimestamp) {
+		return queryParams, namespaceAPIErrf(EnableUserAPIErr, "end_date must not be before start_date")
+	}
+	queryParams[monitoringEndTimeColumn+" < ?"] = endTimestamp
+
+	return queryParams, nil
+}
+
 func get_user_permissions(c echo.Context) map[string][]string {
 	var user_permissions map[string][]string
 	switch t := c.Get("user.permissions").(type) {
@@ -489,6 +583,19 @@ func get_user_permissions(c echo.Context) map[string][]string {
 	return user_permissions
 }
 
+// get_user_write_permissions returns the permissions of the user with a write verb, required by the
+// endpoints changing state.
+func get_user_write_permissions(c echo.Context) map[string][]string {
+	var write_permissions map[string][]string
+	switch t := c.Get("user.write_permissions").(type) {
+	case map[string][]string:
+		write_permissions = t
+	default:
+		write_permissions = map[string][]string{}
+	}
+	return write_permissions
+}
+
 func convertCPUUnit(cpuUnit string, cpuValue float64) float64 {
 	var convertedValueCPU float64
 
@@ -922,6 +1029,14 @@ func injectStoredRequestVariationPct(data map[string]interface{}, pcts *model.St
 	return data
 }
 
+// listHandlerNames are the handlers returning collections of recommendations.
+var listHandlerNames = []string{
+	"recommendationset-list",
+	"namespace-recommendationset-list",
+	"recommendationset-history",
+	"namespace-recommendationset-history",
+}
+
 // UpdateRecommendationJSON transforms raw recommendation JSON for API output: unit conversion,
 // notification filtering, and variation-to-percentage conversion.
 // When storedPcts is provided and has values, the requests variation percentages are taken
@@ -935,7 +1050,7 @@ func UpdateRecommendationJSON(handlerName string, recommendationID string, clust
 	}
 
 	// box-plots data is not required from list endpoints
-	if handlerName == "recommendationset-list" || handlerName == "namespace-recommendationset-list" {
+	if slices.Contains(listHandlerNames, handlerName) {
 		data = dropBoxPlotsObject(data)
 	}
 
@@ -951,19 +1066,62 @@ func UpdateRecommendationJSON(handlerName string, recommendationID string, clust
 }
 
 func GenerateCSVRows(recommendationSet model.RecommendationSetResult) ([][]string, error) {
+	recommendationRows, err := flattenRecommendationJSON(recommendationSet.ID, recommendationSet.RecommendationsJSON)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([][]string, 0, len(recommendationRows))
+	for _, recommendationRow := range recommendationRows {
+		rows = append(rows, append([]string{
+			recommendationSet.ID,
+			recommendationSet.ClusterUUID,
+			recommendationSet.ClusterAlias,
+			recommendationSet.Container,
+			recommendationSet.Project,
+			recommendationSet.Workload,
+			recommendationSet.WorkloadType,
+			recommendationSet.LastReported,
+			recommendationSet.SourceID,
+		}, recommendationRow...))
+	}
+	return rows, nil
+}
+
+func GenerateNamespaceCSVRows(nsRecommendationSet model.NamespaceRecommendationSetResult) ([][]string, error) {
+	recommendationRows, err := flattenRecommendationJSON(nsRecommendationSet.ID, nsRecommendationSet.RecommendationsJSON)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([][]string, 0, len(recommendationRows))
+	for _, recommendationRow := range recommendationRows {
+		rows = append(rows, append([]string{
+			nsRecommendationSet.ID,
+			nsRecommendationSet.ClusterUUID,
+			nsRecommendationSet.ClusterAlias,
+			nsRecommendationSet.Project,
+			nsRecommendationSet.LastReported,
+			nsRecommendationSet.SourceID,
+		}, recommendationRow...))
+	}
+	return rows, nil
+}
+
+// flattenRecommendationJSON returns one row per term and engine holding the recommendation
+// columns shared by the container and namespace CSV headers (everything after source_id).
+func flattenRecommendationJSON(recommendationID string, recommendationsJSON map[string]interface{}) ([][]string, error) {
 	rows := [][]string{}
 	variationFormat := "percent"
 	var recommendationObj kruizePayload.RecommendationData
 
-	if recommendationSet.RecommendationsJSON == nil {
-		return nil, fmt.Errorf("RecommendationsJSON not set for %s: call UpdateRecommendationJSON first", recommendationSet.ID)
+	if recommendationsJSON == nil {
+		return nil, fmt.Errorf("RecommendationsJSON not set for %s: call UpdateRecommendationJSON first", recommendationID)
 	}
-	b, err := json.Marshal(recommendationSet.RecommendationsJSON)
+	b, err := json.Marshal(recommendationsJSON)
 	if err != nil {
-		return nil, fmt.Errorf("unable to marshal RecommendationsJSON %s: %w", recommendationSet.ID, err)
+		return nil, fmt.Errorf("unable to marshal RecommendationsJSON %s: %w", recommendationID, err)
 	}
 	if err := json.Unmarshal(b, &recommendationObj); err != nil {
-		return nil, fmt.Errorf("unable to unmarshall recommendation %s: %w", recommendationSet.ID, err)
+		return nil, fmt.Errorf("unable to unmarshall recommendation %s: %w", recommendationID, err)
 	}
 
 	f := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
@@ -996,16 +1154,7 @@ func GenerateCSVRows(recommendationSet model.RecommendationSetResult) ([][]strin
 		for _, ne := range orderedEngines {
 			recommendationType := ne.name
 			recommendationEngine := ne.engine
-			rows = append(rows, []string{
-				recommendationSet.ID,
-				recommendationSet.ClusterUUID,
-				recommendationSet.ClusterAlias,
-				recommendationSet.Container,
-				recommendationSet.Project,
-				recommendationSet.Workload,
-				recommendationSet.WorkloadType,
-				recommendationSet.LastReported,
-				recommendationSet.SourceID,
+			rows = append(rows, append([]string{
 				f(recommendationObj.Current.Limits.Cpu.Amount),
 				recommendationObj.Current.Limits.Cpu.Format,
 				f(recommendationObj.Current.Limits.Memory.Amount),
@@ -1035,22 +1184,29 @@ func GenerateCSVRows(recommendationSet model.RecommendationSetResult) ([][]strin
 				variationFormat,
 				f(recommendationEngine.Variation.Requests.Memory.Amount),
 				variationFormat,
-			})
+			}, costEstimateColumns(recommendationsJSON, termName, recommendationType)...))
 		}
 	}
 	return rows, nil
 }
 
 func GenerateAndStreamCSV(w io.Writer, recommendationSets []model.RecommendationSetResult) error {
+	return streamCSV(w, FlattenedCSVHeader, recommendationSets, GenerateCSVRows)
+}
+
+func GenerateAndStreamNamespaceCSV(w io.Writer, nsRecommendationSets []model.NamespaceRecommendationSetResult) error {
+	return streamCSV(w, FlattenedNamespaceCSVHeader, nsRecommendationSets, GenerateNamespaceCSVRows)
+}
+
+func streamCSV[T any](w io.Writer, header []string, records []T, generateRows func(T) ([][]string, error)) error {
 	writer := csv.NewWriter(w)
-	header := FlattenedCSVHeader
 
 	if err := writer.Write(header); err != nil {
 		return fmt.Errorf("unable to write header: %w", err)
 	}
 
-	for i := range recommendationSets {
-		CSVRows, generateRowErr := GenerateCSVRows(recommendationSets[i])
+	for i := range records {
+		CSVRows, generateRowErr := generateRows(records[i])
 		if generateRowErr != nil {
 			return fmt.Errorf("unable to generate rows: %w", generateRowErr)
 		}
@@ -1,8 +1,11 @@
 package api
 
 import (
+	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"strconv"
 	"testing"
 
@@ -133,6 +136,72 @@ func TestGenerateCSVRows_TermOrdering(t *testing.T) {
 	}
 }
 
+func TestGenerateNamespaceCSVRows(t *testing.T) {
+	nsRec := model.NamespaceRecommendationSetResult{
+		ID:              "ns-test-id",
+		ClusterUUID:     "cluster-uuid",
+		ClusterAlias:    "cluster-alias",
+		Project:         "my-project",
+		LastReported:    "2024-01-15",
+		SourceID:        "src-1",
+		Recommendations: datatypes.JSON(testRecommendationJSON),
+	}
+	nsRec.RecommendationsJSON = UpdateRecommendationJSON("", "", "", map[string]string{"cpu": "cores", "memory": "bytes"}, false, nsRec.Recommendations, &model.StoredVariationPcts{})
+
+	rows, err := GenerateNamespaceCSVRows(nsRec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("expected 4 rows, got %d", len(rows))
+	}
+
+	termIdx := slices.Index(FlattenedNamespaceCSVHeader, "recommendation_term")
+	engineIdx := slices.Index(FlattenedNamespaceCSVHeader, "recommendation_type")
+	expectedOrder := [][2]string{
+		{KruizeShortTerm, KruizeEngineCost},
+		{KruizeShortTerm, KruizeEnginePerformance},
+		{KruizeMediumTerm, KruizeEngineCost},
+		{KruizeMediumTerm, KruizeEnginePerformance},
+	}
+	for i, exp := range expectedOrder {
+		if len(rows[i]) != len(FlattenedNamespaceCSVHeader) {
+			t.Fatalf("row %d: got %d columns, want %d", i, len(rows[i]), len(FlattenedNamespaceCSVHeader))
+		}
+		if rows[i][0] != "ns-test-id" || rows[i][3] != "my-project" {
+			t.Errorf("row %d: unexpected identity columns %v", i, rows[i][:6])
+		}
+		if rows[i][termIdx] != exp[0] || rows[i][engineIdx] != exp[1] {
+			t.Errorf("row %d: got term=%q engine=%q, want term=%q engine=%q",
+				i, rows[i][termIdx], rows[i][engineIdx], exp[0], exp[1])
+		}
+	}
+}
+
+func TestGenerateAndStreamNamespaceCSV(t *testing.T) {
+	nsRec := model.NamespaceRecommendationSetResult{
+		ID:              "ns-test-id",
+		Project:         "my-project",
+		Recommendations: datatypes.JSON(testRecommendationJSON),
+	}
+	nsRec.RecommendationsJSON = UpdateRecommendationJSON("", "", "", map[string]string{"cpu": "cores", "memory": "bytes"}, false, nsRec.Recommendations, &model.StoredVariationPcts{})
+
+	var buf bytes.Buffer
+	if err := GenerateAndStreamNamespaceCSV(&buf, []model.NamespaceRecommendationSetResult{nsRec}); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(FlattenedNamespaceCSVHeader, records[0]); diff != "" {
+		t.Errorf("header mismatch (-want +got):\n%s", diff)
+	}
+	if len(records) != 5 {
+		t.Errorf("expected header + 4 rows, got %d records", len(records))
+	}
+}
+
 // injectTestJSON is minimal: one term/engine with variation limits + requests (raw units before inject).
 const injectTestJSON = `{
 	"recommendation_terms": {
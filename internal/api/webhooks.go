@@ -0,0 +1,44 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/model"
+)
+
+const (
+	maxWebhookURLLen    = 2048
+	minWebhookSecretLen = 16
+	maxWebhookSecretLen = 256
+	// maxWebhookVariationPct is the largest threshold fitting the NUMERIC(10, 4) column.
+	maxWebhookVariationPct = 999999.9999
+)
+
+// webhookRequest is the body accepted by PUT /webhooks.
+type webhookRequest struct {
+	URL             string  `json:"url"`
+	Secret          string  `json:"secret"`
+	MinVariationPct float64 `json:"min_variation_pct"`
+}
+
+// validate checks the request and returns the webhook to store for orgID. Only https
+// endpoints are accepted since payloads describe the org's workloads.
+func (r webhookRequest) validate(orgID string) (model.Webhook, error) {
+	webhook := model.Webhook{OrgID: orgID, URL: r.URL, Secret: r.Secret, MinVariationPct: r.MinVariationPct}
+	if len(r.URL) > maxWebhookURLLen {
+		return webhook, fmt.Errorf("url must be at most %d characters", maxWebhookURLLen)
+	}
+	parsed, err := url.Parse(r.URL)
+	if err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+		return webhook, fmt.Errorf("url must be an absolute https URL")
+	}
+	if len(r.Secret) < minWebhookSecretLen || len(r.Secret) > maxWebhookSecretLen {
+		return webhook, fmt.Errorf("secret must be between %d and %d characters", minWebhookSecretLen, maxWebhookSecretLen)
+	}
+	if r.MinVariationPct < 0 || r.MinVariationPct > maxWebhookVariationPct || math.IsNaN(r.MinVariationPct) {
+		return webhook, fmt.Errorf("min_variation_pct must be between 0 and %v", maxWebhookVariationPct)
+	}
+	return webhook, nil
+}
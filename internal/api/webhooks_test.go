@@ -0,0 +1,34 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/model"
+)
+
+func TestWebhookRequestValidate(t *testing.T) {
+	webhook, err := webhookRequest{URL: "https://hooks.example.com/ros", Secret: "0123456789abcdef", MinVariationPct: 30}.validate("org")
+	assert.NoError(t, err)
+	assert.Equal(t, model.Webhook{OrgID: "org", URL: "https://hooks.example.com/ros", Secret: "0123456789abcdef", MinVariationPct: 30}, webhook)
+
+	tests := []struct {
+		name    string
+		request webhookRequest
+		wantErr string
+	}{
+		{"relative url", webhookRequest{URL: "/ros", Secret: "0123456789abcdef"}, "absolute https URL"},
+		{"plain http", webhookRequest{URL: "http://hooks.example.com/ros", Secret: "0123456789abcdef"}, "absolute https URL"},
+		{"url too long", webhookRequest{URL: "https://hooks.example.com/" + strings.Repeat("a", maxWebhookURLLen), Secret: "0123456789abcdef"}, "at most"},
+		{"missing secret", webhookRequest{URL: "https://hooks.example.com/ros"}, "secret must be between"},
+		{"threshold too large", webhookRequest{URL: "https://hooks.example.com/ros", Secret: "0123456789abcdef", MinVariationPct: maxWebhookVariationPct + 1}, "min_variation_pct must be between"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.request.validate("org")
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
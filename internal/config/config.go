@@ -19,11 +19,23 @@ type Config struct {
 	RecommendationPollIntervalHours int    `mapstructure:"RECOMMENDATION_POLL_INTERVAL_HOURS"`
 	DataRetentionPeriod             int    `mapstructure:"DATA_RETENTION_PERIOD"`
 	ReadHeaderTimeout               int    `mapstructure:"READ_HEADER_TIMEOUT"`
+	ShutdownTimeoutSecs             int    `mapstructure:"SHUTDOWN_TIMEOUT_SECS"`
 	RecordLimitCSV                  int    `mapstructure:"RECORD_LIMIT_CSV"`
 	CSVStreamInterval               int    `mapstructure:"CSV_STREAM_INTERVAL"`
+	CSVMaxFileSizeMB                int    `mapstructure:"CSV_MAX_FILE_SIZE_MB"`
 	MaxCountPerQueryParam           int    `mapstructure:"MAXIMUM_COUNT_PER_QUERY_PARAM"`
 	UpdateKruizePerfProfile         bool   `mapstructure:"UPDATE_KRUIZE_PERF_PROFILE"`
 
+	// Workloads without an upload for StaleWorkloadDays are marked stale, and deleted along with
+	// their Kruize experiment StaleWorkloadDeleteDays after that.
+	StaleWorkloadDays       int `mapstructure:"STALE_WORKLOAD_DAYS"`
+	StaleWorkloadDeleteDays int `mapstructure:"STALE_WORKLOAD_DELETE_DAYS"`
+
+	// Kruize experiments without a workload are deleted by the reconcile job once they have been
+	// orphaned for OrphanedExperimentGraceMinutes, so that the experiment of an upload being
+	// processed is not deleted before its workload is saved.
+	OrphanedExperimentGraceMinutes int `mapstructure:"ORPHANED_EXPERIMENT_GRACE_MINUTES"`
+
 	// Kafka config
 	KafkaBootstrapServers string `mapstructure:"KAFKA_BOOTSTRAP_SERVERS"`
 	KafkaConsumerGroupId  string `mapstructure:"KAFKA_CONSUMER_GROUP_ID"`
@@ -31,21 +43,58 @@ type Config struct {
 	UploadTopic           string `mapstructure:"UPLOAD_TOPIC"`
 	RecommendationTopic   string `mapstructure:"RECOMMENDATION_TOPIC"`
 	SourcesEventTopic     string `mapstructure:"SOURCES_EVENT_TOPIC"`
-	KafkaUsername         string
-	KafkaPassword         string
-	KafkaSASLMechanism    string
-	KafkaSecurityProtocol string
-	KafkaCA               string
+	// RecommendationEventsTopic receives saved recommendations; empty disables publishing.
+	RecommendationEventsTopic string `mapstructure:"RECOMMENDATION_EVENTS_TOPIC"`
+	// UploadDLQTopic receives upload messages that failed processing; empty disables dead-lettering.
+	UploadDLQTopic string `mapstructure:"UPLOAD_DLQ_TOPIC"`
+	// RecommendationDLQTopic receives recommendation requests that ran out of attempts.
+	RecommendationDLQTopic string `mapstructure:"RECOMMENDATION_DLQ_TOPIC"`
+	KafkaUsername          string
+	KafkaPassword          string
+	KafkaSASLMechanism     string
+	KafkaSecurityProtocol  string
+	KafkaCA                string
+
+	// ProcessorWorkers is how many upload messages the processor handles at a time, and
+	// ProcessorGroupConcurrency how many workload groups of one upload file.
+	ProcessorWorkers          int `mapstructure:"PROCESSOR_WORKERS"`
+	ProcessorGroupConcurrency int `mapstructure:"PROCESSOR_GROUP_CONCURRENCY"`
 
 	// HTTP client config
 	GlobalHTTPClientTimeoutSecs int `mapstructure:"GLOBAL_HTTP_CLIENT_TIMEOUT_SECS"`
 
+	// Upload file fetching config. FetchFileRoot is the directory file:// URLs may point into;
+	// empty disables them.
+	FetchTimeoutSecs      int    `mapstructure:"FETCH_TIMEOUT_SECS"`
+	FetchMaxRetries       int    `mapstructure:"FETCH_MAX_RETRIES"`
+	FetchRetryBackoffSecs int    `mapstructure:"FETCH_RETRY_BACKOFF_SECS"`
+	FetchFileRoot         string `mapstructure:"FETCH_FILE_ROOT"`
+
+	// S3 config for s3:// upload file URLs; empty credentials use the default AWS chain.
+	S3Endpoint  string `mapstructure:"S3_ENDPOINT"`
+	S3Region    string `mapstructure:"S3_REGION"`
+	S3AccessKey string `mapstructure:"S3_ACCESS_KEY"`
+	S3SecretKey string `mapstructure:"S3_SECRET_KEY"`
+
 	// Kruize config
 	KruizeUrl                       string `mapstructure:"KRUIZE_URL"`
 	KruizeWaitTime                  string `mapstructure:"KRUIZE_WAIT_TIME"`
 	KruizeMaxBulkChunkSize          int    `mapstructure:"KRUIZE_MAX_BULK_CHUNK_SIZE"`
 	KruizePerformanceProfileVersion string `mapstructure:"KRUIZE_PERFORMANCE_PROFILE_VERSION"`
 
+	// KruizeBreakerThreshold is how many Kruize calls in a row may fail before the processor and
+	// the poller stop consuming; they resume once a health probe, every KruizeHealthProbeSecs,
+	// succeeds.
+	KruizeBreakerThreshold int `mapstructure:"KRUIZE_BREAKER_THRESHOLD"`
+	KruizeHealthProbeSecs  int `mapstructure:"KRUIZE_HEALTH_PROBE_SECS"`
+
+	// Kruize API timeouts per endpoint, in seconds; 0 leaves the calls unbounded.
+	KruizeCreateExperimentTimeoutSecs      int `mapstructure:"KRUIZE_CREATE_EXPERIMENT_TIMEOUT_SECS"`
+	KruizeUpdateResultsTimeoutSecs         int `mapstructure:"KRUIZE_UPDATE_RESULTS_TIMEOUT_SECS"`
+	KruizeUpdateRecommendationsTimeoutSecs int `mapstructure:"KRUIZE_UPDATE_RECOMMENDATIONS_TIMEOUT_SECS"`
+	KruizeDeleteExperimentTimeoutSecs      int `mapstructure:"KRUIZE_DELETE_EXPERIMENT_TIMEOUT_SECS"`
+	KruizeListExperimentsTimeoutSecs       int `mapstructure:"KRUIZE_LIST_EXPERIMENTS_TIMEOUT_SECS"`
+
 	// Database config
 	DBName     string
 	DBUser     string
@@ -77,6 +126,21 @@ type Config struct {
 	SourceApiBaseUrl string `mapstructure:"SOURCES_API_BASE_URL"`
 	SourceApiPrefix  string `mapstructure:"SOURCES_API_PREFIX"`
 
+	// Recommendation poller retry config
+	RecommendationMaxAttempts         int `mapstructure:"RECOMMENDATION_MAX_ATTEMPTS"`
+	RecommendationRetryBackoffSecs    int `mapstructure:"RECOMMENDATION_RETRY_BACKOFF_SECS"`
+	RecommendationRetryMaxBackoffSecs int `mapstructure:"RECOMMENDATION_RETRY_MAX_BACKOFF_SECS"`
+
+	// Webhook config
+	WebhookTimeoutSecs      int `mapstructure:"WEBHOOK_TIMEOUT_SECS"`
+	WebhookMaxRetries       int `mapstructure:"WEBHOOK_MAX_RETRIES"`
+	WebhookRetryBackoffSecs int `mapstructure:"WEBHOOK_RETRY_BACKOFF_SECS"`
+	// WebhookSecretKey encrypts the stored webhook secrets; the base64 encoding of 32 bytes.
+	WebhookSecretKey string `mapstructure:"WEBHOOK_SECRET_KEY"`
+	// WebhookWorkers deliver the webhook events, up to WebhookQueueSize more wait for them.
+	WebhookWorkers   int `mapstructure:"WEBHOOK_WORKERS"`
+	WebhookQueueSize int `mapstructure:"WEBHOOK_QUEUE_SIZE"`
+
 	// Namespace recommendation config
 	DisableNamespaceRecommendation bool `mapstructure:"DISABLE_NAMESPACE_RECOMMENDATION"`
 
@@ -101,6 +165,9 @@ func initConfig() {
 		viper.SetDefault("UPLOAD_TOPIC", clowder.KafkaTopics["hccm.ros.events"].Name)
 		viper.SetDefault("RECOMMENDATION_TOPIC", clowder.KafkaTopics["rosocp.kruize.recommendations"].Name)
 		viper.SetDefault("SOURCES_EVENT_TOPIC", clowder.KafkaTopics["platform.sources.event-stream"].Name)
+		viper.SetDefault("RECOMMENDATION_EVENTS_TOPIC", clowder.KafkaTopics["rosocp.recommendation.events"].Name)
+		viper.SetDefault("UPLOAD_DLQ_TOPIC", clowder.KafkaTopics["rosocp.upload.dlq"].Name)
+		viper.SetDefault("RECOMMENDATION_DLQ_TOPIC", clowder.KafkaTopics["rosocp.kruize.recommendations.dlq"].Name)
 
 		// Kafka SSL Config
 		if broker.Authtype != nil {
@@ -174,6 +241,9 @@ func initConfig() {
 		viper.SetDefault("UPLOAD_TOPIC", "hccm.ros.events")
 		viper.SetDefault("RECOMMENDATION_TOPIC", "rosocp.kruize.recommendations")
 		viper.SetDefault("SOURCES_EVENT_TOPIC", "platform.sources.event-stream")
+		viper.SetDefault("RECOMMENDATION_EVENTS_TOPIC", "rosocp.recommendation.events")
+		viper.SetDefault("UPLOAD_DLQ_TOPIC", "rosocp.upload.dlq")
+		viper.SetDefault("RECOMMENDATION_DLQ_TOPIC", "rosocp.kruize.recommendations.dlq")
 
 		// Kafka SASL/TLS config (env vars set by Helm chart when kafka.sasl is configured)
 		_ = viper.BindEnv("KafkaSecurityProtocol", "KAFKA_SECURITY_PROTOCOL")
@@ -232,20 +302,47 @@ func initConfig() {
 	viper.SetDefault("KRUIZE_MAX_BULK_CHUNK_SIZE", 100)
 	viper.SetDefault("KAFKA_CONSUMER_GROUP_ID", "ros-ocp")
 	viper.SetDefault("KAFKA_AUTO_COMMIT", true)
+	viper.SetDefault("PROCESSOR_WORKERS", 1)
+	viper.SetDefault("PROCESSOR_GROUP_CONCURRENCY", 4)
 	viper.SetDefault("LOG_LEVEL", "INFO")
 	viper.SetDefault("KRUIZE_HOST", "localhost")
 	viper.SetDefault("KRUIZE_PORT", "8080")
 	viper.SetDefault("KRUIZE_URL", fmt.Sprintf("http://%s:%s", viper.GetString("KRUIZE_HOST"), viper.GetString("KRUIZE_PORT")))
 	viper.SetDefault("KRUIZE_PERFORMANCE_PROFILE_VERSION", "v2.0")
+	viper.SetDefault("KRUIZE_BREAKER_THRESHOLD", 5)
+	viper.SetDefault("KRUIZE_HEALTH_PROBE_SECS", 15)
+	viper.SetDefault("KRUIZE_CREATE_EXPERIMENT_TIMEOUT_SECS", 30)
+	viper.SetDefault("KRUIZE_UPDATE_RESULTS_TIMEOUT_SECS", 120)
+	viper.SetDefault("KRUIZE_UPDATE_RECOMMENDATIONS_TIMEOUT_SECS", 120)
+	viper.SetDefault("KRUIZE_DELETE_EXPERIMENT_TIMEOUT_SECS", 30)
+	viper.SetDefault("KRUIZE_LIST_EXPERIMENTS_TIMEOUT_SECS", 300)
 	viper.SetDefault("RECOMMENDATION_POLL_INTERVAL_HOURS", 24)
 	viper.SetDefault("DATA_RETENTION_PERIOD", 15)
+	viper.SetDefault("STALE_WORKLOAD_DAYS", 7)
+	viper.SetDefault("STALE_WORKLOAD_DELETE_DAYS", 30)
+	viper.SetDefault("ORPHANED_EXPERIMENT_GRACE_MINUTES", 60)
 	viper.SetDefault("READ_HEADER_TIMEOUT", 15)
+	// below the default 30s termination grace period of pods
+	viper.SetDefault("SHUTDOWN_TIMEOUT_SECS", 25)
 	viper.SetDefault("RECORD_LIMIT_CSV", 1000)
 	viper.SetDefault("CSV_STREAM_INTERVAL", 100)
+	viper.SetDefault("CSV_MAX_FILE_SIZE_MB", 512)
 	viper.SetDefault("DISABLE_NAMESPACE_RECOMMENDATION", false)
 	viper.SetDefault("MAXIMUM_COUNT_PER_QUERY_PARAM", 5)
 	viper.SetDefault("GLOBAL_HTTP_CLIENT_TIMEOUT_SECS", 30)
+	viper.SetDefault("FETCH_TIMEOUT_SECS", 300)
+	viper.SetDefault("FETCH_MAX_RETRIES", 3)
+	viper.SetDefault("FETCH_RETRY_BACKOFF_SECS", 2)
+	viper.SetDefault("S3_REGION", "us-east-1")
 	viper.SetDefault("UPDATE_KRUIZE_PERF_PROFILE", true)
+	viper.SetDefault("RECOMMENDATION_MAX_ATTEMPTS", 5)
+	viper.SetDefault("RECOMMENDATION_RETRY_BACKOFF_SECS", 60)
+	viper.SetDefault("RECOMMENDATION_RETRY_MAX_BACKOFF_SECS", 3600)
+	viper.SetDefault("WEBHOOK_TIMEOUT_SECS", 10)
+	viper.SetDefault("WEBHOOK_MAX_RETRIES", 3)
+	viper.SetDefault("WEBHOOK_RETRY_BACKOFF_SECS", 2)
+	viper.SetDefault("WEBHOOK_WORKERS", 4)
+	viper.SetDefault("WEBHOOK_QUEUE_SIZE", 1000)
 
 	// Hack till viper issue get fix - https://github.com/spf13/viper/issues/761
 	envKeysMap := &map[string]interface{}{}
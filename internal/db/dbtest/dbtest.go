@@ -0,0 +1,88 @@
+// Package dbtest provides an in-memory SQLite database with the ros-ocp tables for tests.
+package dbtest
+
+import (
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	database "github.com/redhatinsights/ros-ocp-backend/internal/db"
+)
+
+// schema mirrors the tables of the migrations in SQLite. Postgres only features (partitions, GIN
+// indexes, enums) are left out; the unique constraints the upserts rely on are kept.
+var schema = []string{
+	`CREATE TABLE rh_accounts (id integer PRIMARY KEY AUTOINCREMENT, account text, org_id text NOT NULL UNIQUE,
+		created_at datetime DEFAULT CURRENT_TIMESTAMP)`,
+	`CREATE TABLE clusters (id integer PRIMARY KEY AUTOINCREMENT, tenant_id integer NOT NULL REFERENCES rh_accounts (id) ON DELETE CASCADE,
+		source_id text NOT NULL, cluster_uuid text NOT NULL, cluster_alias text NOT NULL, last_reported_at datetime,
+		UNIQUE (tenant_id, source_id, cluster_uuid, cluster_alias))`,
+	`CREATE TABLE workloads (id integer PRIMARY KEY AUTOINCREMENT, org_id text NOT NULL,
+		cluster_id integer NOT NULL REFERENCES clusters (id) ON DELETE CASCADE, experiment_name text NOT NULL,
+		namespace text, workload_type text, workload_name text, containers text, metrics_upload_at datetime, stale_at datetime,
+		UNIQUE (org_id, cluster_id, experiment_name))`,
+	`CREATE TABLE workload_metrics (id integer PRIMARY KEY AUTOINCREMENT, org_id text NOT NULL,
+		workload_id integer NOT NULL REFERENCES workloads (id) ON DELETE CASCADE, container_name text NOT NULL,
+		namespace_name text, metric_type text DEFAULT 'container', interval_start datetime NOT NULL,
+		interval_end datetime NOT NULL, usage_metrics text NOT NULL,
+		UNIQUE (org_id, workload_id, container_name, interval_start, interval_end))`,
+	`CREATE TABLE recommendation_sets (id text PRIMARY KEY DEFAULT (lower(hex(randomblob(16)))),
+		workload_id integer REFERENCES workloads (id) ON DELETE CASCADE, container_name text NOT NULL,
+		monitoring_start_time datetime NOT NULL, monitoring_end_time datetime NOT NULL, recommendations text NOT NULL,
+		updated_at datetime NOT NULL, cpu_request_current numeric, memory_request_current numeric,
+		cpu_variation_short_cost_pct numeric, cpu_variation_short_performance_pct numeric,
+		cpu_variation_medium_cost_pct numeric, cpu_variation_medium_performance_pct numeric,
+		cpu_variation_long_cost_pct numeric, cpu_variation_long_performance_pct numeric,
+		memory_variation_short_cost_pct numeric, memory_variation_short_performance_pct numeric,
+		memory_variation_medium_cost_pct numeric, memory_variation_medium_performance_pct numeric,
+		memory_variation_long_cost_pct numeric, memory_variation_long_performance_pct numeric,
+		applied_status text NOT NULL DEFAULT '', applied_at datetime,
+		UNIQUE (workload_id, container_name))`,
+	`CREATE TABLE historical_recommendation_sets (id integer PRIMARY KEY AUTOINCREMENT, org_id text NOT NULL,
+		workload_id integer NOT NULL REFERENCES workloads (id) ON DELETE CASCADE, container_name text NOT NULL,
+		monitoring_start_time datetime NOT NULL, monitoring_end_time datetime NOT NULL, recommendations text NOT NULL,
+		updated_at datetime NOT NULL, UNIQUE (org_id, workload_id, container_name, monitoring_end_time))`,
+	`CREATE TABLE cost_rates (id integer PRIMARY KEY AUTOINCREMENT, org_id text NOT NULL,
+		cluster_uuid text NOT NULL DEFAULT '', cpu_core_hour_rate numeric NOT NULL, memory_gib_hour_rate numeric NOT NULL,
+		currency text NOT NULL DEFAULT 'USD', updated_at datetime NOT NULL, UNIQUE (org_id, cluster_uuid))`,
+	`CREATE TABLE webhooks (id integer PRIMARY KEY AUTOINCREMENT, org_id text NOT NULL, url text NOT NULL,
+		secret text NOT NULL, min_variation_pct numeric NOT NULL DEFAULT 0, updated_at datetime NOT NULL,
+		UNIQUE (org_id, url))`,
+	`CREATE TABLE orphaned_experiments (experiment_name text PRIMARY KEY, first_seen_at datetime NOT NULL,
+		last_seen_at datetime NOT NULL)`,
+	`CREATE TABLE recommendation_retries (id integer PRIMARY KEY AUTOINCREMENT, message_key text NOT NULL,
+		payload blob NOT NULL, attempt integer NOT NULL, due_at datetime NOT NULL, reason text NOT NULL DEFAULT '')`,
+}
+
+// Use swaps database.DB for a new in-memory database holding the ros-ocp tables until the test
+// ends, and returns it.
+func Use(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("unable to open in-memory SQLite: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("unable to open in-memory SQLite: %v", err)
+	}
+	// every connection to :memory: opens a new database
+	sqlDB.SetMaxOpenConns(1)
+	for _, table := range append([]string{"PRAGMA foreign_keys = ON"}, schema...) {
+		if err := db.Exec(table).Error; err != nil {
+			t.Fatalf("unable to create the test schema: %v", err)
+		}
+	}
+
+	original := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		database.DB = original
+		_ = sqlDB.Close()
+	})
+	return db
+}
@@ -0,0 +1,119 @@
+package kafka
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/logging"
+)
+
+// Backpressure tells a consumer to stop handling messages while they could not succeed, e.g.
+// while a service the handler calls is down.
+type Backpressure interface {
+	// Blocked reports whether messages must wait. It is called before every message is handled,
+	// and about once a second while messages wait.
+	Blocked() bool
+}
+
+// BackpressureHandler handles a message like the handlers of StartConsumer and reports whether it
+// did. A message that could not be handled, e.g. because a service it needs is unavailable, is
+// neither committed nor lost: the consumer rewinds to it and holds it back with the messages after
+// it while backpressure is blocked.
+type BackpressureHandler func(msg *kafka.Message, consumer_object *kafka.Consumer) (handled bool)
+
+// heldBack collects the positions of the messages that were not handled, the earliest per
+// partition, until the poll loop rewinds to them.
+type heldBack struct {
+	mu        sync.Mutex
+	positions map[partitionKey]kafka.TopicPartition
+}
+
+func newHeldBack() *heldBack {
+	return &heldBack{positions: make(map[partitionKey]kafka.TopicPartition)}
+}
+
+func (h *heldBack) add(tp kafka.TopicPartition) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	key := partitionKeyOf(tp)
+	if held, ok := h.positions[key]; ok && held.Offset <= tp.Offset {
+		return
+	}
+	h.positions[key] = tp
+}
+
+func (h *heldBack) pending() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.positions) > 0
+}
+
+// take returns the held positions and forgets them.
+func (h *heldBack) take() []kafka.TopicPartition {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	positions := make([]kafka.TopicPartition, 0, len(h.positions))
+	for _, position := range h.positions {
+		positions = append(positions, position)
+	}
+	h.positions = make(map[partitionKey]kafka.TopicPartition)
+	return positions
+}
+
+// holdBack pauses the assigned partitions and rewinds the partitions of positions, the messages
+// read but not handled, until backpressure is no longer blocked or ctx is done. The consumer keeps
+// polling meanwhile so that it stays in its group. Without backpressure the partitions are only
+// rewound.
+func holdBack(ctx context.Context, consumer *kafka.Consumer, positions []kafka.TopicPartition, backpressure Backpressure) {
+	log := logging.GetLogger()
+	log.Warnf("pausing consumption, handling messages is blocked (partitions=%v)", positions)
+	for _, position := range positions {
+		pause(consumer, position)
+	}
+	assignment, err := consumer.Assignment()
+	if err != nil {
+		log.Errorf("unable to get the consumer assignment: %v", err)
+	} else if err := consumer.Pause(assignment); err != nil {
+		log.Errorf("unable to pause partitions %v: %v", assignment, err)
+	}
+
+	for ctx.Err() == nil && backpressure != nil && backpressure.Blocked() {
+		switch event := consumer.Poll(int(time.Second.Milliseconds())).(type) {
+		case *kafka.Message:
+			// a partition assigned while paused is not paused yet
+			pause(consumer, event.TopicPartition)
+		case kafka.Error:
+			log.Errorf("Consumer error: %v", event)
+		}
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	assignment, err = consumer.Assignment()
+	if err != nil {
+		log.Errorf("unable to get the consumer assignment: %v", err)
+		return
+	}
+	if err := consumer.Resume(assignment); err != nil {
+		log.Errorf("unable to resume partitions %v: %v", assignment, err)
+		return
+	}
+	log.Info("resuming consumption, handling messages is no longer blocked")
+}
+
+// pause pauses the partition of position and rewinds it so that the message at position is
+// read again once the partition is resumed.
+func pause(consumer *kafka.Consumer, position kafka.TopicPartition) {
+	log := logging.GetLogger()
+	position.Error = nil
+	if err := consumer.Pause([]kafka.TopicPartition{position}); err != nil {
+		log.Errorf("unable to pause partition %s: %v", position, err)
+	}
+	if _, err := consumer.SeekPartitions([]kafka.TopicPartition{position}); err != nil {
+		log.Errorf("unable to rewind partition %s: %v", position, err)
+	}
+}
@@ -0,0 +1,197 @@
+package kafka
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/config"
+)
+
+type switchBackpressure struct {
+	blocked atomic.Bool
+}
+
+func (b *switchBackpressure) Blocked() bool {
+	return b.blocked.Load()
+}
+
+// useMockCluster points the consumers at a mock Kafka cluster holding topic, with a partition
+// holding values, and commits offset 0 for the consumer group so that consumers read them all.
+func useMockCluster(t *testing.T, topic string, values ...string) {
+	t.Helper()
+	cluster, err := kafka.NewMockCluster(1)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(cluster.Close)
+	assert.NoError(t, cluster.CreateTopic(topic, 1, 1))
+
+	cfg := config.GetConfig()
+	original := *cfg
+	t.Cleanup(func() { *cfg = original })
+	cfg.KafkaBootstrapServers = cluster.BootstrapServers()
+	cfg.KafkaSASLMechanism = ""
+	cfg.KafkaConsumerGroupId = "ros-ocp-test"
+	cfg.KafkaAutoCommit = true
+
+	producer, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": cluster.BootstrapServers()})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer producer.Close()
+	delivered := make(chan kafka.Event, len(values))
+	for _, value := range values {
+		assert.NoError(t, producer.Produce(&kafka.Message{
+			TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 0},
+			Value:          []byte(value),
+		}, delivered))
+	}
+	for range values {
+		assert.NoError(t, (<-delivered).(*kafka.Message).TopicPartition.Error)
+	}
+
+	withGroupConsumer(t, func(consumer *kafka.Consumer) {
+		_, err := consumer.CommitOffsets([]kafka.TopicPartition{{Topic: &topic, Partition: 0, Offset: 0}})
+		assert.NoError(t, err)
+	})
+}
+
+// withGroupConsumer calls use with a consumer of the consumer group that does not subscribe.
+func withGroupConsumer(t *testing.T, use func(consumer *kafka.Consumer)) {
+	t.Helper()
+	configMap := consumerConfigMap(config.GetConfig().KafkaConsumerGroupId, false)
+	consumer, err := kafka.NewConsumer(&configMap)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer func() {
+		_ = consumer.Close()
+	}()
+	use(consumer)
+}
+
+// committedOffset returns the offset the consumer group committed on the partition of topic.
+func committedOffset(t *testing.T, topic string) kafka.Offset {
+	t.Helper()
+	offset := kafka.OffsetInvalid
+	withGroupConsumer(t, func(consumer *kafka.Consumer) {
+		committed, err := consumer.Committed([]kafka.TopicPartition{{Topic: &topic, Partition: 0}}, 10000)
+		if assert.NoError(t, err) && assert.Len(t, committed, 1) {
+			offset = committed[0].Offset
+		}
+	})
+	return offset
+}
+
+// recordingHandler records the values it is given. It does not handle the message valued
+// unhandled the first time, and blocks backpressure then.
+type recordingHandler struct {
+	mu           sync.Mutex
+	values       []string
+	unhandled    string
+	backpressure *switchBackpressure
+	seen         chan string
+}
+
+func (h *recordingHandler) handle(msg *kafka.Message, _ *kafka.Consumer) bool {
+	value := string(msg.Value)
+	h.mu.Lock()
+	first := true
+	for _, handled := range h.values {
+		if handled == value {
+			first = false
+		}
+	}
+	h.values = append(h.values, value)
+	h.mu.Unlock()
+	h.seen <- value
+	if value == h.unhandled && first {
+		h.backpressure.blocked.Store(true)
+		return false
+	}
+	return true
+}
+
+func (h *recordingHandler) recorded() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.values...)
+}
+
+func waitFor(t *testing.T, seen chan string, value string) {
+	t.Helper()
+	timeout := time.After(30 * time.Second)
+	for {
+		select {
+		case got := <-seen:
+			if got == value {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("message %q was not handled", value)
+		}
+	}
+}
+
+func TestHeldBackKeepsEarliestPositionPerPartition(t *testing.T) {
+	held := newHeldBack()
+	assert.False(t, held.pending())
+	held.add(topicPartition(0, 12))
+	held.add(topicPartition(0, 10))
+	held.add(topicPartition(0, 11))
+	held.add(topicPartition(1, 5))
+	assert.True(t, held.pending())
+
+	assert.ElementsMatch(t, []kafka.TopicPartition{topicPartition(0, 10), topicPartition(1, 5)}, held.take())
+	assert.False(t, held.pending())
+}
+
+func TestStartConsumerWithBackpressure_HandlesUnhandledMessageAgain(t *testing.T) {
+	topic := "hccm.ros.events"
+	useMockCluster(t, topic, "a", "b", "c")
+	backpressure := &switchBackpressure{}
+	handler := &recordingHandler{unhandled: "b", backpressure: backpressure, seen: make(chan string, 10)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- StartConsumerWithBackpressure(ctx, topic, handler.handle, 1, backpressure)
+	}()
+
+	waitFor(t, handler.seen, "b")
+	// the consumer holds b back until backpressure is no longer blocked
+	time.Sleep(time.Second)
+	assert.Equal(t, []string{"a", "b"}, handler.recorded())
+	backpressure.blocked.Store(false)
+
+	waitFor(t, handler.seen, "c")
+	cancel()
+	assert.NoError(t, <-done)
+	assert.Equal(t, []string{"a", "b", "b", "c"}, handler.recorded())
+	assert.Equal(t, kafka.Offset(3), committedOffset(t, topic))
+}
+
+func TestStartConsumerWithBackpressure_DoesNotCommitHeldMessage(t *testing.T) {
+	topic := "hccm.ros.events"
+	useMockCluster(t, topic, "a", "b", "c")
+	backpressure := &switchBackpressure{}
+	handler := &recordingHandler{unhandled: "b", backpressure: backpressure, seen: make(chan string, 10)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- StartConsumerWithBackpressure(ctx, topic, handler.handle, 1, backpressure)
+	}()
+
+	waitFor(t, handler.seen, "b")
+	cancel()
+	assert.NoError(t, <-done)
+	assert.Equal(t, []string{"a", "b"}, handler.recorded())
+	assert.Equal(t, kafka.Offset(1), committedOffset(t, topic), "the next consumer reads b again")
+}
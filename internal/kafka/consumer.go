@@ -1,9 +1,8 @@
 package kafka
 
 import (
-	"os"
-	"os/signal"
-	"syscall"
+	"context"
+	"fmt"
 	"time"
 
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
@@ -13,36 +12,19 @@ import (
 	"github.com/redhatinsights/ros-ocp-backend/internal/logging"
 )
 
-func StartConsumer(kafka_topic string, handler func(msg *kafka.Message, consumer_object *kafka.Consumer), auto_commit_option ...bool) {
-	log := logging.GetLogger()
+// consumerConfigMap returns the consumer configuration shared by every consumer of the service.
+func consumerConfigMap(groupID string, autoCommit bool) kafka.ConfigMap {
 	cfg := config.GetConfig()
-
-	// initialize unleash service
-	if err := featureflags.Init(); err != nil {
-		log.Errorf("Unleash Error: %v", err)
-	}
-
-	// Fetch and validate auto_commit_option value
-	var auto_commit bool
-	if len(auto_commit_option) > 0 && !auto_commit_option[0] {
-		auto_commit = auto_commit_option[0]
-	} else {
-		auto_commit = cfg.KafkaAutoCommit
-	}
-
-	sigchan := make(chan os.Signal, 1)
-	signal.Notify(sigchan, syscall.SIGINT, syscall.SIGTERM)
-
 	var configMap kafka.ConfigMap
 	if cfg.KafkaSASLMechanism != "" {
 		configMap = kafka.ConfigMap{
 			"bootstrap.servers":        cfg.KafkaBootstrapServers,
-			"group.id":                 cfg.KafkaConsumerGroupId,
+			"group.id":                 groupID,
 			"security.protocol":        cfg.KafkaSecurityProtocol,
 			"sasl.mechanism":           cfg.KafkaSASLMechanism,
 			"sasl.username":            cfg.KafkaUsername,
 			"sasl.password":            cfg.KafkaPassword,
-			"enable.auto.commit":       auto_commit,
+			"enable.auto.commit":       autoCommit,
 			"go.logs.channel.enable":   true,
 			"allow.auto.create.topics": true,
 		}
@@ -56,8 +38,8 @@ func StartConsumer(kafka_topic string, handler func(msg *kafka.Message, consumer
 	} else {
 		configMap = kafka.ConfigMap{
 			"bootstrap.servers":        cfg.KafkaBootstrapServers,
-			"group.id":                 cfg.KafkaConsumerGroupId,
-			"enable.auto.commit":       auto_commit,
+			"group.id":                 groupID,
+			"enable.auto.commit":       autoCommit,
 			"go.logs.channel.enable":   true,
 			"allow.auto.create.topics": true,
 		}
@@ -65,43 +47,173 @@ func StartConsumer(kafka_topic string, handler func(msg *kafka.Message, consumer
 
 	configMap["session.timeout.ms"] = 120000
 	configMap["heartbeat.interval.ms"] = 30000
+	return configMap
+}
+
+// StartConsumer handles the messages of kafka_topic until ctx is done. The message being
+// handled when ctx is done is finished and committed before the consumer is closed.
+func StartConsumer(ctx context.Context, kafka_topic string, handler func(msg *kafka.Message, consumer_object *kafka.Consumer), auto_commit_option ...bool) error {
+	return StartConsumerWithWorkers(ctx, kafka_topic, handler, 1, auto_commit_option...)
+}
+
+// StartConsumerWithWorkers is StartConsumer handling up to workers messages at a time. With more
+// than one worker, messages with the same key are still handled in order, and offsets are committed
+// by the consumer once a message and every earlier message of its partition have been handled.
+// Handlers then get a nil consumer and must not commit themselves.
+func StartConsumerWithWorkers(ctx context.Context, kafka_topic string, handler func(msg *kafka.Message, consumer_object *kafka.Consumer), workers int, auto_commit_option ...bool) error {
+	handled := func(msg *kafka.Message, consumer_object *kafka.Consumer) bool {
+		handler(msg, consumer_object)
+		return true
+	}
+	return StartConsumerWithBackpressure(ctx, kafka_topic, handled, workers, nil, auto_commit_option...)
+}
+
+// StartConsumerWithBackpressure is StartConsumerWithWorkers holding messages back while
+// backpressure is blocked: the consumer pauses its partitions, rewinds them to the first message
+// not handled, and resumes once backpressure is no longer blocked. Nothing is committed meanwhile.
+// A message the handler did not handle is held back the same way; with more than one worker, the
+// messages of its partition handled meanwhile are read and handled again.
+func StartConsumerWithBackpressure(ctx context.Context, kafka_topic string, handler BackpressureHandler, workers int, backpressure Backpressure, auto_commit_option ...bool) error {
+	log := logging.GetLogger()
+	cfg := config.GetConfig()
+
+	// initialize unleash service
+	if err := featureflags.Init(); err != nil {
+		log.Errorf("Unleash Error: %v", err)
+	}
 
+	// Fetch and validate auto_commit_option value
+	var auto_commit bool
+	if len(auto_commit_option) > 0 && !auto_commit_option[0] {
+		auto_commit = auto_commit_option[0]
+	} else {
+		auto_commit = cfg.KafkaAutoCommit
+	}
+
+	configMap := consumerConfigMap(cfg.KafkaConsumerGroupId, auto_commit)
+	// offsets are stored once messages are handled, not when they are read, so that auto-commit
+	// never commits past a message held back or still in progress
+	configMap["enable.auto.offset.store"] = false
 	consumer, err := kafka.NewConsumer(&configMap)
 	if err != nil {
-		log.Errorf("Failed to create consumer: %s", err)
-		os.Exit(1)
+		return fmt.Errorf("failed to create consumer: %w", err)
 	}
 
-	err = consumer.Subscribe(kafka_topic, nil)
+	held := newHeldBack()
+	handle := func(msg *kafka.Message) {
+		if !handler(msg, consumer) {
+			held.add(msg.TopicPartition)
+			return
+		}
+		if !auto_commit {
+			// the handler commits
+			return
+		}
+		if err := commitPosition(consumer, nextPosition(msg.TopicPartition), auto_commit); err != nil {
+			log.Errorf("unable to store offset of %s: %v", msg.TopicPartition, err)
+		}
+	}
+	settle := func() {}
+	drain := func() {}
+	var rebalanceCb kafka.RebalanceCb
+	if workers > 1 {
+		log.Infof("handling messages with %d workers", workers)
+		tracker := newOffsetTracker()
+		pool := newWorkerPool(workers, func(msg *kafka.Message) {
+			if !handler(msg, nil) {
+				// the partition is not committed past msg until it is read again
+				held.add(msg.TopicPartition)
+				return
+			}
+			position, ok := tracker.complete(msg.TopicPartition)
+			if !ok {
+				return
+			}
+			if err := commitPosition(consumer, position, auto_commit); err != nil {
+				log.Errorf("unable to commit offset %s: %v", position, err)
+			}
+		})
+		handle = func(msg *kafka.Message) {
+			tracker.track(msg.TopicPartition)
+			pool.dispatch(msg)
+		}
+		settle = func() {
+			pool.wait()
+			tracker.reset()
+		}
+		drain = func() {
+			pool.wait()
+			pool.close()
+		}
+		rebalanceCb = func(c *kafka.Consumer, event kafka.Event) error {
+			if _, ok := event.(kafka.RevokedPartitions); ok {
+				// let in-flight messages finish so their offsets are committed before the partitions move
+				pool.wait()
+				tracker.reset()
+				// the messages held back are read again by the new owner of their partitions
+				held.take()
+			}
+			return nil
+		}
+	}
+
+	err = consumer.Subscribe(kafka_topic, rebalanceCb)
 	if err != nil {
-		log.Errorf("Failed to subscribe to topic %s: %s", kafka_topic, err)
 		_ = consumer.Close()
-		os.Exit(1)
+		return fmt.Errorf("failed to subscribe to topic %s: %w", kafka_topic, err)
 	}
 
-	run := true
-	for run {
-		select {
-		case sig := <-sigchan:
-			log.Infof("Caught Signal %v: terminating", sig)
-			_ = consumer.Close()
-			os.Exit(1)
-		default:
-			msg, err := consumer.ReadMessage(time.Second)
-			if err == nil {
-				// Invoke report processor function in this block.
-				log.Infof("Message received from kafka %s (len=%d)", msg.TopicPartition, len(msg.Value))
-				log.Debugf("Message payload (truncated): %.512s", string(msg.Value))
-				handler(msg, consumer)
-			} else if kerr, ok := err.(kafka.Error); ok && !kerr.IsTimeout() {
-				// The client will automatically try to recover from all errors.
-				// Timeout is not considered an error because it is raised by
-				// ReadMessage in absence of messages.
-				log.Errorf("Consumer error: %v (%v)", err, msg)
-			} else if !ok {
-				log.Errorf("Consumer unexpected error type: %T: %v", err, err)
-			}
+	for ctx.Err() == nil {
+		if held.pending() {
+			// let in-flight messages finish, then read again from the first messages not handled
+			settle()
+			holdBack(ctx, consumer, held.take(), backpressure)
+			continue
+		}
+		msg, err := consumer.ReadMessage(time.Second)
+		if err == nil && backpressure != nil && backpressure.Blocked() {
+			holdBack(ctx, consumer, []kafka.TopicPartition{msg.TopicPartition}, backpressure)
+			continue
 		}
+		if err == nil {
+			// Invoke report processor function in this block.
+			log.Infof("Message received from kafka %s (len=%d)", msg.TopicPartition, len(msg.Value))
+			log.Debugf("Message payload (truncated): %.512s", string(msg.Value))
+			handle(msg)
+		} else if kerr, ok := err.(kafka.Error); ok && !kerr.IsTimeout() {
+			// The client will automatically try to recover from all errors.
+			// Timeout is not considered an error because it is raised by
+			// ReadMessage in absence of messages.
+			log.Errorf("Consumer error: %v (%v)", err, msg)
+		} else if !ok {
+			log.Errorf("Consumer unexpected error type: %T: %v", err, err)
+		}
+	}
+
+	log.Infof("stopping consumer of %s: %v", kafka_topic, context.Cause(ctx))
+	drain()
+	// Close commits the stored offsets when auto-commit is enabled and leaves the group
+	if err := consumer.Close(); err != nil {
+		return fmt.Errorf("failed to close consumer: %w", err)
+	}
+	return nil
+}
+
+// nextPosition is the position after the message at tp.
+func nextPosition(tp kafka.TopicPartition) kafka.TopicPartition {
+	tp.Offset++
+	tp.Error = nil
+	return tp
+}
+
+// commitPosition stores position for the next auto-commit, or commits it right away when
+// auto-commit is disabled.
+func commitPosition(consumer *kafka.Consumer, position kafka.TopicPartition, autoCommit bool) error {
+	var err error
+	if autoCommit {
+		_, err = consumer.StoreOffsets([]kafka.TopicPartition{position})
+	} else {
+		_, err = consumer.CommitOffsets([]kafka.TopicPartition{position})
 	}
-	_ = consumer.Close()
+	return err
 }
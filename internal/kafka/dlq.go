@@ -0,0 +1,110 @@
+package kafka
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/config"
+)
+
+// Headers of dead-lettered messages. HeaderAttempt is also carried by replayed and retried
+// messages so attempts keep counting.
+const (
+	HeaderAttempt            = "rosocp-attempt"
+	HeaderDLQStage           = "rosocp-dlq-stage"
+	HeaderDLQReason          = "rosocp-dlq-reason"
+	HeaderDLQFailedAt        = "rosocp-dlq-failed-at"
+	HeaderDLQSourceTopic     = "rosocp-dlq-source-topic"
+	HeaderDLQSourcePartition = "rosocp-dlq-source-partition"
+	HeaderDLQSourceOffset    = "rosocp-dlq-source-offset"
+)
+
+const (
+	readTopicTimeout           = 10 * time.Second
+	readTopicMetadataTimeoutMs = 10000
+)
+
+// HeaderValue returns the value of the last header named key.
+func HeaderValue(headers []kafka.Header, key string) (string, bool) {
+	for i := len(headers) - 1; i >= 0; i-- {
+		if headers[i].Key == key {
+			return string(headers[i].Value), true
+		}
+	}
+	return "", false
+}
+
+// Attempt returns which processing attempt msg is: 1 unless it was replayed from the dead-letter topic.
+func Attempt(msg *kafka.Message) int {
+	value, ok := HeaderValue(msg.Headers, HeaderAttempt)
+	if !ok {
+		return 1
+	}
+	attempt, err := strconv.Atoi(value)
+	if err != nil || attempt < 1 {
+		return 1
+	}
+	return attempt
+}
+
+// ReadTopic calls handler with every message stored in topic when reading starts, from the
+// earliest offset of each partition. It reads outside of any consumer group and commits nothing,
+// so it does not disturb the consumers of the topic.
+func ReadTopic(topic string, handler func(msg *kafka.Message)) error {
+	cfg := config.GetConfig()
+	configMap := consumerConfigMap(cfg.KafkaConsumerGroupId+"-reader", false)
+	configMap["go.logs.channel.enable"] = false
+	consumer, err := kafka.NewConsumer(&configMap)
+	if err != nil {
+		return fmt.Errorf("unable to create consumer: %w", err)
+	}
+	defer func() {
+		_ = consumer.Close()
+	}()
+
+	metadata, err := consumer.GetMetadata(&topic, false, readTopicMetadataTimeoutMs)
+	if err != nil {
+		return fmt.Errorf("unable to get metadata of topic %s: %w", topic, err)
+	}
+	topicMetadata, ok := metadata.Topics[topic]
+	if !ok || topicMetadata.Error.Code() != kafka.ErrNoError {
+		return fmt.Errorf("topic %s not found", topic)
+	}
+
+	// stop at the high watermarks seen now so messages produced while reading are left alone
+	highWatermarks := make(map[int32]int64)
+	var partitions []kafka.TopicPartition
+	for _, partition := range topicMetadata.Partitions {
+		low, high, err := consumer.QueryWatermarkOffsets(topic, partition.ID, readTopicMetadataTimeoutMs)
+		if err != nil {
+			return fmt.Errorf("unable to get offsets of %s [%d]: %w", topic, partition.ID, err)
+		}
+		if high <= low {
+			continue
+		}
+		highWatermarks[partition.ID] = high
+		partitions = append(partitions, kafka.TopicPartition{Topic: &topic, Partition: partition.ID, Offset: kafka.Offset(low)})
+	}
+	if len(partitions) == 0 {
+		return nil
+	}
+	if err := consumer.Assign(partitions); err != nil {
+		return fmt.Errorf("unable to assign partitions of %s: %w", topic, err)
+	}
+
+	for len(highWatermarks) > 0 {
+		msg, err := consumer.ReadMessage(readTopicTimeout)
+		if err != nil {
+			return fmt.Errorf("unable to read %s: %w", topic, err)
+		}
+		handler(msg)
+		partition := msg.TopicPartition.Partition
+		if high, ok := highWatermarks[partition]; ok && int64(msg.TopicPartition.Offset)+1 >= high {
+			delete(highWatermarks, partition)
+		}
+	}
+	return nil
+}
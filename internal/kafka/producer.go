@@ -2,6 +2,7 @@ package kafka
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
@@ -13,6 +14,22 @@ import (
 var p *kafka.Producer = nil
 var log *logrus.Entry = nil
 
+// producerInit guards the lazy creation of the producer, messages are sent from many goroutines.
+var producerInit sync.Mutex
+
+func getProducer() *kafka.Producer {
+	producerInit.Lock()
+	defer producerInit.Unlock()
+	if log == nil {
+		log = logging.GetLogger()
+	}
+	if p == nil {
+		log.Info("initializing kafka producer")
+		startProducer()
+	}
+	return p
+}
+
 func startProducer() {
 	cfg := config.GetConfig()
 	var configMap kafka.ConfigMap
@@ -52,20 +69,18 @@ func startProducer() {
 const sendMessageMaxRetries = 3
 
 func SendMessage(msg []byte, topic string, key string) error {
-	if log == nil {
-		log = logging.GetLogger()
-	}
-	if p == nil {
-		log.Info("initializing kafka producer")
-		startProducer()
-	}
-	if p == nil {
+	return SendMessageWithHeaders(msg, topic, key, nil)
+}
+
+// SendMessageWithHeaders is SendMessage with Kafka record headers attached.
+func SendMessageWithHeaders(msg []byte, topic string, key string, headers []kafka.Header) error {
+	if getProducer() == nil {
 		return fmt.Errorf("kafka producer failed to initialize; cannot send message to topic %s", topic)
 	}
 
 	var lastErr error
 	for attempt := 0; attempt < sendMessageMaxRetries; attempt++ {
-		lastErr = sendMessageOnce(msg, topic, key)
+		lastErr = sendMessageOnce(msg, topic, key, headers)
 		if lastErr == nil {
 			return nil
 		}
@@ -80,13 +95,14 @@ func SendMessage(msg []byte, topic string, key string) error {
 	return lastErr
 }
 
-func sendMessageOnce(msg []byte, topic string, key string) error {
+func sendMessageOnce(msg []byte, topic string, key string, headers []kafka.Header) error {
 	delivery_chan := make(chan kafka.Event)
 	defer close(delivery_chan)
 	err := p.Produce(&kafka.Message{
 		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
 		Key:            []byte(key),
 		Value:          []byte(msg),
+		Headers:        headers,
 	}, delivery_chan)
 	if err != nil {
 		return fmt.Errorf("produce failed: %w", err)
@@ -0,0 +1,142 @@
+package kafka
+
+import (
+	"hash/fnv"
+	"sync"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// workerQueueSize is how many messages may wait for each worker before dispatch blocks the poll loop.
+const workerQueueSize = 1
+
+type partitionKey struct {
+	topic     string
+	partition int32
+}
+
+func partitionKeyOf(tp kafka.TopicPartition) partitionKey {
+	key := partitionKey{partition: tp.Partition}
+	if tp.Topic != nil {
+		key.topic = *tp.Topic
+	}
+	return key
+}
+
+type partitionOffsets struct {
+	// dispatched offsets in the order they were read, up to the first one still in progress
+	dispatched []kafka.Offset
+	done       map[kafka.Offset]bool
+}
+
+// offsetTracker tracks the messages handed to workers so that the committed position of a
+// partition only moves past a message once it and every earlier message of the partition
+// have been handled, whichever order workers finish in.
+type offsetTracker struct {
+	mu         sync.Mutex
+	partitions map[partitionKey]*partitionOffsets
+}
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{partitions: make(map[partitionKey]*partitionOffsets)}
+}
+
+func (t *offsetTracker) track(tp kafka.TopicPartition) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := partitionKeyOf(tp)
+	offsets, ok := t.partitions[key]
+	if !ok {
+		offsets = &partitionOffsets{done: make(map[kafka.Offset]bool)}
+		t.partitions[key] = offsets
+	}
+	offsets.dispatched = append(offsets.dispatched, tp.Offset)
+}
+
+// complete marks the message at tp handled. It returns the position to commit, the offset
+// after the last of the contiguous handled messages, when that position moved forward.
+func (t *offsetTracker) complete(tp kafka.TopicPartition) (kafka.TopicPartition, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	offsets, ok := t.partitions[partitionKeyOf(tp)]
+	if !ok {
+		// the partition was revoked and reset while the message was handled
+		return kafka.TopicPartition{}, false
+	}
+	offsets.done[tp.Offset] = true
+
+	var last kafka.Offset
+	advanced := false
+	for len(offsets.dispatched) > 0 && offsets.done[offsets.dispatched[0]] {
+		last = offsets.dispatched[0]
+		delete(offsets.done, last)
+		offsets.dispatched = offsets.dispatched[1:]
+		advanced = true
+	}
+	if !advanced {
+		return kafka.TopicPartition{}, false
+	}
+	position := tp
+	position.Offset = last + 1
+	position.Error = nil
+	return position, true
+}
+
+func (t *offsetTracker) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.partitions = make(map[partitionKey]*partitionOffsets)
+}
+
+// workerPool handles messages on a fixed number of goroutines. Messages with the same key
+// always go to the same worker, so they are handled in the order they were read. Messages
+// without a key carry no ordering and are spread round-robin.
+type workerPool struct {
+	queues   []chan *kafka.Message
+	inFlight sync.WaitGroup
+	next     int
+}
+
+func newWorkerPool(workers int, handle func(msg *kafka.Message)) *workerPool {
+	pool := &workerPool{queues: make([]chan *kafka.Message, workers)}
+	for i := range pool.queues {
+		queue := make(chan *kafka.Message, workerQueueSize)
+		pool.queues[i] = queue
+		go func() {
+			for msg := range queue {
+				handle(msg)
+				pool.inFlight.Done()
+			}
+		}()
+	}
+	return pool
+}
+
+func (p *workerPool) worker(key []byte) int {
+	if len(key) == 0 {
+		worker := p.next
+		p.next = (p.next + 1) % len(p.queues)
+		return worker
+	}
+	hash := fnv.New32a()
+	_, _ = hash.Write(key)
+	return int(hash.Sum32() % uint32(len(p.queues)))
+}
+
+// dispatch queues msg for its worker, blocking while that worker is busy. It must only be
+// called from the poll loop.
+func (p *workerPool) dispatch(msg *kafka.Message) {
+	p.inFlight.Add(1)
+	p.queues[p.worker(msg.Key)] <- msg
+}
+
+// wait blocks until every dispatched message has been handled.
+func (p *workerPool) wait() {
+	p.inFlight.Wait()
+}
+
+func (p *workerPool) close() {
+	for _, queue := range p.queues {
+		close(queue)
+	}
+}
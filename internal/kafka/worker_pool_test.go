@@ -0,0 +1,87 @@
+package kafka
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/stretchr/testify/assert"
+)
+
+func topicPartition(partition int32, offset kafka.Offset) kafka.TopicPartition {
+	topic := "hccm.ros.events"
+	return kafka.TopicPartition{Topic: &topic, Partition: partition, Offset: offset}
+}
+
+func TestOffsetTrackerCommitsContiguousOffsets(t *testing.T) {
+	tracker := newOffsetTracker()
+	for _, offset := range []kafka.Offset{10, 11, 13} {
+		tracker.track(topicPartition(0, offset))
+	}
+	tracker.track(topicPartition(1, 5))
+
+	_, ok := tracker.complete(topicPartition(0, 11))
+	assert.False(t, ok, "offset 10 is still in progress")
+
+	position, ok := tracker.complete(topicPartition(0, 10))
+	assert.True(t, ok)
+	assert.Equal(t, kafka.Offset(12), position.Offset)
+	assert.Equal(t, int32(0), position.Partition)
+
+	position, ok = tracker.complete(topicPartition(1, 5))
+	assert.True(t, ok)
+	assert.Equal(t, kafka.Offset(6), position.Offset)
+	assert.Equal(t, int32(1), position.Partition)
+
+	// offsets may have gaps, e.g. after compaction
+	position, ok = tracker.complete(topicPartition(0, 13))
+	assert.True(t, ok)
+	assert.Equal(t, kafka.Offset(14), position.Offset)
+}
+
+func TestOffsetTrackerIgnoresRevokedPartitions(t *testing.T) {
+	tracker := newOffsetTracker()
+	tracker.track(topicPartition(0, 1))
+	tracker.reset()
+
+	_, ok := tracker.complete(topicPartition(0, 1))
+	assert.False(t, ok)
+}
+
+func TestWorkerPoolKeepsKeyOrder(t *testing.T) {
+	var mu sync.Mutex
+	handled := make(map[string][]kafka.Offset)
+	pool := newWorkerPool(4, func(msg *kafka.Message) {
+		mu.Lock()
+		defer mu.Unlock()
+		handled[string(msg.Key)] = append(handled[string(msg.Key)], msg.TopicPartition.Offset)
+	})
+	defer pool.close()
+
+	keys := []string{"cluster-a", "cluster-b", "cluster-c"}
+	for offset := kafka.Offset(0); offset < 30; offset++ {
+		pool.dispatch(&kafka.Message{
+			Key:            []byte(keys[int(offset)%len(keys)]),
+			TopicPartition: topicPartition(0, offset),
+		})
+	}
+	pool.wait()
+
+	for i, key := range keys {
+		var want []kafka.Offset
+		for offset := kafka.Offset(i); offset < 30; offset += kafka.Offset(len(keys)) {
+			want = append(want, offset)
+		}
+		assert.Equal(t, want, handled[key], key)
+	}
+}
+
+func TestWorkerPoolSpreadsKeylessMessages(t *testing.T) {
+	pool := &workerPool{queues: make([]chan *kafka.Message, 3)}
+	var workers []int
+	for i := 0; i < 4; i++ {
+		workers = append(workers, pool.worker(nil))
+	}
+	assert.Equal(t, []int{0, 1, 2, 0}, workers)
+	assert.Equal(t, pool.worker([]byte("cluster-a")), pool.worker([]byte("cluster-a")))
+}
@@ -61,8 +61,10 @@ func GetLogger() *logrus.Entry {
 	return log
 }
 
+// Set_request_details returns the logger with the details of an upload request. The shared
+// logger is left alone: messages are handled concurrently and details must not leak between them.
 func Set_request_details(data types.KafkaMsg) *logrus.Entry {
-	log = log.WithFields(logrus.Fields{
+	return GetLogger().WithFields(logrus.Fields{
 		"request_id":    data.Request_id,
 		"account":       data.Metadata.Account,
 		"org_id":        data.Metadata.Org_id,
@@ -70,16 +72,15 @@ func Set_request_details(data types.KafkaMsg) *logrus.Entry {
 		"cluster_uuid":  data.Metadata.Cluster_uuid,
 		"cluster_alias": data.Metadata.Cluster_alias,
 	})
-	return log
 }
 
+// Set_request_details_recommendations is Set_request_details for recommendation requests.
 func Set_request_details_recommendations(data types.RecommendationKafkaMsg) *logrus.Entry {
-	log = log.WithFields(logrus.Fields{
+	return GetLogger().WithFields(logrus.Fields{
 		"request_id":         data.Request_id,
 		"org_id":             data.Metadata.Org_id,
 		"workload_id":        data.Metadata.Workload_id,
 		"max_endtime_report": data.Metadata.Max_endtime_report,
 		"experiment_name":    data.Metadata.Experiment_name,
 	})
-	return log
 }
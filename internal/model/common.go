@@ -23,18 +23,18 @@ func ptrFloat64(v float64) *float64 {
 // from DB columns. Used in API response building to avoid recomputing from the JSON blob.
 // Fields are pointers to handle nullable DB columns (e.g. existing rows before migration).
 type StoredVariationPcts struct {
-	CPUVariationShortCostPct            *float64 `gorm:"column:cpu_variation_short_cost_pct" json:"-"`
-	CPUVariationShortPerformancePct     *float64 `gorm:"column:cpu_variation_short_performance_pct" json:"-"`
-	CPUVariationMediumCostPct           *float64 `gorm:"column:cpu_variation_medium_cost_pct" json:"-"`
-	CPUVariationMediumPerformancePct    *float64 `gorm:"column:cpu_variation_medium_performance_pct" json:"-"`
-	CPUVariationLongCostPct             *float64 `gorm:"column:cpu_variation_long_cost_pct" json:"-"`
-	CPUVariationLongPerformancePct      *float64 `gorm:"column:cpu_variation_long_performance_pct" json:"-"`
-	MemoryVariationShortCostPct         *float64 `gorm:"column:memory_variation_short_cost_pct" json:"-"`
-	MemoryVariationShortPerformancePct  *float64 `gorm:"column:memory_variation_short_performance_pct" json:"-"`
-	MemoryVariationMediumCostPct        *float64 `gorm:"column:memory_variation_medium_cost_pct" json:"-"`
-	MemoryVariationMediumPerformancePct *float64 `gorm:"column:memory_variation_medium_performance_pct" json:"-"`
-	MemoryVariationLongCostPct          *float64 `gorm:"column:memory_variation_long_cost_pct" json:"-"`
-	MemoryVariationLongPerformancePct   *float64 `gorm:"column:memory_variation_long_performance_pct" json:"-"`
+	CPUVariationShortCostPct            *float64 `gorm:"column:cpu_variation_short_cost_pct;type:numeric(10,4)" json:"-"`
+	CPUVariationShortPerformancePct     *float64 `gorm:"column:cpu_variation_short_performance_pct;type:numeric(10,4)" json:"-"`
+	CPUVariationMediumCostPct           *float64 `gorm:"column:cpu_variation_medium_cost_pct;type:numeric(10,4)" json:"-"`
+	CPUVariationMediumPerformancePct    *float64 `gorm:"column:cpu_variation_medium_performance_pct;type:numeric(10,4)" json:"-"`
+	CPUVariationLongCostPct             *float64 `gorm:"column:cpu_variation_long_cost_pct;type:numeric(10,4)" json:"-"`
+	CPUVariationLongPerformancePct      *float64 `gorm:"column:cpu_variation_long_performance_pct;type:numeric(10,4)" json:"-"`
+	MemoryVariationShortCostPct         *float64 `gorm:"column:memory_variation_short_cost_pct;type:numeric(10,4)" json:"-"`
+	MemoryVariationShortPerformancePct  *float64 `gorm:"column:memory_variation_short_performance_pct;type:numeric(10,4)" json:"-"`
+	MemoryVariationMediumCostPct        *float64 `gorm:"column:memory_variation_medium_cost_pct;type:numeric(10,4)" json:"-"`
+	MemoryVariationMediumPerformancePct *float64 `gorm:"column:memory_variation_medium_performance_pct;type:numeric(10,4)" json:"-"`
+	MemoryVariationLongCostPct          *float64 `gorm:"column:memory_variation_long_cost_pct;type:numeric(10,4)" json:"-"`
+	MemoryVariationLongPerformancePct   *float64 `gorm:"column:memory_variation_long_performance_pct;type:numeric(10,4)" json:"-"`
 }
 
 // StoredVariationSpec is the single source of truth for supported term/engine combinations
@@ -120,21 +120,60 @@ func (s *StoredVariationPcts) Lookup(term, engine string) (cpu, mem *float64) {
 // Pointer fields are nil when a term/engine is absent so GORM persists SQL NULL.
 // Used to populate recommendation_sets and namespace_recommendation_sets columns for sorting.
 type RecommendationColumnValues struct {
-	CPURequestCurrent    *float64
-	MemoryRequestCurrent *float64
-
-	CPUVariationShortCostPct            *float64
-	CPUVariationShortPerformancePct     *float64
-	CPUVariationMediumCostPct           *float64
-	CPUVariationMediumPerformancePct    *float64
-	CPUVariationLongCostPct             *float64
-	CPUVariationLongPerformancePct      *float64
-	MemoryVariationShortCostPct         *float64
-	MemoryVariationShortPerformancePct  *float64
-	MemoryVariationMediumCostPct        *float64
-	MemoryVariationMediumPerformancePct *float64
-	MemoryVariationLongCostPct          *float64
-	MemoryVariationLongPerformancePct   *float64
+	CPURequestCurrent    *float64 `json:"cpu_request_current"`
+	MemoryRequestCurrent *float64 `json:"memory_request_current"`
+
+	CPUVariationShortCostPct            *float64 `json:"cpu_variation_short_cost_pct"`
+	CPUVariationShortPerformancePct     *float64 `json:"cpu_variation_short_performance_pct"`
+	CPUVariationMediumCostPct           *float64 `json:"cpu_variation_medium_cost_pct"`
+	CPUVariationMediumPerformancePct    *float64 `json:"cpu_variation_medium_performance_pct"`
+	CPUVariationLongCostPct             *float64 `json:"cpu_variation_long_cost_pct"`
+	CPUVariationLongPerformancePct      *float64 `json:"cpu_variation_long_performance_pct"`
+	MemoryVariationShortCostPct         *float64 `json:"memory_variation_short_cost_pct"`
+	MemoryVariationShortPerformancePct  *float64 `json:"memory_variation_short_performance_pct"`
+	MemoryVariationMediumCostPct        *float64 `json:"memory_variation_medium_cost_pct"`
+	MemoryVariationMediumPerformancePct *float64 `json:"memory_variation_medium_performance_pct"`
+	MemoryVariationLongCostPct          *float64 `json:"memory_variation_long_cost_pct"`
+	MemoryVariationLongPerformancePct   *float64 `json:"memory_variation_long_performance_pct"`
+}
+
+// VariationPcts returns the variation percentages to store with the recommendation.
+func (v RecommendationColumnValues) VariationPcts() StoredVariationPcts {
+	return StoredVariationPcts{
+		CPUVariationShortCostPct:            v.CPUVariationShortCostPct,
+		CPUVariationShortPerformancePct:     v.CPUVariationShortPerformancePct,
+		CPUVariationMediumCostPct:           v.CPUVariationMediumCostPct,
+		CPUVariationMediumPerformancePct:    v.CPUVariationMediumPerformancePct,
+		CPUVariationLongCostPct:             v.CPUVariationLongCostPct,
+		CPUVariationLongPerformancePct:      v.CPUVariationLongPerformancePct,
+		MemoryVariationShortCostPct:         v.MemoryVariationShortCostPct,
+		MemoryVariationShortPerformancePct:  v.MemoryVariationShortPerformancePct,
+		MemoryVariationMediumCostPct:        v.MemoryVariationMediumCostPct,
+		MemoryVariationMediumPerformancePct: v.MemoryVariationMediumPerformancePct,
+		MemoryVariationLongCostPct:          v.MemoryVariationLongCostPct,
+		MemoryVariationLongPerformancePct:   v.MemoryVariationLongPerformancePct,
+	}
+}
+
+// columnValues is the inverse of RecommendationColumnValues.VariationPcts, shared by the
+// container and namespace recommendation sets.
+func (s StoredVariationPcts) columnValues(cpuRequestCurrent, memoryRequestCurrent *float64) RecommendationColumnValues {
+	return RecommendationColumnValues{
+		CPURequestCurrent:                   cpuRequestCurrent,
+		MemoryRequestCurrent:                memoryRequestCurrent,
+		CPUVariationShortCostPct:            s.CPUVariationShortCostPct,
+		CPUVariationShortPerformancePct:     s.CPUVariationShortPerformancePct,
+		CPUVariationMediumCostPct:           s.CPUVariationMediumCostPct,
+		CPUVariationMediumPerformancePct:    s.CPUVariationMediumPerformancePct,
+		CPUVariationLongCostPct:             s.CPUVariationLongCostPct,
+		CPUVariationLongPerformancePct:      s.CPUVariationLongPerformancePct,
+		MemoryVariationShortCostPct:         s.MemoryVariationShortCostPct,
+		MemoryVariationShortPerformancePct:  s.MemoryVariationShortPerformancePct,
+		MemoryVariationMediumCostPct:        s.MemoryVariationMediumCostPct,
+		MemoryVariationMediumPerformancePct: s.MemoryVariationMediumPerformancePct,
+		MemoryVariationLongCostPct:          s.MemoryVariationLongCostPct,
+		MemoryVariationLongPerformancePct:   s.MemoryVariationLongPerformancePct,
+	}
 }
 
 // ExtractRecommendationColumnValues extracts current requests and per-term, per-engine
@@ -176,6 +215,27 @@ func extractTermVariations(recommVals *RecommendationColumnValues, terms kruizeP
 	}
 }
 
+// applyQueryParams adds the SQL clauses built by the API query param mappers to query.
+func applyQueryParams(query *gorm.DB, queryParams map[string]interface{}) *gorm.DB {
+	for key, values := range queryParams {
+		switch v := values.(type) {
+		case []string:
+			// Convert []string to []interface{} for unpacking multiple values
+			args := make([]interface{}, len(v))
+			for i, s := range v {
+				args[i] = s
+			}
+			query = query.Where(key, args...)
+		case nil:
+			// clauses without args, e.g. IS NULL checks
+			query = query.Where(key)
+		default:
+			query = query.Where(key, v)
+		}
+	}
+	return query
+}
+
 func getRecommendationQuery(orgID string) *gorm.DB {
 	db := database.GetDB()
 	query := db.Table("recommendation_sets").
@@ -200,12 +260,16 @@ func getRecommendationQuery(orgID string) *gorm.DB {
 			"recommendation_sets.memory_variation_medium_cost_pct, "+
 			"recommendation_sets.memory_variation_medium_performance_pct, "+
 			"recommendation_sets.memory_variation_long_cost_pct, "+
-			"recommendation_sets.memory_variation_long_performance_pct").
+			"recommendation_sets.memory_variation_long_performance_pct, "+
+			"recommendation_sets.applied_status, "+
+			"recommendation_sets.applied_at"+
+			recommendationStatusSelect).
 		Joins(`
 			JOIN workloads ON recommendation_sets.workload_id = workloads.id
 			JOIN clusters ON workloads.cluster_id = clusters.id
 			JOIN rh_accounts ON clusters.tenant_id = rh_accounts.id
-		`).Model(&RecommendationSetResult{}).
+		`).
+		Joins(recommendationStatusContainerJoin).Model(&RecommendationSetResult{}).
 		Where("rh_accounts.org_id = ?", orgID)
 	return query
 }
@@ -231,11 +295,65 @@ func getNamespaceRecommendationQuery(orgID string) *gorm.DB {
 			"namespace_recommendation_sets.memory_variation_medium_cost_pct, "+
 			"namespace_recommendation_sets.memory_variation_medium_performance_pct, "+
 			"namespace_recommendation_sets.memory_variation_long_cost_pct, "+
-			"namespace_recommendation_sets.memory_variation_long_performance_pct").
+			"namespace_recommendation_sets.memory_variation_long_performance_pct"+
+			recommendationStatusSelect).
 		Joins(`
 			JOIN workloads ON namespace_recommendation_sets.workload_id = workloads.id
 			JOIN clusters ON workloads.cluster_id = clusters.id
-		`).Model(&NamespaceRecommendationSetResult{}).
+		`).
+		Joins(recommendationStatusNamespaceJoin).Model(&NamespaceRecommendationSetResult{}).
 		Where("namespace_recommendation_sets.org_id = ?", orgID)
 	return query
 }
+
+func getHistoricalRecommendationQuery(orgID string, recommendationID string) *gorm.DB {
+	db := database.GetDB()
+	query := db.Table("historical_recommendation_sets").
+		Select("historical_recommendation_sets.id, "+
+			"recommendation_sets.id AS recommendation_id, "+
+			"historical_recommendation_sets.container_name AS container, "+
+			"workloads.namespace AS project, "+
+			"workloads.workload_name as workload, "+
+			"workloads.workload_type, "+
+			"clusters.source_id, "+
+			"clusters.cluster_uuid, "+
+			"clusters.cluster_alias, "+
+			"historical_recommendation_sets.monitoring_start_time, "+
+			"historical_recommendation_sets.monitoring_end_time, "+
+			"historical_recommendation_sets.recommendations").
+		Joins(`
+			JOIN recommendation_sets ON recommendation_sets.workload_id = historical_recommendation_sets.workload_id
+				AND recommendation_sets.container_name = historical_recommendation_sets.container_name
+			JOIN workloads ON historical_recommendation_sets.workload_id = workloads.id
+			JOIN clusters ON workloads.cluster_id = clusters.id
+			JOIN rh_accounts ON clusters.tenant_id = rh_accounts.id
+		`).Model(&HistoricalRecommendationSetResult{}).
+		// org_id on the historical table lets Postgres prune to the org partition
+		Where("historical_recommendation_sets.org_id = ?", orgID).
+		Where("rh_accounts.org_id = ?", orgID).
+		Where("recommendation_sets.id = ?", recommendationID)
+	return query
+}
+
+func getHistoricalNamespaceRecommendationQuery(orgID string, recommendationID string) *gorm.DB {
+	db := database.GetDB()
+	query := db.Table("historical_namespace_recommendation_sets").
+		Select("historical_namespace_recommendation_sets.id, "+
+			"namespace_recommendation_sets.id AS recommendation_id, "+
+			"historical_namespace_recommendation_sets.namespace_name AS project, "+
+			"clusters.source_id, "+
+			"clusters.cluster_uuid, "+
+			"clusters.cluster_alias, "+
+			"historical_namespace_recommendation_sets.monitoring_start_time, "+
+			"historical_namespace_recommendation_sets.monitoring_end_time, "+
+			"historical_namespace_recommendation_sets.recommendations").
+		Joins(`
+			JOIN namespace_recommendation_sets ON namespace_recommendation_sets.workload_id = historical_namespace_recommendation_sets.workload_id
+			JOIN workloads ON historical_namespace_recommendation_sets.workload_id = workloads.id
+			JOIN clusters ON workloads.cluster_id = clusters.id
+		`).Model(&HistoricalNamespaceRecommendationSetResult{}).
+		Where("historical_namespace_recommendation_sets.org_id = ?", orgID).
+		Where("namespace_recommendation_sets.org_id = ?", orgID).
+		Where("namespace_recommendation_sets.id = ?", recommendationID)
+	return query
+}
@@ -0,0 +1,62 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm/clause"
+
+	database "github.com/redhatinsights/ros-ocp-backend/internal/db"
+	"github.com/redhatinsights/ros-ocp-backend/internal/rbac"
+)
+
+// CostRate is a rate card entry used to estimate the cost of recommendations.
+// An empty ClusterUUID holds the org-wide rate.
+type CostRate struct {
+	ID                uint      `gorm:"primaryKey;not null;autoIncrement" json:"-"`
+	OrgID             string    `gorm:"column:org_id;type:text;not null" json:"-"`
+	ClusterUUID       string    `gorm:"column:cluster_uuid;type:text;not null;default:''" json:"cluster_uuid"`
+	CPUCoreHourRate   float64   `gorm:"column:cpu_core_hour_rate;type:numeric(12,6);not null" json:"cpu_core_hour_rate"`
+	MemoryGiBHourRate float64   `gorm:"column:memory_gib_hour_rate;type:numeric(12,6);not null" json:"memory_gib_hour_rate"`
+	Currency          string    `gorm:"type:text;not null;default:'USD'" json:"currency"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// GetCostRates returns the org-wide rate and the rates of the clusters user_permissions cover.
+func GetCostRates(orgID string, user_permissions map[string][]string) ([]CostRate, error) {
+	var costRates []CostRate
+	db := database.GetDB()
+	query := db.Where("cost_rates.org_id = ?", orgID)
+	if err := rbac.AddRBACFilter(query, user_permissions, rbac.ResourceCostRate); err != nil {
+		return costRates, err
+	}
+	err := query.Order("cluster_uuid ASC").Find(&costRates).Error
+	if err != nil {
+		dbError.Inc()
+	}
+	return costRates, err
+}
+
+func (r *CostRate) UpsertCostRate() error {
+	db := database.GetDB()
+	r.UpdatedAt = time.Now().UTC()
+	result := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "org_id"}, {Name: "cluster_uuid"}},
+		DoUpdates: clause.AssignmentColumns([]string{"cpu_core_hour_rate", "memory_gib_hour_rate", "currency", "updated_at"}),
+	}).Create(r)
+	if result.Error != nil {
+		dbError.Inc()
+		return result.Error
+	}
+	return nil
+}
+
+// DeleteCostRate removes a rate card entry and reports whether one existed.
+func DeleteCostRate(orgID string, clusterUUID string) (bool, error) {
+	db := database.GetDB()
+	result := db.Where("org_id = ? AND cluster_uuid = ?", orgID, clusterUUID).Delete(&CostRate{})
+	if result.Error != nil {
+		dbError.Inc()
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -9,6 +9,9 @@ import (
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/api/listoptions"
+	"github.com/redhatinsights/ros-ocp-backend/internal/rbac"
 )
 
 type HistoricalRecommendationSet struct {
@@ -23,6 +26,50 @@ type HistoricalRecommendationSet struct {
 	UpdatedAt           time.Time `gorm:"type:timestamp"`
 }
 
+type HistoricalRecommendationSetResult struct {
+	/*
+		API-ready struct for a single historical entry of a container recommendation.
+		Updated recommendation data is saved to RecommendationsJSON before the API response is sent.
+	*/
+	ClusterAlias        string                 `json:"cluster_alias"`
+	ClusterUUID         string                 `json:"cluster_uuid"`
+	Container           string                 `json:"container"`
+	ID                  uint                   `json:"id"`
+	MonitoringEndTime   time.Time              `json:"monitoring_end_time"`
+	MonitoringStartTime time.Time              `json:"monitoring_start_time"`
+	Project             string                 `json:"project"`
+	RecommendationID    string                 `json:"recommendation_id"`
+	Recommendations     datatypes.JSON         `json:"-"`
+	RecommendationsJSON map[string]interface{} `gorm:"-" json:"recommendations"`
+	SourceID            string                 `json:"source_id"`
+	Workload            string                 `json:"workload"`
+	WorkloadType        string                 `json:"workload_type"`
+}
+
+// GetHistoricalRecommendationSets returns the stored history of the workload/container
+// behind the given container recommendation ID.
+func (r *HistoricalRecommendationSet) GetHistoricalRecommendationSets(orgID string, recommendationID string, opts listoptions.ListOptions, queryParams map[string]interface{}, user_permissions map[string][]string) ([]HistoricalRecommendationSetResult, int, error) {
+	var historicalSets []HistoricalRecommendationSetResult
+	var count int64 = 0
+	query := getHistoricalRecommendationQuery(orgID, recommendationID)
+
+	if err := rbac.AddRBACFilter(
+		query,
+		user_permissions,
+		rbac.ResourceContainer,
+	); err != nil {
+		return historicalSets, int(count), err
+	}
+
+	query = applyQueryParams(query, queryParams)
+
+	query.Count(&count)
+	query = query.Order(listoptions.SQLOrderByFragment(opts.OrderBy, opts.OrderHow)).Order("historical_recommendation_sets.id ASC")
+	err := query.Offset(opts.Offset).Limit(opts.Limit).Scan(&historicalSets).Error
+
+	return historicalSets, int(count), err
+}
+
 func (r *HistoricalRecommendationSet) CreateHistoricalRecommendationSet(tx *gorm.DB) error {
 	result := tx.Clauses(clause.OnConflict{
 		Columns:   []clause.Column{{Name: "org_id"}, {Name: "workload_id"}, {Name: "container_name"}, {Name: "monitoring_end_time"}},
@@ -6,6 +6,9 @@ import (
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/api/listoptions"
+	"github.com/redhatinsights/ros-ocp-backend/internal/rbac"
 )
 
 type HistoricalNamespaceRecommendationSet struct {
@@ -20,6 +23,43 @@ type HistoricalNamespaceRecommendationSet struct {
 	UpdatedAt           time.Time `gorm:"type:timestamp with time zone;not null"`
 }
 
+type HistoricalNamespaceRecommendationSetResult struct {
+	ClusterAlias        string         `json:"cluster_alias"`
+	ClusterUUID         string         `json:"cluster_uuid"`
+	ID                  uint           `json:"id"`
+	MonitoringEndTime   time.Time      `json:"monitoring_end_time"`
+	MonitoringStartTime time.Time      `json:"monitoring_start_time"`
+	Project             string         `json:"project"`
+	RecommendationID    string         `json:"recommendation_id"`
+	Recommendations     datatypes.JSON `json:"-"`
+	RecommendationsJSON map[string]any `gorm:"-" json:"recommendations"`
+	SourceID            string         `json:"source_id"`
+}
+
+// GetHistoricalNamespaceRecommendationSets returns the stored history of the namespace
+// behind the given project recommendation ID.
+func (h *HistoricalNamespaceRecommendationSet) GetHistoricalNamespaceRecommendationSets(orgID string, recommendationID string, opts listoptions.ListOptions, queryParams map[string]any, user_permissions map[string][]string) ([]HistoricalNamespaceRecommendationSetResult, int, error) {
+	var historicalSets []HistoricalNamespaceRecommendationSetResult
+	var count int64 = 0
+	query := getHistoricalNamespaceRecommendationQuery(orgID, recommendationID)
+
+	if err := rbac.AddRBACFilter(
+		query,
+		user_permissions,
+		rbac.ResourceProject,
+	); err != nil {
+		return historicalSets, int(count), err
+	}
+
+	query = applyQueryParams(query, queryParams)
+
+	query.Count(&count)
+	query = query.Order(listoptions.SQLOrderByFragment(opts.OrderBy, opts.OrderHow)).Order("historical_namespace_recommendation_sets.id ASC")
+	err := query.Offset(opts.Offset).Limit(opts.Limit).Scan(&historicalSets).Error
+
+	return historicalSets, int(count), err
+}
+
 func (h *HistoricalNamespaceRecommendationSet) CreateHistoricalRecommendationSet(tx *gorm.DB) error {
 	result := tx.Clauses(clause.OnConflict{
 		Columns:   []clause.Column{{Name: "org_id"}, {Name: "workload_id"}, {Name: "monitoring_end_time"}},
@@ -24,18 +24,7 @@ type NamespaceRecommendationSet struct {
 	MemoryRequestCurrent *float64 `gorm:"column:memory_request_current;type:numeric(20,4)"`
 
 	// Variation fields: percent of current CPU/memory request (aligned with API response).
-	CPUVariationShortCostPct            *float64 `gorm:"column:cpu_variation_short_cost_pct;type:numeric(10,4)"`
-	CPUVariationShortPerformancePct     *float64 `gorm:"column:cpu_variation_short_performance_pct;type:numeric(10,4)"`
-	CPUVariationMediumCostPct           *float64 `gorm:"column:cpu_variation_medium_cost_pct;type:numeric(10,4)"`
-	CPUVariationMediumPerformancePct    *float64 `gorm:"column:cpu_variation_medium_performance_pct;type:numeric(10,4)"`
-	CPUVariationLongCostPct             *float64 `gorm:"column:cpu_variation_long_cost_pct;type:numeric(10,4)"`
-	CPUVariationLongPerformancePct      *float64 `gorm:"column:cpu_variation_long_performance_pct;type:numeric(10,4)"`
-	MemoryVariationShortCostPct         *float64 `gorm:"column:memory_variation_short_cost_pct;type:numeric(10,4)"`
-	MemoryVariationShortPerformancePct  *float64 `gorm:"column:memory_variation_short_performance_pct;type:numeric(10,4)"`
-	MemoryVariationMediumCostPct        *float64 `gorm:"column:memory_variation_medium_cost_pct;type:numeric(10,4)"`
-	MemoryVariationMediumPerformancePct *float64 `gorm:"column:memory_variation_medium_performance_pct;type:numeric(10,4)"`
-	MemoryVariationLongCostPct          *float64 `gorm:"column:memory_variation_long_cost_pct;type:numeric(10,4)"`
-	MemoryVariationLongPerformancePct   *float64 `gorm:"column:memory_variation_long_performance_pct;type:numeric(10,4)"`
+	StoredVariationPcts `gorm:"embedded"`
 
 	MonitoringStartTime    time.Time `gorm:"type:timestamp"`
 	MonitoringEndTime      time.Time `gorm:"type:timestamp"`
@@ -58,6 +47,13 @@ type NamespaceRecommendationSetResult struct {
 	SourceID            string         `json:"source_id"`
 	// Embedded stored variation percentages (scanned from SELECT, excluded from JSON output).
 	StoredVariationPcts `gorm:"embedded"`
+	// Embedded user decision on the recommendation (LEFT JOIN recommendation_statuses).
+	RecommendationStatusResult `gorm:"embedded"`
+}
+
+// ColumnValues returns the current requests and variation percentages stored for the recommendation.
+func (r *NamespaceRecommendationSet) ColumnValues() RecommendationColumnValues {
+	return r.StoredVariationPcts.columnValues(r.CPURequestCurrent, r.MemoryRequestCurrent)
 }
 
 func (r *NamespaceRecommendationSet) AfterFind(tx *gorm.DB) error {
@@ -78,18 +74,7 @@ func (r *NamespaceRecommendationSet) GetNamespaceRecommendationSets(orgID string
 		return recommendationSets, int(count), err
 	}
 
-	for key, values := range queryParams {
-		switch v := values.(type) {
-		case []string:
-			args := make([]any, len(v))
-			for i, s := range v {
-				args[i] = s
-			}
-			query = query.Where(key, args...)
-		default:
-			query = query.Where(key, v)
-		}
-	}
+	query = applyQueryParams(query, queryParams)
 
 	query.Count(&count)
 	// OrderBy/OrderHow come from ListAPIOptions (allowlisted); secondary sort for stable ordering.
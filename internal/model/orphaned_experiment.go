@@ -0,0 +1,60 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm/clause"
+
+	database "github.com/redhatinsights/ros-ocp-backend/internal/db"
+)
+
+// OrphanedExperiment is a Kruize experiment the reconcile job found without a workload, first at
+// FirstSeenAt and last at LastSeenAt.
+type OrphanedExperiment struct {
+	ExperimentName string    `gorm:"column:experiment_name;type:text;primaryKey"`
+	FirstSeenAt    time.Time `gorm:"column:first_seen_at;not null"`
+	LastSeenAt     time.Time `gorm:"column:last_seen_at;not null"`
+}
+
+// RecordOrphanedExperiments records that the experiments called names are orphaned at now, and
+// returns when each of them was first found orphaned.
+func RecordOrphanedExperiments(names []string, now time.Time) (map[string]time.Time, error) {
+	firstSeen := make(map[string]time.Time, len(names))
+	if len(names) == 0 {
+		return firstSeen, nil
+	}
+	db := database.GetDB()
+	orphaned := make([]OrphanedExperiment, 0, len(names))
+	for _, name := range names {
+		orphaned = append(orphaned, OrphanedExperiment{ExperimentName: name, FirstSeenAt: now, LastSeenAt: now})
+	}
+	err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "experiment_name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"last_seen_at"}),
+	}).Create(&orphaned).Error
+	if err != nil {
+		dbError.Inc()
+		return nil, err
+	}
+
+	var recorded []OrphanedExperiment
+	if err := db.Where("experiment_name IN ?", names).Find(&recorded).Error; err != nil {
+		dbError.Inc()
+		return nil, err
+	}
+	for _, experiment := range recorded {
+		firstSeen[experiment.ExperimentName] = experiment.FirstSeenAt
+	}
+	return firstSeen, nil
+}
+
+// ForgetOrphanedExperiments deletes the records of the experiments last found orphaned before
+// before: they have a workload again or are gone from Kruize.
+func ForgetOrphanedExperiments(before time.Time) error {
+	db := database.GetDB()
+	if err := db.Where("last_seen_at < ?", before).Delete(&OrphanedExperiment{}).Error; err != nil {
+		dbError.Inc()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	database "github.com/redhatinsights/ros-ocp-backend/internal/db"
+)
+
+// RecommendationRetry is a recommendation request waiting until DueAt for its next attempt.
+type RecommendationRetry struct {
+	ID         uint      `gorm:"primaryKey;not null;autoIncrement"`
+	MessageKey string    `gorm:"column:message_key;type:text;not null"`
+	Payload    []byte    `gorm:"type:bytea;not null"`
+	Attempt    int       `gorm:"not null"`
+	DueAt      time.Time `gorm:"column:due_at;not null"`
+	Reason     string    `gorm:"type:text;not null;default:''"`
+}
+
+func (r *RecommendationRetry) CreateRecommendationRetry() error {
+	db := database.GetDB()
+	if err := db.Create(r).Error; err != nil {
+		dbError.Inc()
+		return err
+	}
+	return nil
+}
+
+// ReleaseDueRecommendationRetries passes up to limit retries due at now to release and deletes
+// the ones it released without error. Rows are locked until then, so pollers running side by
+// side do not release a retry twice. It returns how many retries were released.
+func ReleaseDueRecommendationRetries(now time.Time, limit int, release func(RecommendationRetry) error) (int, error) {
+	db := database.GetDB()
+	released := 0
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var retries []RecommendationRetry
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("due_at <= ?", now).Order("due_at ASC").Limit(limit).Find(&retries).Error
+		if err != nil {
+			return err
+		}
+		var ids []uint
+		for _, retry := range retries {
+			if err := release(retry); err != nil {
+				// left for the next run
+				continue
+			}
+			ids = append(ids, retry.ID)
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		if err := tx.Delete(&RecommendationRetry{}, ids).Error; err != nil {
+			return err
+		}
+		released = len(ids)
+		return nil
+	})
+	if err != nil {
+		dbError.Inc()
+		return 0, err
+	}
+	return released, nil
+}
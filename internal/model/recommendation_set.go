@@ -14,6 +14,13 @@ import (
 	"github.com/redhatinsights/ros-ocp-backend/internal/rbac"
 )
 
+// Applied statuses of a container recommendation, detected from the requests reported by later
+// uploads. An empty status means no change matching the recommendation was seen.
+const (
+	RecommendationApplied          = "applied"
+	RecommendationPartiallyApplied = "partially_applied"
+)
+
 type RecommendationSet struct {
 	ID            string `gorm:"primaryKey;not null;autoIncrement"`
 	WorkloadID    uint
@@ -24,18 +31,7 @@ type RecommendationSet struct {
 	MemoryRequestCurrent *float64 `gorm:"column:memory_request_current;type:numeric(20,4)"`
 
 	// Variation fields: percent of current CPU/memory request (aligned with API response).
-	CPUVariationShortCostPct            *float64 `gorm:"column:cpu_variation_short_cost_pct;type:numeric(10,4)"`
-	CPUVariationShortPerformancePct     *float64 `gorm:"column:cpu_variation_short_performance_pct;type:numeric(10,4)"`
-	CPUVariationMediumCostPct           *float64 `gorm:"column:cpu_variation_medium_cost_pct;type:numeric(10,4)"`
-	CPUVariationMediumPerformancePct    *float64 `gorm:"column:cpu_variation_medium_performance_pct;type:numeric(10,4)"`
-	CPUVariationLongCostPct             *float64 `gorm:"column:cpu_variation_long_cost_pct;type:numeric(10,4)"`
-	CPUVariationLongPerformancePct      *float64 `gorm:"column:cpu_variation_long_performance_pct;type:numeric(10,4)"`
-	MemoryVariationShortCostPct         *float64 `gorm:"column:memory_variation_short_cost_pct;type:numeric(10,4)"`
-	MemoryVariationShortPerformancePct  *float64 `gorm:"column:memory_variation_short_performance_pct;type:numeric(10,4)"`
-	MemoryVariationMediumCostPct        *float64 `gorm:"column:memory_variation_medium_cost_pct;type:numeric(10,4)"`
-	MemoryVariationMediumPerformancePct *float64 `gorm:"column:memory_variation_medium_performance_pct;type:numeric(10,4)"`
-	MemoryVariationLongCostPct          *float64 `gorm:"column:memory_variation_long_cost_pct;type:numeric(10,4)"`
-	MemoryVariationLongPerformancePct   *float64 `gorm:"column:memory_variation_long_performance_pct;type:numeric(10,4)"`
+	StoredVariationPcts `gorm:"embedded"`
 
 	MonitoringStartTime    time.Time `gorm:"type:timestamp"`
 	MonitoringEndTime      time.Time `gorm:"type:timestamp"`
@@ -44,6 +40,9 @@ type RecommendationSet struct {
 	MonitoringStartTimeStr string    `gorm:"-"`
 	MonitoringEndTimeStr   string    `gorm:"-"`
 	UpdatedAtStr           string    `gorm:"-"`
+
+	AppliedStatus string     `gorm:"column:applied_status;type:text;not null;default:''"`
+	AppliedAt     *time.Time `gorm:"column:applied_at"`
 }
 
 type RecommendationSetResult struct {
@@ -62,8 +61,17 @@ type RecommendationSetResult struct {
 	SourceID            string                 `json:"source_id"`
 	Workload            string                 `json:"workload"`
 	WorkloadType        string                 `json:"workload_type"`
+	AppliedStatus       string                 `json:"applied_status"`
+	AppliedAt           *time.Time             `json:"applied_at"`
 	// Embedded stored variation percentages (scanned from SELECT, excluded from JSON output).
 	StoredVariationPcts `gorm:"embedded"`
+	// Embedded user decision on the recommendation (LEFT JOIN recommendation_statuses).
+	RecommendationStatusResult `gorm:"embedded"`
+}
+
+// ColumnValues returns the current requests and variation percentages stored for the recommendation.
+func (r *RecommendationSet) ColumnValues() RecommendationColumnValues {
+	return r.StoredVariationPcts.columnValues(r.CPURequestCurrent, r.MemoryRequestCurrent)
 }
 
 func (r *RecommendationSet) AfterFind(tx *gorm.DB) error {
@@ -81,6 +89,33 @@ func GetFirstRecommendationSetsByWorkloadID(workload_id uint) (RecommendationSet
 	return recommendationSets, query.Error
 }
 
+// GetRecommendationSetsByWorkloadID returns the stored recommendation of every container of a workload.
+func GetRecommendationSetsByWorkloadID(workload_id uint) ([]RecommendationSet, error) {
+	var recommendationSets []RecommendationSet
+	db := database.GetDB()
+	err := db.Where("workload_id = ?", workload_id).Find(&recommendationSets).Error
+	if err != nil {
+		dbError.Inc()
+	}
+	return recommendationSets, err
+}
+
+// UpdateAppliedStatus records that the recommendation was found applied, fully or partially, at appliedAt.
+func (r *RecommendationSet) UpdateAppliedStatus(status string, appliedAt time.Time) error {
+	db := database.GetDB()
+	result := db.Model(&RecommendationSet{}).Where("id = ?", r.ID).Updates(map[string]interface{}{
+		"applied_status": status,
+		"applied_at":     appliedAt,
+	})
+	if result.Error != nil {
+		dbError.Inc()
+		return result.Error
+	}
+	r.AppliedStatus = status
+	r.AppliedAt = &appliedAt
+	return nil
+}
+
 func (r *RecommendationSet) GetRecommendationSets(orgID string, opts listoptions.ListOptions, queryParams map[string]interface{}, user_permissions map[string][]string) ([]RecommendationSetResult, int, error) {
 	var recommendationSets []RecommendationSetResult
 	var count int64 = 0
@@ -94,19 +129,7 @@ func (r *RecommendationSet) GetRecommendationSets(orgID string, opts listoptions
 		return recommendationSets, int(count), err
 	}
 
-	for key, values := range queryParams {
-		switch v := values.(type) {
-		case []string:
-			// Convert []string to []interface{} for unpacking multiple values
-			args := make([]interface{}, len(v))
-			for i, s := range v {
-				args[i] = s
-			}
-			query = query.Where(key, args...)
-		default:
-			query = query.Where(key, v)
-		}
-	}
+	query = applyQueryParams(query, queryParams)
 
 	query.Count(&count)
 	// OrderBy/OrderHow come from ListAPIOptions (allowlisted); secondary sort for stable ordering.
@@ -166,6 +189,8 @@ func (r *RecommendationSet) CreateRecommendationSet(tx *gorm.DB) error {
 			"memory_variation_medium_performance_pct",
 			"memory_variation_long_cost_pct",
 			"memory_variation_long_performance_pct",
+			// applied_status and applied_at are kept: the recommendation replacing an applied one
+			// reports the applied requests as current, so no later upload would record them again
 		}),
 	}).Create(r)
 
@@ -0,0 +1,90 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm/clause"
+
+	database "github.com/redhatinsights/ros-ocp-backend/internal/db"
+)
+
+// Recommendation statuses. Only accepted, dismissed and snoozed are stored; a recommendation
+// without a status row, or whose snooze has expired, is open.
+const (
+	RecommendationStatusOpen      = "open"
+	RecommendationStatusAccepted  = "accepted"
+	RecommendationStatusDismissed = "dismissed"
+	RecommendationStatusSnoozed   = "snoozed"
+)
+
+// RecommendationStatusSQL evaluates to the effective status of a recommendation LEFT JOINed
+// with recommendation_statuses. Use it to select or filter on the status.
+const RecommendationStatusSQL = "CASE WHEN recommendation_statuses.status IS NULL OR " +
+	"(recommendation_statuses.status = 'snoozed' AND recommendation_statuses.snoozed_until <= CURRENT_TIMESTAMP) " +
+	"THEN 'open' ELSE recommendation_statuses.status END"
+
+const (
+	recommendationStatusContainerJoin = "LEFT JOIN recommendation_statuses ON recommendation_statuses.recommendation_set_id = recommendation_sets.id"
+	recommendationStatusNamespaceJoin = "LEFT JOIN recommendation_statuses ON recommendation_statuses.namespace_recommendation_set_id = namespace_recommendation_sets.id"
+	recommendationStatusSelect        = ", " + RecommendationStatusSQL + " AS status, " +
+		"COALESCE(recommendation_statuses.comment, '') AS status_comment, " +
+		"COALESCE(recommendation_statuses.username, '') AS status_updated_by, " +
+		"recommendation_statuses.updated_at AS status_updated_at, " +
+		"recommendation_statuses.snoozed_until"
+)
+
+// RecommendationStatusResult holds the status columns scanned alongside a recommendation.
+type RecommendationStatusResult struct {
+	Status          string     `json:"status"`
+	StatusComment   string     `json:"status_comment"`
+	StatusUpdatedBy string     `json:"status_updated_by"`
+	StatusUpdatedAt *time.Time `json:"status_updated_at"`
+	SnoozedUntil    *time.Time `json:"snoozed_until"`
+}
+
+type RecommendationStatus struct {
+	ID                           uint       `gorm:"primaryKey;not null;autoIncrement" json:"-"`
+	OrgID                        string     `gorm:"column:org_id;type:text;not null" json:"-"`
+	RecommendationSetID          *string    `gorm:"column:recommendation_set_id;type:uuid" json:"-"`
+	NamespaceRecommendationSetID *string    `gorm:"column:namespace_recommendation_set_id;type:uuid" json:"-"`
+	Status                       string     `gorm:"type:text;not null" json:"status"`
+	SnoozedUntil                 *time.Time `json:"snoozed_until"`
+	Comment                      string     `gorm:"type:text;not null;default:''" json:"status_comment"`
+	Username                     string     `gorm:"type:text;not null;default:''" json:"status_updated_by"`
+	UpdatedAt                    time.Time  `json:"status_updated_at"`
+}
+
+// UpsertRecommendationStatus stores the status, replacing any previous decision on the same recommendation.
+func (r *RecommendationStatus) UpsertRecommendationStatus() error {
+	db := database.GetDB()
+	conflictColumn := "recommendation_set_id"
+	if r.NamespaceRecommendationSetID != nil {
+		conflictColumn = "namespace_recommendation_set_id"
+	}
+	r.UpdatedAt = time.Now().UTC()
+	result := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: conflictColumn}},
+		DoUpdates: clause.AssignmentColumns([]string{"status", "snoozed_until", "comment", "username", "updated_at"}),
+	}).Create(r)
+	if result.Error != nil {
+		dbError.Inc()
+		return result.Error
+	}
+	return nil
+}
+
+// DeleteRecommendationStatus reopens a container recommendation, or a namespace one when
+// namespace is set. Reports whether a status existed.
+func DeleteRecommendationStatus(orgID string, recommendationID string, namespace bool) (bool, error) {
+	db := database.GetDB()
+	column := "recommendation_set_id"
+	if namespace {
+		column = "namespace_recommendation_set_id"
+	}
+	result := db.Where("org_id = ? AND "+column+" = ?", orgID, recommendationID).Delete(&RecommendationStatus{})
+	if result.Error != nil {
+		dbError.Inc()
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,202 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	database "github.com/redhatinsights/ros-ocp-backend/internal/db"
+	"github.com/redhatinsights/ros-ocp-backend/internal/rbac"
+)
+
+// Groupings supported by GetRecommendationSummaries. SummaryGroupTotal aggregates every
+// matching container into a single row.
+const (
+	SummaryGroupTotal        = "total"
+	SummaryGroupCluster      = "cluster"
+	SummaryGroupProject      = "project"
+	SummaryGroupWorkloadType = "workload_type"
+)
+
+// summaryGroupColumns holds the SELECT expressions and GROUP BY columns per grouping.
+// Projects are grouped per cluster since namespace names are only unique within a cluster.
+var summaryGroupColumns = map[string]struct {
+	selects []string
+	groupBy []string
+}{
+	SummaryGroupTotal: {},
+	SummaryGroupCluster: {
+		selects: []string{"clusters.cluster_uuid", "clusters.cluster_alias"},
+		groupBy: []string{"clusters.cluster_uuid", "clusters.cluster_alias"},
+	},
+	SummaryGroupProject: {
+		selects: []string{"clusters.cluster_uuid", "clusters.cluster_alias", "workloads.namespace AS project"},
+		groupBy: []string{"clusters.cluster_uuid", "clusters.cluster_alias", "workloads.namespace"},
+	},
+	SummaryGroupWorkloadType: {
+		selects: []string{"workloads.workload_type"},
+		groupBy: []string{"workloads.workload_type"},
+	},
+}
+
+// ProvisioningTotals holds how much CPU (cores) and memory (bytes) current requests exceed
+// (over-provisioned) or fall short of (under-provisioned) a recommendation.
+type ProvisioningTotals struct {
+	CPUOverProvisioned     float64
+	CPUUnderProvisioned    float64
+	MemoryOverProvisioned  float64
+	MemoryUnderProvisioned float64
+}
+
+// RecommendationSummary is one aggregated row of the potential savings summary.
+// Terms is keyed by term, then engine.
+type RecommendationSummary struct {
+	ClusterUUID          string
+	ClusterAlias         string
+	Project              string
+	WorkloadType         string
+	Containers           int64
+	CPURequestCurrent    float64
+	MemoryRequestCurrent float64
+	Terms                map[string]map[string]ProvisioningTotals
+}
+
+func variationColumn(resource string, spec StoredVariationSpec) string {
+	return fmt.Sprintf("recommendation_sets.%s_variation_%s_%s_pct", resource, strings.TrimSuffix(spec.Term, "_term"), spec.Engine)
+}
+
+func summaryAlias(resource, direction string, spec StoredVariationSpec) string {
+	return fmt.Sprintf("%s_%s_%s_%s", resource, direction, spec.Term, spec.Engine)
+}
+
+// summarySelects returns the aggregate expressions for every term/engine pair. A negative
+// variation means the recommendation is below the current request, so the container is
+// over-provisioned by that share of its request; a positive one means it is under-provisioned.
+func summarySelects() []string {
+	selects := []string{
+		"COUNT(*) AS containers",
+		"COALESCE(SUM(recommendation_sets.cpu_request_current), 0)::float8 AS cpu_request_current",
+		"COALESCE(SUM(recommendation_sets.memory_request_current), 0)::float8 AS memory_request_current",
+	}
+	resources := []struct{ name, current string }{
+		{"cpu", "recommendation_sets.cpu_request_current"},
+		{"memory", "recommendation_sets.memory_request_current"},
+	}
+	for _, spec := range StoredVariationSpecs {
+		for _, resource := range resources {
+			pct := variationColumn(resource.name, spec)
+			selects = append(selects,
+				fmt.Sprintf("COALESCE(SUM(CASE WHEN %[1]s < 0 THEN -%[1]s / 100 * %[2]s ELSE 0 END), 0)::float8 AS %[3]s",
+					pct, resource.current, summaryAlias(resource.name, "over", spec)),
+				fmt.Sprintf("COALESCE(SUM(CASE WHEN %[1]s > 0 THEN %[1]s / 100 * %[2]s ELSE 0 END), 0)::float8 AS %[3]s",
+					pct, resource.current, summaryAlias(resource.name, "under", spec)),
+			)
+		}
+	}
+	return selects
+}
+
+func summaryFloat(v any) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case float32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case int32:
+		return float64(n)
+	case int:
+		return float64(n)
+	case []byte:
+		f, _ := strconv.ParseFloat(string(n), 64)
+		return f
+	case string:
+		f, _ := strconv.ParseFloat(n, 64)
+		return f
+	}
+	return 0
+}
+
+func summaryString(v any) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case []byte:
+		return string(s)
+	}
+	return ""
+}
+
+// newRecommendationSummary maps a row scanned by GetRecommendationSummaries to a RecommendationSummary.
+func newRecommendationSummary(row map[string]any) RecommendationSummary {
+	summary := RecommendationSummary{
+		ClusterUUID:          summaryString(row["cluster_uuid"]),
+		ClusterAlias:         summaryString(row["cluster_alias"]),
+		Project:              summaryString(row["project"]),
+		WorkloadType:         summaryString(row["workload_type"]),
+		Containers:           int64(summaryFloat(row["containers"])),
+		CPURequestCurrent:    summaryFloat(row["cpu_request_current"]),
+		MemoryRequestCurrent: summaryFloat(row["memory_request_current"]),
+		Terms:                make(map[string]map[string]ProvisioningTotals),
+	}
+	for _, spec := range StoredVariationSpecs {
+		if summary.Terms[spec.Term] == nil {
+			summary.Terms[spec.Term] = make(map[string]ProvisioningTotals)
+		}
+		summary.Terms[spec.Term][spec.Engine] = ProvisioningTotals{
+			CPUOverProvisioned:     summaryFloat(row[summaryAlias("cpu", "over", spec)]),
+			CPUUnderProvisioned:    summaryFloat(row[summaryAlias("cpu", "under", spec)]),
+			MemoryOverProvisioned:  summaryFloat(row[summaryAlias("memory", "over", spec)]),
+			MemoryUnderProvisioned: summaryFloat(row[summaryAlias("memory", "under", spec)]),
+		}
+	}
+	return summary
+}
+
+// GetRecommendationSummaries aggregates the stored current requests and variation percentages
+// of the container recommendations matching queryParams, grouped by groupBy.
+func GetRecommendationSummaries(orgID string, groupBy string, queryParams map[string]interface{}, user_permissions map[string][]string) ([]RecommendationSummary, error) {
+	group, ok := summaryGroupColumns[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("unsupported summary grouping %q", groupBy)
+	}
+
+	db := database.GetDB()
+	query := db.Table("recommendation_sets").
+		Select(strings.Join(slices.Concat(group.selects, summarySelects()), ", ")).
+		Joins(`
+			JOIN workloads ON recommendation_sets.workload_id = workloads.id
+			JOIN clusters ON workloads.cluster_id = clusters.id
+			JOIN rh_accounts ON clusters.tenant_id = rh_accounts.id
+		`).
+		Joins(recommendationStatusContainerJoin).
+		Where("rh_accounts.org_id = ?", orgID)
+
+	if err := rbac.AddRBACFilter(
+		query,
+		user_permissions,
+		rbac.ResourceContainer,
+	); err != nil {
+		return nil, err
+	}
+
+	query = applyQueryParams(query, queryParams)
+
+	if len(group.groupBy) > 0 {
+		groupColumns := strings.Join(group.groupBy, ", ")
+		query = query.Group(groupColumns).Order(groupColumns)
+	}
+
+	var rows []map[string]any
+	if err := query.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	summaries := make([]RecommendationSummary, 0, len(rows))
+	for _, row := range rows {
+		summaries = append(summaries, newRecommendationSummary(row))
+	}
+	return summaries, nil
+}
@@ -14,6 +14,10 @@ func (r *RHAccount) CreateRHAccount() error {
 	db := database.GetDB()
 	result := db.Where("org_id = ?", r.OrgId).FirstOrCreate(r)
 	if result.Error != nil {
+		// another upload of the org may have created the account between the lookup and the insert
+		if err := db.Where("org_id = ?", r.OrgId).First(r).Error; err == nil {
+			return nil
+		}
 		dbError.Inc()
 		return result.Error
 	}
@@ -0,0 +1,107 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm/clause"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/api/listoptions"
+	database "github.com/redhatinsights/ros-ocp-backend/internal/db"
+)
+
+// Upload statuses, in the order a file goes through them. A file ends up processed, even if
+// some of its workloads failed, or failed when it could not be processed at all.
+const (
+	UploadStatusReceived   = "received"
+	UploadStatusFetched    = "fetched"
+	UploadStatusAggregated = "aggregated"
+	UploadStatusProcessed  = "processed"
+	UploadStatusFailed     = "failed"
+)
+
+// UploadFailure is an error met while processing an uploaded file, with the stage it happened at.
+type UploadFailure struct {
+	Stage  string `json:"stage"`
+	Reason string `json:"reason"`
+}
+
+// UploadStatus is the progress of one file of an upload through the processor.
+type UploadStatus struct {
+	ID                     uint            `gorm:"primaryKey;not null;autoIncrement" json:"-"`
+	OrgID                  string          `gorm:"column:org_id;type:text;not null" json:"-"`
+	RequestID              string          `gorm:"column:request_id;type:text;not null" json:"request_id"`
+	ClusterUUID            string          `gorm:"column:cluster_uuid;type:text;not null" json:"cluster_uuid"`
+	SourceID               string          `gorm:"column:source_id;type:text;not null;default:''" json:"source_id"`
+	FileName               string          `gorm:"column:file_name;type:text;not null" json:"file_name"`
+	Status                 string          `gorm:"type:text;not null" json:"status"`
+	ReceivedAt             time.Time       `json:"received_at"`
+	FetchedAt              *time.Time      `json:"fetched_at"`
+	AggregatedAt           *time.Time      `json:"aggregated_at"`
+	CompletedAt            *time.Time      `json:"completed_at"`
+	WorkloadsSent          int             `gorm:"not null;default:0" json:"workloads_sent"`
+	WorkloadsFailed        int             `gorm:"not null;default:0" json:"workloads_failed"`
+	RecommendationRequests int             `gorm:"not null;default:0" json:"recommendation_requests"`
+	Failures               []UploadFailure `gorm:"type:jsonb;serializer:json;not null" json:"failures"`
+	UpdatedAt              time.Time       `json:"updated_at"`
+}
+
+// SaveUploadStatus stores the status, replacing the row of an earlier attempt at the same file.
+func (u *UploadStatus) SaveUploadStatus() error {
+	db := database.GetDB()
+	u.UpdatedAt = time.Now().UTC()
+	if u.Failures == nil {
+		u.Failures = []UploadFailure{}
+	}
+	result := db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "org_id"}, {Name: "request_id"}, {Name: "file_name"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"cluster_uuid", "source_id", "status", "received_at", "fetched_at", "aggregated_at", "completed_at",
+			"workloads_sent", "workloads_failed", "recommendation_requests", "failures", "updated_at",
+		}),
+	}).Create(u)
+	if result.Error != nil {
+		dbError.Inc()
+		return result.Error
+	}
+	return nil
+}
+
+// GetUploadStatuses returns the status of every file of an upload.
+func GetUploadStatuses(orgID string, requestID string) ([]UploadStatus, error) {
+	var statuses []UploadStatus
+	db := database.GetDB()
+	err := db.Where("org_id = ? AND request_id = ?", orgID, requestID).Order("file_name ASC").Find(&statuses).Error
+	if err != nil {
+		dbError.Inc()
+	}
+	return statuses, err
+}
+
+// GetClusterUploadStatuses returns a page of the file statuses of a cluster's uploads and their total count.
+func GetClusterUploadStatuses(orgID string, clusterUUID string, opts listoptions.ListOptions) ([]UploadStatus, int, error) {
+	var statuses []UploadStatus
+	var count int64
+	db := database.GetDB()
+	query := db.Model(&UploadStatus{}).Where("org_id = ? AND cluster_uuid = ?", orgID, clusterUUID)
+	if err := query.Count(&count).Error; err != nil {
+		dbError.Inc()
+		return statuses, 0, err
+	}
+	err := query.Order(listoptions.SQLOrderByFragment(opts.OrderBy, opts.OrderHow)).Order("id ASC").
+		Offset(opts.Offset).Limit(opts.Limit).Find(&statuses).Error
+	if err != nil {
+		dbError.Inc()
+	}
+	return statuses, int(count), err
+}
+
+// DeleteUploadStatusesBefore removes the statuses of files received before the given time.
+func DeleteUploadStatusesBefore(receivedBefore time.Time) (int64, error) {
+	db := database.GetDB()
+	result := db.Where("received_at < ?", receivedBefore).Delete(&UploadStatus{})
+	if result.Error != nil {
+		dbError.Inc()
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,86 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm/clause"
+
+	database "github.com/redhatinsights/ros-ocp-backend/internal/db"
+	webhooks_utils "github.com/redhatinsights/ros-ocp-backend/internal/utils/webhooks"
+)
+
+// Webhook is an org endpoint notified when a recommendation is saved. Secret signs the
+// payloads, is encrypted in the database and is never returned by the API.
+type Webhook struct {
+	ID              uint      `gorm:"primaryKey;not null;autoIncrement" json:"id"`
+	OrgID           string    `gorm:"column:org_id;type:text;not null" json:"-"`
+	URL             string    `gorm:"column:url;type:text;not null" json:"url"`
+	Secret          string    `gorm:"type:text;not null" json:"-"`
+	MinVariationPct float64   `gorm:"column:min_variation_pct;type:numeric(10,4);not null;default:0" json:"min_variation_pct"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// ListWebhooks returns the webhooks of the org without their secret, for the API.
+func ListWebhooks(orgID string) ([]Webhook, error) {
+	var webhooks []Webhook
+	db := database.GetDB()
+	err := db.Omit("secret").Where("org_id = ?", orgID).Order("id ASC").Find(&webhooks).Error
+	if err != nil {
+		dbError.Inc()
+	}
+	return webhooks, err
+}
+
+// GetWebhooks returns the webhooks of the org with their decrypted secret, for deliveries.
+func GetWebhooks(orgID string) ([]Webhook, error) {
+	var webhooks []Webhook
+	db := database.GetDB()
+	err := db.Where("org_id = ?", orgID).Order("id ASC").Find(&webhooks).Error
+	if err != nil {
+		dbError.Inc()
+		return webhooks, err
+	}
+	for i := range webhooks {
+		secret, err := webhooks_utils.OpenSecret(webhooks[i].OrgID, webhooks[i].Secret)
+		if err != nil {
+			return nil, fmt.Errorf("webhook %d: %w", webhooks[i].ID, err)
+		}
+		webhooks[i].Secret = secret
+	}
+	return webhooks, nil
+}
+
+// UpsertWebhook stores the webhook, replacing the secret and threshold of an existing one with the same URL.
+// The secret is stored encrypted; w keeps the plain one.
+func (w *Webhook) UpsertWebhook() error {
+	db := database.GetDB()
+	w.UpdatedAt = time.Now().UTC()
+	stored := *w
+	sealed, err := webhooks_utils.SealSecret(w.OrgID, w.Secret)
+	if err != nil {
+		return fmt.Errorf("unable to encrypt webhook secret: %w", err)
+	}
+	stored.Secret = sealed
+	result := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "org_id"}, {Name: "url"}},
+		DoUpdates: clause.AssignmentColumns([]string{"secret", "min_variation_pct", "updated_at"}),
+	}).Create(&stored)
+	if result.Error != nil {
+		dbError.Inc()
+		return result.Error
+	}
+	w.ID = stored.ID
+	return nil
+}
+
+// DeleteWebhook removes a webhook and reports whether one existed.
+func DeleteWebhook(orgID string, webhookID uint) (bool, error) {
+	db := database.GetDB()
+	result := db.Where("org_id = ? AND id = ?", orgID, webhookID).Delete(&Webhook{})
+	if result.Error != nil {
+		dbError.Inc()
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -22,6 +22,8 @@ type Workload struct {
 	WorkloadName    string                `gorm:"type:text"`
 	Containers      pq.StringArray        `gorm:"type:text[];index:,type:gin"`
 	MetricsUploadAt time.Time
+	// StaleAt is when the housekeeper found the workload no longer reporting, nil while it does.
+	StaleAt         *time.Time
 	WorkloadTypeStr string `gorm:"-"`
 }
 
@@ -36,7 +38,7 @@ func (w *Workload) CreateWorkload() error {
 	db := database.GetDB()
 	result := db.Clauses(clause.OnConflict{
 		Columns:   []clause.Column{{Name: "org_id"}, {Name: "cluster_id"}, {Name: "experiment_name"}},
-		DoUpdates: clause.AssignmentColumns([]string{"containers", "metrics_upload_at"}),
+		DoUpdates: clause.AssignmentColumns([]string{"containers", "metrics_upload_at", "stale_at"}),
 	}).Create(w)
 
 	if result.Error != nil {
@@ -54,9 +56,78 @@ func GetWorkloadsByClusterID(cluster_id uint) ([]Workload, error) {
 	return workloads, err
 }
 
+// GetWorkloadsAfter returns up to limit workloads with an ID above afterID, in ID order, along
+// with their cluster. Passing the ID of the last workload returned pages through every workload.
+func GetWorkloadsAfter(afterID uint, limit int) ([]Workload, error) {
+	var workloads []Workload
+	db := database.GetDB()
+	err := db.Preload("Cluster").Where("id > ?", afterID).Order("id").Limit(limit).Find(&workloads).Error
+	return workloads, err
+}
+
+// GetExperimentNamesWithWorkload returns which of the experiments called names are the
+// experiment of a workload.
+func GetExperimentNamesWithWorkload(names []string) (map[string]bool, error) {
+	withWorkload := make(map[string]bool, len(names))
+	if len(names) == 0 {
+		return withWorkload, nil
+	}
+	var found []string
+	db := database.GetDB()
+	if err := db.Model(&Workload{}).Where("experiment_name IN ?", names).Distinct().Pluck("experiment_name", &found).Error; err != nil {
+		return nil, err
+	}
+	for _, name := range found {
+		withWorkload[name] = true
+	}
+	return withWorkload, nil
+}
+
+// GetWorkloadByID returns a workload along with its cluster.
+func GetWorkloadByID(workload_id uint) (Workload, error) {
+	var workload Workload
+	db := database.GetDB()
+	err := db.Preload("Cluster").First(&workload, workload_id).Error
+	return workload, err
+}
+
 func WorkloadExistsByID(workload_id uint) bool {
 	var workload Workload
 	db := database.GetDB()
 	err := db.First(&workload, workload_id).Error
 	return err == nil
 }
+
+// MarkStaleWorkloads sets stale_at to now on the workloads without an upload since uploadedBefore
+// that are not stale yet.
+func MarkStaleWorkloads(uploadedBefore time.Time, now time.Time) (int64, error) {
+	db := database.GetDB()
+	result := db.Model(&Workload{}).
+		Where("metrics_upload_at < ? AND stale_at IS NULL", uploadedBefore).
+		Update("stale_at", now)
+	if result.Error != nil {
+		dbError.Inc()
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+// GetWorkloadsStaleBefore returns the workloads marked stale before staleBefore.
+func GetWorkloadsStaleBefore(staleBefore time.Time) ([]Workload, error) {
+	var workloads []Workload
+	db := database.GetDB()
+	err := db.Where("stale_at < ?", staleBefore).Find(&workloads).Error
+	return workloads, err
+}
+
+// DeleteStaleWorkload removes the workload with workload_id, along with its metrics and
+// recommendations, unless an upload cleared its stale_at in the meantime.
+func DeleteStaleWorkload(workload_id uint) (bool, error) {
+	db := database.GetDB()
+	result := db.Where("stale_at IS NOT NULL").Delete(&Workload{}, workload_id)
+	if result.Error != nil {
+		dbError.Inc()
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -41,3 +41,17 @@ func BatchInsertWorkloadMetrics(data []WorkloadMetrics, org_id string) error {
 	}
 	return nil
 }
+
+// GetWorkloadMetrics returns the metrics of a workload for the intervals ending after from and
+// up to to, oldest first.
+func GetWorkloadMetrics(workload_id uint, from time.Time, to time.Time) ([]WorkloadMetrics, error) {
+	var metrics []WorkloadMetrics
+	db := database.GetDB()
+	err := db.Where("workload_id = ? AND interval_end > ? AND interval_end <= ?", workload_id, from, to).
+		Order("interval_end, container_name").
+		Find(&metrics).Error
+	if err != nil {
+		dbError.Inc()
+	}
+	return metrics, err
+}
@@ -13,6 +13,8 @@ type ResourceType string
 const (
 	ResourceContainer ResourceType = "container"
 	ResourceProject   ResourceType = "namespace"
+	// ResourceCostRate rates are filtered by cluster only; the org-wide rate is always visible.
+	ResourceCostRate ResourceType = "cost_rate"
 )
 
 func AddRBACFilter(query *gorm.DB, userPermissions map[string][]string, resourceType ResourceType) error {
@@ -23,7 +25,7 @@ func AddRBACFilter(query *gorm.DB, userPermissions map[string][]string, resource
 
 	// Validate resource type
 	switch resourceType {
-	case ResourceContainer, ResourceProject:
+	case ResourceContainer, ResourceProject, ResourceCostRate:
 		// valid supported type
 	default:
 		return fmt.Errorf("unsupported resource type: %s", resourceType)
@@ -41,8 +43,11 @@ func AddRBACFilter(query *gorm.DB, userPermissions map[string][]string, resource
 	projectAll := hasProject && utils.StringInSlice("*", projectPerms)
 
 	applyClusterFilter := func() {
-		if resourceType == ResourceContainer || resourceType == ResourceProject {
+		switch resourceType {
+		case ResourceContainer, ResourceProject:
 			query.Where("clusters.cluster_uuid IN (?)", clusterPerms)
+		case ResourceCostRate:
+			query.Where("cost_rates.cluster_uuid IN (?) OR cost_rates.cluster_uuid = ''", clusterPerms)
 		}
 	}
 
@@ -94,3 +99,55 @@ func AddRBACFilter(query *gorm.DB, userPermissions map[string][]string, resource
 
 	return nil
 }
+
+// HasClusterAccess reports whether userPermissions cover every project of the given cluster.
+// An empty clusterUUID asks for access to every cluster of the org.
+func HasClusterAccess(userPermissions map[string][]string, clusterUUID string) bool {
+	cfg := config.GetConfig()
+	if !cfg.RBACEnabled {
+		return true
+	}
+	if _, ok := userPermissions["*"]; ok {
+		return true
+	}
+
+	clusterPerms, hasCluster := userPermissions["openshift.cluster"]
+	projectPerms, hasProject := userPermissions["openshift.project"]
+
+	if hasProject && !utils.StringInSlice("*", projectPerms) {
+		return false
+	}
+	if !hasCluster {
+		// Project-only wildcard -> every project across all clusters
+		return hasProject
+	}
+	if utils.StringInSlice("*", clusterPerms) {
+		return true
+	}
+	return clusterUUID != "" && utils.StringInSlice(clusterUUID, clusterPerms)
+}
+
+// HasProjectAccess reports whether userPermissions cover the given project of the given cluster.
+func HasProjectAccess(userPermissions map[string][]string, clusterUUID string, namespace string) bool {
+	cfg := config.GetConfig()
+	if !cfg.RBACEnabled {
+		return true
+	}
+	if _, ok := userPermissions["*"]; ok {
+		return true
+	}
+
+	clusterPerms, hasCluster := userPermissions["openshift.cluster"]
+	projectPerms, hasProject := userPermissions["openshift.project"]
+
+	if !hasCluster && !hasProject {
+		return false
+	}
+	if hasCluster && !utils.StringInSlice("*", clusterPerms) && !utils.StringInSlice(clusterUUID, clusterPerms) {
+		return false
+	}
+	if hasProject && !utils.StringInSlice("*", projectPerms) && !utils.StringInSlice(namespace, projectPerms) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,37 @@
+package rbac
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/config"
+)
+
+func TestHasProjectAccess(t *testing.T) {
+	cfg := config.GetConfig()
+	original := cfg.RBACEnabled
+	cfg.RBACEnabled = true
+	t.Cleanup(func() { cfg.RBACEnabled = original })
+
+	tests := []struct {
+		name        string
+		permissions map[string][]string
+		want        bool
+	}{
+		{name: "no permission", permissions: map[string][]string{}, want: false},
+		{name: "global wildcard", permissions: map[string][]string{"*": {}}, want: true},
+		{name: "cluster wildcard", permissions: map[string][]string{"openshift.cluster": {"*"}}, want: true},
+		{name: "the cluster", permissions: map[string][]string{"openshift.cluster": {"uuid-1"}}, want: true},
+		{name: "another cluster", permissions: map[string][]string{"openshift.cluster": {"uuid-2"}}, want: false},
+		{name: "the project", permissions: map[string][]string{"openshift.project": {"shop"}}, want: true},
+		{name: "another project", permissions: map[string][]string{"openshift.project": {"billing"}}, want: false},
+		{name: "the project of another cluster", permissions: map[string][]string{"openshift.cluster": {"uuid-2"}, "openshift.project": {"shop"}}, want: false},
+		{name: "nodes only", permissions: map[string][]string{"openshift.node": {"*"}}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, HasProjectAccess(tt.permissions, "uuid-1", "shop"))
+		})
+	}
+}
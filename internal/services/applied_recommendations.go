@@ -0,0 +1,156 @@
+package services
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/logging"
+	"github.com/redhatinsights/ros-ocp-backend/internal/model"
+	"github.com/redhatinsights/ros-ocp-backend/internal/types/kruizePayload"
+	"github.com/redhatinsights/ros-ocp-backend/internal/utils"
+)
+
+// appliedRequestTolerance is the relative difference under which a reported request is
+// considered equal to a recommended one; interval averages rarely match exactly.
+const appliedRequestTolerance = 0.05
+
+// containerRequests holds the average requests of a container, cpu in cores and memory in bytes.
+type containerRequests struct {
+	cpu    float64
+	memory float64
+}
+
+func reportedFloat(data interface{}) float64 {
+	value, err := strconv.ParseFloat(kruizePayload.AssertAndConvertToString(data), 64)
+	if err != nil {
+		return 0
+	}
+	return value
+}
+
+// latestContainerRequests returns the requests of every container of a workload as reported
+// in its most recent interval.
+func latestContainerRequests(rows []map[string]interface{}) map[string]containerRequests {
+	latest := make(map[string]time.Time)
+	requests := make(map[string]containerRequests)
+	for _, row := range rows {
+		containerName := kruizePayload.AssertAndConvertToString(row["container_name"])
+		intervalEnd, err := utils.ConvertStringToTime(kruizePayload.AssertAndConvertToString(row["interval_end"]))
+		if containerName == "" || err != nil {
+			continue
+		}
+		if seen, ok := latest[containerName]; ok && !intervalEnd.After(seen) {
+			continue
+		}
+		latest[containerName] = intervalEnd
+		requests[containerName] = containerRequests{
+			cpu:    reportedFloat(row["cpu_request_container_avg_MEAN"]),
+			memory: reportedFloat(row["memory_request_container_avg_MEAN"]),
+		}
+	}
+	return requests
+}
+
+func withinTolerance(reported, expected float64) bool {
+	if expected <= 0 {
+		return false
+	}
+	return math.Abs(reported-expected) <= appliedRequestTolerance*expected
+}
+
+// requestChanged reports whether a request moved away from its value at recommendation time.
+func requestChanged(reported, current float64) bool {
+	return reported != current && !withinTolerance(reported, current)
+}
+
+// recommendedConfigs returns the config of every term and engine of a recommendation.
+func recommendedConfigs(data kruizePayload.RecommendationData) []kruizePayload.ConfigObject {
+	var configs []kruizePayload.ConfigObject
+	terms := []kruizePayload.RecommendationTerm{
+		data.RecommendationTerms.Short_term,
+		data.RecommendationTerms.Medium_term,
+		data.RecommendationTerms.Long_term,
+	}
+	for _, term := range terms {
+		if term.RecommendationEngines == nil {
+			continue
+		}
+		configs = append(configs,
+			term.RecommendationEngines.Cost.Config,
+			term.RecommendationEngines.Performance.Config,
+		)
+	}
+	return configs
+}
+
+// appliedStatus compares the requests reported after a recommendation was made with the
+// recommended ones. Any term and engine may have been applied. The recommendation is applied
+// when both requests match one config and at least one of them changed; it is partially
+// applied when only one changed request matches. Returns "" when nothing matches.
+func appliedStatus(data kruizePayload.RecommendationData, reported containerRequests) string {
+	cpuChanged := requestChanged(reported.cpu, data.Current.Requests.Cpu.Amount)
+	memoryChanged := requestChanged(reported.memory, data.Current.Requests.Memory.Amount)
+	if !cpuChanged && !memoryChanged {
+		return ""
+	}
+
+	status := ""
+	for _, config := range recommendedConfigs(data) {
+		cpuMatch := withinTolerance(reported.cpu, config.Requests.Cpu.Amount)
+		memoryMatch := withinTolerance(reported.memory, config.Requests.Memory.Amount)
+		if cpuMatch && memoryMatch {
+			return model.RecommendationApplied
+		}
+		if (cpuMatch && cpuChanged) || (memoryMatch && memoryChanged) {
+			status = model.RecommendationPartiallyApplied
+		}
+	}
+	return status
+}
+
+// isNewAppliedStatus reports whether status should be recorded. A status already recorded is
+// recorded again only once the poller has replaced the recommendation it referred to.
+func isNewAppliedStatus(recommendationSet model.RecommendationSet, status string) bool {
+	return status != recommendationSet.AppliedStatus ||
+		recommendationSet.AppliedAt == nil ||
+		recommendationSet.AppliedAt.Before(recommendationSet.UpdatedAt)
+}
+
+// detectAppliedRecommendations compares the requests of a newly uploaded workload with the
+// recommendations stored for it and marks the ones that were applied.
+func detectAppliedRecommendations(workloadID uint, rows []map[string]interface{}, orgID string, clusterUUID string) {
+	log := logging.GetLogger()
+	recommendationSets, err := model.GetRecommendationSetsByWorkloadID(workloadID)
+	if err != nil {
+		log.Errorf("unable to fetch recommendation sets of workload %d: %v", workloadID, err)
+		return
+	}
+	if len(recommendationSets) == 0 {
+		return
+	}
+
+	requests := latestContainerRequests(rows)
+	for _, recommendationSet := range recommendationSets {
+		reported, ok := requests[recommendationSet.ContainerName]
+		if !ok {
+			continue
+		}
+		var data kruizePayload.RecommendationData
+		if err := json.Unmarshal(recommendationSet.Recommendations, &data); err != nil {
+			log.Errorf("unable to unmarshal recommendation %s: %v", recommendationSet.ID, err)
+			continue
+		}
+		status := appliedStatus(data, reported)
+		if status == "" || !isNewAppliedStatus(recommendationSet, status) {
+			continue
+		}
+		if err := recommendationSet.UpdateAppliedStatus(status, time.Now().UTC()); err != nil {
+			log.Errorf("unable to save applied status of recommendation %s: %v", recommendationSet.ID, err)
+			continue
+		}
+		recommendationsApplied.WithLabelValues(orgID, clusterUUID, status).Inc()
+		log.Infof("recommendation %s for container %s is %s", recommendationSet.ID, recommendationSet.ContainerName, status)
+	}
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/model"
+	"github.com/redhatinsights/ros-ocp-backend/internal/types/kruizePayload"
+)
+
+const appliedTestRecommendation = `{
+	"current": {"requests": {"cpu": {"amount": 2, "format": "cores"}, "memory": {"amount": 2000000000, "format": "bytes"}}},
+	"recommendation_terms": {
+		"short_term": {"recommendation_engines": {
+			"cost": {"config": {"requests": {"cpu": {"amount": 0.5, "format": "cores"}, "memory": {"amount": 500000000, "format": "bytes"}}}},
+			"performance": {"config": {"requests": {"cpu": {"amount": 1, "format": "cores"}, "memory": {"amount": 1000000000, "format": "bytes"}}}}
+		}},
+		"medium_term": {},
+		"long_term": {}
+	}
+}`
+
+func TestLatestContainerRequests(t *testing.T) {
+	rows := []map[string]interface{}{
+		{"container_name": "app", "interval_end": "2023-02-01 00:15:00 +0000 UTC", "cpu_request_container_avg_MEAN": 2.0, "memory_request_container_avg_MEAN": 2000000000.0},
+		{"container_name": "app", "interval_end": "2023-02-01 00:30:00 +0000 UTC", "cpu_request_container_avg_MEAN": 1.0, "memory_request_container_avg_MEAN": 1000000000.0},
+		{"container_name": "app", "interval_end": "2023-02-01 00:00:00 +0000 UTC", "cpu_request_container_avg_MEAN": 3.0, "memory_request_container_avg_MEAN": 3000000000.0},
+		{"container_name": "sidecar", "interval_end": "2023-02-01 00:30:00 +0000 UTC", "cpu_request_container_avg_MEAN": "0.25", "memory_request_container_avg_MEAN": 100},
+		{"container_name": "broken", "interval_end": "not a time", "cpu_request_container_avg_MEAN": 1.0},
+	}
+
+	requests := latestContainerRequests(rows)
+
+	assert.Equal(t, map[string]containerRequests{
+		"app":     {cpu: 1, memory: 1000000000},
+		"sidecar": {cpu: 0.25, memory: 100},
+	}, requests)
+}
+
+func TestAppliedStatus(t *testing.T) {
+	var data kruizePayload.RecommendationData
+	if err := json.Unmarshal([]byte(appliedTestRecommendation), &data); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		reported containerRequests
+		want     string
+	}{
+		{"unchanged requests", containerRequests{cpu: 2, memory: 2000000000}, ""},
+		{"cost recommendation applied", containerRequests{cpu: 0.5, memory: 500000000}, model.RecommendationApplied},
+		{"performance recommendation applied within tolerance", containerRequests{cpu: 1.04, memory: 980000000}, model.RecommendationApplied},
+		{"only cpu applied", containerRequests{cpu: 0.5, memory: 2000000000}, model.RecommendationPartiallyApplied},
+		{"only memory applied", containerRequests{cpu: 2, memory: 1000000000}, model.RecommendationPartiallyApplied},
+		{"changed to unrelated values", containerRequests{cpu: 4, memory: 4000000000}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, appliedStatus(data, tt.reported))
+		})
+	}
+}
+
+func TestIsNewAppliedStatus(t *testing.T) {
+	updatedAt := time.Date(2023, 2, 1, 12, 0, 0, 0, time.UTC)
+	before := updatedAt.Add(-time.Hour)
+	after := updatedAt.Add(time.Hour)
+
+	tests := []struct {
+		name              string
+		recommendationSet model.RecommendationSet
+		status            string
+		want              bool
+	}{
+		{"never applied", model.RecommendationSet{UpdatedAt: updatedAt}, model.RecommendationApplied, true},
+		{"already recorded", model.RecommendationSet{UpdatedAt: updatedAt, AppliedStatus: model.RecommendationApplied, AppliedAt: &after}, model.RecommendationApplied, false},
+		{"status changed", model.RecommendationSet{UpdatedAt: updatedAt, AppliedStatus: model.RecommendationPartiallyApplied, AppliedAt: &after}, model.RecommendationApplied, true},
+		{"recommendation replaced since", model.RecommendationSet{UpdatedAt: updatedAt, AppliedStatus: model.RecommendationApplied, AppliedAt: &before}, model.RecommendationApplied, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isNewAppliedStatus(tt.recommendationSet, tt.status))
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/config"
+	kafka_internal "github.com/redhatinsights/ros-ocp-backend/internal/kafka"
+	"github.com/redhatinsights/ros-ocp-backend/internal/logging"
+	"github.com/redhatinsights/ros-ocp-backend/internal/utils/kruize"
+)
+
+// Stages of ProcessReport and PollForRecommendations recorded in the HeaderDLQStage header
+// of dead-lettered messages.
+const (
+	dlqStageDecode   = "decode"
+	dlqStageValidate = "validate"
+	dlqStageFetch    = "fetch_csv"
+	dlqStageParse    = "parse_csv"
+	dlqStageDatabase = "database"
+	dlqStageKruize   = "kruize"
+	dlqStageProduce  = "produce"
+	dlqStagePoll     = "poll"
+)
+
+// maxDLQReasonLen bounds the HeaderDLQReason header; Kruize errors can embed whole responses.
+const maxDLQReasonLen = 1024
+
+// produce, produceWithHeaders and readTopic are the Kafka calls of the services; tests replace them.
+var (
+	produce            = kafka_internal.SendMessage
+	produceWithHeaders = kafka_internal.SendMessageWithHeaders
+	readTopic          = kafka_internal.ReadTopic
+)
+
+func deadLetterHeaders(msg *kafka.Message, stage string, reason string, failedAt time.Time) []kafka.Header {
+	if len(reason) > maxDLQReasonLen {
+		reason = reason[:maxDLQReasonLen]
+	}
+	sourceTopic := ""
+	if msg.TopicPartition.Topic != nil {
+		sourceTopic = *msg.TopicPartition.Topic
+	}
+	return []kafka.Header{
+		{Key: kafka_internal.HeaderAttempt, Value: []byte(strconv.Itoa(kafka_internal.Attempt(msg)))},
+		{Key: kafka_internal.HeaderDLQStage, Value: []byte(stage)},
+		{Key: kafka_internal.HeaderDLQReason, Value: []byte(reason)},
+		{Key: kafka_internal.HeaderDLQFailedAt, Value: []byte(failedAt.UTC().Format(time.RFC3339))},
+		{Key: kafka_internal.HeaderDLQSourceTopic, Value: []byte(sourceTopic)},
+		{Key: kafka_internal.HeaderDLQSourcePartition, Value: []byte(strconv.Itoa(int(msg.TopicPartition.Partition)))},
+		{Key: kafka_internal.HeaderDLQSourceOffset, Value: []byte(msg.TopicPartition.Offset.String())},
+	}
+}
+
+// sendDeadLetter publishes value, the failed message or the part of it to process again, to the
+// dead-letter topic. Dead-lettering is disabled when no topic is configured.
+func sendDeadLetter(msg *kafka.Message, value []byte, stage string, reason string) {
+	if cfg.UploadDLQTopic == "" {
+		return
+	}
+	_ = produceDeadLetter(cfg.UploadDLQTopic, msg, value, stage, reason)
+}
+
+func produceDeadLetter(topic string, msg *kafka.Message, value []byte, stage string, reason string) error {
+	log := logging.GetLogger()
+	headers := deadLetterHeaders(msg, stage, reason, time.Now())
+	if err := produceWithHeaders(value, topic, string(msg.Key), headers); err != nil {
+		log.Errorf("unable to dead-letter message (partition=%s, stage=%s): %v", msg.TopicPartition, stage, err)
+		return err
+	}
+	deadLetteredMessages.WithLabelValues(stage).Inc()
+	return nil
+}
+
+// withSingleFile rewrites an upload message so that it only lists file. Other fields are kept
+// as they are, so the rewritten message can be replayed to process that file alone.
+func withSingleFile(value []byte, file string) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(value, &fields); err != nil {
+		return nil, err
+	}
+	files, err := json.Marshal([]string{file})
+	if err != nil {
+		return nil, err
+	}
+	for key := range fields {
+		if strings.EqualFold(key, "files") {
+			fields[key] = files
+			return json.Marshal(fields)
+		}
+	}
+	return nil, fmt.Errorf("message has no files")
+}
+
+type fileFailure struct {
+	stage  string
+	reason string
+}
+
+// deadLetters collects the failures of an upload message. Only the first failure of every
+// file is kept: replaying a file processes all of its workloads again.
+type deadLetters struct {
+	msg      *kafka.Message
+	failures map[string]fileFailure
+	files    []string
+	// kruizeUnavailable is set when a failure was Kruize being unavailable; the message is then
+	// processed again rather than dead-lettered.
+	kruizeUnavailable bool
+}
+
+func newDeadLetters(msg *kafka.Message) *deadLetters {
+	return &deadLetters{msg: msg, failures: make(map[string]fileFailure)}
+}
+
+func (d *deadLetters) fileFailed(file string, stage string, err error) {
+	if errors.Is(err, kruize.ErrUnavailable) {
+		d.kruizeUnavailable = true
+	}
+	if _, ok := d.failures[file]; ok {
+		return
+	}
+	d.failures[file] = fileFailure{stage: stage, reason: err.Error()}
+	d.files = append(d.files, file)
+}
+
+// markKruizeUnavailable records that Kruize was unavailable for some workload, whatever failure
+// was recorded for its file.
+func (d *deadLetters) markKruizeUnavailable() {
+	d.kruizeUnavailable = true
+}
+
+// fileHasFailed reports whether a failure was recorded for file.
+func (d *deadLetters) fileHasFailed(file string) bool {
+	_, ok := d.failures[file]
+	return ok
+}
+
+// publish dead-letters a copy of the message per failed file.
+func (d *deadLetters) publish() {
+	log := logging.GetLogger()
+	for _, file := range d.files {
+		failure := d.failures[file]
+		value, err := withSingleFile(d.msg.Value, file)
+		if err != nil {
+			log.Errorf("unable to build dead-letter message for %s: %v", file, err)
+			value = d.msg.Value
+		}
+		sendDeadLetter(d.msg, value, failure.stage, failure.reason)
+	}
+}
+
+// DeadLetterFilter selects the dead-lettered messages to replay. A message matches when All is
+// set or when it matches any of the request IDs, stages or "partition:offset" positions.
+type DeadLetterFilter struct {
+	RequestIDs []string
+	Stages     []string
+	Offsets    []string
+	All        bool
+}
+
+func (f DeadLetterFilter) validate() error {
+	if !f.All && len(f.RequestIDs) == 0 && len(f.Stages) == 0 && len(f.Offsets) == 0 {
+		return fmt.Errorf("no messages selected")
+	}
+	for _, offset := range f.Offsets {
+		partition, position, found := strings.Cut(offset, ":")
+		if !found {
+			return fmt.Errorf("invalid offset %q, expected partition:offset", offset)
+		}
+		if _, err := strconv.ParseInt(partition, 10, 32); err != nil {
+			return fmt.Errorf("invalid partition in %q: %v", offset, err)
+		}
+		if _, err := strconv.ParseInt(position, 10, 64); err != nil {
+			return fmt.Errorf("invalid offset in %q: %v", offset, err)
+		}
+	}
+	return nil
+}
+
+func (f DeadLetterFilter) matches(msg *kafka.Message) bool {
+	if f.All {
+		return true
+	}
+	stage, _ := kafka_internal.HeaderValue(msg.Headers, kafka_internal.HeaderDLQStage)
+	if slices.Contains(f.Stages, stage) {
+		return true
+	}
+	position := fmt.Sprintf("%d:%d", msg.TopicPartition.Partition, msg.TopicPartition.Offset)
+	if slices.Contains(f.Offsets, position) {
+		return true
+	}
+	if len(f.RequestIDs) > 0 {
+		var kafkaMsg struct{ Request_id string }
+		if err := json.Unmarshal(msg.Value, &kafkaMsg); err == nil && slices.Contains(f.RequestIDs, kafkaMsg.Request_id) {
+			return true
+		}
+	}
+	return false
+}
+
+// replayHeaders marks a replayed message with the attempt it is, so a message failing again is
+// dead-lettered with a higher attempt count.
+func replayHeaders(msg *kafka.Message) []kafka.Header {
+	return []kafka.Header{
+		{Key: kafka_internal.HeaderAttempt, Value: []byte(strconv.Itoa(kafka_internal.Attempt(msg) + 1))},
+	}
+}
+
+// ReplayDeadLetters sends the dead-lettered messages selected by filter back to the upload topic,
+// with their original key, and returns how many matched. With dryRun the matches are only logged.
+// Messages stay in the dead-letter topic; it is cleaned up by its retention.
+func ReplayDeadLetters(filter DeadLetterFilter, dryRun bool) (int, error) {
+	cfg = config.GetConfig()
+	log := logging.GetLogger()
+	if cfg.UploadDLQTopic == "" {
+		return 0, fmt.Errorf("UPLOAD_DLQ_TOPIC is not configured")
+	}
+	if err := filter.validate(); err != nil {
+		return 0, err
+	}
+
+	var replayed int
+	var produceErr error
+	err := readTopic(cfg.UploadDLQTopic, func(msg *kafka.Message) {
+		if produceErr != nil || !filter.matches(msg) {
+			return
+		}
+		stage, _ := kafka_internal.HeaderValue(msg.Headers, kafka_internal.HeaderDLQStage)
+		reason, _ := kafka_internal.HeaderValue(msg.Headers, kafka_internal.HeaderDLQReason)
+		log.Infof("replaying dead letter %s (stage=%s, attempt=%d, reason=%s)", msg.TopicPartition, stage, kafka_internal.Attempt(msg), reason)
+		if !dryRun {
+			if err := produceWithHeaders(msg.Value, cfg.UploadTopic, string(msg.Key), replayHeaders(msg)); err != nil {
+				produceErr = fmt.Errorf("unable to replay dead letter %s: %w", msg.TopicPartition, err)
+				return
+			}
+		}
+		replayed++
+	})
+	if err != nil {
+		return replayed, err
+	}
+	return replayed, produceErr
+}
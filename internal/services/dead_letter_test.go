@@ -0,0 +1,184 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/stretchr/testify/assert"
+
+	kafka_internal "github.com/redhatinsights/ros-ocp-backend/internal/kafka"
+)
+
+type producedMessage struct {
+	value   []byte
+	topic   string
+	key     string
+	headers []kafka.Header
+}
+
+// captureProduced replaces produce and produceWithHeaders for the duration of the test.
+func captureProduced(t *testing.T) *[]producedMessage {
+	t.Helper()
+	var produced []producedMessage
+	original, originalWithHeaders := produce, produceWithHeaders
+	produceWithHeaders = func(msg []byte, topic string, key string, headers []kafka.Header) error {
+		produced = append(produced, producedMessage{value: msg, topic: topic, key: key, headers: headers})
+		return nil
+	}
+	produce = func(msg []byte, topic string, key string) error {
+		return produceWithHeaders(msg, topic, key, nil)
+	}
+	t.Cleanup(func() { produce, produceWithHeaders = original, originalWithHeaders })
+	return &produced
+}
+
+func header(headers []kafka.Header, key string) string {
+	value, _ := kafka_internal.HeaderValue(headers, key)
+	return value
+}
+
+func TestDeadLetterHeaders(t *testing.T) {
+	topic := "hccm.ros.events"
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 2, Offset: 17},
+		Headers:        []kafka.Header{{Key: kafka_internal.HeaderAttempt, Value: []byte("2")}},
+	}
+	failedAt := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	long := string(make([]byte, maxDLQReasonLen+10))
+
+	headers := deadLetterHeaders(msg, dlqStageKruize, long, failedAt)
+
+	assert.Equal(t, "2", header(headers, kafka_internal.HeaderAttempt))
+	assert.Equal(t, dlqStageKruize, header(headers, kafka_internal.HeaderDLQStage))
+	assert.Len(t, header(headers, kafka_internal.HeaderDLQReason), maxDLQReasonLen)
+	assert.Equal(t, "2024-03-01T10:00:00Z", header(headers, kafka_internal.HeaderDLQFailedAt))
+	assert.Equal(t, topic, header(headers, kafka_internal.HeaderDLQSourceTopic))
+	assert.Equal(t, "2", header(headers, kafka_internal.HeaderDLQSourcePartition))
+	assert.Equal(t, "17", header(headers, kafka_internal.HeaderDLQSourceOffset))
+}
+
+func TestAttempt(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []kafka.Header
+		want    int
+	}{
+		{"no header", nil, 1},
+		{"replayed", []kafka.Header{{Key: kafka_internal.HeaderAttempt, Value: []byte("3")}}, 3},
+		{"invalid", []kafka.Header{{Key: kafka_internal.HeaderAttempt, Value: []byte("x")}}, 1},
+		{"zero", []kafka.Header{{Key: kafka_internal.HeaderAttempt, Value: []byte("0")}}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, kafka_internal.Attempt(&kafka.Message{Headers: tt.headers}))
+		})
+	}
+}
+
+func TestWithSingleFile(t *testing.T) {
+	value := []byte(`{"request_id":"req-1","metadata":{"org_id":"1"},"files":["a.csv","b.csv"]}`)
+
+	rewritten, err := withSingleFile(value, "b.csv")
+	assert.NoError(t, err)
+
+	var got map[string]any
+	assert.NoError(t, json.Unmarshal(rewritten, &got))
+	assert.Equal(t, []any{"b.csv"}, got["files"])
+	assert.Equal(t, "req-1", got["request_id"])
+	assert.Equal(t, map[string]any{"org_id": "1"}, got["metadata"])
+
+	_, err = withSingleFile([]byte(`{"request_id":"req-1"}`), "b.csv")
+	assert.Error(t, err)
+}
+
+func TestDeadLettersPublishesFirstFailurePerFile(t *testing.T) {
+	produced := captureProduced(t)
+	msg := &kafka.Message{
+		Key:   []byte("cluster"),
+		Value: []byte(`{"request_id":"req-1","files":["a.csv","b.csv","c.csv"]}`),
+	}
+
+	failed := newDeadLetters(msg)
+	failed.fileFailed("b.csv", dlqStageKruize, errors.New("kruize down"))
+	failed.fileFailed("b.csv", dlqStageDatabase, errors.New("db down"))
+	failed.fileFailed("a.csv", dlqStageFetch, errors.New("404"))
+	failed.publish()
+
+	if assert.Len(t, *produced, 2) {
+		first, second := (*produced)[0], (*produced)[1]
+		assert.Equal(t, cfg.UploadDLQTopic, first.topic)
+		assert.Equal(t, "cluster", first.key)
+		assert.JSONEq(t, `{"request_id":"req-1","files":["b.csv"]}`, string(first.value))
+		assert.Equal(t, dlqStageKruize, header(first.headers, kafka_internal.HeaderDLQStage))
+		assert.Equal(t, "kruize down", header(first.headers, kafka_internal.HeaderDLQReason))
+		assert.JSONEq(t, `{"request_id":"req-1","files":["a.csv"]}`, string(second.value))
+		assert.Equal(t, dlqStageFetch, header(second.headers, kafka_internal.HeaderDLQStage))
+	}
+}
+
+func TestDeadLetterFilter(t *testing.T) {
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Partition: 1, Offset: 5},
+		Value:          []byte(`{"request_id":"req-1"}`),
+		Headers:        []kafka.Header{{Key: kafka_internal.HeaderDLQStage, Value: []byte(dlqStageKruize)}},
+	}
+	tests := []struct {
+		name   string
+		filter DeadLetterFilter
+		want   bool
+	}{
+		{"all", DeadLetterFilter{All: true}, true},
+		{"request id", DeadLetterFilter{RequestIDs: []string{"req-0", "req-1"}}, true},
+		{"other request id", DeadLetterFilter{RequestIDs: []string{"req-2"}}, false},
+		{"stage", DeadLetterFilter{Stages: []string{dlqStageKruize}}, true},
+		{"other stage", DeadLetterFilter{Stages: []string{dlqStageFetch}}, false},
+		{"offset", DeadLetterFilter{Offsets: []string{"1:5"}}, true},
+		{"other offset", DeadLetterFilter{Offsets: []string{"0:5"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.filter.matches(msg))
+		})
+	}
+}
+
+func TestDeadLetterFilterValidate(t *testing.T) {
+	assert.Error(t, DeadLetterFilter{}.validate())
+	assert.Error(t, DeadLetterFilter{Offsets: []string{"5"}}.validate())
+	assert.Error(t, DeadLetterFilter{Offsets: []string{"a:5"}}.validate())
+	assert.NoError(t, DeadLetterFilter{Offsets: []string{"0:5"}}.validate())
+	assert.NoError(t, DeadLetterFilter{Stages: []string{dlqStageFetch}}.validate())
+}
+
+func TestReplayDeadLetters(t *testing.T) {
+	produced := captureProduced(t)
+	originalReadTopic := readTopic
+	t.Cleanup(func() { readTopic = originalReadTopic })
+	readTopic = func(topic string, handler func(msg *kafka.Message)) error {
+		assert.Equal(t, cfg.UploadDLQTopic, topic)
+		handler(&kafka.Message{
+			Key:     []byte("cluster-1"),
+			Value:   []byte(`{"request_id":"req-1"}`),
+			Headers: []kafka.Header{{Key: kafka_internal.HeaderAttempt, Value: []byte("2")}},
+		})
+		handler(&kafka.Message{Key: []byte("cluster-2"), Value: []byte(`{"request_id":"req-2"}`)})
+		return nil
+	}
+
+	replayed, err := ReplayDeadLetters(DeadLetterFilter{RequestIDs: []string{"req-1"}}, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, replayed)
+	assert.Empty(t, *produced)
+
+	replayed, err = ReplayDeadLetters(DeadLetterFilter{RequestIDs: []string{"req-1"}}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, replayed)
+	if assert.Len(t, *produced, 1) {
+		assert.Equal(t, cfg.UploadTopic, (*produced)[0].topic)
+		assert.Equal(t, "cluster-1", (*produced)[0].key)
+		assert.Equal(t, "3", header((*produced)[0].headers, kafka_internal.HeaderAttempt))
+	}
+}
@@ -0,0 +1,218 @@
+package housekeeper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/config"
+	"github.com/redhatinsights/ros-ocp-backend/internal/logging"
+	"github.com/redhatinsights/ros-ocp-backend/internal/model"
+	"github.com/redhatinsights/ros-ocp-backend/internal/types/kruizePayload"
+	namespacePayload "github.com/redhatinsights/ros-ocp-backend/internal/types/kruizePayload/namespace"
+	w "github.com/redhatinsights/ros-ocp-backend/internal/types/workload"
+)
+
+// recreateMetricsWindow is how much of a workload's latest metrics are sent to a recreated
+// experiment: a day, enough for Kruize's short term recommendations.
+const recreateMetricsWindow = 24 * time.Hour
+
+// reconcileBatchSize is how many experiments or workloads are compared per query.
+var reconcileBatchSize = 1000
+
+// ReconcileSummary is what ReconcileExperiments found and did.
+type ReconcileSummary struct {
+	// Orphaned experiments have no workload, Missing workloads no experiment.
+	Orphaned  []string
+	Deleted   []string
+	Missing   []string
+	Recreated []string
+}
+
+// ReconcileExperiments compares the Kruize experiments with the workloads. Experiments no
+// workload refers to are deleted once they have been orphaned for ORPHANED_EXPERIMENT_GRACE_MINUTES,
+// and workloads whose experiment is missing are reported and, with recreate, get their experiment
+// back with their latest metrics. Experiments that are not named like the ones ros-ocp creates are
+// left alone. Workloads are read reconcileBatchSize at a time.
+func ReconcileExperiments(ctx context.Context, recreate bool) (ReconcileSummary, error) {
+	log := logging.GetLogger()
+	cfg := config.GetConfig()
+	var summary ReconcileSummary
+	now := time.Now().UTC()
+	grace := time.Duration(cfg.OrphanedExperimentGraceMinutes) * time.Minute
+
+	experiments, err := kruizeClient.ListExperiments(ctx)
+	if err != nil {
+		return summary, fmt.Errorf("unable to list Kruize experiments: %w", err)
+	}
+
+	inKruize := make(map[string]bool, len(experiments))
+	var rosExperiments []string
+	for _, experiment := range experiments {
+		inKruize[experiment.Name] = true
+		if isRosExperiment(experiment.Name) {
+			rosExperiments = append(rosExperiments, experiment.Name)
+		}
+	}
+
+	for batch := range slices.Chunk(rosExperiments, reconcileBatchSize) {
+		if err := ctx.Err(); err != nil {
+			return summary, err
+		}
+		withWorkload, err := model.GetExperimentNamesWithWorkload(batch)
+		if err != nil {
+			return summary, fmt.Errorf("unable to get workloads: %w", err)
+		}
+		var orphaned []string
+		for _, name := range batch {
+			if !withWorkload[name] {
+				orphaned = append(orphaned, name)
+			}
+		}
+		// the processor creates the experiment of an upload before saving its workload
+		firstSeen, err := model.RecordOrphanedExperiments(orphaned, now)
+		if err != nil {
+			return summary, fmt.Errorf("unable to record orphaned experiments: %w", err)
+		}
+		for _, name := range orphaned {
+			summary.Orphaned = append(summary.Orphaned, name)
+			if now.Sub(firstSeen[name]) < grace {
+				log.Infof("experiment %s has no workload since %s, keeping it for now", name, firstSeen[name].Format(time.RFC3339))
+				continue
+			}
+			if err := kruizeClient.DeleteExperiment(ctx, name); err != nil {
+				log.Errorf("unable to delete orphaned experiment %s: %v", name, err)
+				continue
+			}
+			summary.Deleted = append(summary.Deleted, name)
+		}
+	}
+	// the experiments not found orphaned by this run have a workload again or are gone
+	if err := model.ForgetOrphanedExperiments(now); err != nil {
+		log.Errorf("unable to forget orphaned experiments: %v", err)
+	}
+
+	workloads := 0
+	var afterID uint
+	for {
+		if err := ctx.Err(); err != nil {
+			return summary, err
+		}
+		page, err := model.GetWorkloadsAfter(afterID, reconcileBatchSize)
+		if err != nil {
+			return summary, fmt.Errorf("unable to get workloads: %w", err)
+		}
+		for _, workload := range page {
+			if inKruize[workload.ExperimentName] {
+				continue
+			}
+			log.Warnf("experiment %s of workload %d is missing from Kruize", workload.ExperimentName, workload.ID)
+			summary.Missing = append(summary.Missing, workload.ExperimentName)
+			if !recreate {
+				continue
+			}
+			if err := recreateExperiment(ctx, workload); err != nil {
+				log.Errorf("unable to recreate experiment %s: %v", workload.ExperimentName, err)
+				continue
+			}
+			summary.Recreated = append(summary.Recreated, workload.ExperimentName)
+		}
+		workloads += len(page)
+		if len(page) < reconcileBatchSize {
+			break
+		}
+		afterID = page[len(page)-1].ID
+	}
+
+	log.Infof("reconciled %d Kruize experiments with %d workloads: %d orphaned experiments (%d deleted), %d missing experiments (%d recreated)",
+		len(experiments), workloads, len(summary.Orphaned), len(summary.Deleted), len(summary.Missing), len(summary.Recreated))
+	return summary, nil
+}
+
+// isRosExperiment reports whether name is shaped like the experiment names of
+// utils.GenerateExperimentName and utils.GenerateNamespaceExperimentName.
+func isRosExperiment(name string) bool {
+	parts := strings.Split(name, "|")
+	return len(parts) == 6 || (len(parts) == 5 && parts[3] == "namespace")
+}
+
+// recreateExperiment creates the experiment of workload again and sends it the metrics saved for
+// the recreateMetricsWindow up to the workload's last upload.
+func recreateExperiment(ctx context.Context, workload model.Workload) error {
+	cfg := config.GetConfig()
+	clusterIdentifier := workload.OrgId + ";" + workload.Cluster.ClusterUUID
+	metrics, err := model.GetWorkloadMetrics(workload.ID, workload.MetricsUploadAt.Add(-recreateMetricsWindow), workload.MetricsUploadAt)
+	if err != nil {
+		return fmt.Errorf("unable to get workload metrics: %w", err)
+	}
+	chunkSize := max(cfg.KruizeMaxBulkChunkSize, 1)
+
+	if workload.WorkloadType == w.Namespace {
+		if err := kruizeClient.CreateNamespaceExperiment(ctx, workload.ExperimentName, clusterIdentifier, workload.Namespace); err != nil {
+			return err
+		}
+		var results []namespacePayload.UpdateNamespaceResult
+		for _, metric := range metrics {
+			var usage []kruizePayload.Metric
+			if err := json.Unmarshal(metric.UsageMetrics, &usage); err != nil {
+				return fmt.Errorf("unable to unmarshal usage metrics: %w", err)
+			}
+			results = append(results, namespacePayload.GetUpdateNamespaceResultFromMetrics(
+				workload.ExperimentName, workload.Namespace, metric.IntervalStart, metric.IntervalEnd, usage))
+		}
+		for chunk := range slices.Chunk(results, chunkSize) {
+			if _, err := kruizeClient.UpdateNamespaceResults(ctx, workload.ExperimentName, chunk); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	k8s_object := make([]map[string]interface{}, 0, len(workload.Containers))
+	for _, container := range workload.Containers {
+		// image names are not saved; Kruize does not need them for recommendations
+		k8s_object = append(k8s_object, map[string]interface{}{
+			"namespace":       workload.Namespace,
+			"k8s_object_type": string(workload.WorkloadType),
+			"k8s_object_name": workload.WorkloadName,
+			"container_name":  container,
+			"image_name":      "",
+		})
+	}
+	if len(k8s_object) == 0 {
+		return fmt.Errorf("workload %d has no containers", workload.ID)
+	}
+	if _, err := kruizeClient.CreateExperiment(ctx, workload.ExperimentName, clusterIdentifier, k8s_object); err != nil {
+		return err
+	}
+
+	type interval struct{ start, end time.Time }
+	var intervals []interval
+	containerMetrics := make(map[interval]map[string][]kruizePayload.Metric)
+	for _, metric := range metrics {
+		key := interval{metric.IntervalStart, metric.IntervalEnd}
+		if _, ok := containerMetrics[key]; !ok {
+			intervals = append(intervals, key)
+			containerMetrics[key] = make(map[string][]kruizePayload.Metric)
+		}
+		var usage []kruizePayload.Metric
+		if err := json.Unmarshal(metric.UsageMetrics, &usage); err != nil {
+			return fmt.Errorf("unable to unmarshal usage metrics: %w", err)
+		}
+		containerMetrics[key][metric.ContainerName] = usage
+	}
+	results := make([]kruizePayload.UpdateResult, 0, len(intervals))
+	for _, key := range intervals {
+		results = append(results, kruizePayload.GetUpdateResultFromMetrics(
+			workload.ExperimentName, workload.Namespace, string(workload.WorkloadType), workload.WorkloadName, key.start, key.end, containerMetrics[key]))
+	}
+	for chunk := range slices.Chunk(results, chunkSize) {
+		if _, err := kruizeClient.UpdateResults(ctx, workload.ExperimentName, chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package housekeeper
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/datatypes"
+
+	database "github.com/redhatinsights/ros-ocp-backend/internal/db"
+	"github.com/redhatinsights/ros-ocp-backend/internal/db/dbtest"
+	"github.com/redhatinsights/ros-ocp-backend/internal/model"
+	w "github.com/redhatinsights/ros-ocp-backend/internal/types/workload"
+	"github.com/redhatinsights/ros-ocp-backend/internal/utils/kruize/kruizetest"
+)
+
+const usageMetrics = `[{"name":"cpuUsage","results":{"aggregation_info":{"avg":"0.5","format":"cores"}}}]`
+
+// setupReconcileDB swaps in an in-memory database with a cluster and its workloads and metrics.
+func setupReconcileDB(t *testing.T) {
+	t.Helper()
+	db := dbtest.Use(t)
+
+	account := model.RHAccount{OrgId: "org"}
+	cluster := model.Cluster{RHAccount: account, SourceId: "source", ClusterUUID: "cluster", ClusterAlias: "cluster"}
+	assert.NoError(t, db.Create(&cluster).Error)
+	uploadAt := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	for _, workload := range []model.Workload{
+		{ExperimentName: "org|source|cluster|ns|deployment|kept", WorkloadType: w.Deployment, WorkloadName: "kept", Containers: []string{"app"}},
+		{ExperimentName: "org|source|cluster|ns|deployment|missing", WorkloadType: w.Deployment, WorkloadName: "missing", Containers: []string{"app", "sidecar"}},
+		{ExperimentName: "org|source|cluster|namespace|ns", WorkloadType: w.Namespace},
+	} {
+		workload.OrgId = "org"
+		workload.ClusterID = cluster.ID
+		workload.Namespace = "ns"
+		workload.MetricsUploadAt = uploadAt
+		assert.NoError(t, db.Create(&workload).Error)
+
+		metric := model.WorkloadMetrics{OrgId: "org", WorkloadID: workload.ID, UsageMetrics: datatypes.JSON(usageMetrics)}
+		for _, end := range []time.Time{uploadAt.Add(-15 * time.Minute), uploadAt, uploadAt.Add(-48 * time.Hour)} {
+			metric.IntervalStart = end.Add(-15 * time.Minute)
+			metric.IntervalEnd = end
+			if workload.WorkloadType == w.Namespace {
+				metric.MetricType = "namespace"
+				metric.NamespaceName = "ns"
+				metric.ID = 0
+				assert.NoError(t, db.Create(&metric).Error)
+				continue
+			}
+			metric.MetricType = "container"
+			for _, container := range workload.Containers {
+				metric.ContainerName = container
+				metric.ID = 0
+				assert.NoError(t, db.Create(&metric).Error)
+			}
+		}
+	}
+}
+
+// useFakeKruize points the housekeeper at a fake Kruize with the kept, an orphaned and a
+// foreign experiment.
+func useFakeKruize(t *testing.T) *kruizetest.Server {
+	t.Helper()
+	server := kruizetest.Use(t, &kruizeClient)
+
+	ctx := context.Background()
+	for _, name := range []string{"org|source|cluster|ns|deployment|kept", "org|source|cluster|ns|deployment|gone"} {
+		_, err := kruizeClient.CreateExperiment(ctx, name, "org;cluster", []map[string]interface{}{
+			{"namespace": "ns", "k8s_object_type": "deployment", "k8s_object_name": "app", "container_name": "app", "image_name": "image"},
+		})
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, kruizeClient.CreateNamespaceExperiment(ctx, "another-service-experiment", "other", "ns"))
+	return server
+}
+
+func TestReconcileExperiments(t *testing.T) {
+	setupReconcileDB(t)
+	server := useFakeKruize(t)
+
+	summary, err := ReconcileExperiments(context.Background(), false)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"org|source|cluster|ns|deployment|gone"}, summary.Orphaned)
+	assert.Empty(t, summary.Deleted, "the experiment is kept for the grace period")
+	assert.ElementsMatch(t, []string{"org|source|cluster|ns|deployment|missing", "org|source|cluster|namespace|ns"}, summary.Missing)
+	assert.Empty(t, summary.Recreated)
+	assert.ElementsMatch(t, []string{"org|source|cluster|ns|deployment|kept", "org|source|cluster|ns|deployment|gone", "another-service-experiment"}, server.Experiments())
+
+	// the experiment is still orphaned once the grace period is over
+	assert.NoError(t, database.GetDB().Model(&model.OrphanedExperiment{}).
+		Where("experiment_name = ?", "org|source|cluster|ns|deployment|gone").
+		Update("first_seen_at", time.Now().UTC().Add(-2*time.Hour)).Error)
+	summary, err = ReconcileExperiments(context.Background(), false)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"org|source|cluster|ns|deployment|gone"}, summary.Deleted)
+	assert.ElementsMatch(t, []string{"org|source|cluster|ns|deployment|kept", "another-service-experiment"}, server.Experiments())
+}
+
+func TestReconcileExperimentsForgetsExperimentsWithWorkload(t *testing.T) {
+	setupReconcileDB(t)
+	useFakeKruize(t)
+
+	_, err := ReconcileExperiments(context.Background(), false)
+	if !assert.NoError(t, err) {
+		return
+	}
+	// the processor saves the workload of the experiment it created
+	db := database.GetDB()
+	var cluster model.Cluster
+	assert.NoError(t, db.First(&cluster).Error)
+	assert.NoError(t, db.Create(&model.Workload{
+		OrgId: "org", ClusterID: cluster.ID, ExperimentName: "org|source|cluster|ns|deployment|gone",
+		Namespace: "ns", WorkloadType: w.Deployment, WorkloadName: "gone", Containers: []string{"app"},
+	}).Error)
+
+	summary, err := ReconcileExperiments(context.Background(), false)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Empty(t, summary.Orphaned)
+	var orphaned int64
+	assert.NoError(t, db.Model(&model.OrphanedExperiment{}).Count(&orphaned).Error)
+	assert.Zero(t, orphaned)
+}
+
+func TestReconcileExperimentsRecreatesMissingExperiments(t *testing.T) {
+	setupReconcileDB(t)
+	server := useFakeKruize(t)
+	// page through the workloads one at a time
+	originalBatchSize := reconcileBatchSize
+	t.Cleanup(func() { reconcileBatchSize = originalBatchSize })
+	reconcileBatchSize = 1
+
+	summary, err := ReconcileExperiments(context.Background(), true)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.ElementsMatch(t, summary.Missing, summary.Recreated)
+
+	experiment, ok := server.Experiment("org|source|cluster|ns|deployment|missing")
+	if assert.True(t, ok) {
+		assert.Equal(t, "org;cluster", experiment.ClusterName)
+		assert.Equal(t, []string{"app", "sidecar"}, experiment.Containers)
+		assert.Equal(t, 2, experiment.Results, "the metrics of the last day are sent")
+		assert.Equal(t, time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC), experiment.LastResultEnd)
+	}
+	experiment, ok = server.Experiment("org|source|cluster|namespace|ns")
+	if assert.True(t, ok) {
+		assert.Equal(t, "namespace", experiment.Type)
+		assert.Equal(t, "ns", experiment.Namespace)
+		assert.Equal(t, 2, experiment.Results)
+	}
+}
+
+func TestReconcileExperimentsKruizeUnavailable(t *testing.T) {
+	setupReconcileDB(t)
+	server := useFakeKruize(t)
+	server.Close()
+
+	_, err := ReconcileExperiments(context.Background(), false)
+	assert.ErrorContains(t, err, "unable to list Kruize experiments")
+}
@@ -1,9 +1,10 @@
 package housekeeper
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"os"
+	"fmt"
 	"strconv"
 
 	k "github.com/confluentinc/confluent-kafka-go/v2/kafka"
@@ -21,17 +22,18 @@ import (
 
 var cost_app_id int
 
-func StartSourcesListenerService() {
-	log := logging.GetLogger()
+// kruizeClient is the Kruize API of the housekeeper.
+var kruizeClient kruize.KruizeClient = kruize.NewDefaultKruizeClient()
+
+func StartSourcesListenerService(ctx context.Context) error {
 	cfg := config.GetConfig()
 	var err error
 	cost_app_id, err = sources.GetCostApplicationID()
 	if err != nil {
-		log.Error("Unable to get cost application id", err)
-		os.Exit(1)
+		return fmt.Errorf("unable to get cost application id: %w", err)
 	}
 
-	kafka.StartConsumer(cfg.SourcesEventTopic, sourcesListener)
+	return kafka.StartConsumer(ctx, cfg.SourcesEventTopic, sourcesListener)
 }
 
 func sourcesListener(msg *k.Message, _ *k.Consumer) {
@@ -66,7 +68,9 @@ func sourcesListener(msg *k.Message, _ *k.Consumer) {
 				}
 
 				for _, workload := range workloads {
-					kruize.DeleteExperimentFromKruize(workload.ExperimentName)
+					if err := kruizeClient.DeleteExperiment(context.Background(), workload.ExperimentName); err != nil {
+						log.Errorf("error occured while deleting experiment: %s. Error - %s", workload.ExperimentName, err)
+					}
 				}
 
 				if err := cluster.DeleteCluster(); err != nil {
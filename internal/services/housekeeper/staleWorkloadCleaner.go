@@ -0,0 +1,63 @@
+package housekeeper
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/config"
+	"github.com/redhatinsights/ros-ocp-backend/internal/logging"
+	"github.com/redhatinsights/ros-ocp-backend/internal/model"
+)
+
+// StaleSummary is what CleanStaleWorkloads found and did.
+type StaleSummary struct {
+	Marked int64
+	// Deleted are the experiment names of the deleted workloads.
+	Deleted []string
+}
+
+// CleanStaleWorkloads marks the workloads without an upload for STALE_WORKLOAD_DAYS stale, which
+// hides their recommendations from the API lists. Workloads stale for STALE_WORKLOAD_DELETE_DAYS
+// are deleted along with their metrics, recommendations and Kruize experiment. The workload is
+// deleted first, so that an upload reviving it in the meantime keeps it and its experiment; an
+// experiment that could not be deleted is left for the --reconcile job to remove as orphaned.
+func CleanStaleWorkloads(ctx context.Context) (StaleSummary, error) {
+	log := logging.GetLogger()
+	cfg := config.GetConfig()
+	var summary StaleSummary
+	now := time.Now().UTC()
+
+	marked, err := model.MarkStaleWorkloads(now.AddDate(0, 0, -cfg.StaleWorkloadDays), now)
+	if err != nil {
+		return summary, fmt.Errorf("unable to mark stale workloads: %w", err)
+	}
+	summary.Marked = marked
+
+	workloads, err := model.GetWorkloadsStaleBefore(now.AddDate(0, 0, -cfg.StaleWorkloadDeleteDays))
+	if err != nil {
+		return summary, fmt.Errorf("unable to get stale workloads: %w", err)
+	}
+	for _, workload := range workloads {
+		if err := ctx.Err(); err != nil {
+			return summary, err
+		}
+		deleted, err := model.DeleteStaleWorkload(workload.ID)
+		if err != nil {
+			log.Errorf("unable to delete stale workload %d: %v", workload.ID, err)
+			continue
+		}
+		if !deleted {
+			// an upload revived the workload since it was read
+			continue
+		}
+		summary.Deleted = append(summary.Deleted, workload.ExperimentName)
+		if err := kruizeClient.DeleteExperiment(ctx, workload.ExperimentName); err != nil {
+			log.Warnf("unable to delete experiment %s of stale workload %d, leaving it to the reconcile job: %v", workload.ExperimentName, workload.ID, err)
+		}
+	}
+
+	log.Infof("marked %d workloads stale, deleted %d of %d workloads stale for %d days",
+		summary.Marked, len(summary.Deleted), len(workloads), cfg.StaleWorkloadDeleteDays)
+	return summary, nil
+}
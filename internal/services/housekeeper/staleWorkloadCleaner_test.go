@@ -0,0 +1,98 @@
+package housekeeper
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/config"
+	database "github.com/redhatinsights/ros-ocp-backend/internal/db"
+	"github.com/redhatinsights/ros-ocp-backend/internal/model"
+	w "github.com/redhatinsights/ros-ocp-backend/internal/types/workload"
+	"github.com/redhatinsights/ros-ocp-backend/internal/utils/kruize"
+)
+
+// setupStaleWorkloads adds to the reconcile database a workload that still reports, and marks
+// the kept workload stale for longer than StaleWorkloadDeleteDays.
+func setupStaleWorkloads(t *testing.T) {
+	t.Helper()
+	cfg := config.GetConfig()
+	original := *cfg
+	cfg.StaleWorkloadDays = 7
+	cfg.StaleWorkloadDeleteDays = 30
+	t.Cleanup(func() { *cfg = original })
+
+	setupReconcileDB(t)
+	db := database.GetDB()
+	reporting := model.Workload{OrgId: "org", ClusterID: 1, ExperimentName: "org|source|cluster|ns|deployment|reporting",
+		Namespace: "ns", WorkloadType: w.Deployment, WorkloadName: "reporting", Containers: []string{"app"}, MetricsUploadAt: time.Now().UTC()}
+	assert.NoError(t, db.Create(&reporting).Error)
+	staleAt := time.Now().UTC().AddDate(0, 0, -31)
+	assert.NoError(t, db.Model(&model.Workload{}).Where("workload_name = ?", "kept").Update("stale_at", staleAt).Error)
+}
+
+func getWorkloads(t *testing.T) map[string]model.Workload {
+	t.Helper()
+	var workloads []model.Workload
+	assert.NoError(t, database.GetDB().Find(&workloads).Error)
+	byName := make(map[string]model.Workload, len(workloads))
+	for _, workload := range workloads {
+		byName[workload.ExperimentName] = workload
+	}
+	return byName
+}
+
+func TestCleanStaleWorkloads(t *testing.T) {
+	setupStaleWorkloads(t)
+	server := useFakeKruize(t)
+
+	summary, err := CleanStaleWorkloads(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, int64(2), summary.Marked, "the workloads uploaded in 2024 become stale")
+	assert.Equal(t, []string{"org|source|cluster|ns|deployment|kept"}, summary.Deleted)
+	assert.Equal(t, summary.Deleted, server.Deleted())
+
+	workloads := getWorkloads(t)
+	assert.Len(t, workloads, 3)
+	assert.Nil(t, workloads["org|source|cluster|ns|deployment|reporting"].StaleAt)
+	assert.NotNil(t, workloads["org|source|cluster|ns|deployment|missing"].StaleAt)
+	assert.NotNil(t, workloads["org|source|cluster|namespace|ns"].StaleAt)
+}
+
+func TestCleanStaleWorkloadsDeletesWorkloadsWithoutExperiment(t *testing.T) {
+	setupStaleWorkloads(t)
+	server := useFakeKruize(t)
+	assert.NoError(t, kruizeClient.DeleteExperiment(context.Background(), "org|source|cluster|ns|deployment|kept"))
+
+	summary, err := CleanStaleWorkloads(context.Background())
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"org|source|cluster|ns|deployment|kept"}, summary.Deleted)
+	}
+	assert.NotContains(t, getWorkloads(t), "org|source|cluster|ns|deployment|kept")
+	assert.Len(t, server.Deleted(), 1)
+}
+
+func TestCleanStaleWorkloadsKruizeUnavailable(t *testing.T) {
+	setupStaleWorkloads(t)
+	server := useFakeKruize(t)
+	server.Fail(kruize.KruizeDeleteExperiment, http.StatusServiceUnavailable)
+
+	summary, err := CleanStaleWorkloads(context.Background())
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"org|source|cluster|ns|deployment|kept"}, summary.Deleted)
+	}
+	assert.NotContains(t, getWorkloads(t), "org|source|cluster|ns|deployment|kept")
+	assert.Contains(t, server.Experiments(), "org|source|cluster|ns|deployment|kept")
+
+	// the reconcile job finds the experiment left behind orphaned
+	server.Recover()
+	reconciled, err := ReconcileExperiments(context.Background(), false)
+	if assert.NoError(t, err) {
+		assert.Contains(t, reconciled.Orphaned, "org|source|cluster|ns|deployment|kept")
+	}
+}
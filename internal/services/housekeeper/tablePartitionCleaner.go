@@ -6,6 +6,7 @@ import (
 
 	"github.com/redhatinsights/ros-ocp-backend/internal/config"
 	database "github.com/redhatinsights/ros-ocp-backend/internal/db"
+	"github.com/redhatinsights/ros-ocp-backend/internal/model"
 )
 
 func DeletePartitions() {
@@ -30,4 +31,9 @@ func DeletePartitions() {
 	if tx.Error != nil {
 		fmt.Println(tx.Error.Error())
 	}
+
+	// upload statuses are kept as long as the metrics they describe
+	if _, err := model.DeleteUploadStatusesBefore(retentionThresholdDate); err != nil {
+		fmt.Println(err.Error())
+	}
 }
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-gota/gota/dataframe"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/types"
+	"github.com/redhatinsights/ros-ocp-backend/internal/types/kruizePayload"
+	namespacePayload "github.com/redhatinsights/ros-ocp-backend/internal/types/kruizePayload/namespace"
+	"github.com/redhatinsights/ros-ocp-backend/internal/utils"
+	"github.com/redhatinsights/ros-ocp-backend/internal/utils/kruize"
+)
+
+// Kruize requests BuildKruizePayloads can build.
+const (
+	EmitKruizeCreate = "kruize-create"
+	EmitKruizeUpdate = "kruize-update"
+	EmitNamespace    = "namespace"
+)
+
+// KruizePayloads maps experiment names to the request bodies sent to Kruize for them, one per
+// chunk of KRUIZE_MAX_BULK_CHUNK_SIZE results.
+type KruizePayloads map[string][]json.RawMessage
+
+// BuildKruizePayloads builds the Kruize requests of emit the processor sends for the aggregated
+// CSV df of the upload kafkaMsg, without sending them. kruize-create and kruize-update build the
+// createExperiment and updateResults bodies of a container CSV, namespace the updateResults
+// bodies of a namespace CSV.
+func BuildKruizePayloads(emit string, csvType types.PayloadType, df dataframe.DataFrame, kafkaMsg types.KafkaMsg) (KruizePayloads, error) {
+	wantType := types.PayloadTypeContainer
+	switch emit {
+	case EmitKruizeCreate, EmitKruizeUpdate:
+	case EmitNamespace:
+		wantType = types.PayloadTypeNamespace
+	default:
+		return nil, fmt.Errorf("unknown payload %q, expected %s, %s or %s", emit, EmitKruizeCreate, EmitKruizeUpdate, EmitNamespace)
+	}
+	if csvType != wantType {
+		return nil, fmt.Errorf("%s payloads are built from a %s CSV, got a %s CSV", emit, wantType, csvType)
+	}
+
+	metadata := kafkaMsg.Metadata
+	clusterIdentifier := metadata.Org_id + ";" + metadata.Cluster_uuid
+	payloads := KruizePayloads{}
+	if emit == EmitNamespace {
+		for _, group := range df.GroupBy("namespace").GetGroups() {
+			rows := group.Maps()
+			namespace := kruizePayload.AssertAndConvertToString(rows[0]["namespace"])
+			experimentName := utils.GenerateNamespaceExperimentName(metadata.Org_id, metadata.Source_id, metadata.Cluster_uuid, namespace)
+			bodies, err := marshalChunks(SliceMetricsUpdatePayloadToChunks(namespacePayload.GetUpdateNamespaceResultPayload(experimentName, rows)))
+			if err != nil {
+				return nil, err
+			}
+			payloads[experimentName] = bodies
+		}
+		return payloads, nil
+	}
+
+	for _, group := range df.GroupBy("namespace", "k8s_object_type", "k8s_object_name").GetGroups() {
+		k8s_object := group.Maps()
+		experimentName := utils.GenerateExperimentName(
+			metadata.Org_id,
+			metadata.Source_id,
+			metadata.Cluster_uuid,
+			kruizePayload.AssertAndConvertToString(k8s_object[0]["namespace"]),
+			k8s_object[0]["k8s_object_type"].(string),
+			k8s_object[0]["k8s_object_name"].(string),
+		)
+		if emit == EmitKruizeCreate {
+			data, containers := kruize.ExperimentObject(k8s_object)
+			body, err := kruizePayload.GetCreateExperimentPayload(experimentName, clusterIdentifier, containers, data)
+			if err != nil {
+				return nil, fmt.Errorf("unable to create payload: %v", err)
+			}
+			payloads[experimentName] = []json.RawMessage{body}
+			continue
+		}
+		bodies, err := marshalChunks(SliceMetricsUpdatePayloadToChunks(kruizePayload.GetUpdateResultPayload(experimentName, k8s_object)))
+		if err != nil {
+			return nil, err
+		}
+		payloads[experimentName] = bodies
+	}
+	return payloads, nil
+}
+
+func marshalChunks[T AcceptedPayloadType](chunks [][]T) ([]json.RawMessage, error) {
+	bodies := make([]json.RawMessage, 0, len(chunks))
+	for _, chunk := range chunks {
+		body, err := json.Marshal(chunk)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create payload: %v", err)
+		}
+		bodies = append(bodies, body)
+	}
+	return bodies, nil
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/types"
+	"github.com/redhatinsights/ros-ocp-backend/internal/types/kruizePayload"
+	namespacePayload "github.com/redhatinsights/ros-ocp-backend/internal/types/kruizePayload/namespace"
+	"github.com/redhatinsights/ros-ocp-backend/internal/utils/kruize/kruizetest"
+)
+
+func payloadMsg() types.KafkaMsg {
+	var msg types.KafkaMsg
+	msg.Metadata.Org_id = "3340851"
+	msg.Metadata.Source_id = "source"
+	msg.Metadata.Cluster_uuid = "cluster"
+	return msg
+}
+
+func TestBuildKruizePayloadsForContainers(t *testing.T) {
+	df := kruizetest.AggregateSample(t, "../../scripts/samples/ros-ocp-usage.csv", types.PayloadTypeContainer)
+	experimentName := "3340851|source|cluster|Yuptoo-prod|deployment|Yuptoo-service"
+
+	created, err := BuildKruizePayloads(EmitKruizeCreate, types.PayloadTypeContainer, df, payloadMsg())
+	if assert.NoError(t, err) && assert.Len(t, created[experimentName], 1) {
+		var body []map[string]any
+		assert.NoError(t, json.Unmarshal(created[experimentName][0], &body))
+		assert.Equal(t, experimentName, body[0]["experiment_name"])
+		assert.Equal(t, "3340851;cluster", body[0]["cluster_name"])
+	}
+
+	updated, err := BuildKruizePayloads(EmitKruizeUpdate, types.PayloadTypeContainer, df, payloadMsg())
+	if assert.NoError(t, err) && assert.NotEmpty(t, updated[experimentName]) {
+		assert.Equal(t, len(created), len(updated))
+		var body []kruizePayload.UpdateResult
+		assert.NoError(t, json.Unmarshal(updated[experimentName][0], &body))
+		assert.NotEmpty(t, body)
+		assert.Equal(t, experimentName, body[0].Experiment_name)
+	}
+}
+
+func TestBuildKruizePayloadsForNamespaces(t *testing.T) {
+	df := kruizetest.AggregateSample(t, "../../scripts/samples/ros_ocp_namespace.csv", types.PayloadTypeNamespace)
+
+	payloads, err := BuildKruizePayloads(EmitNamespace, types.PayloadTypeNamespace, df, payloadMsg())
+	if assert.NoError(t, err) && assert.NotEmpty(t, payloads) {
+		for experimentName, bodies := range payloads {
+			assert.Regexp(t, `^3340851\|source\|cluster\|namespace\|`, experimentName)
+			var body []namespacePayload.UpdateNamespaceResult
+			assert.NoError(t, json.Unmarshal(bodies[0], &body))
+			assert.Equal(t, experimentName, body[0].ExperimentName)
+		}
+	}
+
+	_, err = BuildKruizePayloads(EmitKruizeCreate, types.PayloadTypeNamespace, df, payloadMsg())
+	assert.EqualError(t, err, "kruize-create payloads are built from a container CSV, got a namespace CSV")
+	_, err = BuildKruizePayloads("kruize-delete", types.PayloadTypeNamespace, df, payloadMsg())
+	assert.EqualError(t, err, `unknown payload "kruize-delete", expected kruize-create, kruize-update or namespace`)
+}
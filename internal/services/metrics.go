@@ -34,4 +34,28 @@ var (
 		Name: "rosocp_csv_fetch_error_total",
 		Help: "The total number of errors encountered while fetching CSV from URL",
 	})
+	recommendationEventError = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "rosocp_recommendation_event_error_total",
+		Help: "The total number of saved recommendations that could not be published to the events topic",
+	})
+	deadLetteredMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "rosocp_dead_letter_total",
+		Help: "The total number of upload messages sent to the dead-letter topic, by failed stage",
+	}, []string{"stage"})
+	recommendationRetry = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "rosocp_recommendation_retry_total",
+		Help: "The total number of failed recommendation requests, by outcome (scheduled, released or parked)",
+	}, []string{"outcome"})
+	workloadGroups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "rosocp_workload_groups_total",
+		Help: "The total number of workload groups processed from uploads, by type and outcome",
+	}, []string{"type", "outcome"})
+	recommendationsApplied = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "rosocp_recommendations_applied_total",
+		Help: "The total number of container recommendations detected as applied or partially applied",
+	}, []string{"org_id", "cluster_uuid", "status"})
+	webhookDropped = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "rosocp_webhook_dropped_total",
+		Help: "The total number of webhook deliveries dropped because the delivery queue was full",
+	})
 )
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/logging"
+	"github.com/redhatinsights/ros-ocp-backend/internal/model"
+	"github.com/redhatinsights/ros-ocp-backend/internal/types"
+)
+
+// recommendationEventVersion is bumped whenever a change to recommendationEvent could
+// break consumers; adding fields does not require a new version.
+const recommendationEventVersion = 1
+
+const (
+	recommendationEventCreated = "created"
+	recommendationEventUpdated = "updated"
+)
+
+// recommendationEvent is published to RECOMMENDATION_EVENTS_TOPIC for every saved recommendation.
+type recommendationEvent struct {
+	Version             int                              `json:"version"`
+	EventType           string                           `json:"event_type"`
+	RecommendationType  types.PayloadType                `json:"recommendation_type"`
+	RecommendationID    string                           `json:"recommendation_id"`
+	OrgID               string                           `json:"org_id"`
+	WorkloadID          uint                             `json:"workload_id"`
+	ExperimentName      string                           `json:"experiment_name"`
+	ContainerName       string                           `json:"container_name,omitempty"`
+	NamespaceName       string                           `json:"namespace_name,omitempty"`
+	MonitoringStartTime time.Time                        `json:"monitoring_start_time"`
+	MonitoringEndTime   time.Time                        `json:"monitoring_end_time"`
+	Values              model.RecommendationColumnValues `json:"values"`
+}
+
+// savedRecommendation is what events and webhooks need to know about a recommendation the poller stored.
+type savedRecommendation struct {
+	id                  string
+	container           string
+	namespace           string
+	monitoringStartTime time.Time
+	monitoringEndTime   time.Time
+	columnValues        model.RecommendationColumnValues
+	variationPcts       model.StoredVariationPcts
+}
+
+func savedContainerRecommendations(recommendationSets []model.RecommendationSet) []savedRecommendation {
+	saved := make([]savedRecommendation, 0, len(recommendationSets))
+	for _, recommendationSet := range recommendationSets {
+		saved = append(saved, savedRecommendation{
+			id:                  recommendationSet.ID,
+			container:           recommendationSet.ContainerName,
+			monitoringStartTime: recommendationSet.MonitoringStartTime,
+			monitoringEndTime:   recommendationSet.MonitoringEndTime,
+			columnValues:        recommendationSet.ColumnValues(),
+			variationPcts:       recommendationSet.StoredVariationPcts,
+		})
+	}
+	return saved
+}
+
+func savedNamespaceRecommendations(recommendationSets []model.NamespaceRecommendationSet) []savedRecommendation {
+	saved := make([]savedRecommendation, 0, len(recommendationSets))
+	for _, recommendationSet := range recommendationSets {
+		saved = append(saved, savedRecommendation{
+			id:                  recommendationSet.ID,
+			namespace:           recommendationSet.NamespaceName,
+			monitoringStartTime: recommendationSet.MonitoringStartTime,
+			monitoringEndTime:   recommendationSet.MonitoringEndTime,
+			columnValues:        recommendationSet.ColumnValues(),
+			variationPcts:       recommendationSet.StoredVariationPcts,
+		})
+	}
+	return saved
+}
+
+// recommendationEventType maps the poller recommendation type ("New" or "Update") to an event type.
+func recommendationEventType(recommendationType string) string {
+	if recommendationType == "New" {
+		return recommendationEventCreated
+	}
+	return recommendationEventUpdated
+}
+
+func newRecommendationEvents(kafkaMsg types.RecommendationKafkaMsg, eventType string, saved []savedRecommendation) []recommendationEvent {
+	events := make([]recommendationEvent, 0, len(saved))
+	for _, recommendation := range saved {
+		events = append(events, recommendationEvent{
+			Version:             recommendationEventVersion,
+			EventType:           eventType,
+			RecommendationType:  kafkaMsg.Metadata.ExperimentType,
+			RecommendationID:    recommendation.id,
+			OrgID:               kafkaMsg.Metadata.Org_id,
+			WorkloadID:          kafkaMsg.Metadata.Workload_id,
+			ExperimentName:      kafkaMsg.Metadata.Experiment_name,
+			ContainerName:       recommendation.container,
+			NamespaceName:       recommendation.namespace,
+			MonitoringStartTime: recommendation.monitoringStartTime,
+			MonitoringEndTime:   recommendation.monitoringEndTime,
+			Values:              recommendation.columnValues,
+		})
+	}
+	return events
+}
+
+// publishRecommendationEvents sends an event per saved recommendation, keyed by experiment name
+// so the events of a workload stay ordered. Publishing is disabled when no topic is configured.
+// The recommendations are already committed, so failures are logged and counted only.
+func publishRecommendationEvents(kafkaMsg types.RecommendationKafkaMsg, recommendationType string, saved []savedRecommendation) {
+	if cfg.RecommendationEventsTopic == "" {
+		return
+	}
+	log := logging.GetLogger()
+	for _, event := range newRecommendationEvents(kafkaMsg, recommendationEventType(recommendationType), saved) {
+		msgBytes, err := json.Marshal(event)
+		if err != nil {
+			log.Errorf("unable to marshal recommendation event: %v", err)
+			recommendationEventError.Inc()
+			continue
+		}
+		if err := produce(msgBytes, cfg.RecommendationEventsTopic, event.ExperimentName); err != nil {
+			log.Errorf("unable to publish event for recommendation %s: %v", event.RecommendationID, err)
+			recommendationEventError.Inc()
+		}
+	}
+}
+
+// afterRecommendationsSaved pushes the recommendations committed by the poller to subscribers.
+func afterRecommendationsSaved(kafkaMsg types.RecommendationKafkaMsg, recommendationType string, saved []savedRecommendation) {
+	publishRecommendationEvents(kafkaMsg, recommendationType, saved)
+	notifyWebhooks(kafkaMsg.Metadata.Org_id, kafkaMsg.Metadata.Workload_id, kafkaMsg.Metadata.ExperimentType, saved)
+}
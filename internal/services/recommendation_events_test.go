@@ -0,0 +1,70 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/model"
+	"github.com/redhatinsights/ros-ocp-backend/internal/types"
+)
+
+func TestRecommendationEventType(t *testing.T) {
+	assert.Equal(t, recommendationEventCreated, recommendationEventType("New"))
+	assert.Equal(t, recommendationEventUpdated, recommendationEventType("Update"))
+}
+
+func TestNewRecommendationEvents(t *testing.T) {
+	kafkaMsg := types.RecommendationKafkaMsg{
+		Request_id: "req-1",
+		Metadata: types.RecommendationMetadata{
+			Org_id:          "org",
+			Workload_id:     42,
+			Experiment_name: "org|source|cluster|shop|deployment|frontend",
+			ExperimentType:  types.PayloadTypeContainer,
+		},
+	}
+	startTime := time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC)
+	endTime := time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC)
+	saved := savedContainerRecommendations([]model.RecommendationSet{{
+		ID:                  "rec-1",
+		ContainerName:       "app",
+		CPURequestCurrent:   pct(2),
+		StoredVariationPcts: model.StoredVariationPcts{CPUVariationShortCostPct: pct(-60)},
+		MonitoringStartTime: startTime,
+		MonitoringEndTime:   endTime,
+	}})
+
+	events := newRecommendationEvents(kafkaMsg, recommendationEventCreated, saved)
+
+	assert.Len(t, events, 1)
+	payload, err := json.Marshal(events[0])
+	assert.NoError(t, err)
+	var decoded map[string]interface{}
+	assert.NoError(t, json.Unmarshal(payload, &decoded))
+	assert.Equal(t, float64(recommendationEventVersion), decoded["version"])
+	assert.Equal(t, "created", decoded["event_type"])
+	assert.Equal(t, "container", decoded["recommendation_type"])
+	assert.Equal(t, "rec-1", decoded["recommendation_id"])
+	assert.Equal(t, "org", decoded["org_id"])
+	assert.Equal(t, float64(42), decoded["workload_id"])
+	assert.Equal(t, "org|source|cluster|shop|deployment|frontend", decoded["experiment_name"])
+	assert.Equal(t, "app", decoded["container_name"])
+	assert.NotContains(t, decoded, "namespace_name")
+	values := decoded["values"].(map[string]interface{})
+	assert.Equal(t, float64(2), values["cpu_request_current"])
+	assert.Equal(t, float64(-60), values["cpu_variation_short_cost_pct"])
+	assert.Nil(t, values["memory_variation_long_performance_pct"])
+}
+
+func TestPublishRecommendationEvents_DisabledWithoutTopic(t *testing.T) {
+	topic := cfg.RecommendationEventsTopic
+	cfg.RecommendationEventsTopic = ""
+	defer func() { cfg.RecommendationEventsTopic = topic }()
+
+	produced := captureProduced(t)
+	publishRecommendationEvents(types.RecommendationKafkaMsg{}, "New", []savedRecommendation{{id: "rec-1"}})
+	assert.Empty(t, *produced)
+}
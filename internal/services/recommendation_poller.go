@@ -1,7 +1,9 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
 	"time"
@@ -14,33 +16,37 @@ import (
 	"github.com/redhatinsights/ros-ocp-backend/internal/logging"
 	"github.com/redhatinsights/ros-ocp-backend/internal/model"
 	"github.com/redhatinsights/ros-ocp-backend/internal/types"
-	"github.com/redhatinsights/ros-ocp-backend/internal/types/kruizePayload"
-	namespacePayload "github.com/redhatinsights/ros-ocp-backend/internal/types/kruizePayload/namespace"
 	"github.com/redhatinsights/ros-ocp-backend/internal/utils"
 	"github.com/redhatinsights/ros-ocp-backend/internal/utils/kruize"
 )
 
 func commitKafkaMsg(msg *kafka.Message, consumer_object *kafka.Consumer) {
 	log := logging.GetLogger()
+	if consumer_object == nil {
+		return
+	}
 	_, err := consumer_object.CommitMessage(msg)
 	if err != nil {
 		log.Error("unable to commit msg: ", err)
 	}
 }
 
-func fetchRecommendationFromKruize(
+// fetchRecommendationFromKruize asks Kruize for the recommendations of an experiment with
+// update, the KruizeClient method of experimentType.
+func fetchRecommendationFromKruize[T any](
 	experimentName string,
 	maxEndTime time.Time,
 	experimentType types.PayloadType,
-) (any, error) {
+	update func(ctx context.Context, experimentName string, maxEndTime time.Time) (T, error),
+) (T, error) {
 	log := logging.GetLogger()
 
-	response, err := kruize.Update_recommendations(experimentName, maxEndTime, experimentType)
+	response, err := update(context.Background(), experimentName, maxEndTime)
 	if err != nil {
 		endInterval, convErr := utils.ConvertDateToISO8601(maxEndTime.String())
 		if convErr != nil {
 			log.Warnf("unable to format maxEndTime for error comparison: %v", convErr)
-			return nil, err
+			return response, err
 		}
 		notFoundMsg := fmt.Sprintf("Recommendation for timestamp - \" %s \" does not exist", endInterval)
 
@@ -53,7 +59,7 @@ func fetchRecommendationFromKruize(
 				namespaceRecommendationRequest.Inc()
 			}
 		}
-		return nil, err
+		return response, err
 	}
 	return response, nil
 }
@@ -72,7 +78,9 @@ func transactionForContainerRecommendation(recommendationSetList []model.Recomme
 		return err
 	}
 
-	for _, recommendationSet := range recommendationSetList {
+	// index the slice so the IDs returned by the upserts are kept for events and webhooks
+	for i := range recommendationSetList {
+		recommendationSet := &recommendationSetList[i]
 		if err := recommendationSet.CreateRecommendationSet(tx); err != nil {
 			log.Errorf("unable to save a record into recommendation set: %v. Error: %v", recommendationSet, err)
 			tx.Rollback()
@@ -105,7 +113,8 @@ func transactionForNamespaceRecommendation(recommendationSetList []model.Namespa
 		return err
 	}
 
-	for _, recommendationSet := range recommendationSetList {
+	for i := range recommendationSetList {
+		recommendationSet := &recommendationSetList[i]
 		if err := recommendationSet.CreateNamespaceRecommendationSet(tx); err != nil {
 			log.Errorf("unable to save a record into recommendation set: %v. Error: %v", recommendationSet, err)
 			tx.Rollback()
@@ -129,7 +138,10 @@ func transactionForNamespaceRecommendation(recommendationSetList []model.Namespa
  * Adding interfaces will change the flow structurally, might increase the complexity of this service
  */
 
-func requestAndSaveRecommendation(kafkaMsg types.RecommendationKafkaMsg, recommendationType string) bool {
+// requestAndSaveRecommendation fetches the recommendations of the workload of kafkaMsg from Kruize
+// and saves them. It reports whether the poll cycle completed, and returns the error of Kruize when
+// the recommendations could not be fetched.
+func requestAndSaveRecommendation(kafkaMsg types.RecommendationKafkaMsg, recommendationType string) (bool, error) {
 	log := logging.GetLogger()
 	experiment_name := kafkaMsg.Metadata.Experiment_name
 	maxEndTimeFromReport := kafkaMsg.Metadata.Max_endtime_report
@@ -142,148 +154,120 @@ func requestAndSaveRecommendation(kafkaMsg types.RecommendationKafkaMsg, recomme
 	namespaceHistRecommendationSetList := []model.HistoricalNamespaceRecommendationSet{}
 
 	if kafkaMsg.Metadata.ExperimentType == types.PayloadTypeContainer {
-		recommendationResponse, err := fetchRecommendationFromKruize(
-			experiment_name, maxEndTimeFromReport, types.PayloadTypeContainer)
+		recommendation, err := fetchRecommendationFromKruize(
+			experiment_name, maxEndTimeFromReport, types.PayloadTypeContainer, kruizeClient.UpdateRecommendations)
 		if err != nil {
-			return poll_cycle_complete
+			return poll_cycle_complete, err
 		}
 		recommendationRequest.Inc()
 
-		if recommendation, ok := recommendationResponse.([]kruizePayload.ListRecommendations); ok {
-
-			if len(recommendation) == 0 || len(recommendation[0].Kubernetes_objects) == 0 {
-				log.Warnf("empty recommendation response for experiment %s", experiment_name)
-				return poll_cycle_complete
-			}
-
-			if recommendation[0].Experiment_type != string(types.PayloadTypeContainer) {
-				log.Errorf("experiment type mismatch: expected %s, got %s", types.PayloadTypeContainer, recommendation[0].Experiment_type)
-				return poll_cycle_complete
-			}
-
-			containers := recommendation[0].Kubernetes_objects[0].Containers
-			for _, container := range containers {
-				if kruize.IsValidRecommendation(container.Recommendations, experiment_name, maxEndTimeFromReport, types.PayloadTypeContainer) {
-					for _, v := range container.Recommendations.Data {
-						marshalData, err := json.Marshal(v)
-						if err != nil {
-							log.Errorf("unable to list recommendation for: %v", err)
-							continue
-						}
-						extractedRecommVals := model.ExtractRecommendationColumnValues(v)
-						// Create RecommendationSet entry into the table.
-						recommendationSet := model.RecommendationSet{
-							WorkloadID:                          kafkaMsg.Metadata.Workload_id,
-							ContainerName:                       container.Container_name,
-							CPURequestCurrent:                   extractedRecommVals.CPURequestCurrent,
-							MemoryRequestCurrent:                extractedRecommVals.MemoryRequestCurrent,
-							CPUVariationShortCostPct:            extractedRecommVals.CPUVariationShortCostPct,
-							CPUVariationShortPerformancePct:     extractedRecommVals.CPUVariationShortPerformancePct,
-							CPUVariationMediumCostPct:           extractedRecommVals.CPUVariationMediumCostPct,
-							CPUVariationMediumPerformancePct:    extractedRecommVals.CPUVariationMediumPerformancePct,
-							CPUVariationLongCostPct:             extractedRecommVals.CPUVariationLongCostPct,
-							CPUVariationLongPerformancePct:      extractedRecommVals.CPUVariationLongPerformancePct,
-							MemoryVariationShortCostPct:         extractedRecommVals.MemoryVariationShortCostPct,
-							MemoryVariationShortPerformancePct:  extractedRecommVals.MemoryVariationShortPerformancePct,
-							MemoryVariationMediumCostPct:        extractedRecommVals.MemoryVariationMediumCostPct,
-							MemoryVariationMediumPerformancePct: extractedRecommVals.MemoryVariationMediumPerformancePct,
-							MemoryVariationLongCostPct:          extractedRecommVals.MemoryVariationLongCostPct,
-							MemoryVariationLongPerformancePct:   extractedRecommVals.MemoryVariationLongPerformancePct,
-							MonitoringStartTime:                 v.RecommendationTerms.Short_term.MonitoringStartTime,
-							MonitoringEndTime:                   v.MonitoringEndTime,
-							Recommendations:                     marshalData,
-						}
-						recommendationSetList = append(recommendationSetList, recommendationSet)
-
-						// Create entry into HistoricalRecommendationSet table.
-						historicalRecommendationSet := model.HistoricalRecommendationSet{
-							OrgId:               kafkaMsg.Metadata.Org_id,
-							WorkloadID:          kafkaMsg.Metadata.Workload_id,
-							ContainerName:       container.Container_name,
-							MonitoringStartTime: v.RecommendationTerms.Short_term.MonitoringStartTime,
-							MonitoringEndTime:   v.MonitoringEndTime,
-							Recommendations:     marshalData,
-						}
-						histRecommendationSetList = append(histRecommendationSetList, historicalRecommendationSet)
-					}
-				} else {
-					poll_cycle_complete = true
-					continue
-				}
-			}
+		if len(recommendation) == 0 || len(recommendation[0].Kubernetes_objects) == 0 {
+			log.Warnf("empty recommendation response for experiment %s", experiment_name)
+			return poll_cycle_complete, nil
 		}
 
-	}
-
-	if kafkaMsg.Metadata.ExperimentType == types.PayloadTypeNamespace && !cfg.DisableNamespaceRecommendation {
-		namespaceRecommendation, err := fetchRecommendationFromKruize(
-			experiment_name, maxEndTimeFromReport, types.PayloadTypeNamespace)
-		if err != nil {
-			return poll_cycle_complete
+		if recommendation[0].Experiment_type != string(types.PayloadTypeContainer) {
+			log.Errorf("experiment type mismatch: expected %s, got %s", types.PayloadTypeContainer, recommendation[0].Experiment_type)
+			return poll_cycle_complete, nil
 		}
-		namespaceRecommendationRequest.Inc()
-
-		if typedNamespaceObj, ok := namespaceRecommendation.(namespacePayload.NamespaceRecommendationResponse); ok {
 
-			if len(typedNamespaceObj) == 0 || len(typedNamespaceObj[0].KubernetesObjects) == 0 {
-				log.Warnf("empty namespace recommendation response for experiment %s", experiment_name)
-				return poll_cycle_complete
-			}
-
-			if typedNamespaceObj[0].ExperimentType != string(types.PayloadTypeNamespace) {
-				log.Errorf("experiment type mismatch: expected %s, got %s", types.PayloadTypeNamespace, typedNamespaceObj[0].ExperimentType)
-				return poll_cycle_complete
-			}
-
-			typedNamespaceRecommendation := typedNamespaceObj[0].KubernetesObjects[0].Namespaces
-			if kruize.IsValidRecommendation(typedNamespaceRecommendation.Recommendations, experiment_name, maxEndTimeFromReport, types.PayloadTypeNamespace) {
-				for _, v := range typedNamespaceRecommendation.Recommendations.Data {
+		containers := recommendation[0].Kubernetes_objects[0].Containers
+		for _, container := range containers {
+			if kruize.IsValidRecommendation(container.Recommendations, experiment_name, maxEndTimeFromReport, types.PayloadTypeContainer) {
+				for _, v := range container.Recommendations.Data {
 					marshalData, err := json.Marshal(v)
 					if err != nil {
 						log.Errorf("unable to list recommendation for: %v", err)
 						continue
 					}
-
-					extractedNamespaceRecommVals := model.ExtractRecommendationColumnValues(v)
-
-					recommendationSet := model.NamespaceRecommendationSet{
-						OrgID:                               kafkaMsg.Metadata.Org_id,
-						WorkloadID:                          kafkaMsg.Metadata.Workload_id,
-						NamespaceName:                       typedNamespaceRecommendation.Namespace,
-						CPURequestCurrent:                   extractedNamespaceRecommVals.CPURequestCurrent,
-						MemoryRequestCurrent:                extractedNamespaceRecommVals.MemoryRequestCurrent,
-						CPUVariationShortCostPct:            extractedNamespaceRecommVals.CPUVariationShortCostPct,
-						CPUVariationShortPerformancePct:     extractedNamespaceRecommVals.CPUVariationShortPerformancePct,
-						CPUVariationMediumCostPct:           extractedNamespaceRecommVals.CPUVariationMediumCostPct,
-						CPUVariationMediumPerformancePct:    extractedNamespaceRecommVals.CPUVariationMediumPerformancePct,
-						CPUVariationLongCostPct:             extractedNamespaceRecommVals.CPUVariationLongCostPct,
-						CPUVariationLongPerformancePct:      extractedNamespaceRecommVals.CPUVariationLongPerformancePct,
-						MemoryVariationShortCostPct:         extractedNamespaceRecommVals.MemoryVariationShortCostPct,
-						MemoryVariationShortPerformancePct:  extractedNamespaceRecommVals.MemoryVariationShortPerformancePct,
-						MemoryVariationMediumCostPct:        extractedNamespaceRecommVals.MemoryVariationMediumCostPct,
-						MemoryVariationMediumPerformancePct: extractedNamespaceRecommVals.MemoryVariationMediumPerformancePct,
-						MemoryVariationLongCostPct:          extractedNamespaceRecommVals.MemoryVariationLongCostPct,
-						MemoryVariationLongPerformancePct:   extractedNamespaceRecommVals.MemoryVariationLongPerformancePct,
-						MonitoringStartTime:                 v.RecommendationTerms.Short_term.MonitoringStartTime,
-						MonitoringEndTime:                   v.MonitoringEndTime,
-						Recommendations:                     marshalData,
-						UpdatedAt:                           time.Now(),
+					extractedRecommVals := model.ExtractRecommendationColumnValues(v)
+					// Create RecommendationSet entry into the table.
+					recommendationSet := model.RecommendationSet{
+						WorkloadID:           kafkaMsg.Metadata.Workload_id,
+						ContainerName:        container.Container_name,
+						CPURequestCurrent:    extractedRecommVals.CPURequestCurrent,
+						MemoryRequestCurrent: extractedRecommVals.MemoryRequestCurrent,
+						StoredVariationPcts:  extractedRecommVals.VariationPcts(),
+						MonitoringStartTime:  v.RecommendationTerms.Short_term.MonitoringStartTime,
+						MonitoringEndTime:    v.MonitoringEndTime,
+						Recommendations:      marshalData,
 					}
-					namespaceRecommendationSetList = append(namespaceRecommendationSetList, recommendationSet)
+					recommendationSetList = append(recommendationSetList, recommendationSet)
 
-					historicalRecommendationSet := model.HistoricalNamespaceRecommendationSet{
-						OrgID:               kafkaMsg.Metadata.Org_id,
+					// Create entry into HistoricalRecommendationSet table.
+					historicalRecommendationSet := model.HistoricalRecommendationSet{
+						OrgId:               kafkaMsg.Metadata.Org_id,
 						WorkloadID:          kafkaMsg.Metadata.Workload_id,
-						NamespaceName:       typedNamespaceRecommendation.Namespace,
+						ContainerName:       container.Container_name,
 						MonitoringStartTime: v.RecommendationTerms.Short_term.MonitoringStartTime,
 						MonitoringEndTime:   v.MonitoringEndTime,
 						Recommendations:     marshalData,
 					}
-					namespaceHistRecommendationSetList = append(namespaceHistRecommendationSetList, historicalRecommendationSet)
+					histRecommendationSetList = append(histRecommendationSetList, historicalRecommendationSet)
 				}
 			} else {
 				poll_cycle_complete = true
+				continue
+			}
+		}
+
+	}
+
+	if kafkaMsg.Metadata.ExperimentType == types.PayloadTypeNamespace && !cfg.DisableNamespaceRecommendation {
+		typedNamespaceObj, err := fetchRecommendationFromKruize(
+			experiment_name, maxEndTimeFromReport, types.PayloadTypeNamespace, kruizeClient.UpdateNamespaceRecommendations)
+		if err != nil {
+			return poll_cycle_complete, err
+		}
+		namespaceRecommendationRequest.Inc()
+
+		if len(typedNamespaceObj) == 0 || len(typedNamespaceObj[0].KubernetesObjects) == 0 {
+			log.Warnf("empty namespace recommendation response for experiment %s", experiment_name)
+			return poll_cycle_complete, nil
+		}
+
+		if typedNamespaceObj[0].ExperimentType != string(types.PayloadTypeNamespace) {
+			log.Errorf("experiment type mismatch: expected %s, got %s", types.PayloadTypeNamespace, typedNamespaceObj[0].ExperimentType)
+			return poll_cycle_complete, nil
+		}
+
+		typedNamespaceRecommendation := typedNamespaceObj[0].KubernetesObjects[0].Namespaces
+		if kruize.IsValidRecommendation(typedNamespaceRecommendation.Recommendations, experiment_name, maxEndTimeFromReport, types.PayloadTypeNamespace) {
+			for _, v := range typedNamespaceRecommendation.Recommendations.Data {
+				marshalData, err := json.Marshal(v)
+				if err != nil {
+					log.Errorf("unable to list recommendation for: %v", err)
+					continue
+				}
+
+				extractedNamespaceRecommVals := model.ExtractRecommendationColumnValues(v)
+
+				recommendationSet := model.NamespaceRecommendationSet{
+					OrgID:                kafkaMsg.Metadata.Org_id,
+					WorkloadID:           kafkaMsg.Metadata.Workload_id,
+					NamespaceName:        typedNamespaceRecommendation.Namespace,
+					CPURequestCurrent:    extractedNamespaceRecommVals.CPURequestCurrent,
+					MemoryRequestCurrent: extractedNamespaceRecommVals.MemoryRequestCurrent,
+					StoredVariationPcts:  extractedNamespaceRecommVals.VariationPcts(),
+					MonitoringStartTime:  v.RecommendationTerms.Short_term.MonitoringStartTime,
+					MonitoringEndTime:    v.MonitoringEndTime,
+					Recommendations:      marshalData,
+					UpdatedAt:            time.Now(),
+				}
+				namespaceRecommendationSetList = append(namespaceRecommendationSetList, recommendationSet)
+
+				historicalRecommendationSet := model.HistoricalNamespaceRecommendationSet{
+					OrgID:               kafkaMsg.Metadata.Org_id,
+					WorkloadID:          kafkaMsg.Metadata.Workload_id,
+					NamespaceName:       typedNamespaceRecommendation.Namespace,
+					MonitoringStartTime: v.RecommendationTerms.Short_term.MonitoringStartTime,
+					MonitoringEndTime:   v.MonitoringEndTime,
+					Recommendations:     marshalData,
+				}
+				namespaceHistRecommendationSetList = append(namespaceHistRecommendationSetList, historicalRecommendationSet)
 			}
+		} else {
+			poll_cycle_complete = true
 		}
 
 	}
@@ -293,6 +277,7 @@ func requestAndSaveRecommendation(kafkaMsg types.RecommendationKafkaMsg, recomme
 		if txError == nil {
 			poll_cycle_complete = true
 			recommendationSuccess.Inc()
+			afterRecommendationsSaved(kafkaMsg, recommendationType, savedContainerRecommendations(recommendationSetList))
 		} else {
 			poll_cycle_complete = false
 		}
@@ -308,15 +293,23 @@ func requestAndSaveRecommendation(kafkaMsg types.RecommendationKafkaMsg, recomme
 		if txError == nil {
 			poll_cycle_complete = true
 			namespaceRecommendationSuccess.Inc()
+			afterRecommendationsSaved(kafkaMsg, recommendationType, savedNamespaceRecommendations(namespaceRecommendationSetList))
 		} else {
 			poll_cycle_complete = false
 		}
 	}
 
-	return poll_cycle_complete
+	return poll_cycle_complete, nil
 }
 
-func PollForRecommendations(msg *kafka.Message, consumer_object *kafka.Consumer) {
+// pollCycleIncomplete is the retry reason when requestAndSaveRecommendation did not complete;
+// the cause is logged where it happened.
+const pollCycleIncomplete = "recommendation could not be fetched from Kruize or saved"
+
+// PollForRecommendations fetches and saves the recommendations requested by a message of the
+// processor. It reports the message not handled when Kruize is unavailable, so no retry attempt is
+// spent on it, and when its retry could not be stored: the consumer reads it again.
+func PollForRecommendations(msg *kafka.Message, consumer_object *kafka.Consumer) bool {
 	log := logging.GetLogger()
 	cfg := config.GetConfig()
 	validate := validator.New()
@@ -325,17 +318,17 @@ func PollForRecommendations(msg *kafka.Message, consumer_object *kafka.Consumer)
 	if !json.Valid([]byte(msg.Value)) {
 		log.Errorf("received message on kafka topic is not valid JSON (len=%d, partition=%s)", len(msg.Value), msg.TopicPartition)
 		commitKafkaMsg(msg, consumer_object)
-		return
+		return true
 	}
 	if err := json.Unmarshal(msg.Value, &kafkaMsg); err != nil {
 		log.Errorf("unable to decode kafka message (len=%d, partition=%s): %v", len(msg.Value), msg.TopicPartition, err)
 		commitKafkaMsg(msg, consumer_object)
-		return
+		return true
 	}
 	if err := validate.Struct(kafkaMsg); err != nil {
 		log.Errorf("invalid kafka message: %s", err)
 		commitKafkaMsg(msg, consumer_object)
-		return
+		return true
 	}
 	log = logging.Set_request_details_recommendations(kafkaMsg)
 
@@ -349,18 +342,18 @@ func PollForRecommendations(msg *kafka.Message, consumer_object *kafka.Consumer)
 		recommendation_stored_in_db, checkRecommExistsErr = model.GetFirstRecommendationSetsByWorkloadID(workloadID)
 		if checkRecommExistsErr != nil {
 			log.Errorf("error while checking for container recommendation_set record: %s", checkRecommExistsErr.Error())
-			return
+			return retryRecommendationRequest(msg, consumer_object, checkRecommExistsErr.Error())
 		}
 	} else if kafkaMsg.Metadata.ExperimentType == types.PayloadTypeNamespace && !cfg.DisableNamespaceRecommendation {
 		recommendation_stored_in_db, checkRecommExistsErr = model.GetFirstNamespaceRecommendationSetsByWorkloadID(workloadID)
 		if checkRecommExistsErr != nil {
 			log.Errorf("error while checking for namespace recommendation_set record: %s", checkRecommExistsErr.Error())
-			return
+			return retryRecommendationRequest(msg, consumer_object, checkRecommExistsErr.Error())
 		}
 	} else {
 		log.Errorf("unknown experiment type: %s", kafkaMsg.Metadata.ExperimentType)
 		commitKafkaMsg(msg, consumer_object)
-		return
+		return true
 	}
 
 	workloadExists := model.WorkloadExistsByID(workloadID)
@@ -370,11 +363,15 @@ func PollForRecommendations(msg *kafka.Message, consumer_object *kafka.Consumer)
 
 		switch recommendationFound {
 		case false:
-			poll_cycle_complete := requestAndSaveRecommendation(kafkaMsg, "New")
-			if poll_cycle_complete {
-				commitKafkaMsg(msg, consumer_object)
+			poll_cycle_complete, err := requestAndSaveRecommendation(kafkaMsg, "New")
+			if errors.Is(err, kruize.ErrUnavailable) {
+				return false
+			}
+			if !poll_cycle_complete {
+				return retryRecommendationRequest(msg, consumer_object, pollCycleIncomplete)
 			}
-			return
+			commitKafkaMsg(msg, consumer_object)
+			return true
 		case true:
 			// MonitoringEndTime.UTC() defaults to 0001-01-01 00:00:00 +0000 UTC if not set
 			var lastRecommRecordDate time.Time
@@ -392,10 +389,14 @@ func PollForRecommendations(msg *kafka.Message, consumer_object *kafka.Consumer)
 				duration := maxEndTimeFromReport.Sub(lastRecommRecordDate)
 
 				if int(duration.Hours()) >= cfg.RecommendationPollIntervalHours || utils.NeedRecommOnFirstOfMonth(lastRecommRecordDate, maxEndTimeFromReport) {
-					poll_cycle_complete := requestAndSaveRecommendation(kafkaMsg, "Update")
-					if poll_cycle_complete {
-						commitKafkaMsg(msg, consumer_object)
+					poll_cycle_complete, err := requestAndSaveRecommendation(kafkaMsg, "Update")
+					if errors.Is(err, kruize.ErrUnavailable) {
+						return false
 					}
+					if !poll_cycle_complete {
+						return retryRecommendationRequest(msg, consumer_object, pollCycleIncomplete)
+					}
+					commitKafkaMsg(msg, consumer_object)
 				} else {
 					commitKafkaMsg(msg, consumer_object)
 				}
@@ -403,10 +404,10 @@ func PollForRecommendations(msg *kafka.Message, consumer_object *kafka.Consumer)
 				commitKafkaMsg(msg, consumer_object)
 				log.Warn("monitoring_end_time is set to 0001-01-01 00:00:00 +0000; recommendationID: ", lastRecommRecordID)
 			}
-			return
+			return true
 		}
 	} else {
 		commitKafkaMsg(msg, consumer_object)
 	}
-
+	return true
 }
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/datatypes"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/db/dbtest"
+	"github.com/redhatinsights/ros-ocp-backend/internal/model"
+	"github.com/redhatinsights/ros-ocp-backend/internal/types"
+	"github.com/redhatinsights/ros-ocp-backend/internal/types/kruizePayload"
+	"github.com/redhatinsights/ros-ocp-backend/internal/utils"
+	"github.com/redhatinsights/ros-ocp-backend/internal/utils/kruize"
+	"github.com/redhatinsights/ros-ocp-backend/internal/utils/kruize/kruizetest"
+)
+
+func TestFetchRecommendationFromKruize(t *testing.T) {
+	kruizetest.Use(t, &kruizeClient)
+
+	df := kruizetest.AggregateSample(t, "../../scripts/samples/ros-ocp-usage.csv", types.PayloadTypeContainer)
+	group := df.GroupBy("namespace", "k8s_object_type", "k8s_object_name").GetGroups()["Yuptoo-prod_deployment_Yuptoo-service"]
+	rows := group.Maps()
+	maxEndTime, err := utils.MaxIntervalEndTime(group.Col("interval_end").Records())
+	if !assert.NoError(t, err) {
+		return
+	}
+	name := "3340851|source|cluster|Yuptoo-prod|deployment|Yuptoo-service"
+	_, err = kruizeClient.CreateExperiment(t.Context(), name, "3340851;cluster", rows)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = fetchRecommendationFromKruize(name, maxEndTime, types.PayloadTypeContainer, kruizeClient.UpdateRecommendations)
+	assert.ErrorContains(t, err, "does not exist", "Kruize has no results up to the report yet")
+
+	_, err = kruizeClient.UpdateResults(t.Context(), name, kruizePayload.GetUpdateResultPayload(name, rows))
+	if !assert.NoError(t, err) {
+		return
+	}
+	recommendations, err := fetchRecommendationFromKruize(name, maxEndTime, types.PayloadTypeContainer, kruizeClient.UpdateRecommendations)
+	if assert.NoError(t, err) && assert.Len(t, recommendations, 1) {
+		containers := recommendations[0].Kubernetes_objects[0].Containers
+		assert.NotEmpty(t, containers)
+		assert.True(t, kruize.IsValidRecommendation(containers[0].Recommendations, name, maxEndTime, types.PayloadTypeContainer))
+	}
+
+	_, err = fetchRecommendationFromKruize(name, maxEndTime.Add(time.Hour), types.PayloadTypeContainer, kruizeClient.UpdateRecommendations)
+	assert.Error(t, err)
+}
+
+func TestTransactionForContainerRecommendation_KeepsAppliedStatus(t *testing.T) {
+	db := dbtest.Use(t)
+	cluster := model.Cluster{RHAccount: model.RHAccount{OrgId: "org"}, SourceId: "source", ClusterUUID: "cluster", ClusterAlias: "cluster"}
+	assert.NoError(t, db.Create(&cluster).Error)
+	workload := model.Workload{OrgId: "org", ClusterID: cluster.ID, ExperimentName: "experiment", Containers: []string{"app"}}
+	assert.NoError(t, db.Create(&workload).Error)
+
+	end := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	recommendationSet := model.RecommendationSet{
+		WorkloadID:          workload.ID,
+		ContainerName:       "app",
+		MonitoringStartTime: end.Add(-24 * time.Hour),
+		MonitoringEndTime:   end,
+		Recommendations:     datatypes.JSON(appliedTestRecommendation),
+		UpdatedAt:           end,
+	}
+	if !assert.NoError(t, transactionForContainerRecommendation([]model.RecommendationSet{recommendationSet}, nil, "experiment", "container")) {
+		return
+	}
+
+	// the upload applying the cost recommendation is processed, then its recommendation polled
+	rows := []map[string]interface{}{
+		{"container_name": "app", "interval_end": "2024-03-02 10:00:00 +0000 UTC", "cpu_request_container_avg_MEAN": 0.5, "memory_request_container_avg_MEAN": 500000000.0},
+	}
+	detectAppliedRecommendations(workload.ID, rows, "org", "cluster")
+	var saved model.RecommendationSet
+	assert.NoError(t, db.Where("workload_id = ?", workload.ID).First(&saved).Error)
+	assert.Equal(t, model.RecommendationApplied, saved.AppliedStatus)
+	if !assert.NotNil(t, saved.AppliedAt) {
+		return
+	}
+
+	recommendationSet.MonitoringEndTime = end.Add(24 * time.Hour)
+	recommendationSet.UpdatedAt = time.Now().UTC()
+	if !assert.NoError(t, transactionForContainerRecommendation([]model.RecommendationSet{recommendationSet}, nil, "experiment", "container")) {
+		return
+	}
+	var updated model.RecommendationSet
+	assert.NoError(t, db.Where("workload_id = ?", workload.ID).First(&updated).Error)
+	assert.Equal(t, saved.ID, updated.ID)
+	assert.Equal(t, end.Add(24*time.Hour), updated.MonitoringEndTime.UTC())
+	assert.Equal(t, updated.MonitoringEndTime.Format(time.RFC3339), updated.MonitoringEndTimeStr, "set by AfterFind")
+	assert.Equal(t, model.RecommendationApplied, updated.AppliedStatus)
+	if assert.NotNil(t, updated.AppliedAt) {
+		assert.True(t, saved.AppliedAt.Equal(*updated.AppliedAt))
+	}
+}
+
+func TestPollForRecommendations_KruizeUnavailable_IsNotRetried(t *testing.T) {
+	db := dbtest.Use(t)
+	server := kruizetest.Use(t, &kruizeClient)
+	produced := captureProduced(t)
+	captureUploadStatuses(t)
+	if !assert.True(t, ProcessReport(sampleUpload(t), nil)) || !assert.NotEmpty(t, *produced) {
+		return
+	}
+	request := (*produced)[0]
+
+	server.Fail(kruize.KruizeUpdateRecommendations, http.StatusServiceUnavailable)
+	msg := &kafka.Message{Value: request.value, Key: []byte(request.key)}
+	assert.False(t, PollForRecommendations(msg, nil), "the request is read again once Kruize is back")
+	var retries int64
+	assert.NoError(t, db.Model(&model.RecommendationRetry{}).Count(&retries).Error)
+	assert.Zero(t, retries, "no retry attempt is spent")
+
+	server.Recover()
+	assert.True(t, PollForRecommendations(msg, nil))
+	var recommendations int64
+	assert.NoError(t, db.Model(&model.RecommendationSet{}).Count(&recommendations).Error)
+	assert.Positive(t, recommendations)
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+
+	kafka_internal "github.com/redhatinsights/ros-ocp-backend/internal/kafka"
+	"github.com/redhatinsights/ros-ocp-backend/internal/logging"
+	"github.com/redhatinsights/ros-ocp-backend/internal/model"
+)
+
+const (
+	// retryReleaseInterval is how often the poller looks for retries that are due.
+	retryReleaseInterval = 10 * time.Second
+	// retryReleaseBatch is how many due retries are republished per transaction.
+	retryReleaseBatch = 100
+)
+
+// retryBackoff returns how long a recommendation request waits after its attempt-th failure:
+// RECOMMENDATION_RETRY_BACKOFF_SECS doubled for every earlier failure, capped at
+// RECOMMENDATION_RETRY_MAX_BACKOFF_SECS.
+func retryBackoff(attempt int) time.Duration {
+	backoff := time.Duration(cfg.RecommendationRetryBackoffSecs) * time.Second
+	maxBackoff := time.Duration(cfg.RecommendationRetryMaxBackoffSecs) * time.Second
+	for i := 1; i < attempt && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxBackoff)
+}
+
+func retryHeaders(attempt int) []kafka.Header {
+	return []kafka.Header{{Key: kafka_internal.HeaderAttempt, Value: []byte(strconv.Itoa(attempt))}}
+}
+
+// retryRecommendationRequest stores a request that could not be completed for another attempt
+// after a backoff, and commits the original so the partition moves on. The request waits in the
+// database rather than on the topic, where it would hold back the requests behind it, and is
+// republished by ReleaseRecommendationRetries once due. Requests out of attempts are parked in
+// RECOMMENDATION_DLQ_TOPIC instead. It reports whether the request was stored or parked: if not,
+// the message is not handled and the consumer reads it again.
+func retryRecommendationRequest(msg *kafka.Message, consumer *kafka.Consumer, reason string) bool {
+	log := logging.GetLogger()
+	attempt := kafka_internal.Attempt(msg)
+
+	if attempt >= cfg.RecommendationMaxAttempts {
+		log.Errorf("recommendation request failed %d times, parking it (partition=%s): %s", attempt, msg.TopicPartition, reason)
+		if cfg.RecommendationDLQTopic != "" {
+			if err := produceDeadLetter(cfg.RecommendationDLQTopic, msg, msg.Value, dlqStagePoll, reason); err != nil {
+				return false
+			}
+		}
+		recommendationRetry.WithLabelValues("parked").Inc()
+		commitKafkaMsg(msg, consumer)
+		return true
+	}
+
+	retry := model.RecommendationRetry{
+		MessageKey: string(msg.Key),
+		Payload:    msg.Value,
+		Attempt:    attempt + 1,
+		DueAt:      time.Now().Add(retryBackoff(attempt)).UTC(),
+		Reason:     reason,
+	}
+	if err := retry.CreateRecommendationRetry(); err != nil {
+		log.Errorf("unable to schedule retry of recommendation request (partition=%s): %v", msg.TopicPartition, err)
+		return false
+	}
+	log.Infof("recommendation request failed (attempt %d/%d), retrying after %s: %s", attempt, cfg.RecommendationMaxAttempts, retry.DueAt.Format(time.RFC3339), reason)
+	recommendationRetry.WithLabelValues("scheduled").Inc()
+	commitKafkaMsg(msg, consumer)
+	return true
+}
+
+// releaseDueRetries republishes the retries due at now to the recommendation topic. Retries that
+// could not be published are kept for the next run.
+func releaseDueRetries(now time.Time) {
+	log := logging.GetLogger()
+	for {
+		released, err := model.ReleaseDueRecommendationRetries(now, retryReleaseBatch, func(retry model.RecommendationRetry) error {
+			err := produceWithHeaders(retry.Payload, cfg.RecommendationTopic, retry.MessageKey, retryHeaders(retry.Attempt))
+			if err != nil {
+				log.Errorf("unable to republish recommendation retry %d: %v", retry.ID, err)
+			}
+			return err
+		})
+		if err != nil {
+			log.Errorf("unable to release recommendation retries: %v", err)
+			return
+		}
+		recommendationRetry.WithLabelValues("released").Add(float64(released))
+		if released < retryReleaseBatch {
+			return
+		}
+	}
+}
+
+// ReleaseRecommendationRetries republishes recommendation requests once their retry is due,
+// until ctx is done.
+func ReleaseRecommendationRetries(ctx context.Context) {
+	ticker := time.NewTicker(retryReleaseInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			releaseDueRetries(time.Now())
+		}
+	}
+}
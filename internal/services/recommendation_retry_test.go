@@ -0,0 +1,146 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/db/dbtest"
+	kafka_internal "github.com/redhatinsights/ros-ocp-backend/internal/kafka"
+	"github.com/redhatinsights/ros-ocp-backend/internal/model"
+)
+
+func setRetryConfig(t *testing.T, maxAttempts int, backoffSecs int, maxBackoffSecs int) {
+	t.Helper()
+	original := *cfg
+	cfg.RecommendationMaxAttempts = maxAttempts
+	cfg.RecommendationRetryBackoffSecs = backoffSecs
+	cfg.RecommendationRetryMaxBackoffSecs = maxBackoffSecs
+	t.Cleanup(func() { *cfg = original })
+}
+
+func TestRetryBackoff(t *testing.T) {
+	setRetryConfig(t, 5, 60, 300)
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{4, 5 * time.Minute},
+		{40, 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, retryBackoff(tt.attempt), "attempt %d", tt.attempt)
+	}
+}
+
+func TestRetryRecommendationRequestSchedulesRetry(t *testing.T) {
+	db := dbtest.Use(t)
+	setRetryConfig(t, 3, 60, 3600)
+	produced := captureProduced(t)
+	msg := &kafka.Message{
+		Key:     []byte("experiment"),
+		Value:   []byte(`{"request_id":"req-1"}`),
+		Headers: retryHeaders(2),
+	}
+
+	before := time.Now()
+	assert.True(t, retryRecommendationRequest(msg, nil, "kruize down"))
+
+	assert.Empty(t, *produced, "the retry waits in the database")
+	var retries []model.RecommendationRetry
+	assert.NoError(t, db.Find(&retries).Error)
+	if assert.Len(t, retries, 1) {
+		assert.Equal(t, "experiment", retries[0].MessageKey)
+		assert.Equal(t, msg.Value, retries[0].Payload)
+		assert.Equal(t, 3, retries[0].Attempt)
+		assert.Equal(t, "kruize down", retries[0].Reason)
+		assert.WithinDuration(t, before.Add(2*time.Minute), retries[0].DueAt, 2*time.Second)
+	}
+}
+
+func TestReleaseDueRetries(t *testing.T) {
+	db := dbtest.Use(t)
+	produced := captureProduced(t)
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	due := model.RecommendationRetry{MessageKey: "due", Payload: []byte(`{"request_id":"req-1"}`), Attempt: 2, DueAt: now.Add(-time.Second)}
+	waiting := model.RecommendationRetry{MessageKey: "waiting", Payload: []byte(`{"request_id":"req-2"}`), Attempt: 4, DueAt: now.Add(time.Minute)}
+	assert.NoError(t, due.CreateRecommendationRetry())
+	assert.NoError(t, waiting.CreateRecommendationRetry())
+
+	releaseDueRetries(now)
+
+	if assert.Len(t, *produced, 1) {
+		released := (*produced)[0]
+		assert.Equal(t, cfg.RecommendationTopic, released.topic)
+		assert.Equal(t, "due", released.key)
+		assert.Equal(t, due.Payload, released.value)
+		assert.Equal(t, "2", header(released.headers, kafka_internal.HeaderAttempt))
+	}
+	var left []model.RecommendationRetry
+	assert.NoError(t, db.Find(&left).Error)
+	if assert.Len(t, left, 1) {
+		assert.Equal(t, "waiting", left[0].MessageKey)
+	}
+}
+
+func TestReleaseDueRetries_KeepsUnpublishedRetries(t *testing.T) {
+	db := dbtest.Use(t)
+	original := produceWithHeaders
+	produceWithHeaders = func([]byte, string, string, []kafka.Header) error { return errors.New("broker down") }
+	t.Cleanup(func() { produceWithHeaders = original })
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	retry := model.RecommendationRetry{MessageKey: "due", Payload: []byte(`{}`), Attempt: 2, DueAt: now}
+	assert.NoError(t, retry.CreateRecommendationRetry())
+
+	releaseDueRetries(now)
+
+	var count int64
+	assert.NoError(t, db.Model(&model.RecommendationRetry{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestRetryRecommendationRequestParksExhaustedRequest(t *testing.T) {
+	setRetryConfig(t, 3, 60, 3600)
+	produced := captureProduced(t)
+	msg := &kafka.Message{
+		Key:     []byte("experiment"),
+		Value:   []byte(`{"request_id":"req-1"}`),
+		Headers: retryHeaders(3),
+	}
+
+	assert.True(t, retryRecommendationRequest(msg, nil, "kruize down"))
+
+	if assert.Len(t, *produced, 1) {
+		parked := (*produced)[0]
+		assert.Equal(t, cfg.RecommendationDLQTopic, parked.topic)
+		assert.Equal(t, "3", header(parked.headers, kafka_internal.HeaderAttempt))
+		assert.Equal(t, dlqStagePoll, header(parked.headers, kafka_internal.HeaderDLQStage))
+		assert.Equal(t, "kruize down", header(parked.headers, kafka_internal.HeaderDLQReason))
+	}
+}
+
+func TestRetryRecommendationRequest_NotHandledWhenRetryIsNotStored(t *testing.T) {
+	db := dbtest.Use(t)
+	setRetryConfig(t, 3, 60, 3600)
+	assert.NoError(t, db.Exec("DROP TABLE recommendation_retries").Error)
+	msg := &kafka.Message{Key: []byte("experiment"), Value: []byte(`{"request_id":"req-1"}`)}
+
+	assert.False(t, retryRecommendationRequest(msg, nil, "kruize down"), "the consumer reads the request again")
+}
+
+func TestRetryRecommendationRequest_NotHandledWhenRequestIsNotParked(t *testing.T) {
+	setRetryConfig(t, 3, 60, 3600)
+	cfg.RecommendationDLQTopic = "rosocp.kruize.recommendations.dlq"
+	original := produceWithHeaders
+	produceWithHeaders = func([]byte, string, string, []kafka.Header) error { return errors.New("broker down") }
+	t.Cleanup(func() { produceWithHeaders = original })
+	msg := &kafka.Message{Key: []byte("experiment"), Value: []byte(`{"request_id":"req-1"}`), Headers: retryHeaders(3)}
+
+	assert.False(t, retryRecommendationRequest(msg, nil, "kruize down"), "the consumer reads the request again")
+}
@@ -1,13 +1,19 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 	"github.com/go-gota/gota/dataframe"
 	"github.com/go-playground/validator/v10"
+	"github.com/sirupsen/logrus"
 
 	"github.com/redhatinsights/ros-ocp-backend/internal/config"
 	"github.com/redhatinsights/ros-ocp-backend/internal/featureflags"
@@ -24,9 +30,24 @@ import (
 
 var cfg *config.Config = config.GetConfig()
 
-func ProcessReport(msg *kafka.Message, consumer *kafka.Consumer) {
+// kruizeBreaker stops the processor and the poller from calling Kruize while it is unavailable.
+var kruizeBreaker = kruize.NewDefaultBreaker()
+
+// kruizeClient is the Kruize API of the processor and the poller.
+var kruizeClient kruize.KruizeClient = kruizeBreaker
+
+// KruizeBackpressure holds the processor and poller consumers back while the Kruize circuit
+// breaker is open, so that uploads wait for Kruize instead of failing.
+func KruizeBackpressure() kafka_internal.Backpressure {
+	return kruizeBreaker
+}
+
+// ProcessReport sends the workloads of an upload message to Kruize and requests their
+// recommendations. It reports the message not handled when Kruize was unavailable for some
+// workload: nothing is dead-lettered then, and the consumer reads the upload again once Kruize is
+// back. Handling it again only retries what failed, see uploadProgress.
+func ProcessReport(msg *kafka.Message, consumer *kafka.Consumer) bool {
 	log := logging.GetLogger()
-	cfg = config.GetConfig()
 	validate := validator.New()
 
 	commitOnPermanentFailure := func(reason string) {
@@ -41,343 +62,659 @@ func ProcessReport(msg *kafka.Message, consumer *kafka.Consumer) {
 	var kafkaMsg types.KafkaMsg
 	if !json.Valid([]byte(msg.Value)) {
 		log.Errorf("Received message on kafka topic is not valid JSON (len=%d, partition=%s)", len(msg.Value), msg.TopicPartition)
+		sendDeadLetter(msg, msg.Value, dlqStageDecode, "invalid JSON")
 		commitOnPermanentFailure("invalid JSON")
-		return
+		return true
 	}
 	if err := json.Unmarshal(msg.Value, &kafkaMsg); err != nil {
 		log.Errorf("Unable to decode kafka message (len=%d, partition=%s): %v", len(msg.Value), msg.TopicPartition, err)
+		sendDeadLetter(msg, msg.Value, dlqStageDecode, err.Error())
 		commitOnPermanentFailure("unmarshal failed")
-		return
+		return true
 	}
 	if err := validate.Struct(kafkaMsg); err != nil {
 		log.Errorf("Invalid kafka message: %s", err)
+		sendDeadLetter(msg, msg.Value, dlqStageValidate, err.Error())
 		commitOnPermanentFailure("validation failed")
-		return
+		return true
 	}
 
 	log = logging.Set_request_details(kafkaMsg)
 
-	var csvType types.PayloadType
-
-	var rhAccount model.RHAccount
-	var rhAccountCreated bool
-
-	var cluster model.Cluster
-	var clusterCreated bool
+	failed := newDeadLetters(msg)
+	progress := heldUploadProgress(msg)
 
+	upload := &reportUpload{kafkaMsg: kafkaMsg, log: log, failed: failed, progress: progress}
 	for _, file := range kafkaMsg.Files {
-		csvType = utils.DetermineCSVType(file)
-		if strings.Contains(file, "namespace") {
-			if !featureflags.IsNamespaceEnabled(kafkaMsg.Metadata.Org_id) {
-				continue
-			}
-		}
-		data, fetchError := utils.ReadCSVFromUrl(file)
-		if fetchError != nil {
-			csvFetchError.Inc()
-			log.Errorf("unable to read CSV from URL: %s", fetchError.Error())
+		if progress.fileDone(file) {
+			log.Infof("skipping %s, processed before Kruize became unavailable", file)
 			continue
 		}
-		columnHeaders := types.GetColumnMapping(csvType)
-		df := dataframe.LoadRecords(
-			data,
-			dataframe.WithTypes(columnHeaders),
-		)
-		df, parseError := utils.Aggregate_data(csvType, df)
-		if parseError != nil {
-			log.Errorf("unable to process %s; error: %s ", file, parseError.Error())
-			switch csvType {
-			case types.PayloadTypeNamespace:
-				invalidNamespaceCSV.Inc()
-			case types.PayloadTypeContainer:
-				invalidCSV.Inc()
-			}
-			continue
+		upload.processFile(file)
+		if !failed.fileHasFailed(file) {
+			progress.markFileDone(file)
 		}
+	}
 
-		if !rhAccountCreated {
-			rhAccount = model.RHAccount{
-				Account: kafkaMsg.Metadata.Account,
-				OrgId:   kafkaMsg.Metadata.Org_id,
-			}
-			if err := rhAccount.CreateRHAccount(); err != nil {
-				log.Errorf("unable to get or add record to rh_accounts table: %v. Error: %v", rhAccount, err)
-				continue
-			}
-			rhAccountCreated = true
-		}
+	if failed.kruizeUnavailable {
+		log.Warnf("Kruize is unavailable, the upload will be processed again (partition=%s)", msg.TopicPartition)
+		return false
+	}
+	forgetHeldUpload(msg)
+	failed.publish()
+	return true
+}
 
-		if !clusterCreated {
-			cluster = model.Cluster{
-				TenantID:       rhAccount.ID,
-				SourceId:       kafkaMsg.Metadata.Source_id,
-				ClusterUUID:    kafkaMsg.Metadata.Cluster_uuid,
-				ClusterAlias:   kafkaMsg.Metadata.Cluster_alias,
-				LastReportedAt: time.Now(),
-			}
-			if err := cluster.CreateCluster(); err != nil {
-				log.Errorf("unable to get or add record to clusters table: %v. Error: %v", cluster, err)
-				continue
-			}
-			clusterCreated = true
+// processFile processes the upload file, a CSV or a payload archive of CSVs.
+func (u *reportUpload) processFile(file string) {
+	kafkaMsg := u.kafkaMsg
+	if !utils.IsArchive(file) {
+		u.processCSV(file, file, func() (io.ReadCloser, error) {
+			return utils.OpenCSVFromUrl(file, kafkaMsg.Metadata.Checksums[file])
+		})
+		return
+	}
+
+	archive, err := utils.OpenArchiveFromUrl(file, kafkaMsg.Metadata.Checksums[file])
+	if err != nil {
+		csvFetchError.Inc()
+		u.log.Errorf("unable to read archive from URL: %s", err.Error())
+		u.failed.fileFailed(file, dlqStageFetch, err)
+		newUploadStatus(kafkaMsg, file, u.log).failed(dlqStageFetch, err)
+		return
+	}
+	for _, name := range archive.Manifest.ResourceOptimizationFiles {
+		u.processCSV(file, name, func() (io.ReadCloser, error) {
+			return archive.Open(name)
+		})
+	}
+	if err := archive.Close(); err != nil {
+		u.log.Warnf("unable to remove downloaded archive %s: %v", file, err)
+	}
+}
+
+// reportUpload holds what the CSVs of an upload share: the rh_account and cluster records are
+// created with the first CSV that parses.
+type reportUpload struct {
+	kafkaMsg         types.KafkaMsg
+	log              *logrus.Entry
+	failed           *deadLetters
+	progress         *uploadProgress
+	rhAccount        model.RHAccount
+	rhAccountCreated bool
+	cluster          model.Cluster
+	clusterCreated   bool
+}
+
+// processCSV aggregates the CSV called name and sends its workloads to Kruize. source is the
+// upload file the CSV came from, either the CSV itself or its payload archive, and is what a
+// replayed dead letter fetches again.
+func (u *reportUpload) processCSV(source string, name string, open func() (io.ReadCloser, error)) {
+	kafkaMsg := u.kafkaMsg
+	log := u.log
+	csvType := utils.DetermineCSVType(name)
+	if strings.Contains(name, "namespace") {
+		if !featureflags.IsNamespaceEnabled(kafkaMsg.Metadata.Org_id) {
+			return
 		}
+	}
+	status := newUploadStatus(kafkaMsg, name, log)
+	fileFailed := func(stage string, err error) {
+		u.failed.fileFailed(source, stage, err)
+		status.failed(stage, err)
+	}
 
+	body, fetchError := open()
+	if fetchError != nil {
+		csvFetchError.Inc()
+		log.Errorf("unable to read CSV from URL: %s", fetchError.Error())
+		fileFailed(dlqStageFetch, fetchError)
+		return
+	}
+	status.fetched()
+	df, parseError := utils.StreamAggregate(csvType, body)
+	_ = body.Close()
+	if parseError != nil {
+		log.Errorf("unable to process %s; error: %s ", name, parseError.Error())
 		switch csvType {
+		case types.PayloadTypeNamespace:
+			invalidNamespaceCSV.Inc()
 		case types.PayloadTypeContainer:
-			// grouping container(row in csv) by deployment.
-			k8s_object_groups := df.GroupBy("namespace", "k8s_object_type", "k8s_object_name").GetGroups()
+			invalidCSV.Inc()
+		}
+		fileFailed(dlqStageParse, parseError)
+		return
+	}
+	status.aggregated()
 
-			for _, v := range k8s_object_groups {
+	if !u.rhAccountCreated {
+		u.rhAccount = model.RHAccount{
+			Account: kafkaMsg.Metadata.Account,
+			OrgId:   kafkaMsg.Metadata.Org_id,
+		}
+		if err := u.rhAccount.CreateRHAccount(); err != nil {
+			log.Errorf("unable to get or add record to rh_accounts table: %v. Error: %v", u.rhAccount, err)
+			fileFailed(dlqStageDatabase, err)
+			return
+		}
+		u.rhAccountCreated = true
+	}
 
-				all_interval_end_time := v.Col("interval_end").Records()
-				maxEndTime, err := utils.MaxIntervalEndTime(all_interval_end_time)
-				if err != nil {
-					log.Errorf("unable to convert string to time: %s", err)
-					continue
-				}
+	if !u.clusterCreated {
+		u.cluster = model.Cluster{
+			TenantID:       u.rhAccount.ID,
+			SourceId:       kafkaMsg.Metadata.Source_id,
+			ClusterUUID:    kafkaMsg.Metadata.Cluster_uuid,
+			ClusterAlias:   kafkaMsg.Metadata.Cluster_alias,
+			LastReportedAt: time.Now(),
+		}
+		if err := u.cluster.CreateCluster(); err != nil {
+			log.Errorf("unable to get or add record to clusters table: %v. Error: %v", u.cluster, err)
+			fileFailed(dlqStageDatabase, err)
+			return
+		}
+		u.clusterCreated = true
+	}
 
-				k8s_object := v.Maps()
-				namespace := kruizePayload.AssertAndConvertToString(k8s_object[0]["namespace"])
-				k8s_object_type := k8s_object[0]["k8s_object_type"].(string)
-				k8s_object_name := k8s_object[0]["k8s_object_name"].(string)
-
-				experiment_name := utils.GenerateExperimentName(
-					kafkaMsg.Metadata.Org_id,
-					kafkaMsg.Metadata.Source_id,
-					kafkaMsg.Metadata.Cluster_uuid,
-					namespace,
-					k8s_object_type,
-					k8s_object_name,
-				)
-
-				cluster_identifier := kafkaMsg.Metadata.Org_id + ";" + kafkaMsg.Metadata.Cluster_uuid
-				container_names, err := kruize.Create_kruize_experiments(experiment_name, cluster_identifier, k8s_object)
-				if err != nil {
-					log.Error(err)
-					continue
-				}
+	upload := uploadContext{kafkaMsg: kafkaMsg, csvName: name, progress: u.progress, rhAccount: u.rhAccount, cluster: u.cluster, log: log}
+	var groups map[string]dataframe.DataFrame
+	var processGroup func(upload uploadContext, group dataframe.DataFrame) groupResult
+	switch csvType {
+	case types.PayloadTypeContainer:
+		// grouping container(row in csv) by deployment.
+		groups = df.GroupBy("namespace", "k8s_object_type", "k8s_object_name").GetGroups()
+		processGroup = processContainerGroup
+	case types.PayloadTypeNamespace:
+		groups = df.GroupBy("namespace").GetGroups()
+		processGroup = processNamespaceGroup
+	default:
+		return
+	}
 
-				// Create workload entry into the table.
-				workload := model.Workload{
-					OrgId:           rhAccount.OrgId,
-					ClusterID:       cluster.ID,
-					ExperimentName:  experiment_name,
-					Namespace:       namespace,
-					WorkloadType:    w.WorkloadType(k8s_object_type),
-					WorkloadName:    k8s_object_name,
-					Containers:      container_names,
-					MetricsUploadAt: maxEndTime,
-				}
-				if err := workload.CreateWorkload(); err != nil {
-					log.Errorf("unable to save workload record: %v. Error: %v", workload, err)
-					continue
-				}
+	start := time.Now()
+	summary := processGroups(groups, func(group dataframe.DataFrame) groupResult {
+		return processGroup(upload, group)
+	})
+	for _, failure := range summary.failures {
+		u.failed.fileFailed(source, failure.stage, failure.err)
+	}
+	if summary.kruizeUnavailable {
+		u.failed.markKruizeUnavailable()
+	}
+	status.processed(summary)
+	workloadGroups.WithLabelValues(string(csvType), "succeeded").Add(float64(len(groups) - len(summary.failures)))
+	workloadGroups.WithLabelValues(string(csvType), "failed").Add(float64(len(summary.failures)))
+	log.Infof("processed %d %s workload groups of %s in %s, %d failed", len(groups), csvType, name, time.Since(start).Round(time.Millisecond), len(summary.failures))
+}
 
-				var k8s_object_chunks [][]kruizePayload.UpdateResult
-				update_result_payload_data := kruizePayload.GetUpdateResultPayload(experiment_name, k8s_object)
-				if len(update_result_payload_data) > cfg.KruizeMaxBulkChunkSize {
-					k8s_object_chunks = SliceMetricsUpdatePayloadToChunks(update_result_payload_data)
-				} else {
-					k8s_object_chunks = append(k8s_object_chunks, update_result_payload_data)
-				}
+// uploadContext is what the workload groups of an upload CSV share.
+type uploadContext struct {
+	kafkaMsg  types.KafkaMsg
+	csvName   string
+	progress  *uploadProgress
+	rhAccount model.RHAccount
+	cluster   model.Cluster
+	log       *logrus.Entry
+}
 
-				for _, chunk := range k8s_object_chunks {
-					usage_data_byte, err := kruize.Update_results(experiment_name, chunk)
-					if err != nil {
-						log.Error(err, experiment_name)
-						continue
-					}
-
-					workload_metric_arr := []model.WorkloadMetrics{}
-					for _, data := range usage_data_byte {
-
-						interval_start_time, err := utils.ConvertISO8601StringToTime(data.Interval_start_time)
-						if err != nil {
-							log.Errorf("Error for start time: %s", err)
-							continue
-						}
-						interval_end_time, err := utils.ConvertISO8601StringToTime(data.Interval_end_time)
-						if err != nil {
-							log.Errorf("Error for end time: %s", err)
-							continue
-						}
-
-						for _, container := range data.Kubernetes_objects[0].Containers {
-							container_usage_metrics, err := json.Marshal(container.Metrics)
-							if err != nil {
-								log.Errorf("Unable to marshal container usage data: %v", err.Error())
-								continue
-							}
-
-							workload_metric := model.WorkloadMetrics{
-								OrgId:         rhAccount.OrgId,
-								WorkloadID:    workload.ID,
-								ContainerName: container.Container_name,
-								IntervalStart: interval_start_time,
-								IntervalEnd:   interval_end_time,
-								UsageMetrics:  container_usage_metrics,
-							}
-							workload_metric_arr = append(workload_metric_arr, workload_metric)
-						}
-
-					}
-					if err := model.BatchInsertWorkloadMetrics(workload_metric_arr, rhAccount.OrgId); err != nil {
-						log.Errorf("unable to batch insert to workload_metrics table. %v", err.Error())
-						continue
-					}
-				}
+// heldUploadTTL is how long the progress of an upload held back for Kruize is kept. The upload is
+// usually handled again by the same processor, unless its partition moved to another one.
+const heldUploadTTL = 24 * time.Hour
+
+// uploadProgress is what was done of an upload that is handled again because Kruize was
+// unavailable. The files whose workloads were all sent are not fetched again, their presigned URLs
+// may have expired meanwhile, and the workload groups whose recommendation was requested are not
+// sent to Kruize nor requested again. The other groups are sent again from the start: creating an
+// experiment that exists, sending results Kruize already has and saving metrics already stored
+// are all harmless.
+type uploadProgress struct {
+	mu     sync.Mutex
+	seenAt time.Time
+	files  map[string]bool
+	groups map[uploadGroup]bool
+}
 
-				// sending kafka msg to poller for recommendation request
-				maxEndtimeFromReport := maxEndTime.UTC()
-				messageData := types.RecommendationKafkaMsg{
-					Request_id: kafkaMsg.Request_id,
-					Metadata: types.RecommendationMetadata{
-						Org_id:             kafkaMsg.Metadata.Org_id,
-						Workload_id:        workload.ID,
-						Max_endtime_report: maxEndtimeFromReport,
-						Experiment_name:    experiment_name,
-						ExperimentType:     types.PayloadTypeContainer,
-					},
-				}
+type uploadGroup struct {
+	csvName        string
+	experimentName string
+}
 
-				msgBytes, err := json.Marshal(messageData)
-				if err != nil {
-					log.Error("Error marshaling JSON:", err)
-					continue
-				}
+func newUploadProgress() *uploadProgress {
+	return &uploadProgress{files: make(map[string]bool), groups: make(map[uploadGroup]bool)}
+}
 
-				msgProduceErr := kafka_internal.SendMessage(msgBytes, cfg.RecommendationTopic, experiment_name)
-				if msgProduceErr != nil {
-					log.Errorf("Failed to produce message: %v for experiment - %s and end_interval - %s\n", msgProduceErr.Error(), experiment_name, maxEndtimeFromReport)
-				} else {
-					log.Infof("Recommendation request sent for experiment - %s and end_interval - %s", experiment_name, maxEndtimeFromReport)
-				}
+// heldUploads is the progress of the uploads held back, by message position.
+var heldUploads = struct {
+	sync.Mutex
+	progress map[string]*uploadProgress
+}{progress: make(map[string]*uploadProgress)}
 
-			}
+func heldUploadKey(msg *kafka.Message) string {
+	topic := ""
+	if msg.TopicPartition.Topic != nil {
+		topic = *msg.TopicPartition.Topic
+	}
+	return fmt.Sprintf("%s[%d]@%d", topic, msg.TopicPartition.Partition, msg.TopicPartition.Offset)
+}
 
-		case types.PayloadTypeNamespace:
-			namespaceGroupMap := df.GroupBy("namespace").GetGroups()
-			for _, v := range namespaceGroupMap {
+// heldUploadProgress returns the progress of the upload msg, empty unless it was held back before.
+func heldUploadProgress(msg *kafka.Message) *uploadProgress {
+	heldUploads.Lock()
+	defer heldUploads.Unlock()
+	now := time.Now()
+	for key, progress := range heldUploads.progress {
+		if now.Sub(progress.seenAt) > heldUploadTTL {
+			delete(heldUploads.progress, key)
+		}
+	}
+	key := heldUploadKey(msg)
+	progress, ok := heldUploads.progress[key]
+	if !ok {
+		progress = newUploadProgress()
+		heldUploads.progress[key] = progress
+	}
+	progress.seenAt = now
+	return progress
+}
 
-				intervalEndTimeValues := v.Col("interval_end").Records()
-				maxEndTime, err := utils.MaxIntervalEndTime(intervalEndTimeValues)
-				if err != nil {
-					log.Errorf("unable to convert string to time: %s", err)
-					continue
-				}
+// forgetHeldUpload drops the progress of the upload msg once it is handled.
+func forgetHeldUpload(msg *kafka.Message) {
+	heldUploads.Lock()
+	defer heldUploads.Unlock()
+	delete(heldUploads.progress, heldUploadKey(msg))
+}
 
-				namespaceRows := v.Maps()
-				namespaceName := kruizePayload.AssertAndConvertToString(namespaceRows[0]["namespace"])
+func (p *uploadProgress) fileDone(file string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.files[file]
+}
 
-				experimentName := utils.GenerateNamespaceExperimentName(
-					kafkaMsg.Metadata.Org_id,
-					kafkaMsg.Metadata.Source_id,
-					kafkaMsg.Metadata.Cluster_uuid,
-					namespaceName,
-				)
+func (p *uploadProgress) markFileDone(file string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.files[file] = true
+}
 
-				clusterIdentifier := kafkaMsg.Metadata.Org_id + ";" + kafkaMsg.Metadata.Cluster_uuid
-				experimentCreateError := kruize.CreateNamespaceExperiment(experimentName, clusterIdentifier, namespaceName)
-				if experimentCreateError != nil {
-					log.Error(experimentCreateError.Error())
-					continue
-				}
+func (p *uploadProgress) groupDone(csvName string, experimentName string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.groups[uploadGroup{csvName, experimentName}]
+}
 
-				workload := model.Workload{
-					OrgId:           rhAccount.OrgId,
-					ClusterID:       cluster.ID,
-					ExperimentName:  experimentName,
-					Namespace:       namespaceName,
-					WorkloadType:    w.Namespace,
-					MetricsUploadAt: maxEndTime,
-				}
-				if workloadCreateErr := workload.CreateWorkload(); workloadCreateErr != nil {
-					log.Errorf("unable to save workload record: %v. Error: %v", workload, workloadCreateErr)
-					continue
-				}
+func (p *uploadProgress) markGroupDone(csvName string, experimentName string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.groups[uploadGroup{csvName, experimentName}] = true
+}
 
-				var namespaceChunks [][]namespacePayload.UpdateNamespaceResult
-				updateResultPayload := namespacePayload.GetUpdateNamespaceResultPayload(experimentName, namespaceRows)
-				if len(updateResultPayload) > cfg.KruizeMaxBulkChunkSize {
-					namespaceChunks = SliceMetricsUpdatePayloadToChunks(updateResultPayload)
-				} else {
-					namespaceChunks = append(namespaceChunks, updateResultPayload)
-				}
+// groupFailure is the first error met while processing a workload group, with the stage it
+// happened at. Processing goes on with the next chunk or group.
+type groupFailure struct {
+	stage string
+	err   error
+}
 
-				for _, chunk := range namespaceChunks {
-					_, err := kruize.UpdateNamespaceResults(experimentName, chunk)
-					if err != nil {
-						log.Error(err, experimentName)
-						continue
-					}
-
-					workloadMetricSlice := []model.WorkloadMetrics{}
-					for _, data := range chunk {
-						interval_start_time, err := utils.ConvertISO8601StringToTime(data.IntervalStartTime)
-						if err != nil {
-							log.Errorf("Error for start time: %s", err)
-							continue
-						}
-						interval_end_time, err := utils.ConvertISO8601StringToTime(data.IntervalEndTime)
-						if err != nil {
-							log.Errorf("Error for end time: %s", err)
-							continue
-						}
-
-						namespaceMetrics := data.KubernetesObjects[0].Namespaces.Metrics
-						namespaceUsageMetrics, err := json.Marshal(namespaceMetrics)
-						if err != nil {
-							log.Errorf("unable to marshal namespace usage data: %v", err)
-							continue
-						}
-
-						workloadMetricNamespace := model.WorkloadMetrics{
-							OrgId:         rhAccount.OrgId,
-							WorkloadID:    workload.ID,
-							NamespaceName: namespaceName,
-							MetricType:    "namespace",
-							IntervalStart: interval_start_time,
-							IntervalEnd:   interval_end_time,
-							UsageMetrics:  namespaceUsageMetrics,
-						}
-						workloadMetricSlice = append(workloadMetricSlice, workloadMetricNamespace)
-					}
-
-					if err := model.BatchInsertWorkloadMetrics(workloadMetricSlice, rhAccount.OrgId); err != nil {
-						log.Errorf("unable to batch insert namespace metrics to workload_metrics table. Error: %v", err)
-						continue
-					}
-				}
+// groupResult is the outcome of processing one workload group.
+type groupResult struct {
+	// sentToKruize is set once Kruize accepted the experiment and some usage data of the workload.
+	sentToKruize            bool
+	recommendationRequested bool
+	failure                 *groupFailure
+	// kruizeUnavailable is set when any call to Kruize failed because it is unavailable, not
+	// only the first failure.
+	kruizeUnavailable bool
+}
 
-				// sending kafka msg to poller for recommendation request
-				maxEndtimeFromReport := maxEndTime.UTC()
-				messageData := types.RecommendationKafkaMsg{
-					Request_id: kafkaMsg.Request_id,
-					Metadata: types.RecommendationMetadata{
-						Org_id:             kafkaMsg.Metadata.Org_id,
-						Workload_id:        workload.ID,
-						Max_endtime_report: maxEndtimeFromReport,
-						Experiment_name:    experimentName,
-						ExperimentType:     types.PayloadTypeNamespace,
-					},
-				}
+// fail records err, met at stage, keeping the first failure.
+func (r *groupResult) fail(stage string, err error) {
+	if errors.Is(err, kruize.ErrUnavailable) {
+		r.kruizeUnavailable = true
+	}
+	if r.failure == nil {
+		r.failure = &groupFailure{stage: stage, err: err}
+	}
+}
+
+// groupSummary adds up the results of the workload groups of a file.
+type groupSummary struct {
+	sentToKruize           int
+	recommendationRequests int
+	failures               []groupFailure
+	kruizeUnavailable      bool
+}
+
+// processGroups runs process for every group, at most PROCESSOR_GROUP_CONCURRENCY at a time,
+// and sums up their results.
+func processGroups(groups map[string]dataframe.DataFrame, process func(group dataframe.DataFrame) groupResult) groupSummary {
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		summary groupSummary
+	)
+	slots := make(chan struct{}, max(cfg.ProcessorGroupConcurrency, 1))
+	for _, group := range groups {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(group dataframe.DataFrame) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			result := process(group)
+			mu.Lock()
+			defer mu.Unlock()
+			if result.sentToKruize {
+				summary.sentToKruize++
+			}
+			if result.recommendationRequested {
+				summary.recommendationRequests++
+			}
+			if result.failure != nil {
+				summary.failures = append(summary.failures, *result.failure)
+			}
+			if result.kruizeUnavailable {
+				summary.kruizeUnavailable = true
+			}
+		}(group)
+	}
+	wg.Wait()
+	return summary
+}
+
+func processContainerGroup(upload uploadContext, v dataframe.DataFrame) groupResult {
+	log := upload.log
+	kafkaMsg := upload.kafkaMsg
+	var result groupResult
+
+	all_interval_end_time := v.Col("interval_end").Records()
+	maxEndTime, err := utils.MaxIntervalEndTime(all_interval_end_time)
+	if err != nil {
+		log.Errorf("unable to convert string to time: %s", err)
+		result.fail(dlqStageParse, err)
+		return result
+	}
 
-				msgBytes, err := json.Marshal(messageData)
+	k8s_object := v.Maps()
+	namespace := kruizePayload.AssertAndConvertToString(k8s_object[0]["namespace"])
+	k8s_object_type := k8s_object[0]["k8s_object_type"].(string)
+	k8s_object_name := k8s_object[0]["k8s_object_name"].(string)
+
+	experiment_name := utils.GenerateExperimentName(
+		kafkaMsg.Metadata.Org_id,
+		kafkaMsg.Metadata.Source_id,
+		kafkaMsg.Metadata.Cluster_uuid,
+		namespace,
+		k8s_object_type,
+		k8s_object_name,
+	)
+
+	if upload.progress.groupDone(upload.csvName, experiment_name) {
+		return groupResult{sentToKruize: true, recommendationRequested: true}
+	}
+
+	cluster_identifier := kafkaMsg.Metadata.Org_id + ";" + kafkaMsg.Metadata.Cluster_uuid
+	container_names, err := kruizeClient.CreateExperiment(context.Background(), experiment_name, cluster_identifier, k8s_object)
+	if err != nil {
+		log.Error(err)
+		result.fail(dlqStageKruize, err)
+		return result
+	}
+
+	// Create workload entry into the table.
+	workload := model.Workload{
+		OrgId:           upload.rhAccount.OrgId,
+		ClusterID:       upload.cluster.ID,
+		ExperimentName:  experiment_name,
+		Namespace:       namespace,
+		WorkloadType:    w.WorkloadType(k8s_object_type),
+		WorkloadName:    k8s_object_name,
+		Containers:      container_names,
+		MetricsUploadAt: maxEndTime,
+	}
+	if err := workload.CreateWorkload(); err != nil {
+		log.Errorf("unable to save workload record: %v. Error: %v", workload, err)
+		result.fail(dlqStageDatabase, err)
+		return result
+	}
+	detectAppliedRecommendations(workload.ID, k8s_object, kafkaMsg.Metadata.Org_id, kafkaMsg.Metadata.Cluster_uuid)
+
+	var k8s_object_chunks [][]kruizePayload.UpdateResult
+	update_result_payload_data := kruizePayload.GetUpdateResultPayload(experiment_name, k8s_object)
+	if len(update_result_payload_data) > cfg.KruizeMaxBulkChunkSize {
+		k8s_object_chunks = SliceMetricsUpdatePayloadToChunks(update_result_payload_data)
+	} else {
+		k8s_object_chunks = append(k8s_object_chunks, update_result_payload_data)
+	}
+
+	for _, chunk := range k8s_object_chunks {
+		usage_data_byte, err := kruizeClient.UpdateResults(context.Background(), experiment_name, chunk)
+		if err != nil {
+			log.Error(err, experiment_name)
+			result.fail(dlqStageKruize, err)
+			continue
+		}
+		result.sentToKruize = true
+
+		workload_metric_arr := []model.WorkloadMetrics{}
+		for _, data := range usage_data_byte {
+
+			interval_start_time, err := utils.ConvertISO8601StringToTime(data.Interval_start_time)
+			if err != nil {
+				log.Errorf("Error for start time: %s", err)
+				continue
+			}
+			interval_end_time, err := utils.ConvertISO8601StringToTime(data.Interval_end_time)
+			if err != nil {
+				log.Errorf("Error for end time: %s", err)
+				continue
+			}
+
+			for _, container := range data.Kubernetes_objects[0].Containers {
+				container_usage_metrics, err := json.Marshal(container.Metrics)
 				if err != nil {
-					log.Error("Error marshaling JSON:", err)
+					log.Errorf("Unable to marshal container usage data: %v", err.Error())
 					continue
 				}
 
-				msgProduceErr := kafka_internal.SendMessage(msgBytes, cfg.RecommendationTopic, experimentName)
-				if msgProduceErr != nil {
-					log.Errorf("failed to produce message: %v for experiment - %s and end_interval - %s\n", msgProduceErr.Error(), experimentName, maxEndtimeFromReport)
-				} else {
-					log.Infof("recommendation request sent for experiment - %s and end_interval - %s", experimentName, maxEndtimeFromReport)
+				workload_metric := model.WorkloadMetrics{
+					OrgId:         upload.rhAccount.OrgId,
+					WorkloadID:    workload.ID,
+					ContainerName: container.Container_name,
+					IntervalStart: interval_start_time,
+					IntervalEnd:   interval_end_time,
+					UsageMetrics:  container_usage_metrics,
 				}
+				workload_metric_arr = append(workload_metric_arr, workload_metric)
+			}
+
+		}
+		if err := model.BatchInsertWorkloadMetrics(workload_metric_arr, upload.rhAccount.OrgId); err != nil {
+			log.Errorf("unable to batch insert to workload_metrics table. %v", err.Error())
+			result.fail(dlqStageDatabase, err)
+			continue
+		}
+	}
+
+	if result.kruizeUnavailable {
+		// the recommendation is requested when the upload is processed again
+		return result
+	}
+
+	// sending kafka msg to poller for recommendation request
+	maxEndtimeFromReport := maxEndTime.UTC()
+	messageData := types.RecommendationKafkaMsg{
+		Request_id: kafkaMsg.Request_id,
+		Metadata: types.RecommendationMetadata{
+			Org_id:             kafkaMsg.Metadata.Org_id,
+			Workload_id:        workload.ID,
+			Max_endtime_report: maxEndtimeFromReport,
+			Experiment_name:    experiment_name,
+			ExperimentType:     types.PayloadTypeContainer,
+		},
+	}
+
+	msgBytes, err := json.Marshal(messageData)
+	if err != nil {
+		log.Error("Error marshaling JSON:", err)
+		result.fail(dlqStageProduce, err)
+		return result
+	}
+
+	msgProduceErr := produce(msgBytes, cfg.RecommendationTopic, experiment_name)
+	if msgProduceErr != nil {
+		log.Errorf("Failed to produce message: %v for experiment - %s and end_interval - %s\n", msgProduceErr.Error(), experiment_name, maxEndtimeFromReport)
+		result.fail(dlqStageProduce, msgProduceErr)
+	} else {
+		log.Infof("Recommendation request sent for experiment - %s and end_interval - %s", experiment_name, maxEndtimeFromReport)
+		result.recommendationRequested = true
+	}
+	if result.failure == nil {
+		upload.progress.markGroupDone(upload.csvName, experiment_name)
+	}
+	return result
+}
+
+func processNamespaceGroup(upload uploadContext, v dataframe.DataFrame) groupResult {
+	log := upload.log
+	kafkaMsg := upload.kafkaMsg
+	var result groupResult
+
+	intervalEndTimeValues := v.Col("interval_end").Records()
+	maxEndTime, err := utils.MaxIntervalEndTime(intervalEndTimeValues)
+	if err != nil {
+		log.Errorf("unable to convert string to time: %s", err)
+		result.fail(dlqStageParse, err)
+		return result
+	}
+
+	namespaceRows := v.Maps()
+	namespaceName := kruizePayload.AssertAndConvertToString(namespaceRows[0]["namespace"])
+
+	experimentName := utils.GenerateNamespaceExperimentName(
+		kafkaMsg.Metadata.Org_id,
+		kafkaMsg.Metadata.Source_id,
+		kafkaMsg.Metadata.Cluster_uuid,
+		namespaceName,
+	)
+
+	if upload.progress.groupDone(upload.csvName, experimentName) {
+		return groupResult{sentToKruize: true, recommendationRequested: true}
+	}
+
+	clusterIdentifier := kafkaMsg.Metadata.Org_id + ";" + kafkaMsg.Metadata.Cluster_uuid
+	experimentCreateError := kruizeClient.CreateNamespaceExperiment(context.Background(), experimentName, clusterIdentifier, namespaceName)
+	if experimentCreateError != nil {
+		log.Error(experimentCreateError.Error())
+		result.fail(dlqStageKruize, experimentCreateError)
+		return result
+	}
+
+	workload := model.Workload{
+		OrgId:           upload.rhAccount.OrgId,
+		ClusterID:       upload.cluster.ID,
+		ExperimentName:  experimentName,
+		Namespace:       namespaceName,
+		WorkloadType:    w.Namespace,
+		MetricsUploadAt: maxEndTime,
+	}
+	if workloadCreateErr := workload.CreateWorkload(); workloadCreateErr != nil {
+		log.Errorf("unable to save workload record: %v. Error: %v", workload, workloadCreateErr)
+		result.fail(dlqStageDatabase, workloadCreateErr)
+		return result
+	}
+
+	var namespaceChunks [][]namespacePayload.UpdateNamespaceResult
+	updateResultPayload := namespacePayload.GetUpdateNamespaceResultPayload(experimentName, namespaceRows)
+	if len(updateResultPayload) > cfg.KruizeMaxBulkChunkSize {
+		namespaceChunks = SliceMetricsUpdatePayloadToChunks(updateResultPayload)
+	} else {
+		namespaceChunks = append(namespaceChunks, updateResultPayload)
+	}
+
+	for _, chunk := range namespaceChunks {
+		_, err := kruizeClient.UpdateNamespaceResults(context.Background(), experimentName, chunk)
+		if err != nil {
+			log.Error(err, experimentName)
+			result.fail(dlqStageKruize, err)
+			continue
+		}
+		result.sentToKruize = true
+
+		workloadMetricSlice := []model.WorkloadMetrics{}
+		for _, data := range chunk {
+			interval_start_time, err := utils.ConvertISO8601StringToTime(data.IntervalStartTime)
+			if err != nil {
+				log.Errorf("Error for start time: %s", err)
+				continue
+			}
+			interval_end_time, err := utils.ConvertISO8601StringToTime(data.IntervalEndTime)
+			if err != nil {
+				log.Errorf("Error for end time: %s", err)
+				continue
+			}
+
+			namespaceMetrics := data.KubernetesObjects[0].Namespaces.Metrics
+			namespaceUsageMetrics, err := json.Marshal(namespaceMetrics)
+			if err != nil {
+				log.Errorf("unable to marshal namespace usage data: %v", err)
+				continue
 			}
 
+			workloadMetricNamespace := model.WorkloadMetrics{
+				OrgId:         upload.rhAccount.OrgId,
+				WorkloadID:    workload.ID,
+				NamespaceName: namespaceName,
+				MetricType:    "namespace",
+				IntervalStart: interval_start_time,
+				IntervalEnd:   interval_end_time,
+				UsageMetrics:  namespaceUsageMetrics,
+			}
+			workloadMetricSlice = append(workloadMetricSlice, workloadMetricNamespace)
+		}
+
+		if err := model.BatchInsertWorkloadMetrics(workloadMetricSlice, upload.rhAccount.OrgId); err != nil {
+			log.Errorf("unable to batch insert namespace metrics to workload_metrics table. Error: %v", err)
+			result.fail(dlqStageDatabase, err)
+			continue
 		}
+	}
 
+	if result.kruizeUnavailable {
+		// the recommendation is requested when the upload is processed again
+		return result
 	}
 
+	// sending kafka msg to poller for recommendation request
+	maxEndtimeFromReport := maxEndTime.UTC()
+	messageData := types.RecommendationKafkaMsg{
+		Request_id: kafkaMsg.Request_id,
+		Metadata: types.RecommendationMetadata{
+			Org_id:             kafkaMsg.Metadata.Org_id,
+			Workload_id:        workload.ID,
+			Max_endtime_report: maxEndtimeFromReport,
+			Experiment_name:    experimentName,
+			ExperimentType:     types.PayloadTypeNamespace,
+		},
+	}
+
+	msgBytes, err := json.Marshal(messageData)
+	if err != nil {
+		log.Error("Error marshaling JSON:", err)
+		result.fail(dlqStageProduce, err)
+		return result
+	}
+
+	msgProduceErr := produce(msgBytes, cfg.RecommendationTopic, experimentName)
+	if msgProduceErr != nil {
+		log.Errorf("failed to produce message: %v for experiment - %s and end_interval - %s\n", msgProduceErr.Error(), experimentName, maxEndtimeFromReport)
+		result.fail(dlqStageProduce, msgProduceErr)
+	} else {
+		log.Infof("recommendation request sent for experiment - %s and end_interval - %s", experimentName, maxEndtimeFromReport)
+		result.recommendationRequested = true
+	}
+	if result.failure == nil {
+		upload.progress.markGroupDone(upload.csvName, experimentName)
+	}
+	return result
 }
@@ -1,17 +1,49 @@
 package services
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/go-gota/gota/dataframe"
+	"github.com/go-gota/gota/series"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	database "github.com/redhatinsights/ros-ocp-backend/internal/db"
+	"github.com/redhatinsights/ros-ocp-backend/internal/db/dbtest"
+	kafka_internal "github.com/redhatinsights/ros-ocp-backend/internal/kafka"
+	"github.com/redhatinsights/ros-ocp-backend/internal/logging"
+	"github.com/redhatinsights/ros-ocp-backend/internal/model"
+	"github.com/redhatinsights/ros-ocp-backend/internal/types"
+	"github.com/redhatinsights/ros-ocp-backend/internal/utils/kruize"
+	"github.com/redhatinsights/ros-ocp-backend/internal/utils/kruize/kruizetest"
 )
 
 // These tests verify that ProcessReport handles poison messages (invalid JSON,
 // unmarshal failures, validation failures) gracefully — returning early without
 // panicking or entering an infinite loop. The nil consumer is safe because
-// commitOnPermanentFailure guards against it.
+// commitOnPermanentFailure guards against it. Poison messages are also
+// dead-lettered as they are.
+
+func assertDeadLettered(t *testing.T, produced []producedMessage, msg *kafka.Message, stage string) {
+	t.Helper()
+	if assert.Len(t, produced, 1) {
+		assert.Equal(t, msg.Value, produced[0].value)
+		assert.Equal(t, stage, header(produced[0].headers, kafka_internal.HeaderDLQStage))
+	}
+}
 
 func TestProcessReport_InvalidJSON_ReturnsEarly(t *testing.T) {
+	produced := captureProduced(t)
 	msg := &kafka.Message{
 		Value:          []byte("{not valid json!!!"),
 		TopicPartition: kafka.TopicPartition{Partition: 0},
@@ -19,22 +51,308 @@ func TestProcessReport_InvalidJSON_ReturnsEarly(t *testing.T) {
 	// nil consumer: commitOnPermanentFailure checks for nil before committing
 	ProcessReport(msg, nil)
 	// If we reach here without panic, the poison message path works.
+	assertDeadLettered(t, *produced, msg, dlqStageDecode)
 }
 
 func TestProcessReport_UnmarshalError_ReturnsEarly(t *testing.T) {
+	produced := captureProduced(t)
 	// Valid JSON but doesn't match KafkaMsg struct fields
 	msg := &kafka.Message{
 		Value:          []byte(`{"unexpected_field": 42}`),
 		TopicPartition: kafka.TopicPartition{Partition: 0},
 	}
 	ProcessReport(msg, nil)
+	assertDeadLettered(t, *produced, msg, dlqStageValidate)
 }
 
 func TestProcessReport_ValidationError_ReturnsEarly(t *testing.T) {
+	produced := captureProduced(t)
 	// Valid JSON, unmarshals to KafkaMsg, but fails validation (missing required fields)
 	msg := &kafka.Message{
 		Value:          []byte(`{"request_id":"","b64_identity":"","metadata":{},"files":[]}`),
 		TopicPartition: kafka.TopicPartition{Partition: 0},
 	}
 	ProcessReport(msg, nil)
+	assertDeadLettered(t, *produced, msg, dlqStageValidate)
+}
+
+func TestProcessGroups_BoundsConcurrencyAndSumsResults(t *testing.T) {
+	original := *cfg
+	cfg.ProcessorGroupConcurrency = 2
+	t.Cleanup(func() { *cfg = original })
+
+	groups := make(map[string]dataframe.DataFrame)
+	for i := 0; i < 6; i++ {
+		name := fmt.Sprintf("namespace-%d", i)
+		groups[name] = dataframe.New(series.New([]string{name}, series.String, "namespace"))
+	}
+
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	release := make(chan struct{})
+	go func() {
+		for i := 0; i < len(groups); i++ {
+			release <- struct{}{}
+		}
+	}()
+	summary := processGroups(groups, func(group dataframe.DataFrame) groupResult {
+		mu.Lock()
+		running++
+		maxRunning = max(maxRunning, running)
+		mu.Unlock()
+		<-release
+		mu.Lock()
+		running--
+		mu.Unlock()
+
+		if namespace := group.Col("namespace").Records()[0]; namespace == "namespace-1" || namespace == "namespace-4" {
+			return groupResult{failure: &groupFailure{stage: dlqStageKruize, err: errors.New(namespace)}}
+		}
+		return groupResult{sentToKruize: true, recommendationRequested: true}
+	})
+
+	assert.LessOrEqual(t, maxRunning, 2)
+	assert.Equal(t, 4, summary.sentToKruize)
+	assert.Equal(t, 4, summary.recommendationRequests)
+	var failed []string
+	for _, failure := range summary.failures {
+		assert.Equal(t, dlqStageKruize, failure.stage)
+		failed = append(failed, failure.err.Error())
+	}
+	assert.ElementsMatch(t, []string{"namespace-1", "namespace-4"}, failed)
+}
+
+// useEmptyDB swaps in an in-memory database without tables, so every write fails.
+func useEmptyDB(t *testing.T) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	original := database.DB
+	database.DB = db
+	t.Cleanup(func() { database.DB = original })
+}
+
+func TestProcessContainerGroup_CreatesExperimentBeforeSavingWorkload(t *testing.T) {
+	server := kruizetest.Use(t, &kruizeClient)
+	useEmptyDB(t)
+
+	df := kruizetest.AggregateSample(t, "../../scripts/samples/ros-ocp-usage.csv", types.PayloadTypeContainer)
+	upload := uploadContext{kafkaMsg: payloadMsg(), progress: newUploadProgress(), log: logging.GetLogger()}
+	for _, group := range df.GroupBy("namespace", "k8s_object_type", "k8s_object_name").GetGroups() {
+		result := processContainerGroup(upload, group)
+		if assert.NotNil(t, result.failure) {
+			assert.Equal(t, dlqStageDatabase, result.failure.stage)
+		}
+		assert.False(t, result.sentToKruize)
+	}
+
+	experiment, ok := server.Experiment("3340851|source|cluster|Yuptoo-prod|deployment|Yuptoo-service")
+	if assert.True(t, ok) {
+		assert.Equal(t, "3340851;cluster", experiment.ClusterName)
+		assert.NotEmpty(t, experiment.Containers)
+	}
+}
+
+func TestProcessContainerGroup_KruizeFailure(t *testing.T) {
+	server := kruizetest.Use(t, &kruizeClient)
+	server.Fail(kruize.KruizeCreateExperiment, http.StatusInternalServerError)
+
+	df := kruizetest.AggregateSample(t, "../../scripts/samples/ros-ocp-usage.csv", types.PayloadTypeContainer)
+	upload := uploadContext{kafkaMsg: payloadMsg(), progress: newUploadProgress(), log: logging.GetLogger()}
+	for _, group := range df.GroupBy("namespace", "k8s_object_type", "k8s_object_name").GetGroups() {
+		result := processContainerGroup(upload, group)
+		if assert.NotNil(t, result.failure) {
+			assert.Equal(t, dlqStageKruize, result.failure.stage)
+			assert.ErrorIs(t, result.failure.err, kruize.ErrUnavailable)
+		}
+		assert.True(t, result.kruizeUnavailable)
+	}
+	assert.Empty(t, server.Experiments())
+}
+
+func TestGroupResultFail_KruizeUnavailableAfterAnotherFailure(t *testing.T) {
+	var result groupResult
+	result.fail(dlqStageDatabase, errors.New("database down"))
+	result.fail(dlqStageKruize, fmt.Errorf("unable to update results: %w", kruize.ErrUnavailable))
+
+	if assert.NotNil(t, result.failure) {
+		assert.Equal(t, dlqStageDatabase, result.failure.stage, "the first failure is kept")
+	}
+	assert.True(t, result.kruizeUnavailable)
+
+	summary := processGroups(map[string]dataframe.DataFrame{"group": {}}, func(dataframe.DataFrame) groupResult {
+		return result
+	})
+	assert.True(t, summary.kruizeUnavailable)
+}
+
+// sampleUpload returns an upload message of the sample CSV, fetched from the samples directory.
+// The topics of the processor and the poller are set until the test ends.
+func sampleUpload(t *testing.T) *kafka.Message {
+	t.Helper()
+	root, err := filepath.Abs("../../scripts/samples")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return uploadOf(t, root, "ros-ocp-usage.csv")
+}
+
+// uploadOf returns an upload message of the CSVs called names, fetched from root. The topics of
+// the processor and the poller are set until the test ends.
+func uploadOf(t *testing.T, root string, names ...string) *kafka.Message {
+	t.Helper()
+	original := *cfg
+	t.Cleanup(func() { *cfg = original })
+	cfg.FetchFileRoot = root
+	cfg.RecommendationTopic = "hccm.ros.recommendations"
+	cfg.UploadDLQTopic = "hccm.ros.events.dlq"
+	cfg.RecommendationEventsTopic = ""
+
+	var upload types.KafkaMsg
+	upload.Request_id = "request"
+	upload.B64_identity = "identity"
+	upload.Metadata.Org_id = "3340851"
+	upload.Metadata.Source_id = "source"
+	upload.Metadata.Cluster_uuid = "6f1b8a2e-7c3d-4e5f-9a0b-1c2d3e4f5a6b"
+	upload.Metadata.Cluster_alias = "cluster"
+	for _, name := range names {
+		upload.Files = append(upload.Files, "file://"+filepath.Join(root, name))
+	}
+	value, err := json.Marshal(upload)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return &kafka.Message{Value: value}
+}
+
+func TestProcessReport_KruizeUnavailable_IsNotHandled(t *testing.T) {
+	dbtest.Use(t)
+	server := kruizetest.Use(t, &kruizeClient)
+	produced := captureProduced(t)
+	captureUploadStatuses(t)
+	msg := sampleUpload(t)
+
+	server.Fail(kruize.KruizeUpdateResults, http.StatusServiceUnavailable)
+	assert.False(t, ProcessReport(msg, nil), "the upload is read again once Kruize is back")
+	assert.Empty(t, *produced, "nothing is dead-lettered")
+
+	server.Recover()
+	assert.True(t, ProcessReport(msg, nil))
+	if assert.NotEmpty(t, *produced) {
+		for _, message := range *produced {
+			assert.Equal(t, cfg.RecommendationTopic, message.topic)
+		}
+	}
+}
+
+func TestProcessReport_KruizeUnavailable_RetriesOnlyWhatFailed(t *testing.T) {
+	dbtest.Use(t)
+	server := kruizetest.Use(t, &kruizeClient)
+	produced := captureProduced(t)
+	captureUploadStatuses(t)
+	root := t.TempDir()
+	for _, name := range []string{"ros-ocp-usage-24Hrs.csv", "ros-ocp-usage.csv"} {
+		data, err := os.ReadFile(filepath.Join("../../scripts/samples", name))
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.NoError(t, os.WriteFile(filepath.Join(root, name), data, 0o600))
+	}
+	msg := uploadOf(t, root, "ros-ocp-usage-24Hrs.csv", "ros-ocp-usage.csv")
+	cfg.ProcessorGroupConcurrency = 1
+
+	// Kruize goes down after the workload of the first file and one of the second
+	server.FailAfter(kruize.KruizeCreateExperiment, 2, http.StatusServiceUnavailable)
+	assert.False(t, ProcessReport(msg, nil))
+	assert.Len(t, *produced, 2, "the recommendations of the workloads sent are requested")
+	created := server.Calls(kruize.KruizeCreateExperiment)
+
+	// the presigned URL of the first file expired meanwhile
+	assert.NoError(t, os.Remove(filepath.Join(root, "ros-ocp-usage-24Hrs.csv")))
+	server.Recover()
+	assert.True(t, ProcessReport(msg, nil))
+
+	requested := make(map[string]int)
+	for _, message := range *produced {
+		if assert.Equal(t, cfg.RecommendationTopic, message.topic, "nothing is dead-lettered") {
+			requested[string(message.value)]++
+		}
+	}
+	assert.Greater(t, len(requested), 2)
+	for request, times := range requested {
+		assert.Equal(t, 1, times, "requested once: %s", request)
+	}
+	assert.Equal(t, len(*produced)-2, server.Calls(kruize.KruizeCreateExperiment)-created, "only the workloads not sent are sent again")
+}
+
+// TestProcessReport_ThenPollForRecommendations follows an upload of the sample CSV through the
+// processor and the poller, against the fake Kruize and the test database.
+func TestProcessReport_ThenPollForRecommendations(t *testing.T) {
+	db := dbtest.Use(t)
+	server := kruizetest.Use(t, &kruizeClient)
+	produced := captureProduced(t)
+	statuses := captureUploadStatuses(t)
+	msg := sampleUpload(t)
+	cfg.RecommendationEventsTopic = "hccm.ros.recommendation.events"
+
+	assert.True(t, ProcessReport(msg, nil))
+
+	var requests []producedMessage
+	for _, message := range *produced {
+		if assert.Equal(t, cfg.RecommendationTopic, message.topic, "nothing is dead-lettered") {
+			requests = append(requests, message)
+		}
+	}
+	if !assert.NotEmpty(t, requests) || !assert.NotEmpty(t, *statuses) {
+		return
+	}
+	status := (*statuses)[len(*statuses)-1]
+	assert.Empty(t, status.Failures)
+	assert.Equal(t, len(requests), status.RecommendationRequests)
+
+	var workloads []model.Workload
+	assert.NoError(t, db.Find(&workloads).Error)
+	assert.Len(t, workloads, len(requests), "one recommendation request per workload")
+	var metrics int64
+	assert.NoError(t, db.Model(&model.WorkloadMetrics{}).Count(&metrics).Error)
+	assert.Positive(t, metrics)
+
+	*produced = nil
+	for _, request := range requests {
+		PollForRecommendations(&kafka.Message{Value: request.value, Key: []byte(request.key)}, nil)
+	}
+	var recommendations int64
+	assert.NoError(t, db.Model(&model.RecommendationSet{}).Count(&recommendations).Error)
+	assert.Positive(t, recommendations)
+	if assert.Len(t, *produced, int(recommendations), "one event per recommendation") {
+		assert.Equal(t, cfg.RecommendationEventsTopic, (*produced)[0].topic)
+	}
+
+	for _, workload := range workloads {
+		experiment, ok := server.Experiment(workload.ExperimentName)
+		if !assert.True(t, ok, workload.ExperimentName) {
+			continue
+		}
+		assert.Positive(t, experiment.Results)
+
+		var sets []model.RecommendationSet
+		assert.NoError(t, db.Where("workload_id = ?", workload.ID).Find(&sets).Error)
+		var containers []string
+		for _, set := range sets {
+			containers = append(containers, set.ContainerName)
+			assert.Equal(t, experiment.LastResultEnd, set.MonitoringEndTime.UTC())
+		}
+		assert.ElementsMatch(t, []string(workload.Containers), containers, workload.ExperimentName)
+
+		var historical int64
+		assert.NoError(t, db.Model(&model.HistoricalRecommendationSet{}).Where("workload_id = ?", workload.ID).Count(&historical).Error)
+		assert.Equal(t, int64(len(sets)), historical)
+	}
+	var retries int64
+	assert.NoError(t, db.Model(&model.RecommendationRetry{}).Count(&retries).Error)
+	assert.Zero(t, retries)
 }
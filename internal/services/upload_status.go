@@ -0,0 +1,110 @@
+package services
+
+import (
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/model"
+	"github.com/redhatinsights/ros-ocp-backend/internal/types"
+)
+
+// maxUploadFailures caps the failures recorded per file; the processor logs every one of them.
+const maxUploadFailures = 20
+
+var saveUploadStatus = func(status *model.UploadStatus) error {
+	return status.SaveUploadStatus()
+}
+
+// uploadStatus records the progress of one file of an upload in upload_statuses. Failing to
+// record it is logged and never stops processing.
+type uploadStatus struct {
+	status model.UploadStatus
+	log    *logrus.Entry
+}
+
+// uploadFileName is the name of an uploaded file without the rest of its download URL, which
+// is presigned and must not be stored.
+func uploadFileName(file string) string {
+	parsed, err := url.Parse(file)
+	if err != nil || parsed.Path == "" {
+		return file
+	}
+	return path.Base(parsed.Path)
+}
+
+func newUploadStatus(kafkaMsg types.KafkaMsg, file string, log *logrus.Entry) *uploadStatus {
+	u := &uploadStatus{
+		status: model.UploadStatus{
+			OrgID:       kafkaMsg.Metadata.Org_id,
+			RequestID:   kafkaMsg.Request_id,
+			ClusterUUID: kafkaMsg.Metadata.Cluster_uuid,
+			SourceID:    kafkaMsg.Metadata.Source_id,
+			FileName:    uploadFileName(file),
+			Status:      model.UploadStatusReceived,
+			ReceivedAt:  time.Now().UTC(),
+			Failures:    []model.UploadFailure{},
+		},
+		log: log,
+	}
+	u.save()
+	return u
+}
+
+func (u *uploadStatus) save() {
+	if err := saveUploadStatus(&u.status); err != nil {
+		u.log.Errorf("unable to save upload status of %s: %v", u.status.FileName, err)
+	}
+}
+
+func (u *uploadStatus) addFailure(stage string, err error) {
+	if len(u.status.Failures) >= maxUploadFailures {
+		return
+	}
+	reason := err.Error()
+	if len(reason) > maxDLQReasonLen {
+		reason = reason[:maxDLQReasonLen]
+	}
+	u.status.Failures = append(u.status.Failures, model.UploadFailure{Stage: stage, Reason: reason})
+}
+
+func (u *uploadStatus) advance(status string) {
+	now := time.Now().UTC()
+	u.status.Status = status
+	switch status {
+	case model.UploadStatusFetched:
+		u.status.FetchedAt = &now
+	case model.UploadStatusAggregated:
+		u.status.AggregatedAt = &now
+	case model.UploadStatusProcessed, model.UploadStatusFailed:
+		u.status.CompletedAt = &now
+	}
+	u.save()
+}
+
+func (u *uploadStatus) fetched() {
+	u.advance(model.UploadStatusFetched)
+}
+
+func (u *uploadStatus) aggregated() {
+	u.advance(model.UploadStatusAggregated)
+}
+
+// failed records a failure that stopped the file from being processed.
+func (u *uploadStatus) failed(stage string, err error) {
+	u.addFailure(stage, err)
+	u.advance(model.UploadStatusFailed)
+}
+
+// processed records the outcome of the workload groups of the file.
+func (u *uploadStatus) processed(summary groupSummary) {
+	u.status.WorkloadsSent = summary.sentToKruize
+	u.status.WorkloadsFailed = len(summary.failures)
+	u.status.RecommendationRequests = summary.recommendationRequests
+	for _, failure := range summary.failures {
+		u.addFailure(failure.stage, failure.err)
+	}
+	u.advance(model.UploadStatusProcessed)
+}
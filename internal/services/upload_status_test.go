@@ -0,0 +1,103 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/logging"
+	"github.com/redhatinsights/ros-ocp-backend/internal/model"
+	"github.com/redhatinsights/ros-ocp-backend/internal/types"
+)
+
+func captureUploadStatuses(t *testing.T) *[]model.UploadStatus {
+	t.Helper()
+	var saved []model.UploadStatus
+	original := saveUploadStatus
+	saveUploadStatus = func(status *model.UploadStatus) error {
+		saved = append(saved, *status)
+		return nil
+	}
+	t.Cleanup(func() { saveUploadStatus = original })
+	return &saved
+}
+
+func TestUploadFileName(t *testing.T) {
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"https://bucket.s3.amazonaws.com/org/upload.1.csv?X-Amz-Signature=secret", "upload.1.csv"},
+		{"http://localhost:8888/upload.2.csv", "upload.2.csv"},
+		{"upload.3.csv", "upload.3.csv"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, uploadFileName(tt.file), tt.file)
+	}
+}
+
+func TestUploadStatusRecordsProgress(t *testing.T) {
+	saved := captureUploadStatuses(t)
+	kafkaMsg := types.KafkaMsg{Request_id: "req-1"}
+	kafkaMsg.Metadata.Org_id = "org-1"
+	kafkaMsg.Metadata.Cluster_uuid = "cluster-1"
+
+	status := newUploadStatus(kafkaMsg, "https://bucket/upload.1.csv?sig=secret", logging.GetLogger())
+	status.fetched()
+	status.aggregated()
+	status.processed(groupSummary{
+		sentToKruize:           3,
+		recommendationRequests: 2,
+		failures:               []groupFailure{{stage: dlqStageProduce, err: errors.New("broker down")}},
+	})
+
+	if assert.Len(t, *saved, 4) {
+		assert.Equal(t, model.UploadStatusReceived, (*saved)[0].Status)
+		assert.Equal(t, model.UploadStatusFetched, (*saved)[1].Status)
+		assert.Equal(t, model.UploadStatusAggregated, (*saved)[2].Status)
+
+		last := (*saved)[3]
+		assert.Equal(t, "org-1", last.OrgID)
+		assert.Equal(t, "req-1", last.RequestID)
+		assert.Equal(t, "cluster-1", last.ClusterUUID)
+		assert.Equal(t, "upload.1.csv", last.FileName)
+		assert.Equal(t, model.UploadStatusProcessed, last.Status)
+		assert.NotNil(t, last.FetchedAt)
+		assert.NotNil(t, last.AggregatedAt)
+		assert.NotNil(t, last.CompletedAt)
+		assert.Equal(t, 3, last.WorkloadsSent)
+		assert.Equal(t, 1, last.WorkloadsFailed)
+		assert.Equal(t, 2, last.RecommendationRequests)
+		assert.Equal(t, []model.UploadFailure{{Stage: dlqStageProduce, Reason: "broker down"}}, last.Failures)
+	}
+}
+
+func TestUploadStatusCapsFailures(t *testing.T) {
+	saved := captureUploadStatuses(t)
+	var failures []groupFailure
+	for i := 0; i < maxUploadFailures+5; i++ {
+		failures = append(failures, groupFailure{stage: dlqStageKruize, err: fmt.Errorf("failure %d", i)})
+	}
+
+	status := newUploadStatus(types.KafkaMsg{}, "upload.1.csv", logging.GetLogger())
+	status.processed(groupSummary{failures: failures})
+
+	last := (*saved)[len(*saved)-1]
+	assert.Equal(t, maxUploadFailures+5, last.WorkloadsFailed)
+	assert.Len(t, last.Failures, maxUploadFailures)
+}
+
+func TestUploadStatusFailed(t *testing.T) {
+	saved := captureUploadStatuses(t)
+
+	status := newUploadStatus(types.KafkaMsg{}, "upload.1.csv", logging.GetLogger())
+	status.failed(dlqStageFetch, errors.New("403 Forbidden"))
+
+	last := (*saved)[len(*saved)-1]
+	assert.Equal(t, model.UploadStatusFailed, last.Status)
+	assert.Nil(t, last.FetchedAt)
+	assert.NotNil(t, last.CompletedAt)
+	assert.Equal(t, []model.UploadFailure{{Stage: dlqStageFetch, Reason: "403 Forbidden"}}, last.Failures)
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"math"
+	"sync"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/logging"
+	"github.com/redhatinsights/ros-ocp-backend/internal/model"
+	"github.com/redhatinsights/ros-ocp-backend/internal/types"
+	"github.com/redhatinsights/ros-ocp-backend/internal/utils/webhooks"
+)
+
+// webhookDelivery is the events of a saved workload to post to one of its org's webhooks.
+type webhookDelivery struct {
+	hook   model.Webhook
+	events []webhooks.Event
+}
+
+// webhookWorkers deliver the webhook events queued for them.
+type webhookWorkers struct {
+	queue   chan webhookDelivery
+	running sync.WaitGroup
+	// cancel gives up on the deliveries in progress.
+	cancel context.CancelFunc
+}
+
+var (
+	// deliverWebhook posts an event; tests swap it.
+	deliverWebhook = webhooks.Deliver
+
+	// webhookPool is nil while no workers run.
+	webhookPool   *webhookWorkers
+	webhookPoolMu sync.Mutex
+)
+
+func reachesThreshold(pct *float64, threshold float64) bool {
+	return pct != nil && math.Abs(*pct) >= threshold
+}
+
+// variationsAboveThreshold returns the term/engine variations where the cpu or memory change
+// reaches threshold in magnitude.
+func variationsAboveThreshold(pcts model.StoredVariationPcts, threshold float64) []webhooks.Variation {
+	var variations []webhooks.Variation
+	for _, spec := range model.StoredVariationSpecs {
+		cpu, memory := spec.CPU(&pcts), spec.Mem(&pcts)
+		if !reachesThreshold(cpu, threshold) && !reachesThreshold(memory, threshold) {
+			continue
+		}
+		variations = append(variations, webhooks.Variation{
+			Term:               spec.Term,
+			Engine:             spec.Engine,
+			CPUVariationPct:    cpu,
+			MemoryVariationPct: memory,
+		})
+	}
+	return variations
+}
+
+// webhookEvents returns the events to post to webhook, skipping recommendations below its threshold.
+func webhookEvents(webhook model.Webhook, workload model.Workload, recommendationType types.PayloadType, saved []savedRecommendation) []webhooks.Event {
+	var events []webhooks.Event
+	for _, recommendation := range saved {
+		variations := variationsAboveThreshold(recommendation.variationPcts, webhook.MinVariationPct)
+		if len(variations) == 0 {
+			continue
+		}
+		event := webhooks.Event{
+			OrgID:              workload.OrgId,
+			RecommendationID:   recommendation.id,
+			RecommendationType: string(recommendationType),
+			ClusterUUID:        workload.Cluster.ClusterUUID,
+			ClusterAlias:       workload.Cluster.ClusterAlias,
+			Namespace:          workload.Namespace,
+			Container:          recommendation.container,
+			MonitoringEndTime:  recommendation.monitoringEndTime,
+			Variations:         variations,
+		}
+		if recommendationType == types.PayloadTypeContainer {
+			event.Workload = workload.WorkloadName
+			event.WorkloadType = string(workload.WorkloadType)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// notifyWebhooks queues the recommendations saved for a workload for the webhooks of its org.
+// Deliveries run in the background so retries do not hold up the poller.
+func notifyWebhooks(orgID string, workloadID uint, recommendationType types.PayloadType, saved []savedRecommendation) {
+	log := logging.GetLogger()
+	hooks, err := model.GetWebhooks(orgID)
+	if err != nil {
+		log.Errorf("unable to fetch webhooks: %v", err)
+		return
+	}
+	if len(hooks) == 0 {
+		return
+	}
+	workload, err := model.GetWorkloadByID(workloadID)
+	if err != nil {
+		log.Errorf("unable to fetch workload %d for webhooks: %v", workloadID, err)
+		return
+	}
+
+	for _, hook := range hooks {
+		events := webhookEvents(hook, workload, recommendationType, saved)
+		if len(events) == 0 {
+			continue
+		}
+		if !queueWebhookDelivery(webhookDelivery{hook: hook, events: events}) {
+			log.Errorf("webhook delivery queue is full or stopped, dropping %d events for webhook %d", len(events), hook.ID)
+			webhookDropped.Inc()
+		}
+	}
+}
+
+// queueWebhookDelivery queues delivery for the workers and reports whether they run and had room.
+func queueWebhookDelivery(delivery webhookDelivery) bool {
+	webhookPoolMu.Lock()
+	defer webhookPoolMu.Unlock()
+	if webhookPool == nil {
+		return false
+	}
+	select {
+	case webhookPool.queue <- delivery:
+		return true
+	default:
+		return false
+	}
+}
+
+// StartWebhookDeliveries starts WEBHOOK_WORKERS workers posting the queued webhook events, with
+// room for WEBHOOK_QUEUE_SIZE events to wait, until WaitForWebhookDeliveries.
+func StartWebhookDeliveries() {
+	log := logging.GetLogger()
+	ctx, cancel := context.WithCancel(context.Background())
+	pool := &webhookWorkers{queue: make(chan webhookDelivery, cfg.WebhookQueueSize), cancel: cancel}
+	for range max(cfg.WebhookWorkers, 1) {
+		pool.running.Add(1)
+		go func() {
+			defer pool.running.Done()
+			for delivery := range pool.queue {
+				for _, event := range delivery.events {
+					if err := deliverWebhook(ctx, delivery.hook.URL, delivery.hook.Secret, event); err != nil {
+						log.Errorf("unable to deliver recommendation %s to webhook %d: %v", event.RecommendationID, delivery.hook.ID, err)
+					}
+				}
+			}
+		}()
+	}
+
+	webhookPoolMu.Lock()
+	webhookPool = pool
+	webhookPoolMu.Unlock()
+}
+
+// WaitForWebhookDeliveries stops queueing webhook events and waits for the queued ones to be
+// delivered, retries included. Once ctx is done the remaining deliveries are given up on.
+func WaitForWebhookDeliveries(ctx context.Context) error {
+	webhookPoolMu.Lock()
+	pool := webhookPool
+	webhookPool = nil
+	webhookPoolMu.Unlock()
+	if pool == nil {
+		return nil
+	}
+	close(pool.queue)
+	defer pool.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		pool.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/model"
+	"github.com/redhatinsights/ros-ocp-backend/internal/types"
+	w "github.com/redhatinsights/ros-ocp-backend/internal/types/workload"
+	"github.com/redhatinsights/ros-ocp-backend/internal/utils/webhooks"
+)
+
+func pct(v float64) *float64 { return &v }
+
+func TestVariationsAboveThreshold(t *testing.T) {
+	pcts := model.StoredVariationPcts{
+		CPUVariationShortCostPct:           pct(-60),
+		MemoryVariationShortCostPct:        pct(-10),
+		CPUVariationShortPerformancePct:    pct(-20),
+		MemoryVariationShortPerformancePct: pct(5),
+		MemoryVariationLongCostPct:         pct(55),
+	}
+
+	variations := variationsAboveThreshold(pcts, 50)
+
+	assert.Equal(t, []webhooks.Variation{
+		{Term: "short_term", Engine: "cost", CPUVariationPct: pct(-60), MemoryVariationPct: pct(-10)},
+		{Term: "long_term", Engine: "cost", MemoryVariationPct: pct(55)},
+	}, variations)
+	assert.Len(t, variationsAboveThreshold(pcts, 0), 3)
+	assert.Empty(t, variationsAboveThreshold(model.StoredVariationPcts{}, 0))
+}
+
+func TestWebhookEvents(t *testing.T) {
+	workload := model.Workload{
+		OrgId:        "org",
+		Namespace:    "shop",
+		WorkloadName: "frontend",
+		WorkloadType: w.Deployment,
+		Cluster:      model.Cluster{ClusterUUID: "uuid-1", ClusterAlias: "prod"},
+	}
+	endTime := time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC)
+	saved := []savedRecommendation{
+		{id: "rec-1", container: "app", monitoringEndTime: endTime, variationPcts: model.StoredVariationPcts{CPUVariationShortCostPct: pct(-80)}},
+		{id: "rec-2", container: "sidecar", monitoringEndTime: endTime, variationPcts: model.StoredVariationPcts{CPUVariationShortCostPct: pct(-5)}},
+	}
+
+	events := webhookEvents(model.Webhook{MinVariationPct: 50}, workload, types.PayloadTypeContainer, saved)
+
+	assert.Equal(t, []webhooks.Event{{
+		OrgID:              "org",
+		RecommendationID:   "rec-1",
+		RecommendationType: string(types.PayloadTypeContainer),
+		ClusterUUID:        "uuid-1",
+		ClusterAlias:       "prod",
+		Namespace:          "shop",
+		Workload:           "frontend",
+		WorkloadType:       "deployment",
+		Container:          "app",
+		MonitoringEndTime:  endTime,
+		Variations:         []webhooks.Variation{{Term: "short_term", Engine: "cost", CPUVariationPct: pct(-80)}},
+	}}, events)
+
+	namespaceSaved := []savedRecommendation{{id: "rec-3", variationPcts: model.StoredVariationPcts{MemoryVariationShortCostPct: pct(-5)}}}
+	namespaceEvents := webhookEvents(model.Webhook{}, workload, types.PayloadTypeNamespace, namespaceSaved)
+	assert.Len(t, namespaceEvents, 1)
+	assert.Empty(t, namespaceEvents[0].Workload)
+	assert.Empty(t, namespaceEvents[0].WorkloadType)
+}
+
+// useWebhookWorkers starts workers and queue room for the test, with deliveries made by deliver.
+func useWebhookWorkers(t *testing.T, workers int, queueSize int, deliver func(context.Context, string, string, webhooks.Event) error) {
+	t.Helper()
+	original := *cfg
+	originalDeliver := deliverWebhook
+	t.Cleanup(func() {
+		_ = WaitForWebhookDeliveries(context.Background())
+		*cfg = original
+		deliverWebhook = originalDeliver
+	})
+	cfg.WebhookWorkers = workers
+	cfg.WebhookQueueSize = queueSize
+	deliverWebhook = deliver
+	StartWebhookDeliveries()
+}
+
+func TestWebhookDeliveries_QueueIsBounded(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan string, 3)
+	var delivered atomic.Int32
+	useWebhookWorkers(t, 1, 1, func(_ context.Context, _ string, _ string, event webhooks.Event) error {
+		started <- event.RecommendationID
+		<-release
+		delivered.Add(1)
+		return nil
+	})
+	delivery := func(id string) webhookDelivery {
+		return webhookDelivery{events: []webhooks.Event{{RecommendationID: id}}}
+	}
+
+	assert.True(t, queueWebhookDelivery(delivery("running")))
+	assert.Equal(t, "running", <-started)
+	assert.True(t, queueWebhookDelivery(delivery("waiting")))
+	assert.False(t, queueWebhookDelivery(delivery("dropped")), "the queue is full")
+
+	close(release)
+	assert.NoError(t, WaitForWebhookDeliveries(context.Background()))
+	assert.Equal(t, int32(2), delivered.Load())
+	assert.False(t, queueWebhookDelivery(delivery("late")), "the workers are stopped")
+}
+
+func TestWaitForWebhookDeliveries_GivesUpOnceContextIsDone(t *testing.T) {
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+	useWebhookWorkers(t, 1, 1, func(ctx context.Context, _ string, _ string, _ webhooks.Event) error {
+		close(started)
+		<-ctx.Done()
+		close(cancelled)
+		return ctx.Err()
+	})
+	assert.True(t, queueWebhookDelivery(webhookDelivery{events: []webhooks.Event{{RecommendationID: "slow"}}}))
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, WaitForWebhookDeliveries(ctx), context.DeadlineExceeded)
+	<-cancelled
+}
@@ -18,6 +18,9 @@ type KafkaMsg struct {
 		Source_id     string `validate:"required"`
 		Cluster_uuid  string `validate:"required,uuid"`
 		Cluster_alias string `validate:"required"`
+		// Checksums optionally maps entries of Files to the SHA-256 of their content,
+		// "sha256:<hex>" or bare hex.
+		Checksums map[string]string
 	} `validate:"required"`
 	Files []string `validate:"required"`
 }
@@ -1,6 +1,8 @@
 package namespace
 
 import (
+	"time"
+
 	"github.com/go-gota/gota/dataframe"
 	"github.com/redhatinsights/ros-ocp-backend/internal/logging"
 	"github.com/redhatinsights/ros-ocp-backend/internal/types"
@@ -67,3 +69,22 @@ func GetUpdateNamespaceResultPayload(experiment_name string, namespaceData []map
 	}
 	return payload
 }
+
+// GetUpdateNamespaceResultFromMetrics builds the update result of one interval of a namespace
+// from its metrics, e.g. as saved in workload_metrics.
+func GetUpdateNamespaceResultFromMetrics(experiment_name string, namespace string, interval_start time.Time, interval_end time.Time, metrics []kruizePayload.Metric) UpdateNamespaceResult {
+	return UpdateNamespaceResult{
+		Version:           "1.0",
+		ExperimentName:    experiment_name,
+		IntervalStartTime: interval_start.UTC().Format("2006-01-02T15:04:05.000Z"),
+		IntervalEndTime:   interval_end.UTC().Format("2006-01-02T15:04:05.000Z"),
+		KubernetesObjects: []NamespaceK8SObjectUpdateResult{
+			{
+				Namespaces: NamespaceMetrics{
+					Namespace: namespace,
+					Metrics:   metrics,
+				},
+			},
+		},
+	}
+}
@@ -1,6 +1,9 @@
 package kruizePayload
 
 import (
+	"slices"
+	"time"
+
 	"github.com/go-gota/gota/dataframe"
 	"github.com/redhatinsights/ros-ocp-backend/internal/logging"
 	"github.com/redhatinsights/ros-ocp-backend/internal/types"
@@ -87,3 +90,34 @@ func GetUpdateResultPayload(experiment_name string, containers []map[string]inte
 
 	return payload
 }
+
+// GetUpdateResultFromMetrics builds the update result of one interval of a workload from the
+// metrics of its containers, e.g. as saved in workload_metrics.
+func GetUpdateResultFromMetrics(experiment_name string, namespace string, k8s_object_type string, k8s_object_name string, interval_start time.Time, interval_end time.Time, containerMetrics map[string][]Metric) UpdateResult {
+	container_names := make([]string, 0, len(containerMetrics))
+	for name := range containerMetrics {
+		container_names = append(container_names, name)
+	}
+	slices.Sort(container_names)
+	container_array := []container{}
+	for _, name := range container_names {
+		container_array = append(container_array, container{
+			Container_name: name,
+			Metrics:        containerMetrics[name],
+		})
+	}
+	return UpdateResult{
+		Version:             "1.0",
+		Experiment_name:     experiment_name,
+		Interval_start_time: interval_start.UTC().Format("2006-01-02T15:04:05.000Z"),
+		Interval_end_time:   interval_end.UTC().Format("2006-01-02T15:04:05.000Z"),
+		Kubernetes_objects: []kubernetesObject{
+			{
+				K8stype:    k8s_object_type,
+				Name:       k8s_object_name,
+				Namespace:  namespace,
+				Containers: container_array,
+			},
+		},
+	}
+}
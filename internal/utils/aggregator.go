@@ -13,6 +13,74 @@ import (
 	w "github.com/redhatinsights/ros-ocp-backend/internal/types/workload"
 )
 
+// Columns the rows of a CSV are grouped by before their metrics are aggregated.
+var containerGroupColumns = []string{
+	"namespace",
+	"k8s_object_type",
+	"k8s_object_name",
+	"workload",
+	"container_name",
+	"image_name",
+	"interval_start",
+	"interval_end",
+}
+
+var namespaceGroupColumns = []string{
+	"namespace",
+	"interval_start",
+	"interval_end",
+}
+
+// How each metric column is aggregated; the aggregated column is named <column>_<AGGREGATION>.
+var containerAggregationMapping = map[string]dataframe.AggregationType{
+	"cpu_request_container_avg":      dataframe.Aggregation_MEAN,
+	"cpu_request_container_sum":      dataframe.Aggregation_SUM,
+	"cpu_limit_container_avg":        dataframe.Aggregation_MEAN,
+	"cpu_limit_container_sum":        dataframe.Aggregation_SUM,
+	"cpu_usage_container_avg":        dataframe.Aggregation_MEAN,
+	"cpu_usage_container_min":        dataframe.Aggregation_MIN,
+	"cpu_usage_container_max":        dataframe.Aggregation_MAX,
+	"cpu_usage_container_sum":        dataframe.Aggregation_SUM,
+	"cpu_throttle_container_avg":     dataframe.Aggregation_MEAN,
+	"cpu_throttle_container_max":     dataframe.Aggregation_MAX,
+	"cpu_throttle_container_sum":     dataframe.Aggregation_SUM,
+	"memory_request_container_avg":   dataframe.Aggregation_MEAN,
+	"memory_request_container_sum":   dataframe.Aggregation_SUM,
+	"memory_limit_container_avg":     dataframe.Aggregation_MEAN,
+	"memory_limit_container_sum":     dataframe.Aggregation_SUM,
+	"memory_usage_container_avg":     dataframe.Aggregation_MEAN,
+	"memory_usage_container_min":     dataframe.Aggregation_MIN,
+	"memory_usage_container_max":     dataframe.Aggregation_MAX,
+	"memory_usage_container_sum":     dataframe.Aggregation_SUM,
+	"memory_rss_usage_container_avg": dataframe.Aggregation_MEAN,
+	"memory_rss_usage_container_min": dataframe.Aggregation_MIN,
+	"memory_rss_usage_container_max": dataframe.Aggregation_MAX,
+	"memory_rss_usage_container_sum": dataframe.Aggregation_SUM,
+}
+
+var namespaceAggregationMapping = map[string]dataframe.AggregationType{
+	"cpu_request_namespace_sum":      dataframe.Aggregation_SUM,
+	"cpu_limit_namespace_sum":        dataframe.Aggregation_SUM,
+	"cpu_usage_namespace_avg":        dataframe.Aggregation_MEAN,
+	"cpu_usage_namespace_max":        dataframe.Aggregation_MAX,
+	"cpu_usage_namespace_min":        dataframe.Aggregation_MIN,
+	"cpu_throttle_namespace_avg":     dataframe.Aggregation_MEAN,
+	"cpu_throttle_namespace_max":     dataframe.Aggregation_MAX,
+	"cpu_throttle_namespace_min":     dataframe.Aggregation_MIN,
+	"memory_request_namespace_sum":   dataframe.Aggregation_SUM,
+	"memory_limit_namespace_sum":     dataframe.Aggregation_SUM,
+	"memory_usage_namespace_avg":     dataframe.Aggregation_MEAN,
+	"memory_usage_namespace_max":     dataframe.Aggregation_MAX,
+	"memory_usage_namespace_min":     dataframe.Aggregation_MIN,
+	"memory_rss_usage_namespace_avg": dataframe.Aggregation_MEAN,
+	"memory_rss_usage_namespace_max": dataframe.Aggregation_MAX,
+	"memory_rss_usage_namespace_min": dataframe.Aggregation_MIN,
+	"namespace_running_pods_max":     dataframe.Aggregation_MAX,
+	"namespace_running_pods_avg":     dataframe.Aggregation_MEAN,
+	"namespace_total_pods_max":       dataframe.Aggregation_MAX,
+	"namespace_total_pods_avg":       dataframe.Aggregation_MEAN,
+}
+
 func Aggregate_data(aggregationType types.PayloadType, df dataframe.DataFrame) (dataframe.DataFrame, error) {
 	log = logging.GetLogger()
 
@@ -37,74 +105,12 @@ func Aggregate_data(aggregationType types.PayloadType, df dataframe.DataFrame) (
 
 	if aggregationType == types.PayloadTypeContainer {
 		df = determine_k8s_object_type(df)
-
-		dfGroups = df.GroupBy(
-			"namespace",
-			"k8s_object_type",
-			"k8s_object_name",
-			"workload",
-			"container_name",
-			"image_name",
-			"interval_start",
-			"interval_end",
-		)
-
-		aggregationMapping = map[string]dataframe.AggregationType{
-			"cpu_request_container_avg":      dataframe.Aggregation_MEAN,
-			"cpu_request_container_sum":      dataframe.Aggregation_SUM,
-			"cpu_limit_container_avg":        dataframe.Aggregation_MEAN,
-			"cpu_limit_container_sum":        dataframe.Aggregation_SUM,
-			"cpu_usage_container_avg":        dataframe.Aggregation_MEAN,
-			"cpu_usage_container_min":        dataframe.Aggregation_MIN,
-			"cpu_usage_container_max":        dataframe.Aggregation_MAX,
-			"cpu_usage_container_sum":        dataframe.Aggregation_SUM,
-			"cpu_throttle_container_avg":     dataframe.Aggregation_MEAN,
-			"cpu_throttle_container_max":     dataframe.Aggregation_MAX,
-			"cpu_throttle_container_sum":     dataframe.Aggregation_SUM,
-			"memory_request_container_avg":   dataframe.Aggregation_MEAN,
-			"memory_request_container_sum":   dataframe.Aggregation_SUM,
-			"memory_limit_container_avg":     dataframe.Aggregation_MEAN,
-			"memory_limit_container_sum":     dataframe.Aggregation_SUM,
-			"memory_usage_container_avg":     dataframe.Aggregation_MEAN,
-			"memory_usage_container_min":     dataframe.Aggregation_MIN,
-			"memory_usage_container_max":     dataframe.Aggregation_MAX,
-			"memory_usage_container_sum":     dataframe.Aggregation_SUM,
-			"memory_rss_usage_container_avg": dataframe.Aggregation_MEAN,
-			"memory_rss_usage_container_min": dataframe.Aggregation_MIN,
-			"memory_rss_usage_container_max": dataframe.Aggregation_MAX,
-			"memory_rss_usage_container_sum": dataframe.Aggregation_SUM,
-		}
+		dfGroups = df.GroupBy(containerGroupColumns...)
+		aggregationMapping = containerAggregationMapping
 	} else {
 		// namespace aggregation
-		dfGroups = df.GroupBy(
-			"namespace",
-			"interval_start",
-			"interval_end",
-		)
-
-		aggregationMapping = map[string]dataframe.AggregationType{
-			"cpu_request_namespace_sum":      dataframe.Aggregation_SUM,
-			"cpu_limit_namespace_sum":        dataframe.Aggregation_SUM,
-			"cpu_usage_namespace_avg":        dataframe.Aggregation_MEAN,
-			"cpu_usage_namespace_max":        dataframe.Aggregation_MAX,
-			"cpu_usage_namespace_min":        dataframe.Aggregation_MIN,
-			"cpu_throttle_namespace_avg":     dataframe.Aggregation_MEAN,
-			"cpu_throttle_namespace_max":     dataframe.Aggregation_MAX,
-			"cpu_throttle_namespace_min":     dataframe.Aggregation_MIN,
-			"memory_request_namespace_sum":   dataframe.Aggregation_SUM,
-			"memory_limit_namespace_sum":     dataframe.Aggregation_SUM,
-			"memory_usage_namespace_avg":     dataframe.Aggregation_MEAN,
-			"memory_usage_namespace_max":     dataframe.Aggregation_MAX,
-			"memory_usage_namespace_min":     dataframe.Aggregation_MIN,
-			"memory_rss_usage_namespace_avg": dataframe.Aggregation_MEAN,
-			"memory_rss_usage_namespace_max": dataframe.Aggregation_MAX,
-			"memory_rss_usage_namespace_min": dataframe.Aggregation_MIN,
-			"namespace_running_pods_max":     dataframe.Aggregation_MAX,
-			"namespace_running_pods_avg":     dataframe.Aggregation_MEAN,
-			"namespace_total_pods_max":       dataframe.Aggregation_MAX,
-			"namespace_total_pods_avg":       dataframe.Aggregation_MEAN,
-		}
-
+		dfGroups = df.GroupBy(namespaceGroupColumns...)
+		aggregationMapping = namespaceAggregationMapping
 	}
 
 	columnsToAggregate := []string{}
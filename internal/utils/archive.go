@@ -0,0 +1,129 @@
+package utils
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+)
+
+const archiveManifest = "manifest.json"
+
+// Manifest is the manifest.json the cost management operator packages with its reports.
+// ResourceOptimizationFiles are the ROS CSVs of the archive; Files are cost reports.
+type Manifest struct {
+	UUID                      string   `json:"uuid"`
+	ClusterID                 string   `json:"cluster_id"`
+	Version                   string   `json:"version"`
+	Files                     []string `json:"files"`
+	ResourceOptimizationFiles []string `json:"resource_optimization_files"`
+}
+
+// IsArchive reports whether the upload file at fileURL is a tar.gz payload archive rather than a CSV.
+func IsArchive(fileURL string) bool {
+	name := fileURL
+	if parsed, err := url.Parse(fileURL); err == nil && parsed.Path != "" {
+		name = parsed.Path
+	}
+	return strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz")
+}
+
+// Archive is a payload archive downloaded to a temporary file. Its files are read straight
+// from the archive, one at a time, and never extracted.
+type Archive struct {
+	path     string
+	Manifest Manifest
+}
+
+// OpenArchiveFromUrl downloads the archive at archiveURL, see FetchFile, and reads its
+// manifest. The caller closes the archive to remove the download.
+func OpenArchiveFromUrl(archiveURL string, checksum string) (*Archive, error) {
+	body, err := FetchFile(archiveURL, checksum)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = body.Close()
+	}()
+
+	f, err := os.CreateTemp("", "rosocp-archive-*.tar.gz")
+	if err != nil {
+		return nil, err
+	}
+	archive := &Archive{path: f.Name()}
+	_, err = io.Copy(f, &limitedReader{r: body, remaining: maxCSVFileSize()})
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = archive.readManifest()
+	}
+	if err != nil {
+		_ = archive.Close()
+		return nil, err
+	}
+	return archive, nil
+}
+
+func (a *Archive) readManifest() error {
+	manifest, err := a.Open(archiveManifest)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = manifest.Close()
+	}()
+	if err := json.NewDecoder(manifest).Decode(&a.Manifest); err != nil {
+		return fmt.Errorf("invalid archive manifest: %w", err)
+	}
+	return nil
+}
+
+// Open returns the content of the file called name in the archive, with the size limit and
+// decompression of NewCSVReader. The caller closes the returned reader.
+func (a *Archive) Open(name string) (io.ReadCloser, error) {
+	f, err := os.Open(a.path)
+	if err != nil {
+		return nil, err
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("unable to decompress archive: %w", err)
+	}
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			_ = f.Close()
+			return nil, fmt.Errorf("%s not found in archive", name)
+		}
+		if err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("unable to read archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg || path.Clean(header.Name) != name {
+			continue
+		}
+		content, err := NewCSVReader(reader, maxCSVFileSize())
+		if err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		return struct {
+			io.Reader
+			io.Closer
+		}{content, f}, nil
+	}
+}
+
+// Close removes the downloaded archive.
+func (a *Archive) Close() error {
+	return os.Remove(a.path)
+}
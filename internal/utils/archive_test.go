@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/types"
+)
+
+const sampleROSFile = "795eb333-370a-4653-aa40-0f7fd911b61b_openshift_usage_report.4.csv"
+
+func TestIsArchive(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://s3.example.com/bucket/payload.tar.gz?X-Amz-Signature=abc", true},
+		{"https://s3.example.com/bucket/payload.tgz", true},
+		{"https://s3.example.com/bucket/report.csv?name=payload.tar.gz", false},
+		{"https://s3.example.com/bucket/ros-ocp-usage.csv", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, IsArchive(tt.url), tt.url)
+	}
+}
+
+func serveSampleArchive(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "../../scripts/samples/cost-mgmt.tar.gz")
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestOpenArchiveFromUrl(t *testing.T) {
+	server := serveSampleArchive(t)
+
+	archive, err := OpenArchiveFromUrl(server.URL+"/cost-mgmt.tar.gz", "")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "023d9b0e-7ca6-481d-b04f-ea606becd54e", archive.Manifest.ClusterID)
+	assert.Equal(t, []string{sampleROSFile}, archive.Manifest.ResourceOptimizationFiles)
+
+	content, err := archive.Open(sampleROSFile)
+	if assert.NoError(t, err) {
+		df, err := StreamAggregate(types.PayloadTypeContainer, content)
+		assert.NoError(t, err)
+		assert.NotZero(t, df.Nrow())
+		assert.NoError(t, content.Close())
+	}
+
+	_, err = archive.Open("missing.csv")
+	assert.EqualError(t, err, "missing.csv not found in archive")
+
+	assert.NoError(t, archive.Close())
+	_, err = os.Stat(archive.path)
+	assert.True(t, os.IsNotExist(err), "the downloaded archive is removed")
+}
+
+func TestOpenArchiveFromUrlRejectsInvalidArchive(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("not an archive"))
+	}))
+	defer server.Close()
+
+	_, err := OpenArchiveFromUrl(server.URL+"/payload.tar.gz", "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,270 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"             //nolint:staticcheck
+	"github.com/aws/aws-sdk-go/aws/awserr"      //nolint:staticcheck
+	"github.com/aws/aws-sdk-go/aws/credentials" //nolint:staticcheck
+	"github.com/aws/aws-sdk-go/aws/session"     //nolint:staticcheck
+	"github.com/aws/aws-sdk-go/service/s3"      //nolint:staticcheck
+)
+
+// ErrChecksumMismatch is returned once a fetched file was read to the end and its content does
+// not match the checksum of the upload metadata.
+var ErrChecksumMismatch = errors.New("file content does not match its checksum")
+
+// Fetcher fetches the upload files of a URL scheme. Fetch gives up once ctx is done, also while
+// the returned content is read.
+type Fetcher interface {
+	Fetch(ctx context.Context, location *url.URL) (io.ReadCloser, error)
+}
+
+// fetchers maps the supported URL schemes to their fetcher. It is never written, so FetchFile
+// reads it without locking.
+var fetchers = map[string]Fetcher{
+	"http":  httpFetcher{},
+	"https": httpFetcher{},
+	"s3":    &s3Fetcher{},
+	"file":  fileFetcher{},
+}
+
+// permanentError is a fetch failure that retrying would not fix.
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+// FetchFile starts fetching the upload file at fileURL with the fetcher of its scheme. Failures
+// to start the download are retried up to FETCH_MAX_RETRIES times, doubling
+// FETCH_RETRY_BACKOFF_SECS between attempts, unless they are permanent like a missing file.
+// The whole download must complete within FETCH_TIMEOUT_SECS. When checksum is set, as
+// "sha256:<hex>" or a bare hex SHA-256, reading the content to the end fails with
+// ErrChecksumMismatch if it does not match. The caller closes the returned reader.
+func FetchFile(fileURL string, checksum string) (io.ReadCloser, error) {
+	location, err := url.Parse(fileURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file URL: %w", err)
+	}
+	scheme := strings.ToLower(location.Scheme)
+	fetcher, ok := fetchers[scheme]
+	if !ok {
+		return nil, fmt.Errorf("unsupported file URL scheme %q", location.Scheme)
+	}
+	want, err := parseChecksum(checksum)
+	if err != nil {
+		return nil, err
+	}
+
+	backoff := time.Duration(cfg.FetchRetryBackoffSecs) * time.Second
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.FetchTimeoutSecs)*time.Second)
+		body, err := fetcher.Fetch(ctx, location)
+		if err == nil {
+			fileFetches.WithLabelValues(scheme, "succeeded").Inc()
+			return newFetchedFile(body, cancel, want), nil
+		}
+		cancel()
+		var permanent permanentError
+		if errors.As(err, &permanent) || attempt >= cfg.FetchMaxRetries {
+			fileFetches.WithLabelValues(scheme, "failed").Inc()
+			return nil, err
+		}
+		fileFetches.WithLabelValues(scheme, "retried").Inc()
+		log.Warnf("unable to fetch %s://%s%s, retrying in %s: %v", location.Scheme, location.Host, location.Path, backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func parseChecksum(checksum string) ([]byte, error) {
+	if checksum == "" {
+		return nil, nil
+	}
+	algorithm, digest, found := strings.Cut(checksum, ":")
+	if !found {
+		algorithm, digest = "sha256", checksum
+	}
+	if !strings.EqualFold(algorithm, "sha256") {
+		return nil, fmt.Errorf("unsupported checksum algorithm %q", algorithm)
+	}
+	want, err := hex.DecodeString(digest)
+	if err != nil || len(want) != sha256.Size {
+		return nil, fmt.Errorf("invalid sha256 checksum %q", digest)
+	}
+	return want, nil
+}
+
+// fetchedFile is the content of a fetched file; closing it ends the fetch.
+type fetchedFile struct {
+	body   io.ReadCloser
+	cancel context.CancelFunc
+	hash   hash.Hash
+	want   []byte
+}
+
+func newFetchedFile(body io.ReadCloser, cancel context.CancelFunc, want []byte) *fetchedFile {
+	file := &fetchedFile{body: body, cancel: cancel, want: want}
+	if want != nil {
+		file.hash = sha256.New()
+	}
+	return file
+}
+
+func (f *fetchedFile) Read(p []byte) (int, error) {
+	n, err := f.body.Read(p)
+	if f.hash != nil {
+		f.hash.Write(p[:n])
+		if errors.Is(err, io.EOF) && !bytes.Equal(f.hash.Sum(nil), f.want) {
+			return n, ErrChecksumMismatch
+		}
+	}
+	return n, err
+}
+
+func (f *fetchedFile) Close() error {
+	defer f.cancel()
+	return f.body.Close()
+}
+
+// checkFileSize refuses files larger than CSV_MAX_FILE_SIZE_MB before downloading them; a
+// compressed file is never larger than its content.
+func checkFileSize(size int64) error {
+	if size > maxCSVFileSize() {
+		return permanentError{ErrCSVTooLarge}
+	}
+	return nil
+}
+
+type httpFetcher struct{}
+
+// retryableStatus reports whether a server answering with statusCode may serve the file later.
+func retryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
+
+func (httpFetcher) Fetch(ctx context.Context, location *url.URL) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location.String(), nil)
+	if err != nil {
+		return nil, permanentError{err}
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		_ = resp.Body.Close()
+		err := fmt.Errorf("unexpected status code %d when fetching %s://%s%s", resp.StatusCode, location.Scheme, location.Host, location.Path)
+		if retryableStatus(resp.StatusCode) {
+			return nil, err
+		}
+		return nil, permanentError{err}
+	}
+	if err := checkFileSize(resp.ContentLength); err != nil {
+		_ = resp.Body.Close()
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// s3Fetcher reads s3://bucket/key URLs with the S3_* config, creating its client on first use.
+type s3Fetcher struct {
+	once   sync.Once
+	client *s3.S3
+	err    error
+}
+
+func (f *s3Fetcher) s3Client() (*s3.S3, error) {
+	f.once.Do(func() {
+		awsconf := aws.NewConfig().WithRegion(cfg.S3Region)
+		if cfg.S3Endpoint != "" {
+			awsconf = awsconf.WithEndpoint(cfg.S3Endpoint).WithS3ForcePathStyle(true)
+		}
+		if cfg.S3AccessKey != "" {
+			awsconf = awsconf.WithCredentials(credentials.NewStaticCredentials(cfg.S3AccessKey, cfg.S3SecretKey, ""))
+		}
+		sess, err := session.NewSession(awsconf)
+		if err != nil {
+			f.err = fmt.Errorf("unable to create S3 session: %w", err)
+			return
+		}
+		f.client = s3.New(sess)
+	})
+	return f.client, f.err
+}
+
+func (f *s3Fetcher) Fetch(ctx context.Context, location *url.URL) (io.ReadCloser, error) {
+	client, err := f.s3Client()
+	if err != nil {
+		return nil, permanentError{err}
+	}
+	object, err := client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(location.Host),
+		Key:    aws.String(strings.TrimPrefix(location.Path, "/")),
+	})
+	if err != nil {
+		var requestErr awserr.RequestFailure
+		if errors.As(err, &requestErr) && !retryableStatus(requestErr.StatusCode()) {
+			return nil, permanentError{err}
+		}
+		return nil, err
+	}
+	if object.ContentLength != nil {
+		if err := checkFileSize(*object.ContentLength); err != nil {
+			_ = object.Body.Close()
+			return nil, err
+		}
+	}
+	return object.Body, nil
+}
+
+// fileFetcher reads file:// URLs from shared storage mounted at FETCH_FILE_ROOT. Files outside
+// of it, symbolic links included, cannot be read.
+type fileFetcher struct{}
+
+func (fileFetcher) Fetch(_ context.Context, location *url.URL) (io.ReadCloser, error) {
+	if cfg.FetchFileRoot == "" {
+		return nil, permanentError{errors.New("file URLs are disabled, FETCH_FILE_ROOT is not set")}
+	}
+	if location.Host != "" && location.Host != "localhost" {
+		return nil, permanentError{fmt.Errorf("file URL host %q is not local", location.Host)}
+	}
+	name, err := filepath.Rel(cfg.FetchFileRoot, filepath.Clean(location.Path))
+	if err != nil || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+		return nil, permanentError{fmt.Errorf("%s is outside of FETCH_FILE_ROOT", location.Path)}
+	}
+	f, err := os.OpenInRoot(cfg.FetchFileRoot, name)
+	if err != nil {
+		return nil, permanentError{err}
+	}
+	info, err := f.Stat()
+	if err == nil {
+		err = checkFileSize(info.Size())
+	}
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setFetchConfig(t *testing.T, maxRetries int, fileRoot string) {
+	t.Helper()
+	retries, backoff, root := cfg.FetchMaxRetries, cfg.FetchRetryBackoffSecs, cfg.FetchFileRoot
+	cfg.FetchMaxRetries, cfg.FetchRetryBackoffSecs, cfg.FetchFileRoot = maxRetries, 0, fileRoot
+	t.Cleanup(func() {
+		cfg.FetchMaxRetries, cfg.FetchRetryBackoffSecs, cfg.FetchFileRoot = retries, backoff, root
+	})
+}
+
+func sha256Hex(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestFetchFileOverHTTP(t *testing.T) {
+	setFetchConfig(t, 2, "")
+
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantErr      bool
+		wantAttempts int32
+	}{
+		{"fetched", []int{http.StatusOK}, false, 1},
+		{"retried after server error", []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK}, false, 3},
+		{"retries exhausted", []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway}, true, 3},
+		{"missing file is not retried", []int{http.StatusNotFound}, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempt := attempts.Add(1)
+				w.WriteHeader(tt.statuses[attempt-1])
+				_, _ = w.Write([]byte("a,b\n"))
+			}))
+			defer server.Close()
+
+			body, err := FetchFile(server.URL+"/upload.csv", "")
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.wantAttempts, attempts.Load())
+			if err == nil {
+				content, err := io.ReadAll(body)
+				assert.NoError(t, err)
+				assert.Equal(t, "a,b\n", string(content))
+				assert.NoError(t, body.Close())
+			}
+		})
+	}
+}
+
+func TestFetchFileVerifiesChecksum(t *testing.T) {
+	setFetchConfig(t, 0, "")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("a,b\n1,2\n"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		checksum string
+		wantErr  error
+	}{
+		{"no checksum", "", nil},
+		{"prefixed", "sha256:" + sha256Hex("a,b\n1,2\n"), nil},
+		{"bare hex", sha256Hex("a,b\n1,2\n"), nil},
+		{"mismatch", "sha256:" + sha256Hex("a,b\n"), ErrChecksumMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := FetchFile(server.URL+"/upload.csv", tt.checksum)
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer func() {
+				_ = body.Close()
+			}()
+			_, err = io.ReadAll(body)
+			assert.True(t, errors.Is(err, tt.wantErr), err)
+		})
+	}
+
+	_, err := FetchFile(server.URL+"/upload.csv", "md5:d41d8cd98f00b204e9800998ecf8427e")
+	assert.EqualError(t, err, `unsupported checksum algorithm "md5"`)
+	_, err = FetchFile(server.URL+"/upload.csv", "sha256:abc")
+	assert.EqualError(t, err, `invalid sha256 checksum "abc"`)
+}
+
+func TestFetchFileFromFileRoot(t *testing.T) {
+	root := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "upload.csv"), []byte("a,b\n"), 0o600))
+	outside := filepath.Join(t.TempDir(), "secret.csv")
+	assert.NoError(t, os.WriteFile(outside, []byte("secret"), 0o600))
+	assert.NoError(t, os.Symlink(outside, filepath.Join(root, "link.csv")))
+
+	setFetchConfig(t, 0, "")
+	_, err := FetchFile("file://"+filepath.Join(root, "upload.csv"), "")
+	assert.EqualError(t, err, "file URLs are disabled, FETCH_FILE_ROOT is not set")
+
+	setFetchConfig(t, 0, root)
+	body, err := FetchFile("file://"+filepath.Join(root, "upload.csv"), "")
+	if assert.NoError(t, err) {
+		content, err := io.ReadAll(body)
+		assert.NoError(t, err)
+		assert.Equal(t, "a,b\n", string(content))
+		assert.NoError(t, body.Close())
+	}
+
+	for _, path := range []string{outside, filepath.Join(root, "..", filepath.Base(filepath.Dir(outside)), "secret.csv"), filepath.Join(root, "link.csv")} {
+		_, err = FetchFile("file://"+path, "")
+		assert.Error(t, err, path)
+	}
+	_, err = FetchFile("file://"+filepath.Join(root, "missing.csv"), "")
+	assert.True(t, errors.Is(err, os.ErrNotExist), err)
+}
+
+func TestFetchFileUnsupportedScheme(t *testing.T) {
+	_, err := FetchFile("ftp://example.com/upload.csv", "")
+	assert.EqualError(t, err, `unsupported file URL scheme "ftp"`)
+}
@@ -0,0 +1,179 @@
+package kruize
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/types/kruizePayload"
+	namespacePayload "github.com/redhatinsights/ros-ocp-backend/internal/types/kruizePayload/namespace"
+)
+
+// ErrCircuitOpen is returned without calling Kruize while the breaker is open. It matches
+// ErrUnavailable.
+var ErrCircuitOpen = fmt.Errorf("%w: circuit breaker is open", ErrUnavailable)
+
+// Breaker is a KruizeClient that stops calling Kruize once threshold calls in a row found it
+// unavailable. While open, every call fails with ErrCircuitOpen until a health probe, at most
+// every probeInterval, finds Kruize healthy again.
+type Breaker struct {
+	KruizeClient
+	threshold     int
+	probeInterval time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	open      bool
+	nextProbe time.Time
+	probing   bool
+}
+
+// NewBreaker returns a breaker around client. A threshold below one never opens it.
+func NewBreaker(client KruizeClient, threshold int, probeInterval time.Duration) *Breaker {
+	kruizeBreakerOpen.Set(0)
+	return &Breaker{KruizeClient: client, threshold: threshold, probeInterval: probeInterval}
+}
+
+// NewDefaultBreaker returns a breaker around the client of NewDefaultKruizeClient, configured by
+// KRUIZE_BREAKER_THRESHOLD and KRUIZE_HEALTH_PROBE_SECS.
+func NewDefaultBreaker() *Breaker {
+	probeInterval := time.Duration(max(cfg.KruizeHealthProbeSecs, 1)) * time.Second
+	return NewBreaker(NewDefaultKruizeClient(), cfg.KruizeBreakerThreshold, probeInterval)
+}
+
+// Blocked reports whether the breaker is open. While it is, Blocked probes Kruize's health when a
+// probe is due and closes the breaker once Kruize is healthy.
+func (b *Breaker) Blocked() bool {
+	b.mu.Lock()
+	if !b.open || b.probing || time.Now().Before(b.nextProbe) {
+		open := b.open
+		b.mu.Unlock()
+		return open
+	}
+	b.probing = true
+	b.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), b.probeInterval)
+	defer cancel()
+	err := b.KruizeClient.Health(ctx)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if err != nil {
+		log.Warnf("kruize is still unavailable: %v", err)
+		b.nextProbe = time.Now().Add(b.probeInterval)
+		return true
+	}
+	log.Info("kruize is healthy again, closing the circuit breaker")
+	b.open = false
+	b.failures = 0
+	kruizeBreakerOpen.Set(0)
+	return false
+}
+
+// allow returns ErrCircuitOpen while the breaker is open.
+func (b *Breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.open {
+		return ErrCircuitOpen
+	}
+	return nil
+}
+
+// record counts err towards opening the breaker when it shows Kruize unavailable, and resets the
+// count otherwise. Calls the caller cancelled are not counted either way.
+func (b *Breaker) record(err error) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !errors.Is(err, ErrUnavailable) {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.open || b.threshold < 1 || b.failures < b.threshold {
+		return
+	}
+	log.Errorf("kruize failed %d calls in a row, opening the circuit breaker: %v", b.failures, err)
+	b.open = true
+	b.nextProbe = time.Now().Add(b.probeInterval)
+	kruizeBreakerOpen.Set(1)
+}
+
+func (b *Breaker) CreateExperiment(ctx context.Context, experiment_name string, cluster_identifier string, k8s_object []map[string]interface{}) ([]string, error) {
+	if err := b.allow(); err != nil {
+		return nil, err
+	}
+	containers, err := b.KruizeClient.CreateExperiment(ctx, experiment_name, cluster_identifier, k8s_object)
+	b.record(err)
+	return containers, err
+}
+
+func (b *Breaker) CreateNamespaceExperiment(ctx context.Context, experiment_name string, cluster_identifier string, namespace string) error {
+	if err := b.allow(); err != nil {
+		return err
+	}
+	err := b.KruizeClient.CreateNamespaceExperiment(ctx, experiment_name, cluster_identifier, namespace)
+	b.record(err)
+	return err
+}
+
+func (b *Breaker) UpdateResults(ctx context.Context, experiment_name string, results []kruizePayload.UpdateResult) ([]kruizePayload.UpdateResult, error) {
+	if err := b.allow(); err != nil {
+		return nil, err
+	}
+	accepted, err := b.KruizeClient.UpdateResults(ctx, experiment_name, results)
+	b.record(err)
+	return accepted, err
+}
+
+func (b *Breaker) UpdateNamespaceResults(ctx context.Context, experiment_name string, results []namespacePayload.UpdateNamespaceResult) ([]namespacePayload.UpdateNamespaceResult, error) {
+	if err := b.allow(); err != nil {
+		return nil, err
+	}
+	accepted, err := b.KruizeClient.UpdateNamespaceResults(ctx, experiment_name, results)
+	b.record(err)
+	return accepted, err
+}
+
+func (b *Breaker) UpdateRecommendations(ctx context.Context, experiment_name string, interval_end_time time.Time) ([]kruizePayload.ListRecommendations, error) {
+	if err := b.allow(); err != nil {
+		return nil, err
+	}
+	recommendations, err := b.KruizeClient.UpdateRecommendations(ctx, experiment_name, interval_end_time)
+	b.record(err)
+	return recommendations, err
+}
+
+func (b *Breaker) UpdateNamespaceRecommendations(ctx context.Context, experiment_name string, interval_end_time time.Time) (namespacePayload.NamespaceRecommendationResponse, error) {
+	if err := b.allow(); err != nil {
+		return nil, err
+	}
+	recommendations, err := b.KruizeClient.UpdateNamespaceRecommendations(ctx, experiment_name, interval_end_time)
+	b.record(err)
+	return recommendations, err
+}
+
+func (b *Breaker) DeleteExperiment(ctx context.Context, experiment_name string) error {
+	if err := b.allow(); err != nil {
+		return err
+	}
+	err := b.KruizeClient.DeleteExperiment(ctx, experiment_name)
+	b.record(err)
+	return err
+}
+
+func (b *Breaker) ListExperiments(ctx context.Context) ([]Experiment, error) {
+	if err := b.allow(); err != nil {
+		return nil, err
+	}
+	experiments, err := b.KruizeClient.ListExperiments(ctx)
+	b.record(err)
+	return experiments, err
+}
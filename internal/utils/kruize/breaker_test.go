@@ -0,0 +1,74 @@
+package kruize_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/utils/kruize"
+	"github.com/redhatinsights/ros-ocp-backend/internal/utils/kruize/kruizetest"
+)
+
+func TestBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	server := kruizetest.NewServer()
+	defer server.Close()
+	breaker := kruize.NewBreaker(server.Client(), 3, 20*time.Millisecond)
+	ctx := context.Background()
+
+	server.Fail(kruize.KruizeCreateExperiment, http.StatusServiceUnavailable)
+	for i := 0; i < 2; i++ {
+		assert.ErrorIs(t, breaker.CreateNamespaceExperiment(ctx, "experiment", "org;cluster", "ns"), kruize.ErrUnavailable)
+	}
+	assert.False(t, breaker.Blocked())
+
+	// a call Kruize answers, even with an error, resets the count
+	_, err := breaker.UpdateRecommendations(ctx, "missing", time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC))
+	assert.NotErrorIs(t, err, kruize.ErrUnavailable)
+	for i := 0; i < 2; i++ {
+		assert.Error(t, breaker.CreateNamespaceExperiment(ctx, "experiment", "org;cluster", "ns"))
+	}
+	assert.False(t, breaker.Blocked())
+
+	assert.Error(t, breaker.CreateNamespaceExperiment(ctx, "experiment", "org;cluster", "ns"))
+	assert.True(t, breaker.Blocked())
+
+	server.Recover()
+	err = breaker.CreateNamespaceExperiment(ctx, "experiment", "org;cluster", "ns")
+	assert.ErrorIs(t, err, kruize.ErrCircuitOpen, "calls fail fast while the breaker is open")
+	assert.ErrorIs(t, err, kruize.ErrUnavailable)
+	assert.Empty(t, server.Experiments())
+}
+
+func TestBreakerClosesOnceKruizeIsHealthy(t *testing.T) {
+	server := kruizetest.NewServer()
+	defer server.Close()
+	breaker := kruize.NewBreaker(server.Client(), 1, 20*time.Millisecond)
+	ctx := context.Background()
+
+	server.Fail(kruize.KruizeCreateExperiment, http.StatusInternalServerError)
+	server.Fail(kruize.KruizeHealth, http.StatusServiceUnavailable)
+	assert.Error(t, breaker.CreateNamespaceExperiment(ctx, "experiment", "org;cluster", "ns"))
+	assert.True(t, breaker.Blocked())
+
+	time.Sleep(30 * time.Millisecond)
+	assert.True(t, breaker.Blocked(), "the health probe fails")
+
+	server.Recover()
+	assert.Eventually(t, func() bool { return !breaker.Blocked() }, time.Second, 10*time.Millisecond)
+	assert.NoError(t, breaker.CreateNamespaceExperiment(ctx, "experiment", "org;cluster", "ns"))
+	assert.Equal(t, []string{"experiment"}, server.Experiments())
+}
+
+func TestBreakerIgnoresCancelledCalls(t *testing.T) {
+	server := kruizetest.NewServer()
+	defer server.Close()
+	breaker := kruize.NewBreaker(server.Client(), 1, time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, breaker.CreateNamespaceExperiment(ctx, "experiment", "org;cluster", "ns"), context.Canceled)
+	assert.False(t, breaker.Blocked())
+}
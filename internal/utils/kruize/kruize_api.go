@@ -2,11 +2,16 @@ package kruize
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/redhatinsights/ros-ocp-backend/internal/config"
@@ -19,18 +24,225 @@ import (
 )
 
 var (
-	log                     *logrus.Entry  = logging.GetLogger()
-	cfg                     *config.Config = config.GetConfig()
-	experimentCreateAttempt bool           = true
+	log *logrus.Entry  = logging.GetLogger()
+	cfg *config.Config = config.GetConfig()
+
+	// performanceProfileSetup keeps concurrent uploads from recreating the performance profile at once
+	performanceProfileSetup sync.Mutex
 )
 
 const (
 	KruizeCreateExperiment      string = "/createExperiment"
 	KruizeUpdateResults         string = "/updateResults"
 	KruizeUpdateRecommendations string = "/updateRecommendations"
+	// KruizeDeleteExperiment labels the DELETE calls of /createExperiment.
+	KruizeDeleteExperiment string = "/deleteExperiment"
+	KruizeHealth           string = "/health"
+	KruizeListExperiments  string = "/listExperiments"
+
+	KruizeListPerformanceProfiles  string = "/listPerformanceProfiles"
+	KruizeCreatePerformanceProfile string = "/createPerformanceProfile"
+	KruizeUpdatePerformanceProfile string = "/updatePerformanceProfile"
 )
 
-func Create_kruize_experiments(experiment_name string, cluster_identifier string, k8s_object []map[string]interface{}) ([]string, error) {
+// ErrUnavailable matches the errors of calls Kruize could not serve: it could not be reached, did
+// not answer in time, or answered with a server error.
+var ErrUnavailable = errors.New("kruize is unavailable")
+
+// unavailableError is an error of a call Kruize could not serve.
+type unavailableError struct {
+	err error
+}
+
+func (e *unavailableError) Error() string        { return e.err.Error() }
+func (e *unavailableError) Unwrap() error        { return e.err }
+func (e *unavailableError) Is(target error) bool { return target == ErrUnavailable }
+
+// KruizeClient is the Kruize API used by the processor, the poller and the housekeeper.
+type KruizeClient interface {
+	// CreateExperiment creates the experiment of the k8s_object rows of a workload and returns
+	// the names of its containers. An existing experiment is not an error.
+	CreateExperiment(ctx context.Context, experiment_name string, cluster_identifier string, k8s_object []map[string]interface{}) ([]string, error)
+	CreateNamespaceExperiment(ctx context.Context, experiment_name string, cluster_identifier string, namespace string) error
+	// UpdateResults sends usage data to an experiment and returns the results Kruize accepted.
+	UpdateResults(ctx context.Context, experiment_name string, results []kruizePayload.UpdateResult) ([]kruizePayload.UpdateResult, error)
+	UpdateNamespaceResults(ctx context.Context, experiment_name string, results []namespacePayload.UpdateNamespaceResult) ([]namespacePayload.UpdateNamespaceResult, error)
+	// UpdateRecommendations generates the recommendations of an experiment up to interval_end_time.
+	UpdateRecommendations(ctx context.Context, experiment_name string, interval_end_time time.Time) ([]kruizePayload.ListRecommendations, error)
+	UpdateNamespaceRecommendations(ctx context.Context, experiment_name string, interval_end_time time.Time) (namespacePayload.NamespaceRecommendationResponse, error)
+	DeleteExperiment(ctx context.Context, experiment_name string) error
+	// ListExperiments returns every experiment Kruize has, without results or recommendations.
+	ListExperiments(ctx context.Context) ([]Experiment, error)
+	// Health returns an error unless Kruize reports itself healthy.
+	Health(ctx context.Context) error
+	// SetupPerformanceProfile creates or updates the performance profile the experiments use.
+	SetupPerformanceProfile()
+}
+
+// Experiment is an experiment listed by Kruize.
+type Experiment struct {
+	Name        string `json:"experiment_name"`
+	ClusterName string `json:"cluster_name"`
+	// Type is "namespace" or "container"; older Kruize versions leave it empty for containers.
+	Type string `json:"experiment_type"`
+}
+
+// Timeouts bounds the calls to each Kruize endpoint; zero leaves them unbounded.
+type Timeouts struct {
+	CreateExperiment      time.Duration
+	UpdateResults         time.Duration
+	UpdateRecommendations time.Duration
+	DeleteExperiment      time.Duration
+	ListExperiments       time.Duration
+}
+
+// client is the KruizeClient of the Kruize API at baseURL.
+type client struct {
+	baseURL  string
+	http     *http.Client
+	timeouts Timeouts
+}
+
+// NewKruizeClient returns a client of the Kruize API at baseURL.
+func NewKruizeClient(baseURL string, timeouts Timeouts) KruizeClient {
+	return &client{baseURL: strings.TrimSuffix(baseURL, "/"), http: &http.Client{}, timeouts: timeouts}
+}
+
+// NewDefaultKruizeClient returns a client of the Kruize API at KRUIZE_URL, with the timeouts of the
+// KRUIZE_*_TIMEOUT_SECS settings.
+func NewDefaultKruizeClient() KruizeClient {
+	seconds := func(secs int) time.Duration {
+		return time.Duration(max(secs, 0)) * time.Second
+	}
+	return NewKruizeClient(cfg.KruizeUrl, Timeouts{
+		CreateExperiment:      seconds(cfg.KruizeCreateExperimentTimeoutSecs),
+		UpdateResults:         seconds(cfg.KruizeUpdateResultsTimeoutSecs),
+		UpdateRecommendations: seconds(cfg.KruizeUpdateRecommendationsTimeoutSecs),
+		DeleteExperiment:      seconds(cfg.KruizeDeleteExperimentTimeoutSecs),
+		ListExperiments:       seconds(cfg.KruizeListExperimentsTimeoutSecs),
+	})
+}
+
+// call sends a request to path and returns the status code and body of the response. When Kruize
+// cannot be reached or answers with a server error, the error matches ErrUnavailable. endpoint
+// labels the latency and exception metrics.
+func (c *client) call(ctx context.Context, endpoint string, timeout time.Duration, method string, path string, query url.Values, body []byte) (status int, resBody []byte, err error) {
+	start := time.Now()
+	defer func() {
+		statusLabel := "error"
+		if status != 0 {
+			statusLabel = strconv.Itoa(status)
+		}
+		kruizeAPIDuration.WithLabelValues(endpoint, statusLabel).Observe(time.Since(start).Seconds())
+	}()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	requestURL := c.baseURL + path
+	if len(query) > 0 {
+		requestURL += "?" + query.Encode()
+	}
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, reqBody)
+	if err != nil {
+		return 0, nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	res, err := c.http.Do(req)
+	if err != nil {
+		kruizeAPIException.WithLabelValues(endpoint).Inc()
+		return 0, nil, &unavailableError{err}
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+	resBody, err = io.ReadAll(res.Body)
+	if err != nil {
+		kruizeAPIException.WithLabelValues(endpoint).Inc()
+		return 0, nil, &unavailableError{err}
+	}
+	if res.StatusCode >= http.StatusInternalServerError {
+		kruizeAPIException.WithLabelValues(endpoint).Inc()
+		message, err := responseMessage(resBody)
+		if err != nil || message == "" {
+			message = fmt.Sprintf("unexpected status code %d", res.StatusCode)
+		}
+		return res.StatusCode, resBody, &unavailableError{errors.New(message)}
+	}
+	return res.StatusCode, resBody, nil
+}
+
+// responseMessage is the message of a Kruize error response.
+func responseMessage(body []byte) (string, error) {
+	resdata := struct {
+		Message string `json:"message"`
+	}{}
+	if err := json.Unmarshal(body, &resdata); err != nil {
+		return "", fmt.Errorf("can not unmarshal response data: %v", err)
+	}
+	return resdata.Message, nil
+}
+
+// createExperiment posts an experiment creation payload; when setupProfile is set, a missing
+// performance profile is created and the experiment is created once more.
+func (c *client) createExperiment(ctx context.Context, postBody []byte, setupProfile bool) error {
+	status, body, err := c.call(ctx, KruizeCreateExperiment, c.timeouts.CreateExperiment, http.MethodPost, KruizeCreateExperiment, nil, postBody)
+	if err != nil {
+		return fmt.Errorf("error Occured while creating experiment: %w", err)
+	}
+	log.Debugf("experiment creation response: %s", string(body))
+	if status == http.StatusCreated {
+		return nil
+	}
+	message, err := responseMessage(body)
+	if err != nil {
+		return err
+	}
+
+	// Temporary fix
+	// Currently, once Kruize pod inits it does not load performance-profile from DB
+	if strings.Contains(message, "Performance Profile doesn't exist") && setupProfile {
+		log.Error("Performance profile does not exist")
+		log.Info("Trying to create resource_optimization_openshift performance profile")
+		c.SetupPerformanceProfile()
+		// Attempting only once
+		return c.createExperiment(ctx, postBody, false)
+	}
+
+	if strings.Contains(message, "Experiment name already exists") {
+		log.Debug("Experiment already exist")
+		return nil
+	}
+	return fmt.Errorf("%s", message)
+}
+
+func (c *client) CreateExperiment(ctx context.Context, experiment_name string, cluster_identifier string, k8s_object []map[string]interface{}) ([]string, error) {
+	data, containers := ExperimentObject(k8s_object)
+	payload, err := kruizePayload.GetCreateExperimentPayload(experiment_name, cluster_identifier, containers, data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create payload: %v", err)
+	}
+	createExperimentRequest.Inc()
+	if err := c.createExperiment(ctx, payload, true); err != nil {
+		return nil, err
+	}
+	container_names := make([]string, 0, len(containers))
+	for _, value := range containers {
+		container_names = append(container_names, value["container_name"])
+	}
+	return container_names, nil
+}
+
+// ExperimentObject returns the Kubernetes object of the k8s_object rows and its distinct
+// containers, as GetCreateExperimentPayload takes them.
+func ExperimentObject(k8s_object []map[string]interface{}) (map[string]string, []map[string]string) {
 	// k8s_object (can) contain multiple containers of same k8s object type.
 	data := map[string]string{
 		"namespace":       kruizePayload.AssertAndConvertToString(k8s_object[0]["namespace"]),
@@ -49,268 +261,175 @@ func Create_kruize_experiments(experiment_name string, cluster_identifier string
 			})
 		}
 	}
-	payload, err := kruizePayload.GetCreateExperimentPayload(experiment_name, cluster_identifier, containers, data)
-	if err != nil {
-		return nil, fmt.Errorf("unable to create payload: %v", err)
-	}
-	// Create experiment in kruize
-	url := cfg.KruizeUrl + KruizeCreateExperiment
-	res, err := utils.HTTPClient.Post(url, "application/json", bytes.NewBuffer(payload))
-	if err != nil {
-		kruizeAPIException.WithLabelValues(KruizeCreateExperiment).Inc()
-		return nil, fmt.Errorf("error Occured while creating experiment: %v", err)
-	}
-	createExperimentRequest.Inc()
-	if res.StatusCode != 201 {
-		defer func() {
-			_ = res.Body.Close()
-		}()
-		body, _ := io.ReadAll(res.Body)
-		resdata := map[string]interface{}{}
-		if err := json.Unmarshal(body, &resdata); err != nil {
-			return nil, fmt.Errorf("can not unmarshal response data: %v", err)
-		}
-
-		// Temporary fix
-		// Currently, once Kruize pod inits it does not load performance-profile from DB
-		if strings.Contains(resdata["message"].(string), "Performance Profile doesn't exist") && experimentCreateAttempt {
-			log.Error("Performance profile does not exist")
-			log.Info("Tring to create resource_optimization_openshift performance profile")
-			utils.SetupKruizePerformanceProfile()
-			experimentCreateAttempt = false // Attempting only once
-			container_names, err := Create_kruize_experiments(experiment_name, cluster_identifier, k8s_object)
-			experimentCreateAttempt = true
-			if err != nil {
-				return nil, err
-			} else {
-				return container_names, nil
-			}
-		}
-
-		if strings.Contains(resdata["message"].(string), "Experiment name already exists") {
-			log.Debug("Experiment already exist")
-		} else {
-			return nil, fmt.Errorf("%s", resdata["message"])
-		}
-	}
-	container_names := make([]string, 0, len(containers))
-	for _, value := range containers {
-		container_names = append(container_names, value["container_name"])
-	}
-
-	return container_names, nil
+	return data, containers
 }
 
-func CreateNamespaceExperiment(experiment_name string, cluster_identifier string, namespace string) error {
-
+func (c *client) CreateNamespaceExperiment(ctx context.Context, experiment_name string, cluster_identifier string, namespace string) error {
 	payload := namespacePayload.GetCreateNamespaceExperimentPayload(experiment_name, cluster_identifier, namespace)
-
 	postBody, err := json.Marshal(payload)
 	if err != nil {
 		return fmt.Errorf("unable to create payload: %v", err)
 	}
 	log.Debugf("creating namespace experiment with payload: %s", string(postBody))
+	createNamespaceExperimentRequest.Inc()
+	return c.createExperiment(ctx, postBody, true)
+}
 
-	url := cfg.KruizeUrl + KruizeCreateExperiment
-	res, err := utils.HTTPClient.Post(url, "application/json", bytes.NewBuffer(postBody))
+// updateResults posts usage data; when setupProfile is set, a missing performance profile is
+// created and the data is sent once more. Results Kruize already has are not errors.
+func (c *client) updateResults(ctx context.Context, postBody []byte, setupProfile bool) error {
+	log.Debugf("\n Sending /updateResult request to kruize with payload - %s \n", string(postBody))
+	status, body, err := c.call(ctx, KruizeUpdateResults, c.timeouts.UpdateResults, http.MethodPost, KruizeUpdateResults, nil, postBody)
 	if err != nil {
-		kruizeAPIException.WithLabelValues(KruizeCreateExperiment).Inc()
-		return fmt.Errorf("error occured while creating namespace experiment: %v", err)
+		return fmt.Errorf("an Error Occured while sending metrics: %w", err)
+	}
+	log.Debugf("\n Response from API /updateResult - %s \n", string(body))
+	if status == http.StatusCreated {
+		return nil
+	}
+	resdata := kruizePayload.UpdateResultResponse{}
+	if err := json.Unmarshal(body, &resdata); err != nil {
+		return fmt.Errorf("can not unmarshal response data: %v", err)
 	}
-	createNamespaceExperimentRequest.Inc()
-
-	defer func() {
-		_ = res.Body.Close()
-	}()
-	body, _ := io.ReadAll(res.Body)
-
-	log.Debugf("namespace experiment creation response: %s", string(body))
-
-	if res.StatusCode != 201 {
-		resdata := map[string]any{}
-		if err := json.Unmarshal(body, &resdata); err != nil {
-			return fmt.Errorf("can not unmarshal response data: %v", err)
-		}
 
-		// Temporary fix: performance profile not loaded on Kruize init
-		if strings.Contains(resdata["message"].(string), "Performance Profile doesn't exist") && experimentCreateAttempt {
-			log.Error("Performance profile does not exist")
-			log.Info("Trying to create resource_optimization_openshift performance profile")
-			utils.SetupKruizePerformanceProfile()
-			experimentCreateAttempt = false
-			err := CreateNamespaceExperiment(experiment_name, cluster_identifier, namespace)
-			experimentCreateAttempt = true
-			return err
-		}
+	// Comparing string should be changed once kruize fix it some standard error message
+	if strings.Contains(resdata.Message, "because \"performanceProfile\" is null") && setupProfile {
+		log.Error("Performance profile does not exist")
+		log.Info("Trying to create resource_optimization_openshift performance profile")
+		c.SetupPerformanceProfile()
+		return c.updateResults(ctx, postBody, false)
+	}
 
-		if strings.Contains(resdata["message"].(string), "Experiment name already exists") {
-			log.Debug("Namespace experiment already exists")
-		} else {
-			return fmt.Errorf("%s", resdata["message"])
+	for _, data := range resdata.Data {
+		if len(data.Errors) > 0 && data.Errors[0].Message != "An entry for this record already exists!" {
+			log.Error(data.Errors[0].Message)
 		}
 	}
-
 	return nil
 }
 
-func Update_results(experiment_name string, payload_data []kruizePayload.UpdateResult) ([]kruizePayload.UpdateResult, error) {
-	postBody, err := json.Marshal(payload_data)
+func (c *client) UpdateResults(ctx context.Context, experiment_name string, results []kruizePayload.UpdateResult) ([]kruizePayload.UpdateResult, error) {
+	postBody, err := json.Marshal(results)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create payload: %v", err)
 	}
-
-	// TODO(FLPATH-3407): use a bounded client once we have Prometheus latency data for /updateResults
-	url := cfg.KruizeUrl + KruizeUpdateResults
-	log.Debugf("\n Sending /updateResult request to kruize with payload - %s \n", string(postBody))
-	res, err := http.Post(url, "application/json", bytes.NewBuffer(postBody)) //nolint:gosec // URL from trusted config
-	if err != nil {
-		kruizeAPIException.WithLabelValues(KruizeUpdateResults).Inc()
-		return nil, fmt.Errorf("an Error Occured while sending metrics: %v", err)
-	}
 	updateResultRequest.Inc()
-	defer func() {
-		_ = res.Body.Close()
-	}()
-	body, _ := io.ReadAll(res.Body)
-	log.Debugf("\n Respose from API /updateResult - %s \n", string(body))
-	if res.StatusCode != 201 {
-		resdata := kruizePayload.UpdateResultResponse{}
-		if err := json.Unmarshal(body, &resdata); err != nil {
-			return nil, fmt.Errorf("can not unmarshal response data: %v", err)
-		}
-
-		// Comparing string should be changed once kruize fix it some standard error message
-		if strings.Contains(resdata.Message, "because \"performanceProfile\" is null") {
-			log.Error("Performance profile does not exist")
-			log.Info("Tring to create resource_optimization_openshift performance profile")
-			utils.SetupKruizePerformanceProfile()
-			if payload_data, err := Update_results(experiment_name, payload_data); err != nil {
-				return nil, err
-			} else {
-				return payload_data, nil
-			}
-		}
-
-		if len(resdata.Data) > 0 {
-			for _, err := range resdata.Data {
-				if err.Errors[0].Message == "An entry for this record already exists!" {
-					continue
-				} else {
-					log.Error(err.Errors[0].Message)
-				}
-			}
-		}
+	if err := c.updateResults(ctx, postBody, true); err != nil {
+		return nil, err
 	}
-
-	return payload_data, nil
+	return results, nil
 }
 
-func UpdateNamespaceResults(experiment_name string, payload_data []namespacePayload.UpdateNamespaceResult) ([]namespacePayload.UpdateNamespaceResult, error) {
-	postBody, err := json.Marshal(payload_data)
+func (c *client) UpdateNamespaceResults(ctx context.Context, experiment_name string, results []namespacePayload.UpdateNamespaceResult) ([]namespacePayload.UpdateNamespaceResult, error) {
+	postBody, err := json.Marshal(results)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create payload: %v", err)
 	}
+	updateNamespaceResultRequest.Inc()
+	if err := c.updateResults(ctx, postBody, true); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
 
-	// TODO(FLPATH-3407): use a bounded client once we have Prometheus latency data for /updateResults
-	url := cfg.KruizeUrl + KruizeUpdateResults
-	log.Debugf("\n Sending /updateResult request to kruize with namespace payload - %s \n", string(postBody))
-	res, err := http.Post(url, "application/json", bytes.NewBuffer(postBody)) //nolint:gosec // URL from trusted config
+// updateRecommendations calls /updateRecommendations and decodes its response into response.
+func (c *client) updateRecommendations(ctx context.Context, experiment_name string, interval_end_time time.Time, response any) error {
+	endTimeISO, err := utils.ConvertDateToISO8601(interval_end_time.String())
 	if err != nil {
-		kruizeAPIException.WithLabelValues(KruizeUpdateResults).Inc()
-		return nil, fmt.Errorf("an Error Occured while sending namespace metrics: %v", err)
+		return fmt.Errorf("unable to format interval_end_time for /updateRecommendations: %w", err)
 	}
-	updateNamespaceResultRequest.Inc()
-	defer func() {
-		_ = res.Body.Close()
-	}()
-	body, _ := io.ReadAll(res.Body)
-	log.Debugf("\n Response from API /updateResult - %s \n", string(body))
-	if res.StatusCode != 201 {
-		resdata := namespacePayload.UpdateNamespaceResultResponse{}
-		if err := json.Unmarshal(body, &resdata); err != nil {
-			return nil, fmt.Errorf("can not unmarshal response data: %v", err)
-		}
-
-		// Comparing string should be changed once kruize fix it some standard error message
-		if strings.Contains(resdata.Message, "because \"performanceProfile\" is null") {
-			log.Error("Performance profile does not exist")
-			log.Info("Trying to create resource_optimization_openshift performance profile")
-			utils.SetupKruizePerformanceProfile()
-			if payload_data, err := UpdateNamespaceResults(experiment_name, payload_data); err != nil {
-				return nil, err
-			} else {
-				return payload_data, nil
-			}
+	query := url.Values{}
+	query.Add("experiment_name", experiment_name)
+	query.Add("interval_end_time", endTimeISO)
+	log.Debugf("\n Sending /updateRecommendations request to kruize - %s \n", query)
+	status, body, err := c.call(ctx, KruizeUpdateRecommendations, c.timeouts.UpdateRecommendations, http.MethodPost, KruizeUpdateRecommendations, query, nil)
+	if err != nil {
+		return fmt.Errorf("error Occured while calling /updateRecommendations API %w", err)
+	}
+	log.Debugf("\nResponse from /updateRecommendations - %s \n", string(body))
+	if status == http.StatusBadRequest {
+		message, err := responseMessage(body)
+		if err != nil {
+			return fmt.Errorf("unable to unmarshal response of /updateRecommendations API %v", err)
 		}
+		return fmt.Errorf("%s", message)
+	}
+	if err := json.Unmarshal(body, response); err != nil {
+		return fmt.Errorf("unable to unmarshal response of /updateRecommendations API %v", err)
+	}
+	return nil
+}
 
-		if len(resdata.Data) > 0 {
-			for _, err := range resdata.Data {
-				if len(err.Errors) > 0 {
-					if err.Errors[0].Message == "An entry for this record already exists!" {
-						continue
-					} else {
-						log.Error(err.Errors[0].Message)
-					}
-				}
-			}
-		}
+func (c *client) UpdateRecommendations(ctx context.Context, experiment_name string, interval_end_time time.Time) ([]kruizePayload.ListRecommendations, error) {
+	var response []kruizePayload.ListRecommendations
+	if err := c.updateRecommendations(ctx, experiment_name, interval_end_time, &response); err != nil {
+		return nil, err
 	}
+	return response, nil
+}
 
-	return payload_data, nil
+func (c *client) UpdateNamespaceRecommendations(ctx context.Context, experiment_name string, interval_end_time time.Time) (namespacePayload.NamespaceRecommendationResponse, error) {
+	var response namespacePayload.NamespaceRecommendationResponse
+	if err := c.updateRecommendations(ctx, experiment_name, interval_end_time, &response); err != nil {
+		return nil, err
+	}
+	return response, nil
 }
 
-func Update_recommendations(experiment_name string, interval_end_time time.Time, experimentType types.PayloadType) (any, error) {
-	url := cfg.KruizeUrl + KruizeUpdateRecommendations
-	req, err := http.NewRequest("POST", url, nil)
+func (c *client) DeleteExperiment(ctx context.Context, experiment_name string) error {
+	payload, err := json.Marshal([]map[string]string{
+		{"experiment_name": experiment_name},
+	})
 	if err != nil {
-		return nil, fmt.Errorf("an Error Occured %v", err)
+		return fmt.Errorf("unable to create payload: %v", err)
 	}
-	q := req.URL.Query()
-	q.Add("experiment_name", experiment_name)
-	endTimeISO, err := utils.ConvertDateToISO8601(interval_end_time.String())
-	if err != nil {
-		return nil, fmt.Errorf("unable to format interval_end_time for /updateRecommendations: %w", err)
-	}
-	q.Add("interval_end_time", endTimeISO)
-	req.URL.RawQuery = q.Encode()
-	log.Debugf("\n Sending /updateRecommendations request to kruize - %s \n", q)
-	// TODO(FLPATH-3407): use a bounded client once we have Prometheus latency data for /updateRecommendations
-	client := &http.Client{}
-	res, err := client.Do(req)
+	status, body, err := c.call(ctx, KruizeDeleteExperiment, c.timeouts.DeleteExperiment, http.MethodDelete, KruizeCreateExperiment, nil, payload)
 	if err != nil {
-		kruizeAPIException.WithLabelValues(KruizeUpdateRecommendations).Inc()
-		return nil, fmt.Errorf("error Occured while calling /updateRecommendations API %v", err)
+		return fmt.Errorf("error occured while deleting experiment: %w", err)
 	}
-	defer func() {
-		_ = res.Body.Close()
-	}()
-	body, _ := io.ReadAll(res.Body)
-	log.Debugf("\nResponse from /updateRecommendations - %s \n", string(body))
-	if res.StatusCode == 400 {
-		data := map[string]interface{}{}
-		if err := json.Unmarshal(body, &data); err != nil {
-			return nil, fmt.Errorf("unable to unmarshal response of /updateRecommendations API %v", err)
+	if status != http.StatusCreated {
+		kruizeAPIException.WithLabelValues(KruizeDeleteExperiment).Inc()
+		message, err := responseMessage(body)
+		if err != nil || message == "" {
+			message = fmt.Sprintf("unexpected status code %d", status)
 		}
-		return nil, fmt.Errorf("%s", data["message"].(string))
+		return fmt.Errorf("unable to delete experiment: %s", message)
 	}
+	log.Infof("Experiment - %s deleted successfully", experiment_name)
+	return nil
+}
 
-	var response any
-
-	if experimentType == types.PayloadTypeNamespace {
-		var namespaceResponse namespacePayload.NamespaceRecommendationResponse
-		if err := json.Unmarshal(body, &namespaceResponse); err != nil {
-			return nil, fmt.Errorf("unable to unmarshal response of /updateRecommendations API %v", err)
-		}
-		response = namespaceResponse
-	} else {
-		var containerResponse []kruizePayload.ListRecommendations
-		if err := json.Unmarshal(body, &containerResponse); err != nil {
-			return nil, fmt.Errorf("unable to unmarshal response of /updateRecommendations API %v", err)
+func (c *client) ListExperiments(ctx context.Context) ([]Experiment, error) {
+	query := url.Values{}
+	query.Add("results", "false")
+	query.Add("recommendations", "false")
+	status, body, err := c.call(ctx, KruizeListExperiments, c.timeouts.ListExperiments, http.MethodGet, KruizeListExperiments, query, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error occured while listing experiments: %w", err)
+	}
+	if status != http.StatusOK {
+		message, err := responseMessage(body)
+		if err != nil || message == "" {
+			message = fmt.Sprintf("unexpected status code %d", status)
 		}
-		response = containerResponse
+		return nil, fmt.Errorf("unable to list experiments: %s", message)
+	}
+	var experiments []Experiment
+	if err := json.Unmarshal(body, &experiments); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal response of /listExperiments API %v", err)
 	}
+	return experiments, nil
+}
 
-	return response, nil
+func (c *client) Health(ctx context.Context) error {
+	status, _, err := c.call(ctx, KruizeHealth, 0, http.MethodGet, KruizeHealth, nil, nil)
+	if err != nil {
+		return fmt.Errorf("kruize health check failed: %w", err)
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("kruize health check failed: unexpected status code %d", status)
+	}
+	return nil
 }
 
 func IsValidRecommendation(recommendation kruizePayload.Recommendation, experiment_name string, maxEndTime time.Time, experimentType types.PayloadType) bool {
@@ -335,37 +454,3 @@ func IsValidRecommendation(recommendation kruizePayload.Recommendation, experime
 		return false
 	}
 }
-
-func DeleteExperimentFromKruize(experiment_name string) {
-	deletion_err_log := func(err error) {
-		kruizeAPIException.WithLabelValues("/deleteExperiment").Inc()
-		log.Errorf("error occured while deleting experiment: %s. Error - %s", experiment_name, err)
-	}
-
-	url := cfg.KruizeUrl + KruizeCreateExperiment
-	data := []map[string]string{
-		{"experiment_name": experiment_name},
-	}
-	payload, _ := json.Marshal(data)
-
-	req, err := http.NewRequest("DELETE", url, bytes.NewBuffer(payload))
-	if err != nil {
-		deletion_err_log(err)
-		return
-	}
-	// TODO(FLPATH-3407): use a bounded client once we have Prometheus latency data for /deleteExperiment
-	client := &http.Client{}
-	res, err := client.Do(req)
-	if err != nil {
-		deletion_err_log(err)
-		return
-	}
-	defer func() {
-		_ = res.Body.Close()
-	}()
-	if res.StatusCode == 201 {
-		log.Infof("Experiment - %s deleted successfully", experiment_name)
-	} else {
-		deletion_err_log(err)
-	}
-}
@@ -0,0 +1,159 @@
+package kruize_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-gota/gota/dataframe"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/types"
+	"github.com/redhatinsights/ros-ocp-backend/internal/types/kruizePayload"
+	namespacePayload "github.com/redhatinsights/ros-ocp-backend/internal/types/kruizePayload/namespace"
+	"github.com/redhatinsights/ros-ocp-backend/internal/utils"
+	"github.com/redhatinsights/ros-ocp-backend/internal/utils/kruize"
+	"github.com/redhatinsights/ros-ocp-backend/internal/utils/kruize/kruizetest"
+)
+
+// sampleGroups returns the aggregated rows of the sample CSV at path, by workload or namespace.
+func sampleGroups(t *testing.T, path string, csvType types.PayloadType, groupBy ...string) []dataframe.DataFrame {
+	t.Helper()
+	df := kruizetest.AggregateSample(t, path, csvType)
+	var groups []dataframe.DataFrame
+	for _, group := range df.GroupBy(groupBy...).GetGroups() {
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+func TestKruizeClientContainerExperiment(t *testing.T) {
+	server := kruizetest.NewServer()
+	defer server.Close()
+	client := server.Client()
+	ctx := context.Background()
+
+	group := sampleGroups(t, "../../../scripts/samples/ros-ocp-usage.csv", types.PayloadTypeContainer, "namespace", "k8s_object_type", "k8s_object_name")[0]
+	rows := group.Maps()
+	maxEndTime, err := utils.MaxIntervalEndTime(group.Col("interval_end").Records())
+	if !assert.NoError(t, err) {
+		return
+	}
+	name := "org|source|cluster|workload"
+
+	containers, err := client.CreateExperiment(ctx, name, "org;cluster", rows)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, containers)
+	_, err = client.CreateExperiment(ctx, name, "org;cluster", rows)
+	assert.NoError(t, err, "an existing experiment is not an error")
+
+	_, err = client.UpdateRecommendations(ctx, name, maxEndTime)
+	assert.ErrorContains(t, err, "does not exist", "no recommendation without results")
+
+	results, err := client.UpdateResults(ctx, name, kruizePayload.GetUpdateResultPayload(name, rows))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, results)
+
+	recommendations, err := client.UpdateRecommendations(ctx, name, maxEndTime)
+	if assert.NoError(t, err) && assert.Len(t, recommendations, 1) {
+		assert.Equal(t, "container", recommendations[0].Experiment_type)
+		for _, container := range recommendations[0].Kubernetes_objects[0].Containers {
+			assert.True(t, kruize.IsValidRecommendation(container.Recommendations, name, maxEndTime, types.PayloadTypeContainer))
+		}
+	}
+
+	experiments, err := client.ListExperiments(ctx)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []kruize.Experiment{{Name: name, ClusterName: "org;cluster", Type: "container"}}, experiments)
+	}
+
+	assert.NoError(t, client.DeleteExperiment(ctx, name))
+	assert.Equal(t, []string{name}, server.Deleted())
+	assert.Error(t, client.DeleteExperiment(ctx, name))
+}
+
+func TestKruizeClientNamespaceExperiment(t *testing.T) {
+	server := kruizetest.NewServer()
+	defer server.Close()
+	client := server.Client()
+	ctx := context.Background()
+
+	group := sampleGroups(t, "../../../scripts/samples/ros_ocp_namespace.csv", types.PayloadTypeNamespace, "namespace")[0]
+	rows := group.Maps()
+	maxEndTime, err := utils.MaxIntervalEndTime(group.Col("interval_end").Records())
+	if !assert.NoError(t, err) {
+		return
+	}
+	namespace := kruizePayload.AssertAndConvertToString(rows[0]["namespace"])
+	name := "org|source|cluster|namespace|" + namespace
+
+	assert.NoError(t, client.CreateNamespaceExperiment(ctx, name, "org;cluster", namespace))
+	_, err = client.UpdateNamespaceResults(ctx, name, namespacePayload.GetUpdateNamespaceResultPayload(name, rows))
+	assert.NoError(t, err)
+
+	recommendations, err := client.UpdateNamespaceRecommendations(ctx, name, maxEndTime)
+	if assert.NoError(t, err) && assert.Len(t, recommendations, 1) {
+		assert.Equal(t, "namespace", recommendations[0].ExperimentType)
+		object := recommendations[0].KubernetesObjects[0].Namespaces
+		assert.Equal(t, namespace, object.Namespace)
+		assert.True(t, kruize.IsValidRecommendation(object.Recommendations, name, maxEndTime, types.PayloadTypeNamespace))
+	}
+}
+
+func TestKruizeClientSetupPerformanceProfile(t *testing.T) {
+	server := kruizetest.NewServer()
+	defer server.Close()
+	client := server.Client()
+	// the profile is read from the working directory of the services
+	t.Chdir("../../..")
+
+	client.SetupPerformanceProfile()
+	assert.Equal(t, []kruizetest.PerformanceProfile{{Name: "resource-optimization-openshift", Version: 2.0}}, server.PerformanceProfiles())
+
+	client.SetupPerformanceProfile()
+	assert.Equal(t, 2, server.Calls(kruize.KruizeListPerformanceProfiles))
+	assert.Equal(t, 1, server.Calls(kruize.KruizeCreatePerformanceProfile), "an up to date profile is kept")
+}
+
+func TestKruizeClientErrors(t *testing.T) {
+	server := kruizetest.NewServer()
+	defer server.Close()
+	client := server.Client()
+	ctx := context.Background()
+
+	server.Fail(kruize.KruizeCreateExperiment, http.StatusInternalServerError)
+	err := client.CreateNamespaceExperiment(ctx, "experiment", "org;cluster", "ns")
+	assert.EqualError(t, err, "error Occured while creating experiment: fake Kruize failure")
+	assert.ErrorIs(t, err, kruize.ErrUnavailable, "server errors mean Kruize is unavailable")
+	server.Recover()
+	assert.NoError(t, client.CreateNamespaceExperiment(ctx, "experiment", "org;cluster", "ns"))
+
+	_, err = client.UpdateRecommendations(ctx, "missing", time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC))
+	assert.EqualError(t, err, "Not Found: experiment_name does not exist: missing")
+	assert.NotErrorIs(t, err, kruize.ErrUnavailable)
+
+	assert.NoError(t, client.Health(ctx))
+	server.Fail(kruize.KruizeHealth, http.StatusServiceUnavailable)
+	assert.ErrorIs(t, client.Health(ctx), kruize.ErrUnavailable)
+}
+
+func TestKruizeClientTimeouts(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	defer close(release)
+
+	client := kruize.NewKruizeClient(slow.URL, kruize.Timeouts{DeleteExperiment: 50 * time.Millisecond})
+	err := client.DeleteExperiment(context.Background(), "experiment")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorIs(t, err, kruize.ErrUnavailable)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = client.UpdateRecommendations(ctx, "experiment", time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC))
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "the caller's context bounds unbounded endpoints")
+}
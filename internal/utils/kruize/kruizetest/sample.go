@@ -0,0 +1,28 @@
+package kruizetest
+
+import (
+	"testing"
+
+	"github.com/go-gota/gota/dataframe"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/types"
+	"github.com/redhatinsights/ros-ocp-backend/internal/utils"
+)
+
+// AggregateSample returns the aggregated rows of the usage CSV at path, as the processor builds
+// them from an upload.
+func AggregateSample(t testing.TB, path string, csvType types.PayloadType) dataframe.DataFrame {
+	t.Helper()
+	f, err := utils.OpenCSVFile(path)
+	if err != nil {
+		t.Fatalf("unable to open %s: %v", path, err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	df, err := utils.StreamAggregate(csvType, f)
+	if err != nil {
+		t.Fatalf("unable to aggregate %s: %v", path, err)
+	}
+	return df
+}
@@ -0,0 +1,457 @@
+// Package kruizetest provides an in-process fake of the Kruize API for tests.
+package kruizetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/utils/kruize"
+)
+
+const kruizeTimeFormat = "2006-01-02T15:04:05.000Z"
+
+// Experiment is an experiment created on the fake Kruize.
+type Experiment struct {
+	Name        string
+	ClusterName string
+	// Type is "namespace" for namespace experiments and empty for container experiments.
+	Type       string
+	Namespace  string
+	Containers []string
+	// Results is the number of results sent to the experiment.
+	Results int
+	// LastResultEnd is the latest interval end of the results.
+	LastResultEnd time.Time
+}
+
+// Server is a fake Kruize. It keeps the experiments and results it is sent and answers
+// /updateRecommendations with canned recommendations for the experiments that have results up
+// to the requested interval end.
+type Server struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	experiments map[string]*Experiment
+	deleted     []string
+	failures    map[string]failure
+	calls       map[string]int
+	profiles    []PerformanceProfile
+}
+
+// failure is how an endpoint fails: with status once it was called more than after times.
+type failure struct {
+	status int
+	after  int
+}
+
+// PerformanceProfile is a performance profile created on the fake Kruize.
+type PerformanceProfile struct {
+	Name    string  `json:"name"`
+	Version float64 `json:"profile_version"`
+}
+
+// NewServer starts a fake Kruize. The caller closes it.
+func NewServer() *Server {
+	s := &Server{experiments: make(map[string]*Experiment), failures: make(map[string]failure), calls: make(map[string]int)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+kruize.KruizeCreateExperiment, s.failable(kruize.KruizeCreateExperiment, s.createExperiment))
+	mux.HandleFunc("DELETE "+kruize.KruizeCreateExperiment, s.failable(kruize.KruizeDeleteExperiment, s.deleteExperiment))
+	mux.HandleFunc("POST "+kruize.KruizeUpdateResults, s.failable(kruize.KruizeUpdateResults, s.updateResults))
+	mux.HandleFunc("POST "+kruize.KruizeUpdateRecommendations, s.failable(kruize.KruizeUpdateRecommendations, s.updateRecommendations))
+	mux.HandleFunc("GET "+kruize.KruizeListExperiments, s.failable(kruize.KruizeListExperiments, s.listExperiments))
+	mux.HandleFunc("GET "+kruize.KruizeListPerformanceProfiles, s.failable(kruize.KruizeListPerformanceProfiles, s.listPerformanceProfiles))
+	mux.HandleFunc("POST "+kruize.KruizeCreatePerformanceProfile, s.failable(kruize.KruizeCreatePerformanceProfile, s.createPerformanceProfile))
+	mux.HandleFunc("GET "+kruize.KruizeHealth, s.failable(kruize.KruizeHealth, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, "Healthy")
+	}))
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Use starts a fake Kruize and points *client at it until the test ends.
+func Use(t testing.TB, client *kruize.KruizeClient) *Server {
+	t.Helper()
+	server := NewServer()
+	original := *client
+	*client = server.Client()
+	t.Cleanup(func() {
+		*client = original
+		server.Close()
+	})
+	return server
+}
+
+// Client returns a KruizeClient of the fake.
+func (s *Server) Client() kruize.KruizeClient {
+	return kruize.NewKruizeClient(s.URL, kruize.Timeouts{})
+}
+
+// Fail makes the endpoint, one of the kruize.Kruize* paths, answer with status until Recover.
+func (s *Server) Fail(endpoint string, status int) {
+	s.FailAfter(endpoint, 0, status)
+}
+
+// FailAfter is Fail once the endpoint answered calls more calls normally.
+func (s *Server) FailAfter(endpoint string, calls int, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[endpoint] = failure{status: status, after: s.calls[endpoint] + calls}
+}
+
+// Recover makes every endpoint answer normally again.
+func (s *Server) Recover() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = make(map[string]failure)
+}
+
+// Experiment returns a copy of the experiment called name.
+func (s *Server) Experiment(name string) (Experiment, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	experiment, ok := s.experiments[name]
+	if !ok {
+		return Experiment{}, false
+	}
+	return *experiment, true
+}
+
+// Experiments returns the names of the experiments.
+func (s *Server) Experiments() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.experiments))
+	for name := range s.experiments {
+		names = append(names, name)
+	}
+	return names
+}
+
+// AddPerformanceProfile creates a performance profile as if it had been posted.
+func (s *Server) AddPerformanceProfile(profile PerformanceProfile) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.profiles = append(s.profiles, profile)
+}
+
+// PerformanceProfiles returns the performance profiles.
+func (s *Server) PerformanceProfiles() []PerformanceProfile {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]PerformanceProfile(nil), s.profiles...)
+}
+
+// Calls returns the number of calls to the endpoint, one of the kruize.Kruize* paths.
+func (s *Server) Calls(endpoint string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[endpoint]
+}
+
+// Deleted returns the names of the deleted experiments, in order.
+func (s *Server) Deleted() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.deleted...)
+}
+
+func (s *Server) failable(endpoint string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.calls[endpoint]++
+		failure, failing := s.failures[endpoint]
+		failing = failing && s.calls[endpoint] > failure.after
+		s.mu.Unlock()
+		if failing {
+			writeMessage(w, failure.status, "fake Kruize failure")
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func writeMessage(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]any{"message": message, "httpcode": status, "status": "ERROR"})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func decode(r *http.Request, v any) error {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+func (s *Server) createExperiment(w http.ResponseWriter, r *http.Request) {
+	var payload []struct {
+		ExperimentName    string `json:"experiment_name"`
+		ClusterName       string `json:"cluster_name"`
+		ExperimentType    string `json:"experiment_type"`
+		KubernetesObjects []struct {
+			Namespace  string `json:"namespace"`
+			Containers []struct {
+				ContainerName string `json:"container_name"`
+			} `json:"containers"`
+			Namespaces struct {
+				Namespace string `json:"namespace"`
+			} `json:"namespaces"`
+		} `json:"kubernetes_objects"`
+	}
+	if err := decode(r, &payload); err != nil || len(payload) != 1 || len(payload[0].KubernetesObjects) != 1 {
+		writeMessage(w, http.StatusBadRequest, "invalid experiment")
+		return
+	}
+	created := payload[0]
+	object := created.KubernetesObjects[0]
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.experiments[created.ExperimentName]; ok {
+		writeMessage(w, http.StatusConflict, fmt.Sprintf("Experiment name already exists: %s", created.ExperimentName))
+		return
+	}
+	experiment := &Experiment{
+		Name:        created.ExperimentName,
+		ClusterName: created.ClusterName,
+		Type:        created.ExperimentType,
+		Namespace:   object.Namespace,
+	}
+	if experiment.Type == "namespace" {
+		experiment.Namespace = object.Namespaces.Namespace
+	}
+	for _, container := range object.Containers {
+		experiment.Containers = append(experiment.Containers, container.ContainerName)
+	}
+	s.experiments[experiment.Name] = experiment
+	writeMessage(w, http.StatusCreated, "Experiment registered successfully with Kruize.")
+}
+
+func (s *Server) deleteExperiment(w http.ResponseWriter, r *http.Request) {
+	var payload []struct {
+		ExperimentName string `json:"experiment_name"`
+	}
+	if err := decode(r, &payload); err != nil || len(payload) != 1 {
+		writeMessage(w, http.StatusBadRequest, "invalid experiment")
+		return
+	}
+	name := payload[0].ExperimentName
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.experiments[name]; !ok {
+		writeMessage(w, http.StatusBadRequest, fmt.Sprintf("Experiment not found: %s", name))
+		return
+	}
+	delete(s.experiments, name)
+	s.deleted = append(s.deleted, name)
+	writeMessage(w, http.StatusCreated, "Experiment deleted successfully.")
+}
+
+func (s *Server) listExperiments(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	experiments := make([]kruize.Experiment, 0, len(s.experiments))
+	for _, experiment := range s.experiments {
+		experimentType := experiment.Type
+		if experimentType == "" {
+			experimentType = "container"
+		}
+		experiments = append(experiments, kruize.Experiment{
+			Name:        experiment.Name,
+			ClusterName: experiment.ClusterName,
+			Type:        experimentType,
+		})
+	}
+	writeJSON(w, http.StatusOK, experiments)
+}
+
+func (s *Server) listPerformanceProfiles(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.PerformanceProfiles())
+}
+
+func (s *Server) createPerformanceProfile(w http.ResponseWriter, r *http.Request) {
+	var profile PerformanceProfile
+	if err := decode(r, &profile); err != nil || profile.Name == "" {
+		writeMessage(w, http.StatusBadRequest, "invalid performance profile")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.profiles {
+		if existing.Name == profile.Name {
+			writeMessage(w, http.StatusConflict, fmt.Sprintf("Performance Profile already exists: %s", profile.Name))
+			return
+		}
+	}
+	s.profiles = append(s.profiles, profile)
+	writeMessage(w, http.StatusCreated, "Performance Profile : "+profile.Name+" created successfully.")
+}
+
+func (s *Server) updateResults(w http.ResponseWriter, r *http.Request) {
+	var payload []struct {
+		ExperimentName  string `json:"experiment_name"`
+		IntervalEndTime string `json:"interval_end_time"`
+	}
+	if err := decode(r, &payload); err != nil {
+		writeMessage(w, http.StatusBadRequest, "invalid results")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, result := range payload {
+		experiment, ok := s.experiments[result.ExperimentName]
+		if !ok {
+			writeMessage(w, http.StatusBadRequest, fmt.Sprintf("Not Found: experiment_name does not exist: %s", result.ExperimentName))
+			return
+		}
+		end, err := time.Parse(kruizeTimeFormat, result.IntervalEndTime)
+		if err != nil {
+			writeMessage(w, http.StatusBadRequest, fmt.Sprintf("invalid interval_end_time: %s", result.IntervalEndTime))
+			return
+		}
+		experiment.Results++
+		if end.After(experiment.LastResultEnd) {
+			experiment.LastResultEnd = end
+		}
+	}
+	writeMessage(w, http.StatusCreated, "Results added successfully! View saved results at /listExperiments .")
+}
+
+func (s *Server) updateRecommendations(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("experiment_name")
+	endParam := r.URL.Query().Get("interval_end_time")
+	end, err := time.Parse(kruizeTimeFormat, endParam)
+	if err != nil {
+		writeMessage(w, http.StatusBadRequest, fmt.Sprintf("invalid interval_end_time: %s", endParam))
+		return
+	}
+
+	s.mu.Lock()
+	experiment, ok := s.experiments[name]
+	var found Experiment
+	if ok {
+		found = *experiment
+	}
+	s.mu.Unlock()
+	if !ok {
+		writeMessage(w, http.StatusBadRequest, fmt.Sprintf("Not Found: experiment_name does not exist: %s", name))
+		return
+	}
+	if found.Results == 0 || found.LastResultEnd.Before(end) {
+		writeMessage(w, http.StatusBadRequest, fmt.Sprintf("Recommendation for timestamp - \" %s \" does not exist", endParam))
+		return
+	}
+	writeJSON(w, http.StatusCreated, recommendationResponse(found, end))
+}
+
+// recommendationResponse is the /updateRecommendations answer for experiment at end, with the
+// same canned recommendation for every container or the namespace.
+func recommendationResponse(experiment Experiment, end time.Time) []map[string]any {
+	response := map[string]any{
+		"cluster_name":    experiment.ClusterName,
+		"experiment_name": experiment.Name,
+		"version":         "v2.0",
+	}
+	if experiment.Type == "namespace" {
+		response["experiment_type"] = "namespace"
+		response["kubernetes_objects"] = []map[string]any{{
+			"namespace": experiment.Namespace,
+			"namespaces": map[string]any{
+				"namespace":       experiment.Namespace,
+				"recommendations": CannedRecommendation(end),
+			},
+		}}
+		return []map[string]any{response}
+	}
+	containers := make([]map[string]any, 0, len(experiment.Containers))
+	for _, container := range experiment.Containers {
+		containers = append(containers, map[string]any{
+			"container_name":  container,
+			"recommendations": CannedRecommendation(end),
+		})
+	}
+	response["experiment_type"] = "container"
+	response["kubernetes_objects"] = []map[string]any{{
+		"namespace":  experiment.Namespace,
+		"containers": containers,
+	}}
+	return []map[string]any{response}
+}
+
+func resources(cpuCores float64, memoryBytes float64) map[string]any {
+	return map[string]any{
+		"requests": map[string]any{
+			"cpu":    map[string]any{"amount": cpuCores, "format": "cores"},
+			"memory": map[string]any{"amount": memoryBytes, "format": "bytes"},
+		},
+		"limits": map[string]any{
+			"cpu":    map[string]any{"amount": cpuCores, "format": "cores"},
+			"memory": map[string]any{"amount": memoryBytes, "format": "bytes"},
+		},
+	}
+}
+
+func cannedTerm(end time.Time, hours float64) map[string]any {
+	return map[string]any{
+		"duration_in_hours":     hours,
+		"monitoring_start_time": end.Add(-time.Duration(hours) * time.Hour).Format(kruizeTimeFormat),
+		"notifications": map[string]any{
+			"112101": map[string]any{"type": "info", "message": "Cost Recommendations Available", "code": 112101},
+			"112102": map[string]any{"type": "info", "message": "Performance Recommendations Available", "code": 112102},
+		},
+		"recommendation_engines": map[string]any{
+			"cost": map[string]any{
+				"config":        resources(0.5, 256<<20),
+				"variation":     resources(-0.5, -256<<20),
+				"notifications": map[string]any{},
+			},
+			"performance": map[string]any{
+				"config":        resources(1.5, 768<<20),
+				"variation":     resources(0.5, 256<<20),
+				"notifications": map[string]any{},
+			},
+		},
+	}
+}
+
+// CannedRecommendation is the recommendation the fake returns for an interval ending at end:
+// available short, medium and long term recommendations for a current request of one core
+// and 512 MiB.
+func CannedRecommendation(end time.Time) map[string]any {
+	endTime := end.UTC().Format(kruizeTimeFormat)
+	return map[string]any{
+		"version": "1.0",
+		"notifications": map[string]any{
+			"111000": map[string]any{"type": "info", "message": "Recommendations Are Available", "code": 111000},
+		},
+		"data": map[string]any{
+			endTime: map[string]any{
+				"notifications": map[string]any{
+					"111101": map[string]any{"type": "info", "message": "Short Term Recommendations Available", "code": 111101},
+				},
+				"monitoring_end_time": endTime,
+				"current":             resources(1, 512<<20),
+				"recommendation_terms": map[string]any{
+					"short_term":  cannedTerm(end, 24),
+					"medium_term": cannedTerm(end, 168),
+					"long_term":   cannedTerm(end, 360),
+				},
+			},
+		},
+	}
+}
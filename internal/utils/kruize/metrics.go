@@ -12,6 +12,13 @@ var (
 	},
 		[]string{"path"},
 	)
+	kruizeAPIDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "rosocp_kruize_api_duration_seconds",
+		Help:    "Latency of outbound Kruize API calls in seconds",
+		Buckets: []float64{0.5, 1, 5, 10, 30, 60, 120, 300},
+	},
+		[]string{"path", "status"},
+	)
 	invalidRecommendation = promauto.NewCounter(prometheus.CounterOpts{
 		Name: "rosocp_invalid_recommendation_total",
 		Help: "The total number of invalid container recommendations send by Kruize",
@@ -36,4 +43,8 @@ var (
 		Name: "kruize_update_namespace_result_request_total",
 		Help: "The total number of namespace update result requests sent to Kruize",
 	})
+	kruizeBreakerOpen = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "rosocp_kruize_circuit_breaker_open",
+		Help: "1 while the Kruize circuit breaker is open and consuming is paused, 0 otherwise",
+	})
 )
@@ -10,4 +10,8 @@ var (
 		Name: "rosocp_invalid_datapoints_total",
 		Help: "The total number of invalid datapoints(rows) found in received CSVs",
 	})
+	fileFetches = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "rosocp_file_fetches_total",
+		Help: "The total number of upload file fetch attempts by URL scheme and outcome (succeeded, retried, failed)",
+	}, []string{"scheme", "outcome"})
 )
@@ -0,0 +1,364 @@
+package utils
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-gota/gota/dataframe"
+	"github.com/go-gota/gota/series"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/logging"
+	"github.com/redhatinsights/ros-ocp-backend/internal/types"
+	w "github.com/redhatinsights/ros-ocp-backend/internal/types/workload"
+)
+
+// ErrCSVTooLarge is returned while reading a CSV larger than CSV_MAX_FILE_SIZE_MB.
+var ErrCSVTooLarge = errors.New("CSV file exceeds the maximum file size")
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// Metric columns that must hold a non-negative value for a row to be aggregated.
+var (
+	containerRequiredMetrics = []string{
+		"memory_rss_usage_container_sum", "memory_rss_usage_container_max", "memory_rss_usage_container_min", "memory_rss_usage_container_avg",
+		"memory_usage_container_sum", "memory_usage_container_max", "memory_usage_container_min", "memory_usage_container_avg",
+		"cpu_usage_container_sum", "cpu_usage_container_max", "cpu_usage_container_min", "cpu_usage_container_avg",
+	}
+	namespaceRequiredMetrics = []string{
+		"cpu_usage_namespace_avg", "cpu_usage_namespace_max", "cpu_usage_namespace_min",
+		"memory_usage_namespace_avg", "memory_usage_namespace_max", "memory_usage_namespace_min",
+		"memory_rss_usage_namespace_avg", "memory_rss_usage_namespace_max", "memory_rss_usage_namespace_min",
+	}
+	supportedWorkloadTypes = []string{
+		w.Daemonset.String(),
+		w.Deployment.String(),
+		w.Deploymentconfig.String(),
+		w.Replicaset.String(),
+		w.Replicationcontroller.String(),
+		w.Statefulset.String(),
+	}
+)
+
+// limitedReader fails with ErrCSVTooLarge once more than remaining bytes have been read.
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, ErrCSVTooLarge
+	}
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n, ErrCSVTooLarge
+	}
+	return n, err
+}
+
+// NewCSVReader returns the CSV content of r, decompressing it when it is gzip compressed.
+// Reading more than maxBytes of CSV fails with ErrCSVTooLarge; the limit applies to the
+// decompressed content so that a small archive cannot expand without bounds.
+func NewCSVReader(r io.Reader, maxBytes int64) (io.Reader, error) {
+	buffered := bufio.NewReader(r)
+	magic, err := buffered.Peek(len(gzipMagic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	var content io.Reader = buffered
+	if slices.Equal(magic, gzipMagic) {
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decompress CSV: %w", err)
+		}
+		content = gz
+	}
+	return &limitedReader{r: content, remaining: maxBytes}, nil
+}
+
+func maxCSVFileSize() int64 {
+	return int64(cfg.CSVMaxFileSizeMB) << 20
+}
+
+// OpenCSVFromUrl starts fetching the CSV at csvURL, see FetchFile, and returns its content to
+// be read as a stream, see NewCSVReader. The caller closes the returned reader.
+func OpenCSVFromUrl(csvURL string, checksum string) (io.ReadCloser, error) {
+	body, err := FetchFile(csvURL, checksum)
+	if err != nil {
+		return nil, err
+	}
+	content, err := NewCSVReader(body, maxCSVFileSize())
+	if err != nil {
+		_ = body.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{content, body}, nil
+}
+
+// OpenCSVFile opens a local CSV, compressed or not, to be read like OpenCSVFromUrl.
+func OpenCSVFile(path string) (io.ReadCloser, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	content, err := NewCSVReader(f, maxCSVFileSize())
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{content, f}, nil
+}
+
+// aggregate accumulates one aggregated column of a group the way gota aggregates a series:
+// NaN values propagate through sums and means, and min and max keep the first value unless
+// a later one compares lower or higher.
+type aggregate struct {
+	sum      float64
+	count    int
+	min, max float64
+}
+
+func (a *aggregate) add(value float64) {
+	if a.count == 0 {
+		a.min, a.max = value, value
+	} else {
+		if value < a.min {
+			a.min = value
+		}
+		if value > a.max {
+			a.max = value
+		}
+	}
+	a.sum += value
+	a.count++
+}
+
+func (a *aggregate) value(aggregationType dataframe.AggregationType) float64 {
+	switch aggregationType {
+	case dataframe.Aggregation_SUM:
+		return a.sum
+	case dataframe.Aggregation_MEAN:
+		return a.sum / float64(a.count)
+	case dataframe.Aggregation_MIN:
+		return a.min
+	case dataframe.Aggregation_MAX:
+		return a.max
+	}
+	return math.NaN()
+}
+
+type aggregationGroup struct {
+	keys       []string
+	aggregates []aggregate
+}
+
+// streamAggregation holds the groups of a CSV being aggregated row by row.
+type streamAggregation struct {
+	aggregationType types.PayloadType
+	groupColumns    []string
+	metricColumns   []string
+	aggregations    []dataframe.AggregationType
+	columnIndex     map[string]int
+	groups          map[string]*aggregationGroup
+	order           []string
+	droppedRecords  int
+}
+
+func newStreamAggregation(aggregationType types.PayloadType, header []string) (*streamAggregation, error) {
+	columnHeaders := types.GetColumnMapping(aggregationType)
+	requiredColumns := make([]string, 0, len(columnHeaders))
+	for column := range columnHeaders {
+		requiredColumns = append(requiredColumns, column)
+	}
+	if hasMissingColumns(requiredColumns, header) {
+		return nil, fmt.Errorf("CSV file does not have all the required columns")
+	}
+
+	a := &streamAggregation{
+		aggregationType: aggregationType,
+		columnIndex:     make(map[string]int, len(header)),
+		groups:          make(map[string]*aggregationGroup),
+	}
+	for i, column := range header {
+		if _, ok := a.columnIndex[column]; !ok {
+			a.columnIndex[column] = i
+		}
+	}
+	aggregationMapping := namespaceAggregationMapping
+	a.groupColumns = namespaceGroupColumns
+	if aggregationType == types.PayloadTypeContainer {
+		aggregationMapping = containerAggregationMapping
+		a.groupColumns = containerGroupColumns
+	}
+	for column := range aggregationMapping {
+		a.metricColumns = append(a.metricColumns, column)
+	}
+	slices.Sort(a.metricColumns)
+	for _, column := range a.metricColumns {
+		a.aggregations = append(a.aggregations, aggregationMapping[column])
+	}
+	return a, nil
+}
+
+// parseFloat parses a metric like gota does: values that are not numbers are NaN.
+func parseFloat(value string) float64 {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return math.NaN()
+	}
+	return f
+}
+
+func isUnset(value string) bool {
+	return value == "" || value == "<none>"
+}
+
+// groupKeys returns the values the row is grouped by, or false when the row is invalid and
+// dropped, as filterValidCSVRecords would.
+func (a *streamAggregation) groupKeys(record []string) ([]string, bool) {
+	field := func(column string) string {
+		return record[a.columnIndex[column]]
+	}
+
+	requiredMetrics := namespaceRequiredMetrics
+	if a.aggregationType == types.PayloadTypeContainer {
+		requiredMetrics = containerRequiredMetrics
+	}
+	for _, column := range requiredMetrics {
+		// NaN fails the comparison too
+		if !(parseFloat(field(column)) >= 0) {
+			return nil, false
+		}
+	}
+
+	if a.aggregationType != types.PayloadTypeContainer {
+		if isUnset(field("namespace")) {
+			return nil, false
+		}
+		return []string{field("namespace"), field("interval_start"), field("interval_end")}, true
+	}
+
+	ownerKind, ownerName := field("owner_kind"), field("owner_name")
+	workload, workloadType := field("workload"), strings.ToLower(field("workload_type"))
+	if isUnset(ownerKind) || isUnset(ownerName) || !slices.Contains(supportedWorkloadTypes, workloadType) {
+		return nil, false
+	}
+
+	// see determine_k8s_object_type
+	k8sObjectType, k8sObjectName := workloadType, workload
+	switch strings.ToLower(ownerKind) {
+	case string(w.Replicaset), string(w.Replicationcontroller):
+		if isUnset(workload) {
+			k8sObjectType, k8sObjectName = strings.ToLower(ownerKind), ownerName
+		}
+	}
+	return []string{
+		field("namespace"),
+		k8sObjectType,
+		k8sObjectName,
+		workload,
+		field("container_name"),
+		field("image_name"),
+		field("interval_start"),
+		field("interval_end"),
+	}, true
+}
+
+func (a *streamAggregation) add(record []string) {
+	keys, ok := a.groupKeys(record)
+	if !ok {
+		a.droppedRecords++
+		return
+	}
+	groupKey := strings.Join(keys, "\x00")
+	group, ok := a.groups[groupKey]
+	if !ok {
+		group = &aggregationGroup{keys: keys, aggregates: make([]aggregate, len(a.metricColumns))}
+		a.groups[groupKey] = group
+		a.order = append(a.order, groupKey)
+	}
+	for i, column := range a.metricColumns {
+		group.aggregates[i].add(parseFloat(record[a.columnIndex[column]]))
+	}
+}
+
+// dataFrame returns the aggregated rows with the columns Aggregate_data returns.
+func (a *streamAggregation) dataFrame() dataframe.DataFrame {
+	columns := make([]series.Series, 0, len(a.groupColumns)+len(a.metricColumns))
+	for i, column := range a.groupColumns {
+		values := make([]string, len(a.order))
+		for row, groupKey := range a.order {
+			values[row] = a.groups[groupKey].keys[i]
+		}
+		columns = append(columns, series.New(values, series.String, column))
+	}
+	for i, column := range a.metricColumns {
+		values := make([]float64, len(a.order))
+		for row, groupKey := range a.order {
+			values[row] = a.groups[groupKey].aggregates[i].value(a.aggregations[i])
+		}
+		columns = append(columns, series.New(values, series.Float, fmt.Sprintf("%s_%s", column, a.aggregations[i])))
+	}
+	return dataframe.New(columns...)
+}
+
+// StreamAggregate reads the CSV in r one row at a time and aggregates it like Aggregate_data.
+// Only one accumulator per group is kept in memory, so memory grows with the number of
+// containers and intervals in the file rather than with its size.
+func StreamAggregate(aggregationType types.PayloadType, r io.Reader) (dataframe.DataFrame, error) {
+	log = logging.GetLogger()
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return dataframe.DataFrame{}, fmt.Errorf("CSV file is empty")
+	}
+	if err != nil {
+		return dataframe.DataFrame{}, err
+	}
+	aggregation, err := newStreamAggregation(aggregationType, slices.Clone(header))
+	if err != nil {
+		return dataframe.DataFrame{}, err
+	}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return dataframe.DataFrame{}, err
+		}
+		aggregation.add(record)
+	}
+
+	if aggregation.droppedRecords != 0 {
+		invalidDataPoints.Add(float64(aggregation.droppedRecords))
+		log.Infof("Invalid records in CSV - %v", aggregation.droppedRecords)
+	}
+	if len(aggregation.groups) == 0 {
+		return dataframe.DataFrame{}, fmt.Errorf("no valid records present in CSV to process further")
+	}
+	return aggregation.dataFrame(), nil
+}
@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/go-gota/gota/dataframe"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/types"
+)
+
+// aggregatedRows indexes the rows of an aggregated data frame by their group columns.
+func aggregatedRows(t *testing.T, df dataframe.DataFrame, groupColumns []string) map[string]map[string]interface{} {
+	t.Helper()
+	rows := make(map[string]map[string]interface{})
+	for _, row := range df.Maps() {
+		keys := make([]string, len(groupColumns))
+		for i, column := range groupColumns {
+			keys[i] = row[column].(string)
+		}
+		rows[strings.Join(keys, "|")] = row
+	}
+	return rows
+}
+
+func TestStreamAggregateMatchesAggregateData(t *testing.T) {
+	tests := []struct {
+		file         string
+		csvType      types.PayloadType
+		groupColumns []string
+	}{
+		{"../../scripts/samples/ros-ocp-usage.csv", types.PayloadTypeContainer, containerGroupColumns},
+		{"../../scripts/samples/ros_ocp_namespace.csv", types.PayloadTypeNamespace, namespaceGroupColumns},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			content, err := os.ReadFile(tt.file)
+			if !assert.NoError(t, err) {
+				return
+			}
+			records, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+			if !assert.NoError(t, err) {
+				return
+			}
+			want, err := Aggregate_data(tt.csvType, dataframe.LoadRecords(records, dataframe.WithTypes(types.GetColumnMapping(tt.csvType))))
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			got, err := StreamAggregate(tt.csvType, bytes.NewReader(content))
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			assert.ElementsMatch(t, want.Names(), got.Names())
+			wantRows := aggregatedRows(t, want, tt.groupColumns)
+			gotRows := aggregatedRows(t, got, tt.groupColumns)
+			assert.NotEmpty(t, wantRows)
+			assert.Equal(t, len(wantRows), len(gotRows))
+			for key, wantRow := range wantRows {
+				gotRow, ok := gotRows[key]
+				if !assert.True(t, ok, key) {
+					continue
+				}
+				for column, wantValue := range wantRow {
+					wantFloat, isFloat := wantValue.(float64)
+					if !isFloat {
+						assert.Equal(t, wantValue, gotRow[column], "%s %s", key, column)
+						continue
+					}
+					gotFloat := gotRow[column].(float64)
+					if math.IsNaN(wantFloat) {
+						assert.True(t, math.IsNaN(gotFloat), "%s %s", key, column)
+						continue
+					}
+					assert.InDelta(t, wantFloat, gotFloat, 1e-9, "%s %s", key, column)
+				}
+			}
+		})
+	}
+}
+
+func TestStreamAggregateErrors(t *testing.T) {
+	header := strings.Join([]string{"namespace", "interval_start", "interval_end"}, ",")
+	_, err := StreamAggregate(types.PayloadTypeNamespace, strings.NewReader(header+"\nns,a,b\n"))
+	assert.EqualError(t, err, "CSV file does not have all the required columns")
+
+	_, err = StreamAggregate(types.PayloadTypeNamespace, strings.NewReader(""))
+	assert.EqualError(t, err, "CSV file is empty")
+
+	content, err := os.ReadFile("../../scripts/samples/ros_ocp_namespace.csv")
+	if assert.NoError(t, err) {
+		headerOnly := strings.SplitN(string(content), "\n", 2)[0] + "\n"
+		_, err = StreamAggregate(types.PayloadTypeNamespace, strings.NewReader(headerOnly))
+		assert.EqualError(t, err, "no valid records present in CSV to process further")
+	}
+}
+
+func TestNewCSVReaderDecompressesGzip(t *testing.T) {
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	_, _ = gz.Write([]byte("a,b\n1,2\n"))
+	_ = gz.Close()
+
+	for name, input := range map[string][]byte{"plain": []byte("a,b\n1,2\n"), "gzip": compressed.Bytes()} {
+		r, err := NewCSVReader(bytes.NewReader(input), 1024)
+		if assert.NoError(t, err, name) {
+			content, err := io.ReadAll(r)
+			assert.NoError(t, err, name)
+			assert.Equal(t, "a,b\n1,2\n", string(content), name)
+		}
+	}
+}
+
+func TestNewCSVReaderEnforcesMaximumSize(t *testing.T) {
+	r, err := NewCSVReader(strings.NewReader("a,b\n1,2\n"), 8)
+	if assert.NoError(t, err) {
+		content, err := io.ReadAll(r)
+		assert.NoError(t, err, "content of exactly the maximum size is allowed")
+		assert.Equal(t, 8, len(content))
+	}
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	_, _ = gz.Write(bytes.Repeat([]byte("1,2\n"), 1000))
+	_ = gz.Close()
+
+	r, err = NewCSVReader(&compressed, 100)
+	if assert.NoError(t, err) {
+		_, err = io.ReadAll(r)
+		assert.True(t, errors.Is(err, ErrCSVTooLarge))
+	}
+}
@@ -1,14 +1,9 @@
 package utils
 
 import (
-	"bytes"
 	"encoding/csv"
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
-	"net/url"
-	"os"
 	"slices"
 	"strconv"
 	"strings"
@@ -25,21 +20,12 @@ var log *logrus.Entry = logging.GetLogger()
 var cfg *config.Config = config.GetConfig()
 
 // HTTPClient is the shared HTTP client for lightweight outbound requests
-// (health checks, RBAC, experiment creation). The timeout is driven by
+// (health checks, RBAC, performance profiles). The timeout is driven by
 // GLOBAL_HTTP_CLIENT_TIMEOUT_SECS (default 30s) to prevent indefinite
-// hangs when downstream services are slow or unresponsive. See FLPATH-3407.
+// hangs when downstream services are slow or unresponsive.
 //
-// Heavy Kruize calls (/updateResults, /updateRecommendations) and large
-// downloads (ReadCSVFromUrl) intentionally use the default http client
-// until we have Prometheus latency data to set informed timeouts.
-// TODO(FLPATH-3407): add per-endpoint Prometheus histogram to measure
-// Kruize API latency, then set per-call timeouts:
-//
-//	kruizeAPIDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
-//	    Name:    "rosocp_kruize_api_duration_seconds",
-//	    Help:    "Latency of outbound Kruize API calls in seconds",
-//	    Buckets: []float64{0.5, 1, 5, 10, 30, 60, 120, 300},
-//	}, []string{"path"})
+// Kruize API calls are bounded per endpoint by the KRUIZE_*_TIMEOUT_SECS
+// settings, and upload file downloads by FETCH_TIMEOUT_SECS.
 var HTTPClient = newHTTPClient(cfg.GlobalHTTPClientTimeoutSecs)
 
 const minHTTPTimeoutSecs = 1
@@ -52,157 +38,18 @@ func newHTTPClient(timeoutSecs int) *http.Client {
 	return &http.Client{Timeout: time.Duration(timeoutSecs) * time.Second}
 }
 
-func SetupKruizePerformanceProfile() {
-	// This func needs to be revisited once kruize implements this API
-	// Refer - https://github.com/kruize/autotune/blob/mvp_demo/src/main/java/com/autotune/analyzer/Analyzer.java#L50
-	listPerformanceProfileUrl := cfg.KruizeUrl + "/listPerformanceProfiles"
-	// Use the target version from config and strip 'v' prefix if present
-	targetVersion := strings.TrimPrefix(cfg.KruizePerformanceProfileVersion, "v")
-
-	for i := 0; i < 10; i++ {
-		log.Infof("fetching performance profile list")
-		response, err := HTTPClient.Get(listPerformanceProfileUrl)
-		if err != nil {
-			log.Errorf("an error occurred %v \n", err)
-		} else {
-			body, err := io.ReadAll(response.Body)
-			if respBodyErr := response.Body.Close(); respBodyErr != nil {
-				log.Errorf("error closing response body: %v", respBodyErr)
-			}
-			if err != nil {
-				log.Errorf("error reading listPerformanceProfiles response: %v", err)
-				time.Sleep(10 * time.Second)
-				continue
-			}
-
-			if len(body) > 0 {
-				var profiles []map[string]interface{}
-				if err := json.Unmarshal(body, &profiles); err != nil {
-					log.Errorf("error unmarshalling listPerformanceProfiles response: %v", err)
-					continue
-				} else if len(profiles) > 0 {
-					var fetchedVersion string
-					for _, profile := range profiles {
-						log.Debugf("current performance profile version : %v", profile["profile_version"])
-						fetchedVersion = fmt.Sprintf("%.1f", profile["profile_version"])
-					}
-
-					// Convert versions to float64 for comparison
-					fetchedVersionFloat, fetchedErr := strconv.ParseFloat(fetchedVersion, 64)
-					targetVersionFloat, targetErr := strconv.ParseFloat(targetVersion, 64)
-
-					if fetchedErr != nil || targetErr != nil {
-						log.Errorf("failed to parse version numbers for comparison (fetched: %v, target: %v)", fetchedVersion, targetVersion)
-						return
-					}
-
-					// Check if already up to date
-					if fetchedVersionFloat == targetVersionFloat {
-						log.Infof("performance profile already up to date (version: %v)", fetchedVersion)
-						return
-					}
-
-					// Version mismatch -> Update the profile if update flag is enabled
-					// and the fetched version is less than the target version (prevent downgrades)
-					if cfg.UpdateKruizePerfProfile && fetchedVersionFloat < targetVersionFloat {
-						log.Infof("updating performance profile to supported version: %v", targetVersion)
-						postBody, err := os.ReadFile("./resource_optimization_openshift.json")
-						if err != nil {
-							log.Errorf("file reading error: %v \n", err)
-							return
-						}
-
-						// create the PUT request
-						updatePerformanceProfileUrl := cfg.KruizeUrl + "/updatePerformanceProfile"
-						req, err := http.NewRequest(http.MethodPut, updatePerformanceProfileUrl, bytes.NewReader(postBody))
-						if err != nil {
-							log.Errorf("failed to create PUT request: %v", err)
-							return
-						}
-						req.Header.Set("Content-Type", "application/json")
-
-						// call the updatePerformanceProfile API using PUT request
-						log.Debugf("sending PUT request to: %s (len=%d bytes)", updatePerformanceProfileUrl, req.ContentLength)
-						res, err := HTTPClient.Do(req)
-						if err != nil {
-							log.Errorf("PUT request failed: %v", err)
-							return
-						}
-						defer func() {
-							if respBodyErr := res.Body.Close(); respBodyErr != nil {
-								log.Errorf("error closing response body: %v", respBodyErr)
-							}
-						}()
-
-						bodyBytes, _ := io.ReadAll(res.Body)
-						log.Debugf("response status: %d", res.StatusCode)
-						log.Debugf("response body: %s", string(bodyBytes))
-
-						if res.StatusCode == 200 {
-							log.Infof("performance profile updated successfully from %v to %v", fetchedVersion, targetVersion)
-							return
-						}
-						log.Errorf("failed to update performance profile (status=%d): %s", res.StatusCode, targetVersion)
-						return
-					}
-					log.Infof("performance profile version mismatch (fetched: %v, target: %v), update and create not applicable", fetchedVersion, targetVersion)
-					return
-				}
-			}
-
-			// If profile list empty or not found -> create new profile
-			createPerformanceProfileUrl := cfg.KruizeUrl + "/createPerformanceProfile"
-			log.Infof("creating new performance profile...")
-			postBody, err := os.ReadFile("./resource_optimization_openshift.json")
-			if err != nil {
-				log.Errorf("File reading error: %v \n", err)
-				os.Exit(1)
-			}
-			res, e := HTTPClient.Post(createPerformanceProfileUrl, "application/json", bytes.NewBuffer(postBody))
-			if e != nil {
-				log.Errorf("unable to create performance profile in kruize: %v \n", e)
-				continue
-			}
-			defer func() {
-				_ = res.Body.Close()
-			}()
-			if res.StatusCode == 201 {
-				log.Infof("performance profile created successfully")
-				return
-			}
-			if res.StatusCode == 409 {
-				log.Infof("performance profile already exist")
-				return
-			}
-			bodyBytes, _ := io.ReadAll(res.Body)
-			data := map[string]interface{}{}
-			if err := json.Unmarshal(bodyBytes, &data); err != nil {
-				log.Errorf("can not unmarshal response data: %v", err)
-				os.Exit(1)
-			}
-		}
-		log.Infof("sleeping for 10 Seconds")
-		time.Sleep(10 * time.Second)
-	}
-
-}
-
+// ReadCSVFromUrl reads the whole CSV at csvURL into memory. Prefer streaming it with
+// OpenCSVFromUrl and StreamAggregate.
 func ReadCSVFromUrl(csvURL string) ([][]string, error) {
-	// TODO(FLPATH-3407): use a bounded client once we have latency data for CSV downloads
-	parsedCSVURL, _ := url.Parse(csvURL)
-	resp, err := http.Get(parsedCSVURL.String())
+	body, err := OpenCSVFromUrl(csvURL, "")
 	if err != nil {
 		return nil, err
 	}
 	defer func() {
-		_ = resp.Body.Close()
+		_ = body.Close()
 	}()
 
-	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
-		return nil, fmt.Errorf("unexpected status code %d when fetching CSV from %s", resp.StatusCode, csvURL)
-	}
-
-	reader := csv.NewReader(resp.Body)
+	reader := csv.NewReader(body)
 	data, err := reader.ReadAll()
 	if err != nil {
 		return nil, err
@@ -0,0 +1,91 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"syscall"
+)
+
+// ErrDisallowedAddress is returned for webhooks whose host is not publicly routable, so tenants
+// cannot make the notifier call loopback, cluster-internal or metadata service endpoints.
+var ErrDisallowedAddress = errors.New("webhook host is not a public address")
+
+// reservedPrefixes are the non-public ranges netip.Addr has no predicate for.
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"), // NAT64, which embeds IPv4 addresses
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("100::/64"),
+	netip.MustParsePrefix("2001::/32"), // Teredo
+	netip.MustParsePrefix("2001:db8::/32"),
+	netip.MustParsePrefix("2002::/16"), // 6to4
+}
+
+// IsPublicAddress reports whether addr is publicly routable.
+func IsPublicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsUnspecified() || addr.IsLoopback() || addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// allowAddress decides which addresses webhooks may reach; tests swap it to reach httptest servers.
+var allowAddress = IsPublicAddress
+
+// controlDial refuses connections to addresses allowAddress rejects. It sees the address being
+// dialed after DNS resolution, so a host re-pointed to a private address after registration, or a
+// redirect to one, is refused as well.
+func controlDial(_ string, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrDisallowedAddress, address)
+	}
+	if !allowAddress(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrDisallowedAddress, addrPort.Addr())
+	}
+	return nil
+}
+
+// CheckURL resolves the host of rawURL and returns ErrDisallowedAddress when any of its addresses
+// is not public.
+func CheckURL(ctx context.Context, rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid webhook url: %w", err)
+	}
+	host := parsed.Hostname()
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !allowAddress(addr) {
+			return fmt.Errorf("%w: %s", ErrDisallowedAddress, host)
+		}
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("unable to resolve webhook host %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if !allowAddress(addr) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrDisallowedAddress, host, addr)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package webhooks
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	webhookDelivery = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "rosocp_webhook_delivery_total",
+		Help: "The total number of webhook events delivered or given up on after retries",
+	},
+		[]string{"outcome"},
+	)
+	webhookAttempt = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "rosocp_webhook_attempt_total",
+		Help: "The total number of HTTP requests sent to webhooks, retries included",
+	})
+)
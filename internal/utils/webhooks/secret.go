@@ -0,0 +1,70 @@
+package webhooks
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// sealedPrefix marks secrets encrypted by SealSecret; stored secrets without it predate
+// encryption and are returned as they are.
+const sealedPrefix = "enc:v1:"
+
+var ErrNoSecretKey = errors.New("WEBHOOK_SECRET_KEY is not set")
+
+// secretCipher returns the AES-256-GCM cipher keyed with WEBHOOK_SECRET_KEY, the base64 encoding
+// of 32 random bytes.
+func secretCipher() (cipher.AEAD, error) {
+	if cfg.WebhookSecretKey == "" {
+		return nil, ErrNoSecretKey
+	}
+	key, err := base64.StdEncoding.DecodeString(cfg.WebhookSecretKey)
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("WEBHOOK_SECRET_KEY must be the base64 encoding of 32 bytes")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// SealSecret encrypts the signing secret of a webhook of orgID for storage. The org is
+// authenticated with it, so a sealed secret copied to a webhook of another org does not open.
+func SealSecret(orgID, secret string) (string, error) {
+	aead, err := secretCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("unable to generate nonce: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(secret), []byte(orgID))
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// OpenSecret decrypts a secret stored by SealSecret for orgID.
+func OpenSecret(orgID, stored string) (string, error) {
+	encoded, ok := strings.CutPrefix(stored, sealedPrefix)
+	if !ok {
+		return stored, nil
+	}
+	aead, err := secretCipher()
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("malformed webhook secret")
+	}
+	secret, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(orgID))
+	if err != nil {
+		return "", fmt.Errorf("unable to decrypt webhook secret: %w", err)
+	}
+	return string(secret), nil
+}
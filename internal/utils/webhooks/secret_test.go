@@ -0,0 +1,50 @@
+package webhooks
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func useSecretKey(t *testing.T, key string) {
+	t.Helper()
+	original := cfg.WebhookSecretKey
+	cfg.WebhookSecretKey = key
+	t.Cleanup(func() { cfg.WebhookSecretKey = original })
+}
+
+func TestSealSecret(t *testing.T) {
+	useSecretKey(t, base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef")))
+
+	sealed, err := SealSecret("org-1", "0123456789abcdef")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(sealed, sealedPrefix))
+	assert.NotContains(t, sealed, "0123456789abcdef")
+
+	again, err := SealSecret("org-1", "0123456789abcdef")
+	assert.NoError(t, err)
+	assert.NotEqual(t, sealed, again, "every seal uses a new nonce")
+
+	secret, err := OpenSecret("org-1", sealed)
+	assert.NoError(t, err)
+	assert.Equal(t, "0123456789abcdef", secret)
+
+	_, err = OpenSecret("org-2", sealed)
+	assert.Error(t, err, "sealed for another org")
+
+	legacy, err := OpenSecret("org-1", "plaintext-secret")
+	assert.NoError(t, err)
+	assert.Equal(t, "plaintext-secret", legacy)
+}
+
+func TestSealSecret_BadKey(t *testing.T) {
+	useSecretKey(t, "")
+	_, err := SealSecret("org-1", "0123456789abcdef")
+	assert.ErrorIs(t, err, ErrNoSecretKey)
+
+	useSecretKey(t, base64.StdEncoding.EncodeToString([]byte("short")))
+	_, err = SealSecret("org-1", "0123456789abcdef")
+	assert.Error(t, err)
+}
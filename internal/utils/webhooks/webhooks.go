@@ -0,0 +1,171 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/redhatinsights/ros-ocp-backend/internal/config"
+	"github.com/redhatinsights/ros-ocp-backend/internal/logging"
+)
+
+var (
+	log *logrus.Entry  = logging.GetLogger()
+	cfg *config.Config = config.GetConfig()
+
+	httpClient = &http.Client{
+		Timeout: time.Duration(cfg.WebhookTimeoutSecs) * time.Second,
+		// webhooks are dialed directly, without the environment proxy, so controlDial sees
+		// the address of the webhook itself
+		Transport: &http.Transport{
+			DialContext: (&net.Dialer{
+				Timeout:   30 * time.Second,
+				KeepAlive: 30 * time.Second,
+				Control:   controlDial,
+			}).DialContext,
+			ForceAttemptHTTP2:   true,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+			TLSHandshakeTimeout: 10 * time.Second,
+		},
+	}
+)
+
+const (
+	EventRecommendationSaved = "recommendation.saved"
+
+	// SignatureHeader carries "sha256=" followed by the hex HMAC-SHA256 of the body keyed
+	// with the webhook secret.
+	SignatureHeader = "X-Rosocp-Signature-256"
+	EventHeader     = "X-Rosocp-Event"
+)
+
+// Variation is the change a term and engine recommend, in percent of the current request.
+type Variation struct {
+	Term               string   `json:"term"`
+	Engine             string   `json:"engine"`
+	CPUVariationPct    *float64 `json:"cpu_variation_pct"`
+	MemoryVariationPct *float64 `json:"memory_variation_pct"`
+}
+
+// Event is the payload posted to webhooks. Text summarises the event so it can be posted
+// to Slack incoming webhooks as is.
+type Event struct {
+	Event              string      `json:"event"`
+	Text               string      `json:"text"`
+	OrgID              string      `json:"org_id"`
+	RecommendationID   string      `json:"recommendation_id"`
+	RecommendationType string      `json:"recommendation_type"`
+	ClusterUUID        string      `json:"cluster_uuid"`
+	ClusterAlias       string      `json:"cluster_alias"`
+	Namespace          string      `json:"namespace"`
+	Workload           string      `json:"workload,omitempty"`
+	WorkloadType       string      `json:"workload_type,omitempty"`
+	Container          string      `json:"container,omitempty"`
+	MonitoringEndTime  time.Time   `json:"monitoring_end_time"`
+	Variations         []Variation `json:"variations"`
+}
+
+func formatPct(pct *float64) string {
+	if pct == nil {
+		return "n/a"
+	}
+	return strconv.FormatFloat(*pct, 'f', -1, 64) + "%"
+}
+
+func (e Event) summary() string {
+	target := "project " + e.Namespace
+	if e.Container != "" {
+		target = fmt.Sprintf("container %s of %s/%s in project %s", e.Container, e.WorkloadType, e.Workload, e.Namespace)
+	}
+	changes := make([]string, 0, len(e.Variations))
+	for _, v := range e.Variations {
+		changes = append(changes, fmt.Sprintf("%s %s: cpu %s, memory %s", v.Term, v.Engine, formatPct(v.CPUVariationPct), formatPct(v.MemoryVariationPct)))
+	}
+	return fmt.Sprintf("New recommendation for %s on cluster %s (%s)", target, e.ClusterAlias, strings.Join(changes, "; "))
+}
+
+// Sign returns the SignatureHeader value of body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// retryable reports whether a webhook answering with statusCode may accept the event later.
+func retryable(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
+
+// post sends body once and reports whether a failure is worth retrying.
+func post(ctx context.Context, url string, secret string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("unable to create webhook request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, EventRecommendationSaved)
+	req.Header.Set(SignatureHeader, Sign(secret, body))
+
+	webhookAttempt.Inc()
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return !errors.Is(err, ErrDisallowedAddress), fmt.Errorf("error occurred while calling webhook: %w", err)
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+	_, _ = io.Copy(io.Discard, res.Body)
+	if res.StatusCode >= http.StatusOK && res.StatusCode < http.StatusMultipleChoices {
+		return false, nil
+	}
+	return retryable(res.StatusCode), fmt.Errorf("webhook responded with status %d", res.StatusCode)
+}
+
+// Deliver posts the event to url, signed with secret. Network errors, 429 and 5xx answers are
+// retried up to WEBHOOK_MAX_RETRIES times, doubling WEBHOOK_RETRY_BACKOFF_SECS between attempts.
+// It gives up once ctx is done, waiting between attempts included.
+func Deliver(ctx context.Context, url string, secret string, event Event) error {
+	event.Event = EventRecommendationSaved
+	event.Text = event.summary()
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("unable to marshal webhook event: %v", err)
+	}
+
+	backoff := time.Duration(cfg.WebhookRetryBackoffSecs) * time.Second
+	for attempt := 0; ; attempt++ {
+		retry, err := post(ctx, url, secret, body)
+		if err == nil {
+			webhookDelivery.WithLabelValues("delivered").Inc()
+			return nil
+		}
+		if !retry || attempt >= cfg.WebhookMaxRetries || ctx.Err() != nil {
+			webhookDelivery.WithLabelValues("failed").Inc()
+			return err
+		}
+		log.Warnf("webhook delivery of recommendation %s failed, retrying in %s: %v", event.RecommendationID, backoff, err)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			webhookDelivery.WithLabelValues("failed").Inc()
+			return fmt.Errorf("webhook delivery stopped before retrying: %w", ctx.Err())
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
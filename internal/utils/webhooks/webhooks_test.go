@@ -0,0 +1,150 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSign(t *testing.T) {
+	// echo -n 'payload' | openssl dgst -sha256 -hmac 'secret'
+	assert.Equal(t, "sha256=b82fcb791acec57859b989b430a826488ce2e479fdf92326bd0a2e8375a42ba4", Sign("secret", []byte("payload")))
+}
+
+func TestDeliver(t *testing.T) {
+	backoff := cfg.WebhookRetryBackoffSecs
+	cfg.WebhookRetryBackoffSecs = 0
+	defer func() { cfg.WebhookRetryBackoffSecs = backoff }()
+	allowLoopback(t)
+
+	pct := -45.5
+	event := Event{
+		RecommendationID: "rec-1",
+		ClusterAlias:     "prod",
+		Namespace:        "shop",
+		Workload:         "frontend",
+		WorkloadType:     "deployment",
+		Container:        "app",
+		Variations:       []Variation{{Term: "short_term", Engine: "cost", CPUVariationPct: &pct}},
+	}
+
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantErr      bool
+		wantAttempts int32
+	}{
+		{"delivered", []int{http.StatusOK}, false, 1},
+		{"retried after server error", []int{http.StatusBadGateway, http.StatusTooManyRequests, http.StatusNoContent}, false, 3},
+		{"client error is not retried", []int{http.StatusNotFound}, true, 1},
+		{"gives up after max retries", []int{500, 500, 500, 500, 500, 500}, true, int32(cfg.WebhookMaxRetries) + 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempt := attempts.Add(1)
+				body, _ := io.ReadAll(r.Body)
+				assert.Equal(t, Sign("0123456789abcdef", body), r.Header.Get(SignatureHeader))
+				assert.Equal(t, EventRecommendationSaved, r.Header.Get(EventHeader))
+				assert.Contains(t, string(body), `"text":"New recommendation for container app of deployment/frontend in project shop on cluster prod (short_term cost: cpu -45.5%, memory n/a)"`)
+				w.WriteHeader(tt.statuses[attempt-1])
+			}))
+			defer server.Close()
+
+			err := Deliver(context.Background(), server.URL, "0123456789abcdef", event)
+
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.wantAttempts, attempts.Load())
+		})
+	}
+}
+
+// allowLoopback lets webhooks reach httptest servers.
+func allowLoopback(t *testing.T) {
+	t.Helper()
+	allowAddress = func(addr netip.Addr) bool { return addr.IsLoopback() || IsPublicAddress(addr) }
+	t.Cleanup(func() { allowAddress = IsPublicAddress })
+}
+
+func TestDeliver_StopsWaitingWhenContextIsDone(t *testing.T) {
+	backoff := cfg.WebhookRetryBackoffSecs
+	cfg.WebhookRetryBackoffSecs = 3600
+	defer func() { cfg.WebhookRetryBackoffSecs = backoff }()
+	allowLoopback(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		cancel()
+	}))
+	defer server.Close()
+
+	err := Deliver(ctx, server.URL, "0123456789abcdef", Event{})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, int32(1), attempts.Load())
+}
+
+func TestDeliver_PrivateAddressIsRefused(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+	}))
+	defer server.Close()
+
+	err := Deliver(context.Background(), server.URL, "0123456789abcdef", Event{})
+
+	assert.True(t, errors.Is(err, ErrDisallowedAddress), "got %v", err)
+	assert.Equal(t, int32(0), attempts.Load())
+}
+
+func TestIsPublicAddress(t *testing.T) {
+	tests := map[string]bool{
+		"93.184.216.34":    true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"10.1.2.3":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"100.64.0.1":       false,
+		"0.0.0.0":          false,
+		"224.0.0.1":        false,
+		"::1":              false,
+		"fd00::1":          false,
+		"fe80::1":          false,
+		"::ffff:127.0.0.1": false,
+		"64:ff9b::a00:1":   false,
+	}
+	for addr, want := range tests {
+		assert.Equal(t, want, IsPublicAddress(netip.MustParseAddr(addr)), addr)
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{"https://93.184.216.34/hook", false},
+		{"https://[2606:4700::1111]/hook", false},
+		{"https://127.0.0.1/hook", true},
+		{"https://169.254.169.254/latest/meta-data", true},
+		{"https://[::1]:8443/hook", true},
+		{"https://localhost/hook", true},
+	}
+	for _, tt := range tests {
+		err := CheckURL(context.Background(), tt.url)
+		assert.Equal(t, tt.wantErr, err != nil, "%s: %v", tt.url, err)
+	}
+}
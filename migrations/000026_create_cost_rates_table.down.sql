@@ -0,0 +1 @@
+DROP TABLE IF EXISTS cost_rates;
@@ -0,0 +1,14 @@
+-- Rate card used to estimate the monthly cost delta of recommendations.
+-- An empty cluster_uuid holds the org-wide rate; a cluster row overrides it for that cluster.
+CREATE TABLE IF NOT EXISTS cost_rates(
+   id BIGSERIAL PRIMARY KEY,
+   org_id TEXT NOT NULL,
+   cluster_uuid TEXT NOT NULL DEFAULT '',
+   cpu_core_hour_rate NUMERIC(12, 6) NOT NULL,
+   memory_gib_hour_rate NUMERIC(12, 6) NOT NULL,
+   currency TEXT NOT NULL DEFAULT 'USD',
+   updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+ALTER TABLE cost_rates
+ADD CONSTRAINT UQ_Cost_Rate UNIQUE (org_id, cluster_uuid);
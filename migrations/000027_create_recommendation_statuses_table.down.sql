@@ -0,0 +1 @@
+DROP TABLE IF EXISTS recommendation_statuses;
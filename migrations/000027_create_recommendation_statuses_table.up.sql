@@ -0,0 +1,30 @@
+-- User decisions on a container or namespace recommendation. Exactly one of
+-- recommendation_set_id / namespace_recommendation_set_id is set; rows go away with
+-- the recommendation they belong to.
+CREATE TABLE IF NOT EXISTS recommendation_statuses(
+   id BIGSERIAL PRIMARY KEY,
+   org_id TEXT NOT NULL,
+   recommendation_set_id uuid,
+   namespace_recommendation_set_id uuid,
+   status TEXT NOT NULL,
+   snoozed_until TIMESTAMP WITH TIME ZONE,
+   comment TEXT NOT NULL DEFAULT '',
+   username TEXT NOT NULL DEFAULT '',
+   updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+   CONSTRAINT CK_Recommendation_Status_Target CHECK (num_nonnulls(recommendation_set_id, namespace_recommendation_set_id) = 1),
+   CONSTRAINT CK_Recommendation_Status CHECK (status IN ('accepted', 'dismissed', 'snoozed'))
+);
+
+ALTER TABLE recommendation_statuses
+ADD CONSTRAINT fk_recommendation_statuses_recommendation_set FOREIGN KEY (recommendation_set_id) REFERENCES recommendation_sets (id)
+ON DELETE CASCADE;
+
+ALTER TABLE recommendation_statuses
+ADD CONSTRAINT fk_recommendation_statuses_namespace_recommendation_set FOREIGN KEY (namespace_recommendation_set_id) REFERENCES namespace_recommendation_sets (id)
+ON DELETE CASCADE;
+
+ALTER TABLE recommendation_statuses
+ADD CONSTRAINT UQ_Recommendation_Status UNIQUE (recommendation_set_id);
+
+ALTER TABLE recommendation_statuses
+ADD CONSTRAINT UQ_Namespace_Recommendation_Status UNIQUE (namespace_recommendation_set_id);
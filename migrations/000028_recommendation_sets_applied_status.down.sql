@@ -0,0 +1,3 @@
+ALTER TABLE recommendation_sets
+    DROP COLUMN IF EXISTS applied_status,
+    DROP COLUMN IF EXISTS applied_at;
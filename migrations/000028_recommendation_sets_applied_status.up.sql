@@ -0,0 +1,5 @@
+-- Track whether a container recommendation was applied, detected by comparing the requests of
+-- later uploads with the stored recommendation. Empty means no change was detected yet.
+ALTER TABLE recommendation_sets
+    ADD COLUMN applied_status TEXT NOT NULL DEFAULT '',
+    ADD COLUMN applied_at TIMESTAMP WITH TIME ZONE;
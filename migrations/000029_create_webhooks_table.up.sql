@@ -0,0 +1,13 @@
+-- Per-org webhooks notified when the poller saves a recommendation. A recommendation is only
+-- sent when one of its cpu or memory variations reaches min_variation_pct in magnitude.
+CREATE TABLE IF NOT EXISTS webhooks(
+   id BIGSERIAL PRIMARY KEY,
+   org_id TEXT NOT NULL,
+   url TEXT NOT NULL,
+   secret TEXT NOT NULL,
+   min_variation_pct NUMERIC(10, 4) NOT NULL DEFAULT 0,
+   updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+ALTER TABLE webhooks
+ADD CONSTRAINT UQ_Webhook UNIQUE (org_id, url);
@@ -0,0 +1,27 @@
+-- Progress of every file of an upload through the processor, kept so support can tell why a
+-- cluster has no recommendations. A reprocessed file (e.g. a dead-letter replay) overwrites
+-- its row.
+CREATE TABLE IF NOT EXISTS upload_statuses(
+   id BIGSERIAL PRIMARY KEY,
+   org_id TEXT NOT NULL,
+   request_id TEXT NOT NULL,
+   cluster_uuid TEXT NOT NULL,
+   source_id TEXT NOT NULL DEFAULT '',
+   file_name TEXT NOT NULL,
+   status TEXT NOT NULL,
+   received_at TIMESTAMP WITH TIME ZONE NOT NULL,
+   fetched_at TIMESTAMP WITH TIME ZONE,
+   aggregated_at TIMESTAMP WITH TIME ZONE,
+   completed_at TIMESTAMP WITH TIME ZONE,
+   workloads_sent INTEGER NOT NULL DEFAULT 0,
+   workloads_failed INTEGER NOT NULL DEFAULT 0,
+   recommendation_requests INTEGER NOT NULL DEFAULT 0,
+   failures JSONB NOT NULL DEFAULT '[]',
+   updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+   CONSTRAINT CK_Upload_Status CHECK (status IN ('received', 'fetched', 'aggregated', 'processed', 'failed'))
+);
+
+ALTER TABLE upload_statuses
+ADD CONSTRAINT UQ_Upload_Status UNIQUE (org_id, request_id, file_name);
+
+CREATE INDEX IF NOT EXISTS idx_upload_statuses_cluster ON upload_statuses (org_id, cluster_uuid, received_at DESC);
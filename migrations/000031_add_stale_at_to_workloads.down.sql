@@ -0,0 +1 @@
+ALTER TABLE workloads DROP COLUMN stale_at;
@@ -0,0 +1,3 @@
+-- Set by the housekeeper once a workload stops reporting, and cleared by its next upload. Stale
+-- workloads are hidden from the recommendation lists and deleted after a while.
+ALTER TABLE workloads ADD COLUMN stale_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,12 @@
+-- Recommendation requests waiting for their next attempt. The poller republishes a request to the
+-- recommendation topic once it is due, so waiting requests do not hold back the topic.
+CREATE TABLE IF NOT EXISTS recommendation_retries(
+   id BIGSERIAL PRIMARY KEY,
+   message_key TEXT NOT NULL,
+   payload BYTEA NOT NULL,
+   attempt INTEGER NOT NULL,
+   due_at TIMESTAMP WITH TIME ZONE NOT NULL,
+   reason TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_recommendation_retries_due_at ON recommendation_retries (due_at);
@@ -0,0 +1,8 @@
+-- Kruize experiments the reconcile job found without a workload, and since when. The processor
+-- creates the experiment of an upload before saving its workload, so an experiment is only deleted
+-- once it has been orphaned for a grace period.
+CREATE TABLE IF NOT EXISTS orphaned_experiments(
+   experiment_name TEXT PRIMARY KEY,
+   first_seen_at TIMESTAMP WITH TIME ZONE NOT NULL,
+   last_seen_at TIMESTAMP WITH TIME ZONE NOT NULL
+);